@@ -0,0 +1,113 @@
+//go:build cshared
+
+package main
+
+import "C"
+
+import (
+	"strconv"
+	"sync"
+	"unsafe"
+
+	"github.com/iStorm30/PCDTA2/pcdta"
+)
+
+// Fachada C del motor de inferencia para llamarlo desde Python, C++ o Java. Predice con
+// pcdta.Model, así que aplica las mismas codificaciones, features derivadas y nombres
+// de clase que la CLI. Compilar con:
+//
+//	go build -tags cshared -buildmode=c-shared -o libpcdta.so DecisionTreeCShared.go
+//
+// Uso desde C, con los valores en el orden de las columnas de entrada del modelo:
+//
+//	int h = Load("modelo.json");
+//	char class[64];
+//	Predict(h, values, 4, class, sizeof class);
+//	Free(h);
+//
+// Los modelos con columnas categóricas se llaman con PredictRecord, que recibe cada
+// valor como texto.
+
+// Códigos de error devueltos a C
+const (
+	errLoad           = -1
+	errBadHandle      = -2
+	errFeatures       = -3
+	errBufferTooSmall = -4
+)
+
+var (
+	mu         sync.Mutex
+	models     = make(map[C.int]*pcdta.Model[string])
+	nextHandle C.int
+)
+
+func main() {}
+
+//export Load
+func Load(path *C.char) C.int {
+	m, err := pcdta.LoadModel[string](C.GoString(path))
+	if err != nil {
+		return errLoad
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	nextHandle++
+	models[nextHandle] = m
+	return nextHandle
+}
+
+// Predict escribe la clase predicha, terminada en NUL, en out y devuelve su longitud.
+// NaN es un valor ausente.
+//
+//export Predict
+func Predict(handle C.int, values *C.double, n C.int, out *C.char, outSize C.int) C.int {
+	record := make([]string, int(n))
+	for i, value := range unsafe.Slice((*float64)(unsafe.Pointer(values)), int(n)) {
+		record[i] = strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	return predict(handle, record, out, outSize)
+}
+
+// PredictRecord es Predict con los n valores como cadenas C; un valor vacío, NA o ? es
+// ausente
+//
+//export PredictRecord
+func PredictRecord(handle C.int, fields **C.char, n C.int, out *C.char, outSize C.int) C.int {
+	record := make([]string, int(n))
+	for i, field := range unsafe.Slice(fields, int(n)) {
+		record[i] = C.GoString(field)
+	}
+	return predict(handle, record, out, outSize)
+}
+
+func predict(handle C.int, record []string, out *C.char, outSize C.int) C.int {
+	mu.Lock()
+	m, ok := models[handle]
+	mu.Unlock()
+	if !ok {
+		return errBadHandle
+	}
+
+	class, err := m.Predict(record)
+	if err != nil {
+		return errFeatures
+	}
+
+	if len(class)+1 > int(outSize) {
+		return errBufferTooSmall
+	}
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(out)), int(outSize))
+	copy(buf, class)
+	buf[len(class)] = 0
+
+	return C.int(len(class))
+}
+
+//export Free
+func Free(handle C.int) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(models, handle)
+}
@@ -1,250 +1,2011 @@
-package main
-
-import (
-	"fmt"
-	"math"
-	"math/rand"
-	"sort"
-	"sync"
-	"time"
-)
-
-type DecisionTree struct {
-	Left   *DecisionTree
-	Right  *DecisionTree
-	Column int
-	Value  float64
-	Class  string
-}
-
-type Example struct {
-	Features []float64
-	Class    string
-}
-
-func main() {
-	rand.Seed(time.Now().UnixNano())
-
-	// Generar datos de ejemplo
-	numExamples := 100000
-	examples := make([]Example, numExamples)
-	for i := 0; i < numExamples; i++ {
-		features := make([]float64, 4) // 4 features para IRIS dataset
-		for j := range features {
-			features[j] = rand.Float64() * 10 // Números aleatorios entre 0 y 10
-		}
-		class := "ClassA"
-		if i%2 == 0 {
-			class = "ClassB"
-		}
-		examples[i] = Example{
-			Features: features,
-			Class:    class,
-		}
-	}
-
-	// Medir tiempo de entrenamiento
-	startTime := time.Now()
-
-	// Construir árbol de decisión concurrentemente
-	tree := BuildDecisionTreeConcurrent(examples, 0)
-
-	// Medir tiempo después del entrenamiento
-	elapsed := time.Since(startTime)
-
-	// Imprimir el árbol de decisión
-	PrintDecisionTree(tree, 0)
-
-	// Imprimir tiempo de entrenamiento
-	fmt.Println("Tiempo de entrenamiento:", elapsed)
-}
-
-func BuildDecisionTreeConcurrent(examples []Example, depth int) *DecisionTree {
-	// Si no hay ejemplos o se alcanza la profundidad máxima, devuelve un nodo hoja con la clase mayoritaria
-	if len(examples) == 0 || depth >= 3 {
-		return &DecisionTree{
-			Class: MajorityClass(examples),
-		}
-	}
-
-	// Encontrar la mejor división de forma concurrente
-	bestSplit := FindBestSplitConcurrent(examples)
-
-	// Si no se encuentra la mejor división, devuelve un nodo hoja con la clase mayoritaria
-	if bestSplit == nil {
-		return &DecisionTree{
-			Class: MajorityClass(examples),
-		}
-	}
-
-	// Dividir ejemplos
-	var leftExamples, rightExamples []Example
-	for _, example := range examples {
-		if example.Features[bestSplit.Column] <= bestSplit.Value {
-			leftExamples = append(leftExamples, example)
-		} else {
-			rightExamples = append(rightExamples, example)
-		}
-	}
-
-	// Construir recursivamente los subárboles izquierdo y derecho de forma concurrente
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	var left *DecisionTree
-	var right *DecisionTree
-
-	go func() {
-		left = BuildDecisionTreeConcurrent(leftExamples, depth+1)
-		wg.Done()
-	}()
-
-	go func() {
-		right = BuildDecisionTreeConcurrent(rightExamples, depth+1)
-		wg.Done()
-	}()
-
-	wg.Wait()
-
-	return &DecisionTree{
-		Left:   left,
-		Right:  right,
-		Column: bestSplit.Column,
-		Value:  bestSplit.Value,
-	}
-}
-
-func FindBestSplitConcurrent(examples []Example) *DecisionTree {
-	if len(examples) == 0 {
-		return nil
-	}
-
-	numFeatures := len(examples[0].Features)
-	bestGini := math.Inf(1)
-	var bestSplit *DecisionTree
-
-	type SplitResult struct {
-		Split *DecisionTree
-		Gini  float64
-	}
-
-	results := make(chan SplitResult)
-
-	for col := 0; col < numFeatures; col++ {
-		go func(col int) {
-			// Ordenar ejemplos por valor de característica
-			sort.Slice(examples, func(i, j int) bool {
-				return examples[i].Features[col] < examples[j].Features[col]
-			})
-
-			for i := 1; i < len(examples); i++ {
-				// Probar división en punto medio
-				value := (examples[i-1].Features[col] + examples[i].Features[col]) / 2.0
-
-				// Dividir ejemplos
-				var leftCount, rightCount int
-				var leftClasses, rightClasses map[string]int
-				leftClasses = make(map[string]int)
-				rightClasses = make(map[string]int)
-
-				for _, example := range examples {
-					if example.Features[col] <= value {
-						leftCount++
-						leftClasses[example.Class]++
-					} else {
-						rightCount++
-						rightClasses[example.Class]++
-					}
-				}
-
-				// Calcular impureza de Gini
-				gini := CalculateGini(leftClasses, rightClasses, leftCount, rightCount)
-
-				// Actualizar mejor división si es mejor
-				if gini < bestGini {
-					bestGini = gini
-					bestSplit = &DecisionTree{
-						Column: col,
-						Value:  value,
-					}
-				}
-			}
-
-			results <- SplitResult{Split: bestSplit, Gini: bestGini}
-		}(col)
-	}
-
-	// Obtener resultado de la goroutine más rápida
-	for i := 0; i < numFeatures; i++ {
-		result := <-results
-		if result.Gini < bestGini {
-			bestGini = result.Gini
-			bestSplit = result.Split
-		}
-	}
-
-	return bestSplit
-}
-
-func CalculateGini(leftClasses, rightClasses map[string]int, leftCount, rightCount int) float64 {
-	total := float64(leftCount + rightCount)
-	giniLeft := GiniImpurity(leftClasses, leftCount)
-	giniRight := GiniImpurity(rightClasses, rightCount)
-	gini := (float64(leftCount)/total)*giniLeft + (float64(rightCount)/total)*giniRight
-	return gini
-}
-
-func GiniImpurity(classCounts map[string]int, totalCount int) float64 {
-	if totalCount == 0 {
-		return 0.0
-	}
-
-	var impurity float64
-	for _, count := range classCounts {
-		prob := float64(count) / float64(totalCount)
-		impurity += prob * (1 - prob)
-	}
-
-	return impurity
-}
-
-func MajorityClass(examples []Example) string {
-	classCounts := make(map[string]int)
-	for _, example := range examples {
-		classCounts[example.Class]++
-	}
-
-	maxCount := 0
-	var majorityClass string
-	for class, count := range classCounts {
-		if count > maxCount {
-			maxCount = count
-			majorityClass = class
-		}
-	}
-
-	return majorityClass
-}
-
-func PrintDecisionTree(tree *DecisionTree, indent int) {
-	if tree == nil {
-		return
-	}
-
-	for i := 0; i < indent; i++ {
-		fmt.Print("  ")
-	}
-
-	if tree.Left == nil && tree.Right == nil {
-		fmt.Println("Class:", tree.Class)
-	} else {
-		fmt.Printf("Feature %d <= %.2f\n", tree.Column, tree.Value)
-		PrintDecisionTree(tree.Left, indent+1)
-
-		for i := 0; i < indent; i++ {
-			fmt.Print("  ")
-		}
-		fmt.Println("else")
-		PrintDecisionTree(tree.Right, indent+1)
-	}
-}
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iStorm30/PCDTA2/client"
+	"github.com/iStorm30/PCDTA2/experiments"
+	"github.com/iStorm30/PCDTA2/pcdta"
+	"github.com/iStorm30/PCDTA2/registry"
+)
+
+func main() {
+	// Subcomando: diff modelo_v1.json modelo_v2.json
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if len(os.Args) != 4 {
+			log.Fatal("uso: diff modelo_v1.json modelo_v2.json")
+		}
+		runDiff(os.Args[2], os.Args[3])
+		return
+	}
+
+	// Subcomando: score -model m.json -data nuevos.csv -out predicciones.csv
+	if len(os.Args) > 1 && os.Args[1] == "score" {
+		if err := runScore(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Subcomando: fairness -model m.json -data validacion.csv -sensitive columna
+	if len(os.Args) > 1 && os.Args[1] == "fairness" {
+		runFairness(os.Args[2:])
+		return
+	}
+
+	// Subcomando: segments -model m.json -data validacion.csv -by columna
+	if len(os.Args) > 1 && os.Args[1] == "segments" {
+		runSegments(os.Args[2:])
+		return
+	}
+
+	// Subcomando: drift -model m.json -reference ref.csv -current recientes.csv
+	if len(os.Args) > 1 && os.Args[1] == "drift" {
+		runDrift(os.Args[2:])
+		return
+	}
+
+	// Subcomando: robustness -model m.json -data datos.csv -eps 0.1
+	if len(os.Args) > 1 && os.Args[1] == "robustness" {
+		runRobustness(os.Args[2:])
+		return
+	}
+
+	// Subcomando: stress -data datos.csv -depths 2,3,5
+	if len(os.Args) > 1 && os.Args[1] == "stress" {
+		runStress(os.Args[2:])
+		return
+	}
+
+	// Subcomando: nested-cv -data datos.csv -depths 1,2,3
+	if len(os.Args) > 1 && os.Args[1] == "nested-cv" {
+		runNestedCV(os.Args[2:])
+		return
+	}
+
+	// Subcomando: backtest -data datos.csv -time columna -horizon 30
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		runBacktest(os.Args[2:])
+		return
+	}
+
+	// Subcomando: compare -a m1.json -b m2.json -data prueba.csv
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		runCompare(os.Args[2:])
+		return
+	}
+
+	// Subcomando: train-sparse -data datos.svm -o modelo.json
+	if len(os.Args) > 1 && os.Args[1] == "train-sparse" {
+		runTrainSparse(os.Args[2:])
+		return
+	}
+
+	// Subcomando: score-sparse -model modelo.json -data nuevos.svm
+	if len(os.Args) > 1 && os.Args[1] == "score-sparse" {
+		runScoreSparse(os.Args[2:])
+		return
+	}
+
+	// Subcomando: rules -model m.json [-data datos.csv]
+	if len(os.Args) > 1 && os.Args[1] == "rules" {
+		runRules(os.Args[2:])
+		return
+	}
+
+	// Subcomando: export-policy modelo.json politica.json
+	if len(os.Args) > 1 && os.Args[1] == "export-policy" {
+		if len(os.Args) != 4 {
+			log.Fatal("uso: export-policy modelo.json politica.json")
+		}
+		runExportPolicy(os.Args[2], os.Args[3])
+		return
+	}
+
+	// Subcomando: export-code -model m.json -lang c|java|go
+	if len(os.Args) > 1 && os.Args[1] == "export-code" {
+		runExportCode(os.Args[2:])
+		return
+	}
+
+	// Subcomando: export-fixed -model m.json -bits 16
+	if len(os.Args) > 1 && os.Args[1] == "export-fixed" {
+		runExportFixed(os.Args[2:])
+		return
+	}
+
+	// Subcomando: importance -data datos.csv -bootstrap 30
+	if len(os.Args) > 1 && os.Args[1] == "importance" {
+		runImportance(os.Args[2:])
+		return
+	}
+
+	// Subcomando: interactions -model m.json -data datos.csv
+	if len(os.Args) > 1 && os.Args[1] == "interactions" {
+		runInteractions(os.Args[2:])
+		return
+	}
+
+	// Subcomando: surrogate -data datos.csv -teacher ovo -depth 3
+	if len(os.Args) > 1 && os.Args[1] == "surrogate" {
+		runSurrogate(os.Args[2:])
+		return
+	}
+
+	// Subcomando: distill -data datos.csv -teacher ovo -depth 3
+	if len(os.Args) > 1 && os.Args[1] == "distill" {
+		runDistill(os.Args[2:])
+		return
+	}
+
+	// Subcomando: import-sklearn arbol_sklearn.json modelo.json (un bosque se guarda como Forest)
+	if len(os.Args) > 1 && os.Args[1] == "import-sklearn" {
+		if len(os.Args) != 4 {
+			log.Fatal("uso: import-sklearn arbol_sklearn.json modelo.json")
+		}
+		runImportSklearn(os.Args[2], os.Args[3])
+		return
+	}
+
+	// Subcomando: describe datos.csv
+	if len(os.Args) > 1 && os.Args[1] == "describe" {
+		if len(os.Args) != 3 {
+			log.Fatal("uso: describe datos.csv")
+		}
+		runDescribe(os.Args[2])
+		return
+	}
+
+	// Subcomando: convert datos.csv datos.pcd
+	if len(os.Args) > 1 && os.Args[1] == "convert" {
+		if len(os.Args) != 4 {
+			log.Fatal("uso: convert datos.csv datos.pcd")
+		}
+		runConvert(os.Args[2], os.Args[3])
+		return
+	}
+
+	// Subcomando: experiments list|export-mlflow
+	if len(os.Args) > 1 && os.Args[1] == "experiments" {
+		runExperiments(os.Args[2:])
+		return
+	}
+
+	// Subcomando: models list|push|pull
+	if len(os.Args) > 1 && os.Args[1] == "models" {
+		runModels(os.Args[2:])
+		return
+	}
+
+	output := flag.String("o", "", "guardar el modelo entrenado en este archivo JSON")
+	experiment := flag.String("experiment", "", "registrar la ejecución en este experimento")
+	experimentsDir := flag.String("experiments-dir", "experimentos", "directorio donde se guardan los experimentos")
+	dataFile := flag.String("data", "", "entrenar con este CSV o .pcd en lugar de datos generados")
+	dropSuspicious := flag.Bool("drop-suspicious", false, "excluir columnas tipo ID o que filtran la clase")
+	resample := flag.String("resample", "", "reequilibrar clases antes de entrenar: over, under o smote")
+	classWeight := flag.String("class-weight", "", "balanced: ponderar los ejemplos para que todas las clases pesen lo mismo")
+	reportFile := flag.String("report", "", "escribir el informe de entrenamiento en este archivo HTML")
+	skipValidation := flag.Bool("skip-validation", false, "entrenar aunque la validación de datos encuentre errores")
+	weightColumn := flag.String("weight-column", "", "usar esta columna como peso de cada ejemplo en lugar de como feature")
+	multiclass := flag.String("multiclass", "", "entrenar además un meta-clasificador binario: ovr (uno contra el resto) u ovo (uno contra uno)")
+	ordinal := flag.String("ordinal", "", "clases ordenadas separadas por comas (p. ej. bajo,medio,alto) para entrenar además un modelo ordinal")
+	forbid := flag.String("forbid", "", "features separadas por comas que nunca se usan para dividir")
+	rootFeature := flag.String("root-feature", "", "forzar la división de la raíz sobre esta feature")
+	features := flag.String("features", "", "entrenar solo con estas features, por nombre o índice desde 0, separadas por comas")
+	aggregate := flag.Bool("aggregate-duplicates", false, "fundir filas repetidas en ejemplos ponderados antes de entrenar")
+	knnFallback := flag.Int("knn-fallback", 0, "evaluar un respaldo k-NN dentro de las hojas poco seguras con este k (0 = no)")
+	knnConfidence := flag.Float64("knn-confidence", 0.8, "probabilidad máxima de hoja por debajo de la cual se usa el respaldo k-NN")
+	seed := flag.Int64("seed", 0, "semilla raíz de los generadores aleatorios (0 = según la hora)")
+	costsFile := flag.String("costs", "", `JSON con la matriz de costes {"clase real": {"clase predicha": coste}}`)
+	callback := flag.String("callback", "", "URL a la que enviar por POST un resumen JSON al terminar o fallar el entrenamiento")
+	opts := pcdta.DefaultTrainOptions[string]()
+	flag.IntVar(&opts.MaxDepth, "depth", opts.MaxDepth, "profundidad máxima del árbol")
+	flag.IntVar(&opts.NumWorkers, "workers", opts.NumWorkers, "número máximo de goroutines de entrenamiento")
+	flag.Float64Var(&opts.Smoothing, "smoothing", opts.Smoothing, "constante de suavizado de probabilidades en las hojas (0 = sin suavizado)")
+	flag.StringVar(&opts.SmoothingMethod, "smoothing-method", opts.SmoothingMethod, "suavizado: laplace o m-estimate")
+	flag.Float64Var(&opts.MinFeatureVariance, "min-feature-variance", opts.MinFeatureVariance, "omitir features con varianza menor o igual (0 = solo las constantes)")
+	flag.StringVar(&opts.TieBreak, "tie-break", opts.TieBreak, "desempate entre divisiones iguales: lowest o balanced")
+	memoryBudget := flag.Uint64("memory-budget", 0, "límite en MiB de la memoria estimada del entrenamiento; se reduce la muestra o la profundidad para no pasarlo (0 = sin límite)")
+	checkpointFile := flag.String("checkpoint", "", "construir el árbol con puntos de control en este archivo y reanudar desde él si existe")
+	checkpointEvery := flag.Duration("checkpoint-every", time.Minute, "intervalo entre puntos de control")
+	imbalanceWarn := flag.Float64("imbalance-warn", 5, "avisar si la clase mayoritaria pesa más de estas veces la minoritaria (0 = no avisar)")
+	manifestFile := flag.String("manifest", "", "escribir en este archivo JSON el manifiesto para reproducir el entrenamiento")
+	binMethod := flag.String("bin", "", "discretizar las features antes de entrenar: equal-width, equal-frequency o mdlp")
+	numBins := flag.Int("bins", 10, "número de intervalos de -bin equal-width y equal-frequency")
+	targetEncode := flag.String("target-encode", "", "columnas categóricas del CSV, separadas por comas, que se codifican con la proporción de cada clase")
+	targetFolds := flag.Int("target-folds", 5, "pliegues de la codificación fuera de pliegue de -target-encode")
+	targetSmoothing := flag.Float64("target-smoothing", 10, "filas equivalentes de la proporción global con que se suaviza cada categoría")
+	categorical := flag.String("categorical", "", "auto: detectar las columnas no numéricas del CSV y codificarlas según su cardinalidad")
+	policy := pcdta.DefaultCategoricalPolicy()
+	flag.IntVar(&policy.MaxOneHot, "onehot-max", policy.MaxOneHot, "con -categorical auto, one-hot hasta este número de categorías")
+	flag.StringVar(&policy.HighCardinality, "high-cardinality", policy.HighCardinality, "con -categorical auto, codificación por encima de -onehot-max: target o hash")
+	flag.IntVar(&policy.HashBuckets, "hash-buckets", policy.HashBuckets, "features de cada columna codificada con hash")
+	appendFiles := flag.String("append", "", "CSV o .pcd con las mismas columnas que -data, separados por comas, cuyas filas se añaden")
+	joinFile := flag.String("join", "", "CSV con cabecera y sin clase cuyas columnas se unen a -data y a -append por la clave -on")
+	joinOn := flag.String("on", "", "columna clave de -join, numérica o de texto; no se usa como feature")
+	derive := flag.String("derive", "", "features derivadas nombre=expresión separadas por ';' (p. ej. ratio=petal_length/petal_width)")
+	where := flag.String("where", "", "entrenar solo con las filas de -data y -append que cumplen esta expresión sobre sus columnas sin codificar y las de -derive (p. ej. \"sepal_length > 5 && region == 'EU'\")")
+	renameClasses := flag.String("rename-classes", "", "renombrar o fundir clases antes de entrenar: origen=destino separados por comas")
+	mergeRare := flag.Int("merge-rare", 0, "fundir en -other-class las clases con menos ejemplos que este (0 = no)")
+	oneVsRest := flag.String("one-vs-rest", "", "entrenar esta clase frente a todas las demás, que pasan a -other-class")
+	otherClass := flag.String("other-class", "other", "clase que recibe las clases fundidas por -merge-rare y -one-vs-rest")
+	if err := ParseLayered(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	opts.MemoryBudget = *memoryBudget << 20
+
+	// Con -callback, los fallos del entrenamiento también se notifican antes de salir
+	notify := func(summary client.TrainSummary) {
+		if *callback == "" {
+			return
+		}
+		summary.FinishedAt = time.Now().UTC()
+		if err := client.NotifyCallback(*callback, summary); err != nil {
+			fmt.Fprintln(os.Stderr, "No se pudo avisar al callback:", err)
+		}
+	}
+	// Archivos temporales de -join, que también se borran si el entrenamiento falla
+	var joinedFiles []string
+	removeJoined := func() {
+		for _, file := range joinedFiles {
+			os.Remove(file)
+		}
+	}
+	fatalf := func(format string, args ...any) {
+		notify(client.TrainSummary{Status: "error", Error: fmt.Sprintf(format, args...)})
+		removeJoined()
+		log.Fatalf(format, args...)
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	streams := pcdta.SeedStreams{Root: *seed}
+
+	// Las features derivadas se crean tras cargar y unir los datos, pero -where puede
+	// usarlas al filtrar cada archivo
+	var derived []pcdta.DerivedFeature
+	if *derive != "" {
+		for _, spec := range strings.Split(*derive, ";") {
+			name, expr, ok := strings.Cut(spec, "=")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" {
+				fatalf("-derive: se esperaba nombre=expresión, no %q", spec)
+			}
+			derived = append(derived, pcdta.DerivedFeature{Name: name, Expr: strings.TrimSpace(expr)})
+		}
+	}
+
+	// -where se evalúa sobre cada archivo tal como está escrito, antes de codificar las
+	// columnas categóricas, para que pueda comparar sus valores de texto
+	filtered := [2]int{}
+	whereFile := func(file string) []bool {
+		if *where == "" {
+			return nil
+		}
+		keep, err := pcdta.WhereFile(file, *where, derived)
+		if err != nil {
+			fatalf("-where: %v", err)
+		}
+		for _, k := range keep {
+			filtered[0]++
+			if k {
+				filtered[1]++
+			}
+		}
+		return keep
+	}
+
+	// -join se resuelve sobre las filas tal como están escritas, antes de cargarlas, para
+	// que la clave pueda ser un identificador de texto y las columnas unidas pasen por
+	// -where, -categorical y la validación como las demás
+	if *joinFile != "" && *joinOn == "" {
+		fatalf("-join necesita la columna clave -on")
+	}
+	defer removeJoined()
+	joined := func(file string) string {
+		if *joinFile == "" {
+			return file
+		}
+		// El nombre conserva el del archivo para que los errores al cargarlo se entiendan
+		base := filepath.Base(file)
+		out, err := os.CreateTemp("", "pcdta-join-*-"+strings.TrimSuffix(base, filepath.Ext(base))+".csv")
+		if err != nil {
+			fatalf("%v", err)
+		}
+		defer out.Close()
+		joinedFiles = append(joinedFiles, out.Name())
+		rows, matched, err := pcdta.JoinFiles(out, file, *joinFile, *joinOn)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		fmt.Printf("Unión de %s con %s por %s: %d -> %d filas\n", file, *joinFile, *joinOn, rows, matched)
+		return out.Name()
+	}
+	trainFile := *dataFile
+	if trainFile != "" {
+		trainFile = joined(trainFile)
+	}
+
+	// Generar datos de ejemplo o cargarlos del archivo indicado
+	loadStart := time.Now()
+	var examples []pcdta.Example[string]
+	var encodings []pcdta.ColumnEncoding
+	featureNames := []string{"sepal_length", "sepal_width", "petal_length", "petal_width"}
+	if *dataFile != "" && (*targetEncode != "" || *categorical != "") {
+		if *targetFolds < 2 {
+			fatalf("-target-folds debe ser al menos 2")
+		}
+		var columns []string
+		var raw [][]string
+		var err error
+		switch {
+		case *targetEncode != "" && *categorical != "":
+			fatalf("-target-encode y -categorical son excluyentes")
+		case *targetEncode != "":
+			columns = strings.Split(*targetEncode, ",")
+			policy = pcdta.CategoricalPolicy{HighCardinality: "target"}
+			examples, featureNames, raw, err = pcdta.LoadCSVCategorical(trainFile, columns)
+		case *categorical == "auto":
+			if policy.HighCardinality != "target" && policy.HighCardinality != "hash" {
+				fatalf("codificación de alta cardinalidad desconocida %q (target o hash)", policy.HighCardinality)
+			}
+			if policy.HashBuckets < 1 {
+				fatalf("-hash-buckets debe ser al menos 1")
+			}
+			examples, featureNames, columns, raw, err = pcdta.LoadCSVDetectCategorical(trainFile)
+		default:
+			fatalf("política categórica desconocida %q (auto)", *categorical)
+		}
+		if err != nil {
+			fatalf("%v", err)
+		}
+		if keep := whereFile(trainFile); keep != nil {
+			examples = pcdta.Filter(examples, keep)
+			for c := range raw {
+				raw[c] = pcdta.Filter(raw[c], keep)
+			}
+		}
+		policy.TargetSmoothing = *targetSmoothing
+		folds, err := pcdta.KFolds(len(examples), *targetFolds, streams.Stream(pcdta.StreamFolds))
+		if err != nil {
+			fatalf("-target-folds: %v", err)
+		}
+		examples, featureNames, encodings = pcdta.EncodeCategorical(examples, featureNames, columns, raw, folds, policy)
+		for _, encoding := range encodings {
+			fmt.Printf("Columna categórica %s: %d categorías, codificación %s (%d features)\n",
+				encoding.Column, encoding.Cardinality, encoding.Method, len(encoding.Features))
+		}
+	} else if *dataFile != "" {
+		var err error
+		examples, featureNames, err = pcdta.LoadExamples(trainFile)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		if keep := whereFile(trainFile); keep != nil {
+			examples = pcdta.Filter(examples, keep)
+		}
+	} else {
+		examples = pcdta.GenerateExamples(100000, streams.Stream(pcdta.StreamGenerate))
+	}
+	loadTime := time.Since(loadStart)
+
+	// Sacar la columna de pesos de las features si se indicó
+	dataset := &pcdta.Dataset[string]{FeatureNames: featureNames, Examples: examples}
+	if *weightColumn != "" {
+		var err error
+		if dataset, err = dataset.WeightsFromColumn(*weightColumn); err != nil {
+			fatalf("%v", err)
+		}
+		examples, featureNames = dataset.Examples, dataset.FeatureNames
+	}
+
+	// Añadir filas de otros archivos (ya unidos con -join)
+	if *appendFiles != "" {
+		datasets := []*pcdta.Dataset[string]{dataset}
+		for _, file := range strings.Split(*appendFiles, ",") {
+			file = joined(file)
+			more, names, err := pcdta.LoadExamples(file)
+			if err != nil {
+				fatalf("%v", err)
+			}
+			if keep := whereFile(file); keep != nil {
+				more = pcdta.Filter(more, keep)
+			}
+			datasets = append(datasets, &pcdta.Dataset[string]{FeatureNames: names, Examples: more})
+		}
+		var err error
+		if dataset, err = pcdta.Concat(datasets...); err != nil {
+			fatalf("%v", err)
+		}
+		examples = dataset.Examples
+	}
+	// Crear las features derivadas antes de cualquier otra preparación
+	for _, feature := range derived {
+		var err error
+		if dataset, err = dataset.Derive(feature.Name, feature.Expr); err != nil {
+			fatalf("%v", err)
+		}
+		examples, featureNames = dataset.Examples, dataset.FeatureNames
+	}
+	if *where != "" && *dataFile == "" {
+		// Los datos generados no tienen archivo; se filtran ya como números
+		filtered[0] = len(dataset.Examples)
+		var err error
+		if dataset, err = dataset.Where(*where); err != nil {
+			fatalf("%v", err)
+		}
+		examples = dataset.Examples
+		filtered[1] = len(examples)
+	}
+	if *where != "" {
+		fmt.Printf("Filtro %s: %d -> %d filas\n", *where, filtered[0], filtered[1])
+	}
+
+	// Renombrar, fundir o binarizar las clases; la correspondencia se guarda en el modelo
+	// para que al puntuar se predigan las clases originales
+	var classMapping map[string]string
+	if *renameClasses != "" {
+		mapping := make(map[string]string)
+		for _, pair := range strings.Split(*renameClasses, ",") {
+			from, to, ok := strings.Cut(pair, "=")
+			if !ok {
+				fatalf("-rename-classes: se esperaba origen=destino, no %q", pair)
+			}
+			mapping[from] = to
+		}
+		var step map[string]string
+		dataset, step = dataset.MapClasses(mapping)
+		classMapping = pcdta.ComposeClassMappings(classMapping, step)
+	}
+	if *mergeRare > 0 {
+		var step map[string]string
+		dataset, step = dataset.MergeRareClasses(*mergeRare, *otherClass)
+		classMapping = pcdta.ComposeClassMappings(classMapping, step)
+	}
+	if *oneVsRest != "" {
+		var step map[string]string
+		dataset, step = dataset.OneVsRest(*oneVsRest, *otherClass)
+		classMapping = pcdta.ComposeClassMappings(classMapping, step)
+	}
+	if classMapping != nil {
+		examples = dataset.Examples
+		changed := false
+		for from, to := range classMapping {
+			changed = changed || from != to
+		}
+		if !changed {
+			classMapping = nil
+		}
+	}
+
+	// Detectar columnas tipo ID o casi idénticas a la clase y opcionalmente excluirlas
+	suspicious := dataset.SuspiciousColumns(pcdta.DefaultSuspicionOptions())
+	for _, column := range suspicious {
+		fmt.Println("Columna sospechosa:", column)
+	}
+	if *dropSuspicious && len(suspicious) > 0 {
+		columns := make([]int, len(suspicious))
+		for i, column := range suspicious {
+			columns[i] = column.Column
+		}
+		dataset = dataset.DropColumns(columns...)
+		examples, featureNames = dataset.Examples, dataset.FeatureNames
+		fmt.Println("Columnas excluidas del entrenamiento:", len(columns))
+	}
+
+	// Validar los datos antes de entrenar
+	validation := dataset.Validate(pcdta.DefaultValidationOptions())
+	if len(validation.Issues) > 0 {
+		fmt.Print(validation)
+	}
+	if validation.HasErrors() && !*skipValidation {
+		fatalf("los datos no superan la validación (usar -skip-validation para entrenar igualmente)")
+	}
+
+	// Resolver las restricciones de features por nombre o, si no hay ninguna con ese
+	// nombre, por índice
+	featureIndex := func(name string) int {
+		for j, featureName := range featureNames {
+			if featureName == name {
+				return j
+			}
+		}
+		if j, err := strconv.Atoi(name); err == nil && j >= 0 && j < len(featureNames) {
+			return j
+		}
+		fatalf("no existe la feature %q", name)
+		return -1
+	}
+	var selectedFeatures []string
+	if *features != "" {
+		for _, name := range strings.Split(*features, ",") {
+			j := featureIndex(name)
+			opts.Features = append(opts.Features, j)
+			selectedFeatures = append(selectedFeatures, featureNames[j])
+		}
+	}
+	if *forbid != "" {
+		for _, name := range strings.Split(*forbid, ",") {
+			opts.ForbiddenFeatures = append(opts.ForbiddenFeatures, featureIndex(name))
+		}
+	}
+	if *rootFeature != "" {
+		root := featureIndex(*rootFeature)
+		opts.RootFeature = &root
+		for _, forbidden := range opts.ForbiddenFeatures {
+			if forbidden == root {
+				fatalf("la feature %q no puede ser a la vez raíz forzada y prohibida", *rootFeature)
+			}
+		}
+		if len(opts.Features) > 0 && !slices.Contains(opts.Features, root) {
+			fatalf("la raíz forzada %q no está entre las features seleccionadas", *rootFeature)
+		}
+	}
+
+	// Cargar la matriz de costes de error si se indicó
+	if *costsFile != "" {
+		data, err := os.ReadFile(*costsFile)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		if err := json.Unmarshal(data, &opts.CostMatrix); err != nil {
+			fatalf("%s: %v", *costsFile, err)
+		}
+	}
+
+	if opts.SmoothingMethod != "laplace" && opts.SmoothingMethod != "m-estimate" {
+		fatalf("método de suavizado desconocido %q (laplace o m-estimate)", opts.SmoothingMethod)
+	}
+
+	if opts.TieBreak != "lowest" && opts.TieBreak != "balanced" {
+		fatalf("desempate desconocido %q (lowest o balanced)", opts.TieBreak)
+	}
+
+	// Fundir filas duplicadas en ejemplos ponderados
+	if *aggregate {
+		before := len(examples)
+		dataset = dataset.AggregateDuplicates()
+		examples = dataset.Examples
+		fmt.Printf("Filas duplicadas agregadas: %d -> %d ejemplos\n", before, len(examples))
+	}
+
+	// El desequilibrio se mide antes de corregirlo
+	classShares, imbalance := pcdta.ClassBalance(examples)
+
+	// Reequilibrar las clases si se pidió
+	switch *classWeight {
+	case "":
+	case "balanced":
+		if *resample != "" {
+			fatalf("-class-weight y -resample son alternativas; use solo una")
+		}
+		dataset = dataset.BalanceClassWeights()
+		examples = dataset.Examples
+		fmt.Println("Pesos de clase equilibrados")
+	default:
+		fatalf("ponderación de clases desconocida %q (balanced)", *classWeight)
+	}
+	if *resample != "" {
+		rng := streams.Stream(pcdta.StreamResample)
+		switch *resample {
+		case "over":
+			dataset = dataset.RandomOversample(rng)
+		case "under":
+			dataset = dataset.RandomUndersample(rng)
+		case "smote":
+			dataset = dataset.SMOTE(5, rng)
+		default:
+			fatalf("método de remuestreo desconocido %q (over, under o smote)", *resample)
+		}
+		fmt.Printf("Remuestreo %s: %d -> %d ejemplos\n", *resample, len(examples), len(dataset.Examples))
+		examples = dataset.Examples
+	}
+
+	// Discretizar las features: el árbol se entrena con los intervalos y después sus
+	// umbrales se traducen a los cortes en la escala original
+	var binning *pcdta.Binning
+	trainExamples := examples
+	if *binMethod != "" {
+		var err error
+		if binning, err = pcdta.LearnBinning(examples, *binMethod, *numBins); err != nil {
+			fatalf("%v", err)
+		}
+		trainExamples = pcdta.ApplyBinning(binning, examples)
+	}
+
+	// Construir árbol de decisión concurrentemente, o con puntos de control si se pidió;
+	// Ctrl-C guarda el punto de control para reanudar después
+	var tree *pcdta.DecisionTree[string]
+	var report pcdta.TrainReport
+	if *checkpointFile != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		var err error
+		tree, report, err = pcdta.BuildDecisionTreeCheckpointed(ctx, trainExamples, opts, *checkpointFile, *checkpointEvery)
+		stop()
+		if errors.Is(err, context.Canceled) {
+			fatalf("entrenamiento interrumpido: punto de control guardado en %s", *checkpointFile)
+		}
+		if err != nil {
+			fatalf("%v", err)
+		}
+	} else {
+		tree, report = pcdta.BuildDecisionTreeConcurrent(trainExamples, opts)
+	}
+	report.Load = loadTime
+	report.Classes, report.Imbalance = classShares, imbalance
+	report.ImbalanceThreshold = *imbalanceWarn
+	if binning != nil {
+		pcdta.UnbinTree(binning, tree)
+		report.TreeHash = pcdta.TreeHash(tree)
+	}
+
+	// Guardar el manifiesto con las semillas de todos los flujos aleatorios
+	if *manifestFile != "" {
+		manifest := report.Manifest()
+		manifest.Args = os.Args[1:]
+		manifest.Data = *dataFile
+		manifest.Seed = *seed
+		manifest.Streams = make(map[string]int64)
+		for _, name := range []string{pcdta.StreamGenerate, pcdta.StreamResample, pcdta.StreamNoise, pcdta.StreamFolds, pcdta.StreamBootstrap, pcdta.StreamFeatures} {
+			manifest.Streams[name] = streams.Seed(name)
+		}
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			fatalf("%v", err)
+		}
+		if err := os.WriteFile(*manifestFile, data, 0644); err != nil {
+			fatalf("%v", err)
+		}
+	}
+
+	// Imprimir el árbol de decisión
+	pcdta.PrintDecisionTree(tree, 0)
+
+	// Imprimir resumen del entrenamiento
+	fmt.Print(report)
+	if *reportFile != "" {
+		file, err := os.Create(*reportFile)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		err = report.WriteHTML(file, "Informe de entrenamiento")
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fatalf("%s: %v", *reportFile, err)
+		}
+	}
+
+	// Imprimir estadísticas del árbol
+	stats := tree.Stats()
+	fmt.Printf("Profundidad: %d, nodos: %d, hojas: %d, pureza media de hojas: %.3f\n",
+		stats.Depth, stats.NodeCount, stats.LeafCount, stats.AvgLeafPurity)
+
+	// Comparar con la descomposición en submodelos binarios si se pidió
+	var multiclassAccuracy float64
+	switch *multiclass {
+	case "":
+	case "ovr":
+		multiclassAccuracy = pcdta.Accuracy(pcdta.TrainOneVsRest(examples, opts), examples)
+	case "ovo":
+		multiclassAccuracy = pcdta.Accuracy(pcdta.TrainOneVsOne(examples, opts), examples)
+	default:
+		fatalf("meta-clasificador desconocido %q (ovr u ovo)", *multiclass)
+	}
+	if *multiclass != "" {
+		fmt.Printf("Precisión de entrenamiento: árbol %.3f, %s %.3f\n",
+			pcdta.Accuracy(tree, examples), *multiclass, multiclassAccuracy)
+	}
+
+	// Evaluar el respaldo k-NN sobre una partición reservada, ya que en entrenamiento
+	// cada ejemplo sería su propio vecino
+	if *knnFallback > 0 {
+		train, test := dataset.Split(0.2, streams.Stream(pcdta.StreamFolds))
+		holdoutTree, _ := pcdta.BuildDecisionTreeConcurrent(train.Examples, opts)
+		knn := pcdta.NewLeafKNN(holdoutTree, train.Examples, *knnFallback, *knnConfidence)
+		fmt.Printf("Respaldo k-NN (k=%d, confianza < %.2f): precisión en reserva %.3f (árbol: %.3f)\n",
+			*knnFallback, *knnConfidence, pcdta.Accuracy(knn, test.Examples), pcdta.Accuracy(holdoutTree, test.Examples))
+	}
+
+	// Entrenar el modelo ordinal si se indicó el orden de las clases
+	if *ordinal != "" {
+		order := strings.Split(*ordinal, ",")
+		ordinalModel, err := pcdta.TrainOrdinal(examples, order, opts)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		fmt.Printf("Ordinal: precisión %.3f, error medio en posiciones %.3f (árbol: %.3f)\n",
+			pcdta.Accuracy(ordinalModel, examples), pcdta.OrdinalMAE(ordinalModel, examples, order),
+			pcdta.OrdinalMAE(tree, examples, order))
+	}
+
+	// Guardar el modelo con sus metadatos de entrenamiento
+	if *output != "" {
+		model := pcdta.NewModel(tree, examples, featureNames, opts.Hyperparameters())
+		model.Meta.SelectedFeatures = selectedFeatures
+		model.Meta.Binning = binning
+		model.Meta.Encodings = encodings
+		model.Meta.ClassMapping = classMapping
+		model.Meta.Derived = derived
+		if err := pcdta.SaveModel(model, *output); err != nil {
+			fatalf("%v", err)
+		}
+		fmt.Println("Modelo guardado en", *output, "con hash", model.Metadata().TreeHash)
+	}
+
+	// Registrar parámetros, métricas y artefactos de la ejecución
+	if *experiment != "" {
+		run := experiments.NewRun(*experiment)
+		run.StartTime = loadStart
+		run.LogParam("max_depth", opts.MaxDepth)
+		run.LogParam("num_workers", opts.NumWorkers)
+		run.LogParam("data", *dataFile)
+		run.LogParam("rows", len(examples))
+		run.LogParam("seed", *seed)
+		run.LogMetric("train_accuracy", pcdta.Accuracy(tree, examples))
+		run.LogMetric("train_seconds", report.Total.Seconds())
+		run.LogMetric("nodes", float64(stats.NodeCount))
+		run.LogMetric("leaves", float64(stats.LeafCount))
+		run.LogMetric("avg_leaf_purity", stats.AvgLeafPurity)
+		if *multiclass != "" {
+			run.LogParam("multiclass", *multiclass)
+			run.LogMetric("train_accuracy_"+*multiclass, multiclassAccuracy)
+		}
+		if *output != "" {
+			run.LogArtifact(*output)
+		}
+
+		store := experiments.ExperimentStore{Root: *experimentsDir}
+		if err := store.Save(run); err != nil {
+			fatalf("%v", err)
+		}
+		fmt.Println("Ejecución", run.ID, "registrada en el experimento", *experiment)
+	}
+
+	notify(client.TrainSummary{
+		Status:        "ok",
+		Model:         *output,
+		TreeHash:      pcdta.TreeHash(tree),
+		Rows:          len(examples),
+		Nodes:         stats.NodeCount,
+		Leaves:        stats.LeafCount,
+		TrainAccuracy: pcdta.Accuracy(tree, examples),
+		TrainSeconds:  report.Total.Seconds(),
+	})
+}
+
+func runDiff(fileA, fileB string) {
+	a, err := pcdta.LoadModel[string](fileA)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := pcdta.LoadModel[string](fileB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	diffs := pcdta.CompareTrees(a.Tree, b.Tree)
+	if len(diffs) == 0 {
+		fmt.Println("Los modelos son estructuralmente idénticos")
+		return
+	}
+	for _, d := range diffs {
+		fmt.Println(d)
+	}
+}
+
+func runModels(args []string) {
+	fs := flag.NewFlagSet("models", flag.ExitOnError)
+	root := fs.String("registry", "modelos", "directorio del registro de modelos")
+	metric := fs.String("metric", "", "list: solo las versiones con esta métrica, de mayor a menor; pull nombre:best: la mejor según ella")
+	usage := "uso: models [-registry dir] [-metric m] list | push nombre modelo.json [métrica=valor ...] | pull nombre[:versión|:best] destino.json"
+
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		log.Fatal(usage)
+	}
+	models := registry.Registry{Root: *root}
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		var list []registry.RegistryEntry
+		var err error
+		if *metric != "" {
+			list, err = models.ByMetric("", *metric)
+		} else {
+			list, err = models.List()
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, e := range list {
+			fmt.Printf("%s\tv%d\t%s\t%d filas\t%.12s", e.Name, e.Version,
+				e.Metadata.TrainedAt.Format(time.RFC3339), e.Metadata.Rows, e.Metadata.TreeHash)
+			names := slices.Sorted(maps.Keys(e.Metrics))
+			for _, name := range names {
+				fmt.Printf("\t%s=%g", name, e.Metrics[name])
+			}
+			fmt.Println()
+		}
+	case args[0] == "push" && len(args) >= 3:
+		metrics := make(map[string]float64)
+		for _, arg := range args[3:] {
+			name, value, ok := strings.Cut(arg, "=")
+			v, err := strconv.ParseFloat(value, 64)
+			if !ok || name == "" || err != nil {
+				log.Fatalf("métrica inválida %q: se esperaba nombre=valor", arg)
+			}
+			metrics[name] = v
+		}
+		version, err := models.Push(args[1], args[2], metrics)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s v%d publicado\n", args[1], version)
+	case args[0] == "pull" && len(args) == 3:
+		name, version := args[1], 0
+		if i := strings.LastIndex(name, ":"); i >= 0 {
+			name = name[:i]
+			if tag := args[1][i+1:]; tag == "best" {
+				if *metric == "" {
+					log.Fatal("pull nombre:best necesita -metric")
+				}
+				best, err := models.ByMetric(name, *metric)
+				if err != nil {
+					log.Fatal(err)
+				}
+				if len(best) == 0 {
+					log.Fatalf("ninguna versión de %q registró la métrica %s", name, *metric)
+				}
+				version = best[0].Version
+			} else {
+				v, err := strconv.Atoi(strings.TrimPrefix(tag, "v"))
+				if err != nil || v <= 0 {
+					log.Fatal(usage)
+				}
+				version = v
+			}
+		}
+		version, err := models.Pull(name, version, args[2])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s v%d guardado en %s\n", name, version, args[2])
+	default:
+		log.Fatal(usage)
+	}
+}
+
+// runScore devuelve los errores en vez de terminar el proceso para que los defer
+// cierren la salida, también al interrumpir con Ctrl-C
+func runScore(args []string) error {
+	fs := flag.NewFlagSet("score", flag.ExitOnError)
+	modelFile := fs.String("model", "", "archivo JSON del modelo")
+	dataFile := fs.String("data", "", "CSV con las filas a puntuar (- para la entrada estándar)")
+	outFile := fs.String("out", "", "CSV de salida (por defecto la salida estándar)")
+	var opts pcdta.ScoreOptions
+	fs.BoolVar(&opts.FlagOutOfRange, "flag-out-of-range", false, "añadir la columna out_of_range con las features fuera del rango de entrenamiento")
+	fs.BoolVar(&opts.FlagUnseen, "flag-unseen", false, "añadir la columna unseen_categories con las categorías no vistas al entrenar")
+	fs.BoolVar(&opts.Confidence, "confidence", false, "añadir las columnas confidence y margin")
+	fs.Float64Var(&opts.Abstain, "abstain", 0, "predecir \""+pcdta.AbstainClass+"\" si la confianza es menor que este valor (0 = nunca)")
+	fs.BoolVar(&opts.CheckRanges, "check-ranges", false, "fallar en la primera fila con un valor fuera del rango de entrenamiento")
+	if err := ParseLayered(fs, args); err != nil {
+		return err
+	}
+
+	if *modelFile == "" || *dataFile == "" {
+		return errors.New("uso: score -model m.json -data nuevos.csv [-out predicciones.csv]")
+	}
+
+	model, err := pcdta.LoadModel[string](*modelFile)
+	if err != nil {
+		return err
+	}
+
+	in := os.Stdin
+	if *dataFile != "-" {
+		in, err = os.Open(*dataFile)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+
+	out := os.Stdout
+	if *outFile != "" {
+		out, err = os.Create(*outFile)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	// Ctrl-C detiene la puntuación después de escribir las filas ya procesadas
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return pcdta.ScoreStreamWith(ctx, model, in, out, opts)
+}
+
+func runImportSklearn(in, out string) {
+	data, err := os.ReadFile(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	model, err := pcdta.ImportSklearn(data)
+	if errors.Is(err, pcdta.ErrSklearnForest) {
+		forest, err := pcdta.ImportSklearnForest(data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := pcdta.SaveForest(forest, out); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Bosque de %d árboles importado en %s\n", len(forest.Trees), out)
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := pcdta.SaveModel(model, out); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Modelo importado en", out)
+}
+
+func runConvert(in, out string) {
+	examples, featureNames, err := pcdta.LoadCSVExamples(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := pcdta.WritePCD(out, examples, featureNames); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d filas y %d columnas guardadas en %s\n", len(examples), len(featureNames), out)
+}
+
+func runExperiments(args []string) {
+	fs := flag.NewFlagSet("experiments", flag.ExitOnError)
+	root := fs.String("experiments-dir", "experimentos", "directorio donde se guardan los experimentos")
+	usage := "uso: experiments [-experiments-dir dir] list experimento | export-mlflow experimento mlruns"
+
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+	args = fs.Args()
+	store := experiments.ExperimentStore{Root: *root}
+
+	switch {
+	case len(args) == 2 && args[0] == "list":
+		runs, err := store.Runs(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, run := range runs {
+			keys := make([]string, 0, len(run.Metrics))
+			for key := range run.Metrics {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			var metrics []string
+			for _, key := range keys {
+				metrics = append(metrics, fmt.Sprintf("%s=%.4g", key, run.Metrics[key]))
+			}
+			fmt.Printf("%s\t%s\t%s\n", run.ID, run.StartTime.Format(time.RFC3339), strings.Join(metrics, " "))
+		}
+	case len(args) == 3 && args[0] == "export-mlflow":
+		if err := store.ExportMLflow(args[1], args[2]); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Experimento exportado en", args[2])
+	default:
+		log.Fatal(usage)
+	}
+}
+
+// envName convierte el nombre de un flag en su variable de entorno: experiments-dir -> PCDTA_EXPERIMENTS_DIR
+func envName(flagName string) string {
+	return "PCDTA_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// ParseLayered analiza los flags y resuelve cada valor en el orden
+// valores por defecto < archivo -config (JSON con los nombres de los flags) < entorno PCDTA_* < flags,
+// de modo que el mismo archivo sirve en todos los entornos y solo cambian las variables.
+func ParseLayered(fs *flag.FlagSet, args []string) error {
+	fs.VisitAll(func(f *flag.Flag) {
+		f.Usage += fmt.Sprintf(" [%s]", envName(f.Name))
+	})
+	configFile := fs.String("config", os.Getenv("PCDTA_CONFIG"), "archivo JSON de configuración [PCDTA_CONFIG]")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	values := make(map[string]json.Number)
+	if *configFile != "" {
+		data, err := os.ReadFile(*configFile)
+		if err != nil {
+			return err
+		}
+		var raw map[string]any
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			return fmt.Errorf("%s: %w", *configFile, err)
+		}
+		// Una clave que no es una opción suele ser una errata; ignorarla en silencio
+		// dejaría la opción con su valor por defecto sin avisar
+		var unknown []string
+		for key, value := range raw {
+			if f := fs.Lookup(key); f == nil || key == "config" {
+				unknown = append(unknown, key)
+			}
+			values[key] = json.Number(fmt.Sprint(value))
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return fmt.Errorf("%s: opciones desconocidas: %s", *configFile, strings.Join(unknown, ", "))
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || f.Name == "config" || explicit[f.Name] {
+			return
+		}
+		if value, ok := values[f.Name]; ok {
+			if e := fs.Set(f.Name, value.String()); e != nil {
+				err = fmt.Errorf("%s: %s: %w", *configFile, f.Name, e)
+				return
+			}
+		}
+		if value, ok := os.LookupEnv(envName(f.Name)); ok {
+			if e := fs.Set(f.Name, value); e != nil {
+				err = fmt.Errorf("%s: %w", envName(f.Name), e)
+			}
+		}
+	})
+
+	return err
+}
+
+// runDescribe resume un CSV o .pcd; en un CSV las columnas de texto se describen al final
+func runDescribe(filename string) {
+	if strings.HasSuffix(filename, ".pcd") {
+		examples, featureNames, err := pcdta.LoadPCD(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print((&pcdta.Dataset[string]{FeatureNames: featureNames, Examples: examples}).Describe())
+		return
+	}
+	examples, featureNames, categorical, raw, err := pcdta.LoadCSVDetectCategorical(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	summary := (&pcdta.Dataset[string]{FeatureNames: featureNames, Examples: examples}).Describe()
+	for c, name := range categorical {
+		summary.Columns = append(summary.Columns, pcdta.DescribeText(name, raw[c]))
+	}
+	fmt.Print(summary)
+}
+
+func runFairness(args []string) {
+	fs := flag.NewFlagSet("fairness", flag.ExitOnError)
+	modelFile := fs.String("model", "", "archivo JSON del modelo")
+	dataFile := fs.String("data", "", "CSV o .pcd de validación con la clase real")
+	sensitive := fs.String("sensitive", "", "columna con el atributo sensible")
+	positive := fs.String("positive", "", "clase positiva (por defecto la primera clase del modelo)")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *modelFile == "" || *dataFile == "" || *sensitive == "" {
+		log.Fatal("uso: fairness -model m.json -data validacion.csv -sensitive columna [-positive clase]")
+	}
+
+	model, err := pcdta.LoadModel[string](*modelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// El atributo sensible se lee como texto: suele ser categórico y el modelo, si lo
+	// usa, lo tiene codificado
+	groups, err := pcdta.LoadColumn(*dataFile, *sensitive)
+	if err != nil {
+		log.Fatal(err)
+	}
+	aligned, err := pcdta.LoadForModel(*dataFile, model)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *positive == "" {
+		if len(model.Classes()) == 0 {
+			log.Fatalf("%s: el modelo no tiene clases; indica -positive", *modelFile)
+		}
+		*positive = model.Classes()[0]
+	} else if to, ok := model.Meta.ClassMapping[*positive]; ok {
+		// LoadForModel deja las clases con el nombre entrenado
+		*positive = to
+	}
+
+	fmt.Print(pcdta.Fairness(model.Tree, aligned.Examples, groups, *positive))
+}
+
+func runSegments(args []string) {
+	fs := flag.NewFlagSet("segments", flag.ExitOnError)
+	modelFile := fs.String("model", "", "archivo JSON del modelo")
+	dataFile := fs.String("data", "", "CSV o .pcd de validación con la clase real")
+	by := fs.String("by", "", "columna que define los segmentos")
+	alpha := fs.Float64("alpha", 0.05, "nivel de significación para marcar segmentos por debajo de la precisión global")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *modelFile == "" || *dataFile == "" || *by == "" {
+		log.Fatal("uso: segments -model m.json -data validacion.csv -by columna [-alpha 0.05]")
+	}
+
+	model, err := pcdta.LoadModel[string](*modelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	segments, err := pcdta.LoadColumn(*dataFile, *by)
+	if err != nil {
+		log.Fatal(err)
+	}
+	aligned, err := pcdta.LoadForModel(*dataFile, model)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(pcdta.Segments(model.Tree, aligned.Examples, segments, *by, *alpha))
+}
+
+func runDrift(args []string) {
+	fs := flag.NewFlagSet("drift", flag.ExitOnError)
+	modelFile := fs.String("model", "", "archivo JSON del modelo")
+	referenceFile := fs.String("reference", "", "CSV o .pcd de referencia (p. ej. los datos de entrenamiento)")
+	currentFile := fs.String("current", "", "CSV o .pcd con el tráfico reciente; la columna de clase puede estar vacía")
+	bins := fs.Int("bins", 10, "intervalos de cuantiles para el PSI")
+	threshold := fs.Float64("threshold", 0.2, "PSI a partir del cual se marca deriva")
+	outFile := fs.String("o", "", "escribir el informe en JSON en este archivo")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *modelFile == "" || *referenceFile == "" || *currentFile == "" {
+		log.Fatal("uso: drift -model m.json -reference ref.csv -current recientes.csv [-bins 10] [-threshold 0.2] [-o deriva.json]")
+	}
+
+	model, err := pcdta.LoadModel[string](*modelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	load := func(file string) []pcdta.Example[string] {
+		aligned, err := pcdta.LoadForModel(file, model)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return aligned.Examples
+	}
+
+	report := pcdta.Drift(model.Tree, load(*referenceFile), load(*currentFile), model.Meta.FeatureNames, *bins, *threshold)
+	fmt.Print(report)
+	if *outFile != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*outFile, data, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// parseEpsilons interpreta "0.1" (igual para todas las features) o
+// "0.1,petal_width=0.05" (por defecto y excepciones por nombre)
+func parseEpsilons(spec string, featureNames []string) ([]float64, error) {
+	epsilons := make([]float64, len(featureNames))
+	for _, part := range strings.Split(spec, ",") {
+		name, value, named := strings.Cut(strings.TrimSpace(part), "=")
+		if !named {
+			value = name
+		}
+		eps, err := strconv.ParseFloat(value, 64)
+		if err != nil || eps < 0 {
+			return nil, fmt.Errorf("épsilon no válido %q", part)
+		}
+		if !named {
+			for j := range epsilons {
+				epsilons[j] = eps
+			}
+			continue
+		}
+		found := false
+		for j, featureName := range featureNames {
+			if featureName == name {
+				epsilons[j] = eps
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no existe la feature %q", name)
+		}
+	}
+	return epsilons, nil
+}
+
+func runRobustness(args []string) {
+	fs := flag.NewFlagSet("robustness", flag.ExitOnError)
+	modelFile := fs.String("model", "", "archivo JSON del modelo")
+	dataFile := fs.String("data", "", "CSV o .pcd con las filas a perturbar")
+	eps := fs.String("eps", "0.1", "perturbación máxima: un valor para todas las features y/o nombre=valor")
+	steps := fs.Int("steps", 10, "puntos probados a cada lado del valor original")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *modelFile == "" || *dataFile == "" {
+		log.Fatal("uso: robustness -model m.json -data datos.csv [-eps 0.1,feature=0.05] [-steps 10]")
+	}
+
+	model, err := pcdta.LoadModel[string](*modelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	aligned, err := pcdta.LoadForModel(*dataFile, model)
+	if err != nil {
+		log.Fatal(err)
+	}
+	epsilons, err := parseEpsilons(*eps, aligned.FeatureNames)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Print(pcdta.Robustness(model.Tree, aligned.Examples, aligned.FeatureNames, epsilons, *steps))
+}
+
+func parseFloats(spec string) ([]float64, error) {
+	var values []float64
+	for _, part := range strings.Split(spec, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func runStress(args []string) {
+	fs := flag.NewFlagSet("stress", flag.ExitOnError)
+	dataFile := fs.String("data", "", "CSV o .pcd de entrenamiento")
+	depths := fs.String("depths", "2,3,5,8", "profundidades máximas a comparar")
+	featureNoise := fs.String("feature-noise", "0,0.1,0.3", "ruido de features, en desviaciones típicas de cada columna")
+	labelNoise := fs.String("label-noise", "0,0.1,0.2", "fracción de etiquetas cambiadas")
+	testFraction := fs.Float64("test", 0.2, "fracción de filas limpias reservadas para evaluar")
+	seed := fs.Int64("seed", 1, "semilla del ruido y de la partición")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *dataFile == "" {
+		log.Fatal("uso: stress -data datos.csv [-depths 2,3,5] [-feature-noise 0,0.1] [-label-noise 0,0.1]")
+	}
+
+	examples, featureNames, err := pcdta.LoadExamples(*dataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var depthList []int
+	for _, part := range strings.Split(*depths, ",") {
+		depth, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			log.Fatal(err)
+		}
+		depthList = append(depthList, depth)
+	}
+	fNoise, err := parseFloats(*featureNoise)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lNoise, err := parseFloats(*labelNoise)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	streams := pcdta.SeedStreams{Root: *seed}
+	train, test := (&pcdta.Dataset[string]{FeatureNames: featureNames, Examples: examples}).Split(*testFraction, streams.Stream(pcdta.StreamFolds))
+	points := pcdta.NoiseCurves(train, test, pcdta.DefaultTrainOptions[string](), depthList, fNoise, lNoise, streams.Stream(pcdta.StreamNoise))
+
+	fmt.Println("profundidad\truido_features\truido_etiquetas\tprecision")
+	for _, p := range points {
+		fmt.Printf("%d\t%g\t%g\t%.3f\n", p.Depth, p.FeatureNoise, p.LabelNoise, p.Accuracy)
+	}
+}
+
+func runNestedCV(args []string) {
+	fs := flag.NewFlagSet("nested-cv", flag.ExitOnError)
+	dataFile := fs.String("data", "", "CSV o .pcd de entrenamiento")
+	depths := fs.String("depths", "1,2,3,5,8", "profundidades máximas candidatas")
+	outer := fs.Int("outer", 5, "pliegues externos (estimación)")
+	inner := fs.Int("inner", 3, "pliegues internos (ajuste)")
+	seed := fs.Int64("seed", 1, "semilla de los pliegues")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *dataFile == "" {
+		log.Fatal("uso: nested-cv -data datos.csv [-depths 1,2,3] [-outer 5] [-inner 3]")
+	}
+
+	examples, _, err := pcdta.LoadExamples(*dataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var depthList []int
+	for _, part := range strings.Split(*depths, ",") {
+		depth, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			log.Fatal(err)
+		}
+		depthList = append(depthList, depth)
+	}
+
+	rng := pcdta.SeedStreams{Root: *seed}.Stream(pcdta.StreamFolds)
+	result, err := pcdta.NestedCV(examples, pcdta.DefaultTrainOptions[string](), depthList, *outer, *inner, rng)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(result)
+}
+
+func runBacktest(args []string) {
+	fs := flag.NewFlagSet("backtest", flag.ExitOnError)
+	dataFile := fs.String("data", "", "CSV o .pcd con una columna de tiempo")
+	timeColumn := fs.String("time", "", "columna con el instante de cada fila, numérico o fecha (2024-03-01, RFC3339); no se usa como feature")
+	horizon := fs.Float64("horizon", 0, "amplitud de cada ventana de prueba, en las unidades de -time (días si son fechas)")
+	step := fs.Float64("step", 0, "avance del origen entre pliegues (por defecto, el horizonte)")
+	startFlag := fs.String("start", "", "primer origen, número o fecha (por defecto, la mediana de los tiempos)")
+	window := fs.Float64("window", 0, "amplitud de la ventana de entrenamiento; 0 la hace creciente")
+	opts := pcdta.DefaultTrainOptions[string]()
+	fs.IntVar(&opts.MaxDepth, "depth", opts.MaxDepth, "profundidad máxima de los árboles")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *dataFile == "" || *timeColumn == "" || *horizon <= 0 {
+		log.Fatal("uso: backtest -data datos.csv -time columna -horizon h [-step h] [-start t] [-window w] [-depth 3]")
+	}
+	if *step <= 0 {
+		*step = *horizon
+	}
+
+	// LoadTimeSeries no devuelve filas vacías, así que la mediana siempre existe
+	features, times, err := pcdta.LoadTimeSeries(*dataFile, *timeColumn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var start float64
+	if *startFlag == "" {
+		sorted := append([]float64(nil), times...)
+		sort.Float64s(sorted)
+		start = sorted[len(sorted)/2]
+	} else if start, err = pcdta.ParseTime(*startFlag); err != nil {
+		log.Fatalf("-start: %v", err)
+	}
+	report, err := pcdta.Backtest(features.Examples, times, opts, start, *horizon, *step, *window)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(report)
+}
+
+func runImportance(args []string) {
+	fs := flag.NewFlagSet("importance", flag.ExitOnError)
+	dataFile := fs.String("data", "", "CSV o .pcd de entrenamiento")
+	samples := fs.Int("bootstrap", 30, "número de muestras bootstrap")
+	topK := fs.Int("top", 3, "tamaño del grupo de features más importantes que se vigila")
+	seed := fs.Int64("seed", 1, "semilla de las muestras bootstrap")
+	opts := pcdta.DefaultTrainOptions[string]()
+	fs.IntVar(&opts.MaxDepth, "depth", opts.MaxDepth, "profundidad máxima de los árboles")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *dataFile == "" {
+		log.Fatal("uso: importance -data datos.csv [-bootstrap 30] [-top 3] [-depth 3]")
+	}
+	examples, featureNames, err := pcdta.LoadExamples(*dataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rng := pcdta.SeedStreams{Root: *seed}.Stream(pcdta.StreamBootstrap)
+	fmt.Print(pcdta.FeatureImportanceStability(examples, featureNames, opts, *samples, *topK, rng))
+}
+
+func runInteractions(args []string) {
+	fs := flag.NewFlagSet("interactions", flag.ExitOnError)
+	modelFile := fs.String("model", "", "archivo JSON del modelo")
+	dataFile := fs.String("data", "", "CSV o .pcd sobre el que se calculan las dependencias parciales")
+	maxRows := fs.Int("rows", 200, "filas equiespaciadas de los datos que se usan (el coste crece con su cuadrado)")
+	top := fs.Int("top", 10, "pares que se muestran (0 = todos)")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *modelFile == "" || *dataFile == "" {
+		log.Fatal("uso: interactions -model m.json -data datos.csv [-rows 200] [-top 10]")
+	}
+	model, err := pcdta.LoadModel[string](*modelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	aligned, err := pcdta.LoadForModel(*dataFile, model)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	examples := aligned.Examples
+	n := len(examples)
+	if *maxRows > 0 && n > *maxRows {
+		n = *maxRows
+	}
+	rows := make([][]float64, n)
+	for i := range rows {
+		rows[i] = examples[i*len(examples)/n].Features
+	}
+
+	interactions := pcdta.PairwiseInteractions(model.Tree, rows)
+	if *top > 0 && len(interactions) > *top {
+		interactions = interactions[:*top]
+	}
+	fmt.Printf("Interacciones H² de Friedman sobre %d filas:\n", n)
+	for _, interaction := range interactions {
+		fmt.Printf("  %-20s × %-20s %.4f\n", pcdta.ColumnLabel(model.Meta.FeatureNames, interaction.A),
+			pcdta.ColumnLabel(model.Meta.FeatureNames, interaction.B), interaction.H2)
+	}
+}
+
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	modelA := fs.String("a", "", "primer modelo JSON")
+	modelB := fs.String("b", "", "segundo modelo JSON")
+	dataFile := fs.String("data", "", "CSV o .pcd con la clase real")
+	depthA := fs.Int("depth-a", 0, "sin modelos: profundidad de la configuración A")
+	depthB := fs.Int("depth-b", 0, "sin modelos: profundidad de la configuración B")
+	numFolds := fs.Int("folds", 10, "pliegues de la t pareada")
+	seed := fs.Int64("seed", 1, "semilla de los pliegues")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	usage := "uso: compare -a m1.json -b m2.json -data prueba.csv | compare -data datos.csv -depth-a 3 -depth-b 5 [-folds 10]"
+	if *dataFile == "" {
+		log.Fatal(usage)
+	}
+
+	// Dos modelos guardados: McNemar sobre los datos indicados
+	if *modelA != "" || *modelB != "" {
+		if *modelA == "" || *modelB == "" {
+			log.Fatal(usage)
+		}
+		var trees [2]*pcdta.DecisionTree[string]
+		var aligned [2][]pcdta.Example[string]
+		for i, file := range []string{*modelA, *modelB} {
+			model, err := pcdta.LoadModel[string](file)
+			if err != nil {
+				log.Fatal(err)
+			}
+			selected, err := pcdta.LoadForModel(*dataFile, model)
+			if err != nil {
+				log.Fatalf("%s: %v", file, err)
+			}
+			trees[i], aligned[i] = model.Tree, selected.Examples
+		}
+		// Cada modelo puede usar columnas y nombres de clase distintos, así que se
+		// cuentan los desacuerdos a mano
+		onlyA, onlyB := 0, 0
+		for i := range aligned[0] {
+			rightA := trees[0].Predict(aligned[0][i].Features) == aligned[0][i].Class
+			rightB := trees[1].Predict(aligned[1][i].Features) == aligned[1][i].Class
+			if rightA && !rightB {
+				onlyA++
+			} else if rightB && !rightA {
+				onlyB++
+			}
+		}
+		fmt.Printf("Precisión: A %.3f, B %.3f\n", pcdta.Accuracy(trees[0], aligned[0]), pcdta.Accuracy(trees[1], aligned[1]))
+		fmt.Println(pcdta.McNemarCounts(onlyA, onlyB))
+		return
+	}
+
+	// Dos configuraciones: t pareada sobre los mismos pliegues
+	if *depthA <= 0 || *depthB <= 0 {
+		log.Fatal(usage)
+	}
+	examples, _, err := pcdta.LoadExamples(*dataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	folds, err := pcdta.KFolds(len(examples), *numFolds, pcdta.SeedStreams{Root: *seed}.Stream(pcdta.StreamFolds))
+	if err != nil {
+		log.Fatalf("-folds: %v", err)
+	}
+	optsA, optsB := pcdta.DefaultTrainOptions[string](), pcdta.DefaultTrainOptions[string]()
+	optsA.MaxDepth, optsB.MaxDepth = *depthA, *depthB
+	scoresA := pcdta.CrossValidate(examples, optsA, folds)
+	scoresB := pcdta.CrossValidate(examples, optsB, folds)
+	fmt.Printf("Precisión media: A %.3f, B %.3f\n", pcdta.Mean(scoresA), pcdta.Mean(scoresB))
+	result, err := pcdta.PairedTTest(scoresA, scoresB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result)
+}
+
+func runTrainSparse(args []string) {
+	fs := flag.NewFlagSet("train-sparse", flag.ExitOnError)
+	dataFile := fs.String("data", "", "archivo SVMlight/LIBSVM")
+	output := fs.String("o", "", "guardar el modelo entrenado en este archivo JSON")
+	opts := pcdta.DefaultTrainOptions[string]()
+	fs.IntVar(&opts.MaxDepth, "depth", opts.MaxDepth, "profundidad máxima del árbol")
+	fs.IntVar(&opts.NumWorkers, "workers", opts.NumWorkers, "número máximo de goroutines de entrenamiento")
+	fs.Float64Var(&opts.MinFeatureVariance, "min-feature-variance", opts.MinFeatureVariance, "omitir features con varianza menor o igual (0 = solo las constantes)")
+	bundle := fs.Bool("bundle", false, "agrupar features mutuamente excluyentes (p. ej. one-hot) en columnas compartidas")
+	maxConflicts := fs.Int("max-conflicts", 0, "filas en las que se permite que dos features de un grupo coincidan")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *dataFile == "" {
+		log.Fatal("uso: train-sparse -data datos.svm [-depth 3] [-bundle] [-o modelo.json]")
+	}
+
+	d, err := pcdta.LoadSVMLight(*dataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Datos dispersos: %d filas, %d features, %d entradas no nulas\n", d.Rows(), d.NumFeatures, len(d.Values))
+
+	// El modelo se entrena sobre las columnas agrupadas; los grupos se guardan con el
+	// modelo para que score-sparse traduzca las filas nuevas
+	hyperparameters := opts.Hyperparameters()
+	var bundles []pcdta.FeatureBundle
+	if *bundle {
+		bundles = d.ExclusiveBundles(*maxConflicts)
+		d = d.Bundle(bundles)
+		hyperparameters["max_conflicts"] = *maxConflicts
+		fmt.Printf("Features agrupadas: %d columnas\n", d.NumFeatures)
+	}
+
+	tree, report := pcdta.BuildSparseTree(d, opts)
+	pcdta.PrintDecisionTree(tree, 0)
+	fmt.Print(report)
+
+	correct := 0
+	for row := 0; row < d.Rows(); row++ {
+		cols, values := d.ColIndex[d.RowPtr[row]:d.RowPtr[row+1]], d.Values[d.RowPtr[row]:d.RowPtr[row+1]]
+		if tree.PredictSparse(cols, values) == d.Classes[row] {
+			correct++
+		}
+	}
+	fmt.Printf("Precisión de entrenamiento: %.3f\n", float64(correct)/float64(d.Rows()))
+
+	if *output != "" {
+		model := pcdta.NewModel(tree, nil, d.FeatureNames, hyperparameters)
+		model.Meta.Rows = d.Rows()
+		model.Meta.DatasetHash = d.Hash()
+		model.Meta.FeatureBundles = bundles
+		if err := pcdta.SaveModel(model, *output); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Modelo guardado en", *output)
+	}
+}
+
+// runScoreSparse predice las filas de un archivo SVMlight con un modelo de
+// train-sparse, traduciéndolas a las columnas agrupadas si se entrenó con -bundle
+func runScoreSparse(args []string) {
+	fs := flag.NewFlagSet("score-sparse", flag.ExitOnError)
+	modelFile := fs.String("model", "", "archivo JSON del modelo")
+	dataFile := fs.String("data", "", "archivo SVMlight/LIBSVM con las filas a puntuar")
+	outFile := fs.String("out", "", "CSV de salida (por defecto la salida estándar)")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *modelFile == "" || *dataFile == "" {
+		log.Fatal("uso: score-sparse -model m.json -data nuevos.svm [-out predicciones.csv]")
+	}
+
+	model, err := pcdta.LoadModel[string](*modelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	d, err := pcdta.LoadSVMLight(*dataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := os.Stdout
+	if *outFile != "" {
+		out, err = os.Create(*outFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer out.Close()
+	}
+	writer := csv.NewWriter(out)
+	writer.Write([]string{"class", "prediction"})
+	correct := 0
+	for row := 0; row < d.Rows(); row++ {
+		prediction := model.PredictSparse(d.ColIndex[d.RowPtr[row]:d.RowPtr[row+1]], d.Values[d.RowPtr[row]:d.RowPtr[row+1]])
+		if prediction == d.Classes[row] {
+			correct++
+		}
+		writer.Write([]string{d.Classes[row], prediction})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Fatal(err)
+	}
+	if d.Rows() > 0 {
+		fmt.Fprintf(os.Stderr, "Precisión: %.3f (%d de %d filas)\n", float64(correct)/float64(d.Rows()), correct, d.Rows())
+	}
+}
+
+// trainTeacher entrena el modelo de varios árboles que imitan distill y surrogate; el
+// bosque tiene trees árboles y toma sus muestras del flujo bootstrap de streams
+func trainTeacher(kind string, examples []pcdta.Example[string], depth, trees int, streams pcdta.SeedStreams) (pcdta.ProbabilisticClassifier[string], error) {
+	opts := pcdta.DefaultTrainOptions[string]()
+	opts.MaxDepth = depth
+	switch kind {
+	case "forest":
+		forest := pcdta.ForestOptions{Trees: trees}
+		if len(examples) > 0 {
+			// La regla habitual de clasificación: la raíz del número de features
+			forest.MaxFeatures = int(math.Ceil(math.Sqrt(float64(len(examples[0].Features)))))
+		}
+		f, _, err := pcdta.TrainForest(context.Background(), examples, opts, forest, streams.Stream(pcdta.StreamBootstrap))
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	case "ovr":
+		return pcdta.TrainOneVsRest(examples, opts), nil
+	case "ovo":
+		return pcdta.TrainOneVsOne(examples, opts), nil
+	case "knn":
+		tree, _ := pcdta.BuildDecisionTreeConcurrent(examples, opts)
+		return pcdta.NewLeafKNN(tree, examples, 5, 1), nil
+	}
+	return nil, fmt.Errorf("profesor desconocido %q: use forest, ovr, ovo o knn", kind)
+}
+
+// printDistillTable compara profesor y alumno sobre el entrenamiento y la reserva; sin
+// filas reservadas solo muestra la primera columna
+func printDistillTable(teacher, student string, train, heldOut pcdta.DistillReport, heldOutRows int) {
+	row := func(name string, train, heldOut float64) {
+		if heldOutRows > 0 {
+			fmt.Printf("%-22s %10.3f %10.3f\n", name, train, heldOut)
+		} else {
+			fmt.Printf("%-22s %10.3f\n", name, train)
+		}
+	}
+	if heldOutRows > 0 {
+		fmt.Printf("%-22s %10s %10s\n", "", "entreno", "reserva")
+	} else {
+		fmt.Printf("%-22s %10s\n", "", "entreno")
+	}
+	row("precisión "+teacher, train.TeacherAccuracy, heldOut.TeacherAccuracy)
+	row("precisión "+student, train.StudentAccuracy, heldOut.StudentAccuracy)
+	row("fidelidad", train.Fidelity, heldOut.Fidelity)
+}
+
+// runSurrogate entrena el profesor (por defecto un bosque) y su árbol sustituto sobre
+// una partición y los compara sobre la parte reservada, junto a un árbol de la misma
+// profundidad entrenado directamente, con las reglas del sustituto
+func runSurrogate(args []string) {
+	fs := flag.NewFlagSet("surrogate", flag.ExitOnError)
+	dataFile := fs.String("data", "", "CSV o .pcd de entrenamiento")
+	teacherKind := fs.String("teacher", "forest", "modelo profesor: forest, ovr, ovo o knn")
+	teacherDepth := fs.Int("teacher-depth", pcdta.MaxDepth, "profundidad máxima de los árboles del profesor")
+	teacherTrees := fs.Int("teacher-trees", 50, "árboles del profesor forest")
+	testFraction := fs.Float64("test", 0.3, "fracción de filas reservada para comparar")
+	augment := fs.Int("augment", 0, "filas sintéticas etiquetadas por el profesor")
+	tolerance := fs.Float64("tolerance", 0.01, "pérdida de confianza admitida al simplificar las reglas")
+	seed := fs.Int64("seed", 1, "semilla de la partición y del aumento de datos")
+	opts := pcdta.DefaultTrainOptions[string]()
+	opts.MaxDepth = 3
+	fs.IntVar(&opts.MaxDepth, "depth", opts.MaxDepth, "profundidad máxima del árbol sustituto")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *dataFile == "" {
+		log.Fatal("uso: surrogate -data datos.csv [-teacher forest] [-depth 3] [-test 0.3]")
+	}
+	examples, featureNames, err := pcdta.LoadExamples(*dataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	streams := pcdta.SeedStreams{Root: *seed}
+	train, test := (&pcdta.Dataset[string]{FeatureNames: featureNames, Examples: examples}).Split(*testFraction, streams.Stream(pcdta.StreamFolds))
+	teacher, err := trainTeacher(*teacherKind, train.Examples, *teacherDepth, *teacherTrees, streams)
+	if err != nil {
+		log.Fatal(err)
+	}
+	surrogate, report := pcdta.Distill(teacher, train.Examples, opts, *augment, streams.Stream(pcdta.StreamResample))
+	printDistillTable(*teacherKind, "sustituto", report, pcdta.EvaluateDistill[string](teacher, surrogate, test.Examples), len(test.Examples))
+
+	// Referencia: un árbol de la misma profundidad entrenado directamente con las clases
+	direct, _ := pcdta.BuildDecisionTreeConcurrent(train.Examples, opts)
+	if len(test.Examples) > 0 {
+		fmt.Printf("%-22s %10.3f %10.3f\n", "precisión sin profesor", pcdta.Accuracy(direct, train.Examples), pcdta.Accuracy(direct, test.Examples))
+	} else {
+		fmt.Printf("%-22s %10.3f\n", "precisión sin profesor", pcdta.Accuracy(direct, train.Examples))
+	}
+
+	list := pcdta.SimplifyRules(pcdta.ExtractRules(surrogate), train.Examples, *tolerance)
+	fmt.Printf("\nReglas del sustituto (%d hojas):\n", report.Leaves)
+	for i, rule := range list.Rules {
+		fmt.Printf("%d. %s\n", i+1, rule.Format(featureNames))
+	}
+	fmt.Printf("EN OTRO CASO %s\n", list.Default)
+}
+
+func runDistill(args []string) {
+	fs := flag.NewFlagSet("distill", flag.ExitOnError)
+	dataFile := fs.String("data", "", "CSV o .pcd de entrenamiento")
+	teacherKind := fs.String("teacher", "forest", "modelo profesor: forest, ovr, ovo o knn")
+	teacherDepth := fs.Int("teacher-depth", pcdta.MaxDepth, "profundidad máxima de los árboles del profesor")
+	teacherTrees := fs.Int("teacher-trees", 50, "árboles del profesor forest")
+	output := fs.String("o", "", "guardar el árbol alumno en este archivo JSON")
+	testFraction := fs.Float64("test", 0.3, "fracción de filas reservada para medir la fidelidad (0 = solo sobre el entrenamiento)")
+	augment := fs.Int("augment", 0, "filas sintéticas etiquetadas por el profesor")
+	seed := fs.Int64("seed", 1, "semilla de la partición y del aumento de datos")
+	opts := pcdta.DefaultTrainOptions[string]()
+	opts.MaxDepth = 3
+	fs.IntVar(&opts.MaxDepth, "depth", opts.MaxDepth, "profundidad máxima del árbol alumno")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *dataFile == "" {
+		log.Fatal("uso: distill -data datos.csv [-teacher forest] [-depth 3] [-augment 0] [-o alumno.json]")
+	}
+	examples, featureNames, err := pcdta.LoadExamples(*dataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Profesor y alumno se entrenan sin la reserva: sobre sus propias filas un
+	// profesor knn acierta siempre y la fidelidad no significa nada
+	streams := pcdta.SeedStreams{Root: *seed}
+	train, test := (&pcdta.Dataset[string]{FeatureNames: featureNames, Examples: examples}).Split(*testFraction, streams.Stream(pcdta.StreamFolds))
+	teacher, err := trainTeacher(*teacherKind, train.Examples, *teacherDepth, *teacherTrees, streams)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	student, report := pcdta.Distill(teacher, train.Examples, opts, *augment, streams.Stream(pcdta.StreamResample))
+	pcdta.PrintDecisionTree(student, 0)
+	fmt.Printf("Hojas del alumno: %d (%d ejemplos sintéticos)\n", report.Leaves, report.Augmented)
+	printDistillTable(*teacherKind, "alumno", report, pcdta.EvaluateDistill[string](teacher, student, test.Examples), len(test.Examples))
+
+	if *output != "" {
+		hyperparameters := opts.Hyperparameters()
+		hyperparameters["distilled_from"] = *teacherKind
+		if err := pcdta.SaveModel(pcdta.NewModel(student, train.Examples, featureNames, hyperparameters), *output); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Modelo guardado en", *output)
+	}
+}
+
+func runRules(args []string) {
+	fs := flag.NewFlagSet("rules", flag.ExitOnError)
+	modelFile := fs.String("model", "", "archivo JSON del modelo")
+	dataFile := fs.String("data", "", "CSV o .pcd para recalcular soporte y confianza y generalizar las reglas")
+	tolerance := fs.Float64("tolerance", 0.01, "pérdida de confianza admitida al quitar una condición")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *modelFile == "" {
+		log.Fatal("uso: rules -model m.json [-data datos.csv] [-tolerance 0.01]")
+	}
+
+	model, err := pcdta.LoadModel[string](*modelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var examples []pcdta.Example[string]
+	if *dataFile != "" {
+		aligned, err := pcdta.LoadForModel(*dataFile, model)
+		if err != nil {
+			log.Fatal(err)
+		}
+		examples = aligned.Examples
+	}
+
+	rules := pcdta.ExtractRules(model.Tree)
+	list := pcdta.SimplifyRules(rules, examples, *tolerance)
+	fmt.Printf("%d reglas extraídas, %d tras simplificar\n", len(rules), len(list.Rules))
+	// Las reglas se evalúan con las clases entrenadas y se muestran con su nombre original
+	for i, rule := range list.Rules {
+		rule.Class = model.OriginalClass(rule.Class)
+		fmt.Printf("%d. %s\n", i+1, rule.Format(model.Meta.FeatureNames))
+	}
+	fmt.Printf("EN OTRO CASO %s\n", model.OriginalClass(list.Default))
+	if len(examples) > 0 {
+		fmt.Printf("Precisión: reglas %.3f, árbol %.3f\n", pcdta.Accuracy(list, examples), pcdta.Accuracy(model.Tree, examples))
+	}
+}
+
+func runExportPolicy(in, out string) {
+	model, err := pcdta.LoadModel[string](in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Sin escapar "<" para que la política sea legible
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(pcdta.ExportPolicy(model)); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(out, []byte(buf.String()), 0644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Política exportada en", out)
+}
+
+func runExportCode(args []string) {
+	fs := flag.NewFlagSet("export-code", flag.ExitOnError)
+	modelFile := fs.String("model", "", "archivo JSON del modelo")
+	lang := fs.String("lang", "c", "lenguaje destino: c, java o go")
+	name := fs.String("name", "", "nombre de la clase (Java), prefijo (C) o paquete (Go)")
+	outFile := fs.String("o", "", "archivo de salida (por defecto la salida estándar)")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *modelFile == "" {
+		log.Fatal("uso: export-code -model m.json -lang c|java|go [-name nombre] [-o salida]")
+	}
+	if *name == "" {
+		*name = map[string]string{"c": "pcdta", "java": "DecisionTreeModel", "go": "modelo"}[*lang]
+	}
+
+	model, err := pcdta.LoadModel[string](*modelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := os.Stdout
+	if *outFile != "" {
+		out, err = os.Create(*outFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer out.Close()
+	}
+	if err := pcdta.ExportCode(out, model, *lang, *name); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runExportFixed(args []string) {
+	fs := flag.NewFlagSet("export-fixed", flag.ExitOnError)
+	modelFile := fs.String("model", "", "archivo JSON del modelo")
+	dataFile := fs.String("data", "", "CSV o .pcd para fijar el rango de cada feature y medir la fidelidad")
+	bits := fs.Int("bits", 16, "ancho de los enteros: 16 o 32")
+	name := fs.String("name", "pcdta", "prefijo de los símbolos C")
+	outFile := fs.String("o", "", "archivo C de salida (por defecto la salida estándar)")
+	if err := ParseLayered(fs, args); err != nil {
+		log.Fatal(err)
+	}
+
+	if *modelFile == "" {
+		log.Fatal("uso: export-fixed -model m.json [-data datos.csv] [-bits 16] [-o modelo.c]")
+	}
+
+	model, err := pcdta.LoadModel[string](*modelFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Rango de cada feature: el de los datos si se dan, si no el doble del mayor umbral
+	numFeatures := len(model.Meta.FeatureNames)
+	model.Tree.Walk(func(node *pcdta.DecisionTree[string], depth int) {
+		if !node.IsLeaf() && node.Column >= numFeatures {
+			numFeatures = node.Column + 1
+		}
+	})
+	maxAbs := make([]float64, numFeatures)
+	var examples []pcdta.Example[string]
+	if *dataFile != "" {
+		aligned, err := pcdta.LoadForModel(*dataFile, model)
+		if err != nil {
+			log.Fatal(err)
+		}
+		examples = aligned.Examples
+		for _, example := range examples {
+			for j, value := range example.Features {
+				if !math.IsNaN(value) {
+					maxAbs[j] = math.Max(maxAbs[j], math.Abs(value))
+				}
+			}
+		}
+	} else {
+		model.Tree.Walk(func(node *pcdta.DecisionTree[string], depth int) {
+			if !node.IsLeaf() {
+				maxAbs[node.Column] = math.Max(maxAbs[node.Column], 2*math.Abs(node.Value))
+			}
+		})
+	}
+
+	fixed, err := pcdta.QuantizeTree(model.Tree, maxAbs, *bits)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(examples) > 0 {
+		agree := 0
+		for _, example := range examples {
+			if fixed.Classes[fixed.Predict(fixed.Quantize(example.Features))] == model.Tree.Predict(example.Features) {
+				agree++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Fidelidad del modelo en punto fijo: %.4f (%d de %d filas)\n",
+			float64(agree)/float64(len(examples)), agree, len(examples))
+	}
+
+	out := os.Stdout
+	if *outFile != "" {
+		out, err = os.Create(*outFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer out.Close()
+	}
+	for i, class := range fixed.Classes {
+		fixed.Classes[i] = model.OriginalClass(class)
+	}
+	fixed.WriteC(out, *name)
+	if err := fixed.WriteCInput(out, *name, model.Meta); err != nil {
+		log.Fatal(err)
+	}
+}
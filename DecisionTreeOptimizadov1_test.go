@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLayeredRejectsUnknownConfigKeys(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(config, []byte(`{"depth": 4, "depht": 5}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("prueba", flag.ContinueOnError)
+	fs.Int("depth", 3, "profundidad")
+	err := ParseLayered(fs, []string{"-config", config})
+	if err == nil || !strings.Contains(err.Error(), "depht") {
+		t.Fatalf("ParseLayered = %v; se esperaba un error por depht", err)
+	}
+
+	if err := os.WriteFile(config, []byte(`{"depth": 4}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs = flag.NewFlagSet("prueba", flag.ContinueOnError)
+	depth := fs.Int("depth", 3, "profundidad")
+	if err := ParseLayered(fs, []string{"-config", config}); err != nil || *depth != 4 {
+		t.Errorf("ParseLayered = %v, depth %d; se esperaba 4", err, *depth)
+	}
+}
@@ -0,0 +1,96 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"strconv"
+	"syscall/js"
+
+	"github.com/iStorm30/PCDTA2/pcdta"
+)
+
+// Versión mínima de inferencia para el navegador: carga el modelo desde su JSON, sin
+// acceso a archivos, y predice con pcdta.Model, que aplica las mismas codificaciones,
+// features derivadas y nombres de clase que la CLI. Compilar con:
+//
+//	GOOS=js GOARCH=wasm go build -o pcdta.wasm DecisionTreeWasm.go
+
+var model *pcdta.Model[string]
+
+func main() {
+	js.Global().Set("pcdtaLoad", js.FuncOf(load))
+	js.Global().Set("pcdtaColumns", js.FuncOf(columns))
+	js.Global().Set("pcdtaPredict", js.FuncOf(predict))
+
+	// Mantener vivo el programa para que JS pueda seguir llamando a las funciones
+	select {}
+}
+
+// load recibe el JSON de un modelo guardado con -o y devuelve un mensaje de error o null
+func load(this js.Value, args []js.Value) any {
+	if len(args) != 1 {
+		return "uso: pcdtaLoad(jsonDelModelo)"
+	}
+
+	m, err := pcdta.ParseModel[string]([]byte(args[0].String()))
+	if err != nil {
+		return err.Error()
+	}
+
+	model = m
+	return nil
+}
+
+// columns devuelve los nombres de las columnas que espera pcdtaPredict, en orden
+func columns(this js.Value, args []js.Value) any {
+	if model == nil {
+		return "no hay modelo cargado"
+	}
+	var names []any
+	for _, name := range model.Meta.InputColumns() {
+		names = append(names, name)
+	}
+	return names
+}
+
+// predict recibe un array con el valor de cada columna de pcdtaColumns (números, o
+// cadenas para las columnas categóricas; null es un valor ausente) y devuelve
+// {class, probabilities}
+func predict(this js.Value, args []js.Value) any {
+	if model == nil {
+		return "no hay modelo cargado"
+	}
+	if len(args) != 1 || args[0].Type() != js.TypeObject {
+		return "uso: pcdtaPredict([v0, v1, ...])"
+	}
+
+	record := make([]string, args[0].Length())
+	for i := range record {
+		switch value := args[0].Index(i); value.Type() {
+		case js.TypeNumber:
+			record[i] = strconv.FormatFloat(value.Float(), 'g', -1, 64)
+		case js.TypeNull, js.TypeUndefined:
+			record[i] = ""
+		default:
+			record[i] = value.String()
+		}
+	}
+
+	class, err := model.Predict(record)
+	if err != nil {
+		return err.Error()
+	}
+	proba, err := model.PredictProba(record)
+	if err != nil {
+		return err.Error()
+	}
+	probs := make(map[string]any, len(proba))
+	for name, prob := range proba {
+		probs[name] = prob
+	}
+
+	return map[string]any{
+		"class":         class,
+		"probabilities": probs,
+	}
+}
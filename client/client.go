@@ -0,0 +1,44 @@
+// Package client avisa por HTTP a un servicio externo del resultado de un entrenamiento.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// TrainSummary es el aviso que recibe el callback de un entrenamiento; si falló solo
+// lleva Status "error" y el mensaje
+type TrainSummary struct {
+	Status        string    `json:"status"` // "ok" o "error"
+	Error         string    `json:"error,omitempty"`
+	Model         string    `json:"model,omitempty"` // archivo del modelo, si se guardó
+	TreeHash      string    `json:"tree_hash,omitempty"`
+	Rows          int       `json:"rows,omitempty"`
+	Nodes         int       `json:"nodes,omitempty"`
+	Leaves        int       `json:"leaves,omitempty"`
+	TrainAccuracy float64   `json:"train_accuracy,omitempty"`
+	TrainSeconds  float64   `json:"train_seconds,omitempty"`
+	FinishedAt    time.Time `json:"finished_at"`
+}
+
+// NotifyCallback envía el resumen como JSON por POST; una respuesta que no sea 2xx
+// cuenta como error
+func NotifyCallback(url string, summary TrainSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s respondió %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+// Package experiments registra ejecuciones de entrenamiento (parámetros, métricas y
+// artefactos) en un almacén local y las exporta en el formato de MLflow.
+package experiments
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Run es una ejecución registrada: parámetros, métricas y artefactos copiados.
+// Se guarda en <Root>/<experimento>/<id>/run.json junto a artifacts/.
+type Run struct {
+	ID         string             `json:"id"`
+	Experiment string             `json:"experiment"`
+	StartTime  time.Time          `json:"start_time"`
+	EndTime    time.Time          `json:"end_time"`
+	Params     map[string]string  `json:"params"`
+	Metrics    map[string]float64 `json:"metrics"`
+	Artifacts  []string           `json:"artifacts"`
+}
+
+type ExperimentStore struct {
+	Root string
+}
+
+func NewRun(experiment string) *Run {
+	id := make([]byte, 16)
+	cryptorand.Read(id)
+	return &Run{
+		ID:         hex.EncodeToString(id),
+		Experiment: experiment,
+		StartTime:  time.Now(),
+		Params:     make(map[string]string),
+		Metrics:    make(map[string]float64),
+	}
+}
+
+func (r *Run) LogParam(key string, value any) {
+	r.Params[key] = fmt.Sprint(value)
+}
+
+func (r *Run) LogMetric(key string, value float64) {
+	r.Metrics[key] = value
+}
+
+// LogArtifact apunta un archivo que se copiará dentro de la ejecución al guardarla
+func (r *Run) LogArtifact(path string) {
+	r.Artifacts = append(r.Artifacts, path)
+}
+
+// checkName exige que name sea un único componente de ruta, para que un experimento,
+// una ejecución o un artefacto no puedan salir del almacén con ".." o separadores
+func checkName(kind, name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || filepath.Base(name) != name {
+		return fmt.Errorf("nombre de %s inválido %q", kind, name)
+	}
+	return nil
+}
+
+// checkRun valida los nombres de la ejecución que se convierten en rutas
+func checkRun(run *Run) error {
+	if err := checkName("experimento", run.Experiment); err != nil {
+		return err
+	}
+	if err := checkName("ejecución", run.ID); err != nil {
+		return err
+	}
+	for _, artifact := range run.Artifacts {
+		if err := checkName("artefacto", artifact); err != nil {
+			return err
+		}
+	}
+	for key := range run.Params {
+		if err := checkName("parámetro", key); err != nil {
+			return err
+		}
+	}
+	for key := range run.Metrics {
+		if err := checkName("métrica", key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// artifactName devuelve el nombre base de path, con un sufijo -2, -3… si ya lo usa
+// otro artefacto de la misma ejecución
+func artifactName(path string, used map[string]bool) string {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for n := 2; used[name]; n++ {
+		name = fmt.Sprintf("%s-%d%s", stem, n, ext)
+	}
+	used[name] = true
+	return name
+}
+
+func (s ExperimentStore) runDir(experiment, id string) string {
+	return filepath.Join(s.Root, experiment, id)
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+func (s ExperimentStore) Save(run *Run) error {
+	// Los artefactos se guardan con su nombre base dentro de la ejecución; dos con el
+	// mismo nombre base no se pisan
+	stored := make([]string, len(run.Artifacts))
+	used := make(map[string]bool, len(run.Artifacts))
+	for i, artifact := range run.Artifacts {
+		stored[i] = artifactName(artifact, used)
+	}
+	saved := *run
+	saved.Artifacts = stored
+	if err := checkRun(&saved); err != nil {
+		return err
+	}
+	if saved.EndTime.IsZero() {
+		saved.EndTime = time.Now()
+	}
+
+	dir := s.runDir(saved.Experiment, saved.ID)
+	if err := os.MkdirAll(filepath.Join(dir, "artifacts"), 0755); err != nil {
+		return err
+	}
+	for i, artifact := range run.Artifacts {
+		if err := copyFile(artifact, filepath.Join(dir, "artifacts", stored[i])); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(&saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "run.json"), data, 0644); err != nil {
+		return err
+	}
+	*run = saved
+	return nil
+}
+
+// Runs devuelve las ejecuciones del experimento ordenadas por fecha de inicio
+func (s ExperimentStore) Runs(experiment string) ([]*Run, error) {
+	if err := checkName("experimento", experiment); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(s.Root, experiment))
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*Run
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.Root, experiment, entry.Name(), "run.json"))
+		if err != nil {
+			return nil, err
+		}
+		var run Run
+		if err := json.Unmarshal(data, &run); err != nil {
+			return nil, err
+		}
+		// run.json decide las rutas de ExportMLflow, así que se valida al leerlo
+		if err := checkRun(&run); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		runs = append(runs, &run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartTime.Before(runs[j].StartTime)
+	})
+	return runs, nil
+}
+
+// ExportMLflow escribe el experimento con la estructura de directorios del file store
+// de MLflow (mlruns/<id>/<run>/{meta.yaml,params,metrics,artifacts}) para "mlflow ui"
+func (s ExperimentStore) ExportMLflow(experiment, mlrunsDir string) error {
+	runs, err := s.Runs(experiment)
+	if err != nil {
+		return err
+	}
+
+	// El experimento 0 es el "Default" de MLflow; se usa un identificador derivado del nombre
+	sum := sha256.Sum256([]byte(experiment))
+	experimentID := strconv.FormatUint(binary.BigEndian.Uint64(sum[:8])>>12, 10)
+
+	absRoot, err := filepath.Abs(mlrunsDir)
+	if err != nil {
+		return err
+	}
+	expDir := filepath.Join(absRoot, experimentID)
+	if err := os.MkdirAll(expDir, 0755); err != nil {
+		return err
+	}
+	meta := fmt.Sprintf("artifact_location: file://%s\nexperiment_id: '%s'\nlifecycle_stage: active\nname: %s\n",
+		filepath.ToSlash(expDir), experimentID, experiment)
+	if err := os.WriteFile(filepath.Join(expDir, "meta.yaml"), []byte(meta), 0644); err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		runDir := filepath.Join(expDir, run.ID)
+		for _, sub := range []string{"params", "metrics", "artifacts", "tags"} {
+			if err := os.MkdirAll(filepath.Join(runDir, sub), 0755); err != nil {
+				return err
+			}
+		}
+
+		start, end := run.StartTime.UnixMilli(), run.EndTime.UnixMilli()
+		meta := fmt.Sprintf("artifact_uri: file://%s\nend_time: %d\nentry_point_name: ''\nexperiment_id: '%s'\n"+
+			"lifecycle_stage: active\nrun_id: %s\nrun_name: ''\nrun_uuid: %s\nsource_name: ''\nsource_type: 4\n"+
+			"source_version: ''\nstart_time: %d\nstatus: 3\ntags: []\nuser_id: ''\n",
+			filepath.ToSlash(filepath.Join(runDir, "artifacts")), end, experimentID, run.ID, run.ID, start)
+		if err := os.WriteFile(filepath.Join(runDir, "meta.yaml"), []byte(meta), 0644); err != nil {
+			return err
+		}
+
+		for key, value := range run.Params {
+			if err := os.WriteFile(filepath.Join(runDir, "params", key), []byte(value), 0644); err != nil {
+				return err
+			}
+		}
+		for key, value := range run.Metrics {
+			line := fmt.Sprintf("%d %v 0\n", end, value)
+			if err := os.WriteFile(filepath.Join(runDir, "metrics", key), []byte(line), 0644); err != nil {
+				return err
+			}
+		}
+		for _, artifact := range run.Artifacts {
+			src := filepath.Join(s.runDir(experiment, run.ID), "artifacts", artifact)
+			if err := copyFile(src, filepath.Join(runDir, "artifacts", artifact)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
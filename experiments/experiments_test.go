@@ -0,0 +1,63 @@
+package experiments
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExperimentStoreNamesAndArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, sub, "model.json"), []byte(sub), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := ExperimentStore{Root: filepath.Join(dir, "runs")}
+	run := NewRun("barrido")
+	run.LogParam("max_depth", 3)
+	run.LogMetric("accuracy", 0.9)
+	run.LogArtifact(filepath.Join(dir, "a", "model.json"))
+	run.LogArtifact(filepath.Join(dir, "b", "model.json"))
+	if err := store.Save(run); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"model.json", "model-2.json"}; !slices.Equal(run.Artifacts, want) {
+		t.Errorf("artefactos %v, se esperaba %v", run.Artifacts, want)
+	}
+	for i, want := range []string{"a", "b"} {
+		data, err := os.ReadFile(filepath.Join(store.runDir("barrido", run.ID), "artifacts", run.Artifacts[i]))
+		if err != nil || string(data) != want {
+			t.Errorf("artefacto %s = %q, %v; se esperaba %q", run.Artifacts[i], data, err, want)
+		}
+	}
+	runs, err := store.Runs("barrido")
+	if err != nil || len(runs) != 1 || runs[0].Metrics["accuracy"] != 0.9 {
+		t.Fatalf("Runs = %v, %v", runs, err)
+	}
+
+	for _, name := range []string{"", ".", "..", "../fuera", "a/b", `a\b`} {
+		if err := store.Save(NewRun(name)); err == nil {
+			t.Errorf("Save con el experimento %q no devolvió error", name)
+		}
+		if _, err := store.Runs(name); err == nil {
+			t.Errorf("Runs(%q) no devolvió error", name)
+		}
+		if err := store.ExportMLflow(name, filepath.Join(dir, "mlruns")); err == nil {
+			t.Errorf("ExportMLflow(%q) no devolvió error", name)
+		}
+	}
+	escape := NewRun("barrido")
+	escape.ID = ".."
+	if err := store.Save(escape); err == nil {
+		t.Error("Save con el id .. no devolvió error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "runs", "run.json")); err == nil {
+		t.Error("Save escribió fuera del experimento")
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/iStorm30/PCDTA2
+
+go 1.24
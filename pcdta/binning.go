@@ -0,0 +1,191 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Binning son los cortes aprendidos para discretizar cada feature. El intervalo de un
+// valor x es el número de cortes menores que x, así que un corte es el límite superior
+// incluido de su intervalo; los valores ausentes siguen ausentes.
+type Binning struct {
+	Method string      `json:"method"` // "equal-width", "equal-frequency" o "mdlp"
+	Edges  [][]float64 `json:"edges"`  // cortes estrictamente crecientes de cada feature
+}
+
+// LearnBinning aprende los cortes de cada feature con el método indicado; bins es el
+// número de intervalos de los métodos no supervisados, y MDLP elige el suyo
+func LearnBinning[L comparable](examples []Example[L], method string, bins int) (*Binning, error) {
+	if method != "mdlp" && bins < 2 {
+		return nil, fmt.Errorf("se necesitan al menos 2 intervalos, no %d", bins)
+	}
+	binning := &Binning{Method: method}
+	if len(examples) == 0 {
+		return binning, nil
+	}
+	binning.Edges = make([][]float64, len(examples[0].Features))
+	for j := range binning.Edges {
+		var rows []int
+		for i, example := range examples {
+			if !math.IsNaN(example.Features[j]) {
+				rows = append(rows, i)
+			}
+		}
+		sort.SliceStable(rows, func(a, b int) bool {
+			return examples[rows[a]].Features[j] < examples[rows[b]].Features[j]
+		})
+		if len(rows) == 0 {
+			continue
+		}
+		min, max := examples[rows[0]].Features[j], examples[rows[len(rows)-1]].Features[j]
+
+		var edges []float64
+		switch method {
+		case "equal-width":
+			for b := 1; b < bins; b++ {
+				edges = append(edges, min+float64(b)*(max-min)/float64(bins))
+			}
+		case "equal-frequency":
+			// Con menos valores presentes que intervalos cada valor forma el suyo
+			n := bins
+			if len(rows) < n {
+				n = len(rows)
+			}
+			for b := 1; b < n; b++ {
+				edges = append(edges, examples[rows[b*len(rows)/n-1]].Features[j])
+			}
+		case "mdlp":
+			edges = mdlpCuts(examples, rows, j)
+		default:
+			return nil, fmt.Errorf("método de discretización desconocido %q (equal-width, equal-frequency o mdlp)", method)
+		}
+
+		// Quitar cortes repetidos y los que dejarían vacío el último intervalo
+		for _, edge := range edges {
+			if edge < max && (len(binning.Edges[j]) == 0 || edge > binning.Edges[j][len(binning.Edges[j])-1]) {
+				binning.Edges[j] = append(binning.Edges[j], edge)
+			}
+		}
+	}
+	return binning, nil
+}
+
+// mdlpCuts aplica la discretización supervisada de Fayyad e Irani sobre rows, ya
+// ordenadas por la feature j: parte recursivamente por el corte de menor entropía y
+// acepta cada partición solo si su ganancia supera el criterio MDL
+func mdlpCuts[L comparable](examples []Example[L], rows []int, j int) []float64 {
+	index := make(map[L]int)
+	labels := make([]int, len(rows))
+	for i, row := range rows {
+		class := examples[row].Class
+		if _, ok := index[class]; !ok {
+			index[class] = len(index)
+		}
+		labels[i] = index[class]
+	}
+	value := func(i int) float64 { return examples[rows[i]].Features[j] }
+
+	entropy := func(counts []float64, n float64) (float64, int) {
+		var h float64
+		present := 0
+		for _, count := range counts {
+			if count > 0 {
+				p := count / n
+				h -= p * math.Log2(p)
+				present++
+			}
+		}
+		return h, present
+	}
+
+	var cuts []float64
+	var split func(lo, hi int)
+	split = func(lo, hi int) {
+		n := float64(hi - lo)
+		total := make([]float64, len(index))
+		for i := lo; i < hi; i++ {
+			total[labels[i]]++
+		}
+		h, k := entropy(total, n)
+		if k < 2 {
+			return
+		}
+
+		left := make([]float64, len(index))
+		best, bestH := -1, math.Inf(1)
+		for i := lo + 1; i < hi; i++ {
+			left[labels[i-1]]++
+			if value(i-1) == value(i) {
+				continue
+			}
+			right := make([]float64, len(index))
+			for c := range right {
+				right[c] = total[c] - left[c]
+			}
+			nl := float64(i - lo)
+			hl, _ := entropy(left, nl)
+			hr, _ := entropy(right, n-nl)
+			if weighted := (nl*hl + (n-nl)*hr) / n; weighted < bestH {
+				best, bestH = i, weighted
+			}
+		}
+		if best < 0 {
+			return
+		}
+
+		// Criterio MDL: ganancia > (log2(N-1) + log2(3^k-2) - (k·H - k1·H1 - k2·H2)) / N
+		left = make([]float64, len(index))
+		for i := lo; i < best; i++ {
+			left[labels[i]]++
+		}
+		right := make([]float64, len(index))
+		for c := range right {
+			right[c] = total[c] - left[c]
+		}
+		h1, k1 := entropy(left, float64(best-lo))
+		h2, k2 := entropy(right, float64(hi-best))
+		delta := math.Log2(math.Pow(3, float64(k))-2) - (float64(k)*h - float64(k1)*h1 - float64(k2)*h2)
+		if h-bestH <= (math.Log2(n-1)+delta)/n {
+			return
+		}
+		split(lo, best)
+		cuts = append(cuts, (value(best-1)+value(best))/2)
+		split(best, hi)
+	}
+	split(0, len(rows))
+	return cuts
+}
+
+// Bin devuelve el intervalo del valor de la feature j como número, o NaN si falta
+func (b *Binning) Bin(j int, value float64) float64 {
+	if math.IsNaN(value) {
+		return value
+	}
+	return float64(sort.SearchFloat64s(b.Edges[j], value))
+}
+
+// ApplyBinning devuelve copias de los ejemplos con cada feature sustituida por su intervalo
+func ApplyBinning[L comparable](b *Binning, examples []Example[L]) []Example[L] {
+	binned := make([]Example[L], len(examples))
+	for i, example := range examples {
+		features := make([]float64, len(example.Features))
+		for j, value := range example.Features {
+			features[j] = b.Bin(j, value)
+		}
+		binned[i] = Example[L]{Features: features, Class: example.Class, Weight: example.Weight}
+	}
+	return binned
+}
+
+// UnbinTree traduce los umbrales de un árbol entrenado con ApplyBinning a la escala
+// original: intervalo <= v equivale a x <= Edges[j][floor(v)], de modo que el árbol
+// resultante predice sobre valores sin discretizar y sus umbrales son los cortes.
+func UnbinTree[L comparable](b *Binning, tree *DecisionTree[L]) {
+	if tree == nil || tree.IsLeaf() {
+		return
+	}
+	tree.Value = b.Edges[tree.Column][int(math.Floor(tree.Value))]
+	UnbinTree(b, tree.Left)
+	UnbinTree(b, tree.Right)
+}
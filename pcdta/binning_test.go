@@ -0,0 +1,74 @@
+package pcdta
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestLearnBinningEqualFrequencyFewerRowsThanBins(t *testing.T) {
+	nan := math.NaN()
+	examples := []Example[string]{
+		{Features: []float64{1, nan}, Class: "a"},
+		{Features: []float64{2, 5}, Class: "a"},
+		{Features: []float64{3, nan}, Class: "b"},
+		{Features: []float64{4, 7}, Class: "b"},
+	}
+	binning, err := LearnBinning(examples, "equal-frequency", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{1, 2, 3}; !slices.Equal(binning.Edges[0], want) {
+		t.Errorf("cortes de la columna de 4 filas = %v, se esperaba %v", binning.Edges[0], want)
+	}
+	if want := []float64{5}; !slices.Equal(binning.Edges[1], want) {
+		t.Errorf("cortes de la columna con 2 valores presentes = %v, se esperaba %v", binning.Edges[1], want)
+	}
+
+	if bin := binning.Bin(0, 4); bin != 3 {
+		t.Errorf("Bin(0, 4) = %v, se esperaba 3", bin)
+	}
+	if bin := binning.Bin(1, nan); !math.IsNaN(bin) {
+		t.Errorf("un valor ausente debería seguir ausente, no %v", bin)
+	}
+}
+
+func TestLearnBinningMDLPFindsClassBoundary(t *testing.T) {
+	var examples []Example[string]
+	for i := 0; i < 40; i++ {
+		class := "a"
+		if i >= 20 {
+			class = "b"
+		}
+		examples = append(examples, Example[string]{Features: []float64{float64(i)}, Class: class})
+	}
+	binning, err := LearnBinning(examples, "mdlp", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(binning.Edges[0], []float64{19.5}) {
+		t.Fatalf("cortes MDLP = %v, se esperaba [19.5]", binning.Edges[0])
+	}
+}
+
+func TestUnbinTreeRestoresThresholds(t *testing.T) {
+	var examples []Example[string]
+	for i := 0; i < 60; i++ {
+		class := "a"
+		if i%20 >= 10 {
+			class = "b"
+		}
+		examples = append(examples, Example[string]{Features: []float64{float64(i % 20), float64(i)}, Class: class})
+	}
+	binning, err := LearnBinning(examples, "equal-width", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultTrainOptions[string]()
+	opts.MaxDepth = 3
+	tree, _ := BuildDecisionTreeConcurrent(ApplyBinning(binning, examples), opts)
+	UnbinTree(binning, tree)
+	if Accuracy(tree, examples) != 1 {
+		t.Errorf("el árbol desdiscretizado acierta %.3f sobre datos crudos", Accuracy(tree, examples))
+	}
+}
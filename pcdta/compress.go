@@ -0,0 +1,205 @@
+package pcdta
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// CompressOptions configura CompressForest. Ningún paso se acepta si la precisión sobre
+// los ejemplos de validación cae más de MaxAccuracyLoss respecto al bosque original.
+type CompressOptions struct {
+	MaxTrees        int     // tamaño buscado (0 = quitar todos los árboles que permita MaxAccuracyLoss)
+	MaxAccuracyLoss float64 // precisión que se puede perder, como fracción (0.01 = un punto)
+	MergeTolerance  float64 // distancia media entre las probabilidades de dos árboles para fundirlos
+	ThresholdBits   int     // bits de mantisa que conservan los umbrales (0 = no se redondean)
+}
+
+// CompressReport resume lo que hizo CompressForest
+type CompressReport struct {
+	OriginalTrees    int
+	Trees            int
+	Merged           int  // árboles fundidos con otro casi igual
+	Pruned           int  // árboles quitados por aportar poco
+	Quantized        bool // si se redondearon los umbrales
+	OriginalNodes    int
+	Nodes            int
+	OriginalAccuracy float64
+	Accuracy         float64
+}
+
+// forestVotes guarda las probabilidades de cada árbol en cada ejemplo, para medir
+// cualquier combinación de pesos sin volver a recorrer los árboles
+type forestVotes[L comparable] struct {
+	classes  []L
+	examples []Example[L]
+	probs    [][]float64 // probs[t][i*len(classes)+c]
+}
+
+func newForestVotes[L comparable](classes []L, trees []*DecisionTree[L], examples []Example[L]) *forestVotes[L] {
+	v := &forestVotes[L]{classes: classes, examples: examples, probs: make([][]float64, len(trees))}
+	k := len(classes)
+	for t, tree := range trees {
+		v.probs[t] = make([]float64, len(examples)*k)
+		for i, example := range examples {
+			tree.PredictProbaInto(example.Features, classes, v.probs[t][i*k:(i+1)*k])
+		}
+	}
+	return v
+}
+
+// correct cuenta los ejemplos que acierta el bosque con los pesos dados; un peso 0
+// deja fuera el árbol. Los empates se quedan con la primera clase, como argmaxClass.
+func (v *forestVotes[L]) correct(weights []float64) int {
+	k := len(v.classes)
+	sums := make([]float64, k)
+	correct := 0
+	for i, example := range v.examples {
+		clear(sums)
+		for t, w := range weights {
+			if w == 0 {
+				continue
+			}
+			for c, p := range v.probs[t][i*k : (i+1)*k] {
+				sums[c] += w * p
+			}
+		}
+		best := 0
+		for c := 1; c < k; c++ {
+			if sums[c] > sums[best] {
+				best = c
+			}
+		}
+		if v.classes[best] == example.Class {
+			correct++
+		}
+	}
+	return correct
+}
+
+// distance es la media, sobre los ejemplos, de la distancia de variación total entre
+// las probabilidades de los árboles a y b
+func (v *forestVotes[L]) distance(a, b int) float64 {
+	sum := 0.0
+	for i, p := range v.probs[a] {
+		sum += math.Abs(p - v.probs[b][i])
+	}
+	return sum / 2 / float64(len(v.examples))
+}
+
+// roundMantissa redondea v a bits bits de mantisa
+func roundMantissa(v float64, bits int) float64 {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return v
+	}
+	frac, exp := math.Frexp(v)
+	scale := math.Ldexp(1, bits)
+	return math.Ldexp(math.Round(frac*scale)/scale, exp)
+}
+
+// CompressForest reduce el bosque para servirlo. Primero redondea los umbrales a
+// ThresholdBits bits de mantisa; después funde cada par de árboles cuyas probabilidades
+// difieren en media menos de MergeTolerance (el que queda suma el peso del otro) y por
+// último quita, uno a uno, el árbol cuya ausencia menos baja la precisión, hasta
+// MaxTrees. Cada paso se descarta si la precisión sobre examples cae más de
+// MaxAccuracyLoss. examples debería ser un conjunto de validación que los árboles no
+// hayan visto. El bosque original no cambia.
+func CompressForest[L comparable](forest *Forest[L], examples []Example[L], opts CompressOptions) (*Forest[L], CompressReport, error) {
+	if len(forest.Trees) == 0 {
+		return nil, CompressReport{}, errors.New("el bosque no tiene árboles")
+	}
+	if len(examples) == 0 {
+		return nil, CompressReport{}, errors.New("hacen falta ejemplos para medir la pérdida de precisión")
+	}
+	if opts.MaxAccuracyLoss < 0 || opts.MergeTolerance < 0 {
+		return nil, CompressReport{}, errors.New("MaxAccuracyLoss y MergeTolerance no pueden ser negativos")
+	}
+	if opts.ThresholdBits < 0 || opts.ThresholdBits > 52 {
+		return nil, CompressReport{}, fmt.Errorf("ThresholdBits %d fuera de 0..52", opts.ThresholdBits)
+	}
+
+	weights := make([]float64, len(forest.Trees))
+	report := CompressReport{OriginalTrees: len(forest.Trees)}
+	for t, tree := range forest.Trees {
+		weights[t] = forest.weight(t)
+		report.OriginalNodes += tree.Stats().NodeCount
+	}
+	trees := forest.Trees
+	votes := newForestVotes(forest.Classes, trees, examples)
+	original := votes.correct(weights)
+	// Contar aciertos evita que el redondeo de las fracciones rechace una pérdida justa
+	floor := original - int(math.Floor(opts.MaxAccuracyLoss*float64(len(examples))+1e-9))
+
+	if opts.ThresholdBits > 0 {
+		rounded := make([]*DecisionTree[L], len(trees))
+		for t, tree := range trees {
+			rounded[t] = tree.Clone()
+			rounded[t].Walk(func(node *DecisionTree[L], depth int) {
+				if !node.IsLeaf() {
+					node.Value = roundMantissa(node.Value, opts.ThresholdBits)
+				}
+			})
+		}
+		if q := newForestVotes(forest.Classes, rounded, examples); q.correct(weights) >= floor {
+			trees, votes, report.Quantized = rounded, q, true
+		}
+	}
+
+	for i := range trees {
+		for j := i + 1; j < len(trees) && weights[i] != 0; j++ {
+			if weights[j] == 0 || votes.distance(i, j) > opts.MergeTolerance {
+				continue
+			}
+			candidate := slices.Clone(weights)
+			candidate[i] += candidate[j]
+			candidate[j] = 0
+			if votes.correct(candidate) >= floor {
+				weights = candidate
+				report.Merged++
+			}
+		}
+	}
+
+	for active := len(trees) - report.Merged; active > 1 && active > opts.MaxTrees; active-- {
+		best, bestCorrect := -1, -1
+		for t, w := range weights {
+			if w == 0 {
+				continue
+			}
+			weights[t] = 0
+			if c := votes.correct(weights); c > bestCorrect {
+				best, bestCorrect = t, c
+			}
+			weights[t] = w
+		}
+		if bestCorrect < floor {
+			break
+		}
+		weights[best] = 0
+		report.Pruned++
+	}
+
+	out := &Forest[L]{Classes: slices.Clone(forest.Classes)}
+	uniform := true
+	for t, w := range weights {
+		if w == 0 {
+			continue
+		}
+		tree := trees[t]
+		if !report.Quantized {
+			tree = tree.Clone()
+		}
+		out.Trees = append(out.Trees, tree)
+		out.Weights = append(out.Weights, w)
+		uniform = uniform && w == 1
+		report.Nodes += tree.Stats().NodeCount
+	}
+	if uniform {
+		out.Weights = nil
+	}
+	report.Trees = len(out.Trees)
+	report.OriginalAccuracy = float64(original) / float64(len(examples))
+	report.Accuracy = Accuracy[L](out, examples)
+	return out, report, nil
+}
@@ -0,0 +1,255 @@
+package pcdta
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func LoadExamples(filename string) ([]Example[string], []string, error) {
+	if strings.HasSuffix(filename, ".pcd") {
+		return LoadPCD(filename)
+	}
+	return LoadCSVExamples(filename)
+}
+
+// readCSVRecords lee todas las filas de un CSV con al menos una feature y la clase
+func readCSVRecords(filename string) ([][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// El lector no comprueba la longitud para dar un error con el número de fila
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	data, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || len(data[0]) < 2 {
+		return nil, fmt.Errorf("%s: se necesita al menos una feature y la clase", filename)
+	}
+	for i, d := range data[1:] {
+		if len(d) != len(data[0]) {
+			return nil, fmt.Errorf("%s: fila %d: %d columnas, se esperaban %d", filename, i+2, len(d), len(data[0]))
+		}
+	}
+	return data, nil
+}
+
+// readRawRecords lee las filas de un CSV sin convertir, con la cabecera (o
+// feature_<j> y class si no la tiene); un .pcd se lee con los valores formateados
+func readRawRecords(filename string) (header []string, records [][]string, err error) {
+	if strings.HasSuffix(filename, ".pcd") {
+		examples, featureNames, err := LoadPCD(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		header = append(featureNames, "class")
+		records = make([][]string, len(examples))
+		for i, example := range examples {
+			record := make([]string, 0, len(header))
+			for _, value := range example.Features {
+				record = append(record, strconv.FormatFloat(value, 'g', -1, 64))
+			}
+			records[i] = append(record, example.Class)
+		}
+		return header, records, nil
+	}
+
+	if records, err = readCSVRecords(filename); err != nil {
+		return nil, nil, err
+	}
+	header = make([]string, len(records[0]))
+	for j := range header {
+		header[j] = fmt.Sprintf("feature_%d", j)
+	}
+	header[len(header)-1] = "class"
+	// La cabecera es opcional, como en LoadCSVExamples
+	if _, err := strconv.ParseFloat(strings.TrimSpace(records[0][0]), 64); err != nil {
+		header = records[0]
+		records = records[1:]
+	}
+	return header, records, nil
+}
+
+// LoadColumn devuelve la columna column de cada fila de filename tal como está
+// escrita, sin espacios alrededor, p. ej. para agrupar por una columna categórica que
+// el modelo ha codificado o no usa. Las filas están en el mismo orden que las de
+// LoadExamples y LoadForModel.
+func LoadColumn(filename, column string) ([]string, error) {
+	header, records, err := readRawRecords(filename)
+	if err != nil {
+		return nil, err
+	}
+	j := slices.Index(header, column)
+	if j < 0 {
+		return nil, fmt.Errorf("%s no tiene la columna %s", filename, column)
+	}
+	values := make([]string, len(records))
+	for i, record := range records {
+		values[i] = strings.TrimSpace(record[j])
+	}
+	return values, nil
+}
+
+// LoadCSVFeatures lee un CSV con cabecera en el que todas las columnas son features,
+// sin clase, p. ej. para unirlo con Join a otro que sí la tiene
+func LoadCSVFeatures(filename string) (*Dataset[string], error) {
+	data, err := readCSVRecords(filename)
+	if err != nil {
+		return nil, err
+	}
+	out := &Dataset[string]{FeatureNames: data[0], Examples: make([]Example[string], len(data)-1)}
+	for i, d := range data[1:] {
+		features := make([]float64, len(d))
+		for j, field := range d {
+			if features[j], err = parseFeature(field); err != nil {
+				return nil, fmt.Errorf("%s: fila %d, columna %d: %w", filename, i+1, j, err)
+			}
+		}
+		out.Examples[i] = Example[string]{Features: features}
+	}
+	return out, nil
+}
+
+// LoadCSVExamples lee un CSV cuya última columna es la clase; la cabecera es opcional
+func LoadCSVExamples(filename string) ([]Example[string], []string, error) {
+	data, err := readCSVRecords(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	numFeatures := len(data[0]) - 1
+	featureNames := make([]string, numFeatures)
+	for j := range featureNames {
+		featureNames[j] = fmt.Sprintf("feature_%d", j)
+	}
+
+	// Si la primera fila no es numérica se usa como cabecera
+	if _, err := strconv.ParseFloat(strings.TrimSpace(data[0][0]), 64); err != nil {
+		copy(featureNames, data[0][:numFeatures])
+		data = data[1:]
+	}
+
+	examples := make([]Example[string], len(data))
+	for i, d := range data {
+		features := make([]float64, len(d)-1)
+		for j := range features {
+			features[j], err = parseFeature(d[j])
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: fila %d, columna %d: %w", filename, i+1, j, err)
+			}
+		}
+		examples[i] = Example[string]{
+			Features: features,
+			Class:    d[len(d)-1],
+		}
+	}
+
+	return examples, featureNames, nil
+}
+
+// parseFeature convierte un campo en número; vacío, NA, NaN o ? se leen como ausente (NaN)
+func parseFeature(field string) (float64, error) {
+	field = strings.TrimSpace(field)
+	if isMissingField(field) {
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(field, 64)
+}
+
+// isMissingField indica si un campo ya recortado es un valor ausente
+func isMissingField(field string) bool {
+	switch strings.ToUpper(field) {
+	case "", "NA", "NAN", "?":
+		return true
+	}
+	return false
+}
+
+// LoadCSVCategorical es LoadCSVExamples para un CSV con cabecera en el que las columnas
+// categorical son texto: se quitan de las features y raw[c][i] es el valor de la
+// columna categorical[c] en la fila i, recortado
+func LoadCSVCategorical(filename string, categorical []string) (examples []Example[string], featureNames []string, raw [][]string, err error) {
+	data, err := readCSVRecords(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return parseCSVCategorical(filename, data, categorical)
+}
+
+// LoadCSVDetectCategorical es LoadCSVCategorical con las columnas categóricas detectadas:
+// las que tienen algún valor no numérico que no es un ausente
+func LoadCSVDetectCategorical(filename string) (examples []Example[string], featureNames, categorical []string, raw [][]string, err error) {
+	data, err := readCSVRecords(filename)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	header := data[0][:len(data[0])-1]
+	for j, name := range header {
+		for _, d := range data[1:] {
+			if _, err := parseFeature(d[j]); err != nil {
+				categorical = append(categorical, name)
+				break
+			}
+		}
+	}
+	examples, featureNames, raw, err = parseCSVCategorical(filename, data, categorical)
+	return examples, featureNames, categorical, raw, err
+}
+
+func parseCSVCategorical(filename string, data [][]string, categorical []string) (examples []Example[string], featureNames []string, raw [][]string, err error) {
+	header := data[0][:len(data[0])-1]
+	source := make([]int, len(categorical))
+	for c, name := range categorical {
+		source[c] = -1
+		for j, column := range header {
+			if column == name {
+				source[c] = j
+			}
+		}
+		if source[c] < 0 {
+			return nil, nil, nil, fmt.Errorf("%s: no hay ninguna columna %q en la cabecera", filename, name)
+		}
+	}
+	isCategorical := make(map[int]bool, len(source))
+	for _, j := range source {
+		isCategorical[j] = true
+	}
+	for j, name := range header {
+		if !isCategorical[j] {
+			featureNames = append(featureNames, name)
+		}
+	}
+
+	raw = make([][]string, len(categorical))
+	for c := range raw {
+		raw[c] = make([]string, len(data)-1)
+	}
+	examples = make([]Example[string], len(data)-1)
+	for i, d := range data[1:] {
+		features := make([]float64, 0, len(featureNames))
+		for j, field := range d[:len(header)] {
+			if isCategorical[j] {
+				continue
+			}
+			value, err := parseFeature(field)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("%s: fila %d, columna %d: %w", filename, i+1, j, err)
+			}
+			features = append(features, value)
+		}
+		for c, j := range source {
+			raw[c][i] = strings.TrimSpace(d[j])
+		}
+		examples[i] = Example[string]{Features: features, Class: d[len(d)-1]}
+	}
+	return examples, featureNames, raw, nil
+}
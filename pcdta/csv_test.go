@@ -0,0 +1,79 @@
+package pcdta
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeTemp escribe content en un archivo temporal y devuelve su ruta
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCSVExamplesRejectsRaggedRows(t *testing.T) {
+	path := writeTemp(t, "ragged.csv", "a,b,c,class\n1,2,3,x\n4,5,y\n7,8,9,x\n")
+
+	loaders := map[string]func() error{
+		"examples": func() error { _, _, err := LoadCSVExamples(path); return err },
+		"features": func() error { _, err := LoadCSVFeatures(path); return err },
+		"detect":   func() error { _, _, _, _, err := LoadCSVDetectCategorical(path); return err },
+	}
+	for name, load := range loaders {
+		err := load()
+		if err == nil {
+			t.Fatalf("%s: se aceptó una fila con menos columnas", name)
+		}
+		if !strings.Contains(err.Error(), "fila 3") {
+			t.Errorf("%s: el error no indica la fila: %v", name, err)
+		}
+	}
+}
+
+func TestLoadCSVExamplesHeaderIsOptional(t *testing.T) {
+	withHeader := writeTemp(t, "h.csv", "a,b,class\n1,2,x\n3,NA,y\n")
+	examples, names, err := LoadCSVExamples(withHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) != 2 || names[1] != "b" || examples[1].Class != "y" {
+		t.Fatalf("cabecera mal interpretada: %v %v", names, examples)
+	}
+	if !math.IsNaN(examples[1].Features[1]) {
+		t.Fatalf("NA debería leerse como NaN: %v", examples[1].Features)
+	}
+
+	noHeader := writeTemp(t, "n.csv", "1,2,x\n3,4,y\n")
+	examples, names, err = LoadCSVExamples(noHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) != 2 || names[0] != "feature_0" {
+		t.Fatalf("sin cabecera: %v %v", names, examples)
+	}
+}
+
+func TestLoadColumnKeepsText(t *testing.T) {
+	path := writeTemp(t, "grupos.csv", "x,region,class\n1, EU ,a\n2,US,b\n3,,a\n")
+	got, err := LoadColumn(path, "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []string{"EU", "US", ""}) {
+		t.Errorf("LoadColumn = %q", got)
+	}
+	if got, err := LoadColumn(path, "class"); err != nil || !slices.Equal(got, []string{"a", "b", "a"}) {
+		t.Errorf("LoadColumn(class) = %q, %v", got, err)
+	}
+	if _, err := LoadColumn(path, "device"); err == nil {
+		t.Error("se esperaba un error con una columna que no existe")
+	}
+}
@@ -0,0 +1,810 @@
+package pcdta
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Dataset[L comparable] struct {
+	FeatureNames []string
+	Examples     []Example[L]
+}
+
+type ValidationOptions struct {
+	MaxMissingFraction float64 // fracción máxima de valores ausentes por columna
+	MinClassCount      int     // ejemplos mínimos por clase
+	ConstantTolerance  float64 // varianza por debajo de la cual una columna se considera constante
+}
+
+func DefaultValidationOptions() ValidationOptions {
+	return ValidationOptions{
+		MaxMissingFraction: 0.2,
+		MinClassCount:      5,
+	}
+}
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "aviso"
+)
+
+type DataIssue struct {
+	Severity string
+	Check    string // "ragged", "constant", "missing", "leakage", "class_count"
+	Column   int    // -1 si no se refiere a una columna
+	Message  string
+}
+
+type ValidationReport struct {
+	Rows    int
+	Columns int
+	Issues  []DataIssue
+}
+
+func (r ValidationReport) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (r ValidationReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Validación de datos: %d filas, %d columnas, %d problemas\n", r.Rows, r.Columns, len(r.Issues))
+	for _, issue := range r.Issues {
+		fmt.Fprintf(&sb, "  [%s] %s: %s\n", issue.Severity, issue.Check, issue.Message)
+	}
+	return sb.String()
+}
+
+func (d *Dataset[L]) columnName(j int) string {
+	if j < len(d.FeatureNames) {
+		return d.FeatureNames[j]
+	}
+	return fmt.Sprintf("feature_%d", j)
+}
+
+// Validate revisa los datos antes de entrenar: filas irregulares, columnas constantes,
+// valores ausentes, columnas idénticas al objetivo y clases con muy pocos ejemplos
+func (d *Dataset[L]) Validate(opts ValidationOptions) ValidationReport {
+	report := ValidationReport{
+		Rows:    len(d.Examples),
+		Columns: len(d.FeatureNames),
+	}
+	add := func(severity, check string, column int, format string, args ...any) {
+		report.Issues = append(report.Issues, DataIssue{severity, check, column, fmt.Sprintf(format, args...)})
+	}
+
+	if len(d.Examples) == 0 {
+		add(SeverityError, "rows", -1, "el conjunto de datos está vacío")
+		return report
+	}
+	if report.Columns == 0 {
+		report.Columns = len(d.Examples[0].Features)
+	}
+
+	// Filas con un número de features distinto al esperado
+	ragged := 0
+	for i, example := range d.Examples {
+		if len(example.Features) != report.Columns {
+			if ragged < 5 {
+				add(SeverityError, "ragged", -1, "la fila %d tiene %d features, se esperaban %d",
+					i+1, len(example.Features), report.Columns)
+			}
+			ragged++
+		}
+	}
+	if ragged > 5 {
+		add(SeverityError, "ragged", -1, "%d filas irregulares en total", ragged)
+	}
+	if ragged > 0 {
+		return report
+	}
+
+	for j := 0; j < report.Columns; j++ {
+		name := d.columnName(j)
+
+		missing := 0
+		var sum, sumSq float64
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, example := range d.Examples {
+			value := example.Features[j]
+			if math.IsNaN(value) {
+				missing++
+				continue
+			}
+			sum += value
+			sumSq += value * value
+			min = math.Min(min, value)
+			max = math.Max(max, value)
+		}
+
+		present := len(d.Examples) - missing
+		fraction := float64(missing) / float64(len(d.Examples))
+		switch {
+		case fraction > opts.MaxMissingFraction:
+			add(SeverityError, "missing", j, "%s tiene %.1f%% de valores ausentes (máximo %.1f%%)",
+				name, 100*fraction, 100*opts.MaxMissingFraction)
+		case missing > 0:
+			add(SeverityWarning, "missing", j, "%s tiene %d valores ausentes; siempre van a la rama derecha", name, missing)
+		}
+
+		if present > 0 {
+			mean := sum / float64(present)
+			variance := sumSq/float64(present) - mean*mean
+			if min == max || variance <= opts.ConstantTolerance {
+				add(SeverityWarning, "constant", j, "%s es constante y no aporta divisiones", name)
+			}
+		}
+
+		// Fuga del objetivo: cada valor corresponde a una única clase y viceversa
+		valueClass := make(map[float64]L)
+		classValue := make(map[L]float64)
+		leak := present > 0
+		for _, example := range d.Examples {
+			value := example.Features[j]
+			if math.IsNaN(value) {
+				leak = false
+				break
+			}
+			if c, ok := valueClass[value]; ok && c != example.Class {
+				leak = false
+				break
+			}
+			if v, ok := classValue[example.Class]; ok && v != value {
+				leak = false
+				break
+			}
+			valueClass[value] = example.Class
+			classValue[example.Class] = value
+		}
+		if leak && len(classValue) > 1 {
+			add(SeverityError, "leakage", j, "%s es una codificación exacta de la clase", name)
+		}
+	}
+
+	// Clases con pocos ejemplos, en orden estable
+	counts := ClassCounts(d.Examples)
+	classes := make([]L, 0, len(counts))
+	for class := range counts {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return fmt.Sprint(classes[i]) < fmt.Sprint(classes[j])
+	})
+	for _, class := range classes {
+		if counts[class] < opts.MinClassCount {
+			add(SeverityWarning, "class_count", -1, "la clase %v solo tiene %d ejemplos (mínimo %d)",
+				class, counts[class], opts.MinClassCount)
+		}
+	}
+	if len(counts) < 2 {
+		add(SeverityError, "class_count", -1, "se necesitan al menos dos clases, hay %d", len(counts))
+	}
+
+	return report
+}
+
+type SuspicionOptions struct {
+	MinRowsForID     int     // un ID solo se detecta con al menos estas filas
+	LeakageThreshold float64 // razón de correlación η² a partir de la que se sospecha fuga
+}
+
+func DefaultSuspicionOptions() SuspicionOptions {
+	return SuspicionOptions{
+		MinRowsForID:     20,
+		LeakageThreshold: 0.99,
+	}
+}
+
+type SuspiciousColumn struct {
+	Column int
+	Name   string
+	Reason string  // "id" o "leakage"
+	Score  float64 // η² para fugas, 1 para IDs
+}
+
+func (c SuspiciousColumn) String() string {
+	if c.Reason == "id" {
+		return fmt.Sprintf("%s parece un identificador (un valor entero distinto por fila)", c.Name)
+	}
+	return fmt.Sprintf("%s está casi perfectamente correlada con la clase (η² = %.4f)", c.Name, c.Score)
+}
+
+// SuspiciousColumns marca columnas que producen árboles engañosamente exactos:
+// identificadores (enteros únicos por fila) y columnas cuya razón de correlación
+// con la clase (varianza entre clases / varianza total) es casi 1
+func (d *Dataset[L]) SuspiciousColumns(opts SuspicionOptions) []SuspiciousColumn {
+	if len(d.Examples) == 0 {
+		return nil
+	}
+
+	var suspicious []SuspiciousColumn
+	numFeatures := len(d.Examples[0].Features)
+
+	for j := 0; j < numFeatures; j++ {
+		distinct := make(map[float64]bool)
+		integral := true
+		var sum float64
+		classSum := make(map[L]float64)
+		classCount := make(map[L]int)
+		present := 0
+
+		for _, example := range d.Examples {
+			if j >= len(example.Features) || math.IsNaN(example.Features[j]) {
+				continue
+			}
+			value := example.Features[j]
+			distinct[value] = true
+			if value != math.Trunc(value) {
+				integral = false
+			}
+			sum += value
+			classSum[example.Class] += value
+			classCount[example.Class]++
+			present++
+		}
+		if present == 0 {
+			continue
+		}
+
+		if integral && present >= opts.MinRowsForID && len(distinct) == present {
+			suspicious = append(suspicious, SuspiciousColumn{j, d.columnName(j), "id", 1})
+			continue
+		}
+
+		// η² = Σ n_c (media_c - media)² / Σ (x - media)²
+		mean := sum / float64(present)
+		var total, between float64
+		for _, example := range d.Examples {
+			if j >= len(example.Features) || math.IsNaN(example.Features[j]) {
+				continue
+			}
+			diff := example.Features[j] - mean
+			total += diff * diff
+		}
+		for class, count := range classCount {
+			diff := classSum[class]/float64(count) - mean
+			between += float64(count) * diff * diff
+		}
+		if total > 0 && len(classCount) > 1 {
+			if eta := between / total; eta >= opts.LeakageThreshold {
+				suspicious = append(suspicious, SuspiciousColumn{j, d.columnName(j), "leakage", eta})
+			}
+		}
+	}
+
+	return suspicious
+}
+
+// DropColumns devuelve una copia del conjunto de datos sin las columnas indicadas
+func (d *Dataset[L]) DropColumns(columns ...int) *Dataset[L] {
+	drop := make(map[int]bool)
+	for _, column := range columns {
+		drop[column] = true
+	}
+
+	out := &Dataset[L]{Examples: make([]Example[L], len(d.Examples))}
+	for j, name := range d.FeatureNames {
+		if !drop[j] {
+			out.FeatureNames = append(out.FeatureNames, name)
+		}
+	}
+	for i, example := range d.Examples {
+		features := make([]float64, 0, len(example.Features))
+		for j, value := range example.Features {
+			if !drop[j] {
+				features = append(features, value)
+			}
+		}
+		out.Examples[i] = Example[L]{Features: features, Class: example.Class, Weight: example.Weight}
+	}
+
+	return out
+}
+
+// AggregateDuplicates devuelve una copia del conjunto de datos en la que las filas
+// con las mismas features y clase se funden en un único ejemplo cuyo peso es la suma
+// de los pesos originales. El árbol entrenado con el resultado es el mismo, pero cada
+// nodo ordena y recorre menos filas. Las filas se conservan en orden de primera aparición.
+func (d *Dataset[L]) AggregateDuplicates() *Dataset[L] {
+	type key struct {
+		features string
+		class    L
+	}
+	index := make(map[key]int)
+	out := &Dataset[L]{FeatureNames: d.FeatureNames}
+	buf := make([]byte, 0, 64)
+	for _, example := range d.Examples {
+		buf = buf[:0]
+		for _, value := range example.Features {
+			// Todos los NaN se consideran iguales entre sí
+			if math.IsNaN(value) {
+				value = math.NaN()
+			}
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(value))
+		}
+		k := key{string(buf), example.Class}
+		if i, ok := index[k]; ok {
+			out.Examples[i].Weight += example.SampleWeight()
+			continue
+		}
+		index[k] = len(out.Examples)
+		out.Examples = append(out.Examples, Example[L]{Features: example.Features, Class: example.Class, Weight: example.SampleWeight()})
+	}
+	return out
+}
+
+// WeightsFromColumn devuelve una copia del conjunto de datos que usa la columna indicada
+// como peso de cada ejemplo y la quita de las features
+func (d *Dataset[L]) WeightsFromColumn(name string) (*Dataset[L], error) {
+	column := -1
+	for j, featureName := range d.FeatureNames {
+		if featureName == name {
+			column = j
+		}
+	}
+	if column < 0 {
+		return nil, fmt.Errorf("no existe la columna de pesos %q", name)
+	}
+
+	out := d.DropColumns(column)
+	for i, example := range d.Examples {
+		weight := example.Features[column]
+		if math.IsNaN(weight) || weight <= 0 {
+			return nil, fmt.Errorf("fila %d: peso %v no válido (debe ser mayor que 0)", i+1, weight)
+		}
+		out.Examples[i].Weight = weight
+	}
+	return out, nil
+}
+
+// byClass agrupa los ejemplos por clase, con las clases en orden estable
+func (d *Dataset[L]) byClass() ([]L, map[L][]Example[L]) {
+	groups := make(map[L][]Example[L])
+	var classes []L
+	for _, example := range d.Examples {
+		if _, ok := groups[example.Class]; !ok {
+			classes = append(classes, example.Class)
+		}
+		groups[example.Class] = append(groups[example.Class], example)
+	}
+	return classes, groups
+}
+
+// BalanceClassWeights multiplica el peso de cada ejemplo por total/(clases·peso de su
+// clase), de modo que todas las clases pesan lo mismo sin repetir ni descartar filas.
+// Es la alternativa a remuestrear cuando la clase minoritaria tiene pocas filas.
+func (d *Dataset[L]) BalanceClassWeights() *Dataset[L] {
+	weights := ClassWeights(d.Examples)
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+	out := &Dataset[L]{FeatureNames: d.FeatureNames, Examples: make([]Example[L], len(d.Examples))}
+	for i, example := range d.Examples {
+		example.Weight = example.SampleWeight() * total / (float64(len(weights)) * weights[example.Class])
+		out.Examples[i] = example
+	}
+	return out
+}
+
+// RandomOversample repite ejemplos al azar de cada clase hasta igualar a la mayoritaria
+func (d *Dataset[L]) RandomOversample(rng *rand.Rand) *Dataset[L] {
+	classes, groups := d.byClass()
+	target := 0
+	for _, group := range groups {
+		if len(group) > target {
+			target = len(group)
+		}
+	}
+
+	out := &Dataset[L]{FeatureNames: d.FeatureNames}
+	for _, class := range classes {
+		group := groups[class]
+		out.Examples = append(out.Examples, group...)
+		for i := len(group); i < target; i++ {
+			out.Examples = append(out.Examples, group[rng.Intn(len(group))])
+		}
+	}
+	return out
+}
+
+// RandomUndersample conserva al azar tantos ejemplos de cada clase como tiene la minoritaria
+func (d *Dataset[L]) RandomUndersample(rng *rand.Rand) *Dataset[L] {
+	classes, groups := d.byClass()
+	target := len(d.Examples)
+	for _, group := range groups {
+		if len(group) < target {
+			target = len(group)
+		}
+	}
+
+	out := &Dataset[L]{FeatureNames: d.FeatureNames}
+	for _, class := range classes {
+		group := groups[class]
+		for _, i := range rng.Perm(len(group))[:target] {
+			out.Examples = append(out.Examples, group[i])
+		}
+	}
+	return out
+}
+
+// SMOTE genera ejemplos sintéticos de las clases minoritarias interpolando entre cada
+// ejemplo y uno de sus k vecinos más cercanos de la misma clase, también en el peso
+func (d *Dataset[L]) SMOTE(k int, rng *rand.Rand) *Dataset[L] {
+	classes, groups := d.byClass()
+	target := 0
+	for _, group := range groups {
+		if len(group) > target {
+			target = len(group)
+		}
+	}
+
+	distance := func(a, b []float64) float64 {
+		var sum float64
+		for j := range a {
+			diff := a[j] - b[j]
+			sum += diff * diff
+		}
+		return sum
+	}
+
+	out := &Dataset[L]{FeatureNames: d.FeatureNames}
+	for _, class := range classes {
+		group := groups[class]
+		out.Examples = append(out.Examples, group...)
+		if len(group) < 2 || len(group) >= target {
+			continue
+		}
+
+		// Vecinos más cercanos de cada ejemplo dentro de su clase, solo de los que se
+		// eligen. Se guardan los k mejores por inserción en vez de ordenar toda la clase,
+		// y cada distancia se calcula una sola vez.
+		neighbours := make([][]int, len(group))
+		nearest := func(i int) []int {
+			if neighbours[i] != nil {
+				return neighbours[i]
+			}
+			size := min(max(k, 1), len(group)-1)
+			best := make([]int, 0, size)
+			dist := make([]float64, 0, size)
+			for j := range group {
+				if j == i {
+					continue
+				}
+				dj := distance(group[i].Features, group[j].Features)
+				if len(best) == size && !(dj < dist[size-1]) {
+					continue
+				}
+				if len(best) < size {
+					best, dist = append(best, j), append(dist, dj)
+				}
+				pos := len(best) - 1
+				for pos > 0 && dj < dist[pos-1] {
+					best[pos], dist[pos] = best[pos-1], dist[pos-1]
+					pos--
+				}
+				best[pos], dist[pos] = j, dj
+			}
+			neighbours[i] = best
+			return best
+		}
+
+		for n := len(group); n < target; n++ {
+			i := rng.Intn(len(group))
+			candidates := nearest(i)
+			neighbour := group[candidates[rng.Intn(len(candidates))]]
+			gap := rng.Float64()
+
+			features := make([]float64, len(group[i].Features))
+			for j, value := range group[i].Features {
+				features[j] = value + gap*(neighbour.Features[j]-value)
+			}
+			// El peso se interpola como las features; sin pesos queda sin indicar
+			var weight float64
+			if group[i].Weight != 0 || neighbour.Weight != 0 {
+				from := group[i].SampleWeight()
+				weight = from + gap*(neighbour.SampleWeight()-from)
+			}
+			out.Examples = append(out.Examples, Example[L]{Features: features, Class: class, Weight: weight})
+		}
+	}
+	return out
+}
+
+// SelectColumns devuelve una copia del conjunto de datos con solo las columnas
+// indicadas, en ese orden; sirve para alinear datos nuevos con las features de un modelo
+func (d *Dataset[L]) SelectColumns(names []string) (*Dataset[L], error) {
+	index := make(map[string]int, len(d.FeatureNames))
+	for j, name := range d.FeatureNames {
+		index[name] = j
+	}
+	columns := make([]int, len(names))
+	for i, name := range names {
+		j, ok := index[name]
+		if !ok {
+			return nil, fmt.Errorf("no existe la columna %q", name)
+		}
+		columns[i] = j
+	}
+
+	out := &Dataset[L]{FeatureNames: names, Examples: make([]Example[L], len(d.Examples))}
+	for i, example := range d.Examples {
+		features := make([]float64, len(columns))
+		for k, j := range columns {
+			features[k] = example.Features[j]
+		}
+		out.Examples[i] = Example[L]{Features: features, Class: example.Class, Weight: example.Weight}
+	}
+	return out, nil
+}
+
+// Join une a cada ejemplo de d la fila de other con el mismo valor en la columna on,
+// que en other no puede repetirse. Las features de other, salvo on, se añaden tras las
+// de d y no pueden llamarse como ellas; la clase y el peso son los de d. Los ejemplos
+// sin pareja o con la clave ausente se descartan, como en un inner join.
+func (d *Dataset[L]) Join(other *Dataset[L], on string) (*Dataset[L], error) {
+	if err := d.checkWidth("join", "izquierdo"); err != nil {
+		return nil, err
+	}
+	if err := other.checkWidth("join", "derecho"); err != nil {
+		return nil, err
+	}
+	left := slices.Index(d.FeatureNames, on)
+	if left < 0 {
+		return nil, fmt.Errorf("join: no existe la columna %s en el conjunto izquierdo", on)
+	}
+	right := slices.Index(other.FeatureNames, on)
+	if right < 0 {
+		return nil, fmt.Errorf("join: no existe la columna %s en el conjunto derecho", on)
+	}
+	names, err := joinNames(d.FeatureNames, other.FeatureNames, right)
+	if err != nil {
+		return nil, err
+	}
+	key := func(data *Dataset[L], j int) []string {
+		keys := make([]string, len(data.Examples))
+		for i, example := range data.Examples {
+			if value := example.Features[j]; !math.IsNaN(value) {
+				keys[i] = strconv.FormatFloat(value, 'g', -1, 64)
+			}
+		}
+		return keys
+	}
+	rows, err := joinRows(key(d, left), key(other, right), on)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Dataset[L]{FeatureNames: names}
+	for k, example := range d.Examples {
+		i := rows[k]
+		if i < 0 {
+			continue
+		}
+		features := append(make([]float64, 0, len(out.FeatureNames)), example.Features...)
+		for j, value := range other.Examples[i].Features {
+			if j != right {
+				features = append(features, value)
+			}
+		}
+		out.Examples = append(out.Examples, Example[L]{Features: features, Class: example.Class, Weight: example.Weight})
+	}
+	return out, nil
+}
+
+// joinNames devuelve las columnas de una unión: las de left y las de right salvo la
+// clave key. Un nombre repetido es un error, porque las columnas dejarían de poder
+// distinguirse por nombre.
+func joinNames(left, right []string, key int) ([]string, error) {
+	names := slices.Clone(left)
+	for j, name := range right {
+		if j == key {
+			continue
+		}
+		if slices.Contains(names, name) {
+			return nil, fmt.Errorf("join: la columna %s está en los dos conjuntos", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// joinRows devuelve, para cada clave de left, la fila de right con la misma clave o
+// -1. Las claves vacías no se emparejan y una repetida en right es un error.
+func joinRows(left, right []string, on string) ([]int, error) {
+	rows := make(map[string]int, len(right))
+	for i, key := range right {
+		if key == "" {
+			continue
+		}
+		if _, ok := rows[key]; ok {
+			return nil, fmt.Errorf("join: la clave %s = %s se repite en el conjunto derecho", on, key)
+		}
+		rows[key] = i
+	}
+	out := make([]int, len(left))
+	for k, key := range left {
+		out[k] = -1
+		if i, ok := rows[key]; ok && key != "" {
+			out[k] = i
+		}
+	}
+	return out, nil
+}
+
+// JoinFiles escribe en w como CSV el inner join de left (CSV o .pcd con la clase en la
+// última columna) con el CSV con cabecera right por la columna on. La clave se compara
+// como texto (salvo los números, por su valor), así que puede ser un identificador no
+// numérico, y no se copia: la salida
+// tiene las columnas de left, las de right y la clase. Devuelve las filas de left y las
+// que han encontrado pareja.
+func JoinFiles(w io.Writer, left, right, on string) (rows, joined int, err error) {
+	header, records, err := readRawRecords(left)
+	if err != nil {
+		return 0, 0, err
+	}
+	other, err := readCSVRecords(right)
+	if err != nil {
+		return 0, 0, err
+	}
+	otherHeader, otherRecords := other[0], other[1:]
+
+	leftKey := slices.Index(header[:len(header)-1], on)
+	if leftKey < 0 {
+		return 0, 0, fmt.Errorf("join: %s no tiene la columna %s", left, on)
+	}
+	rightKey := slices.Index(otherHeader, on)
+	if rightKey < 0 {
+		return 0, 0, fmt.Errorf("join: %s no tiene la columna %s", right, on)
+	}
+	leftNames := slices.Delete(slices.Clone(header[:len(header)-1]), leftKey, leftKey+1)
+	names, err := joinNames(leftNames, otherHeader, rightKey)
+	if err != nil {
+		return 0, 0, err
+	}
+	key := func(records [][]string, j int) []string {
+		keys := make([]string, len(records))
+		for i, record := range records {
+			field := strings.TrimSpace(record[j])
+			if value, err := strconv.ParseFloat(field, 64); err == nil {
+				// 1 y 1.0 son la misma clave numérica, como en Join
+				field = strconv.FormatFloat(value, 'g', -1, 64)
+			}
+			if !isMissingField(field) {
+				keys[i] = field
+			}
+		}
+		return keys
+	}
+	matches, err := joinRows(key(records, leftKey), key(otherRecords, rightKey), on)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	out := csv.NewWriter(w)
+	out.Write(append(names, header[len(header)-1]))
+	for k, record := range records {
+		i := matches[k]
+		if i < 0 {
+			continue
+		}
+		row := make([]string, 0, len(names)+1)
+		for j, field := range record[:len(record)-1] {
+			if j != leftKey {
+				row = append(row, field)
+			}
+		}
+		for j, field := range otherRecords[i] {
+			if j != rightKey {
+				row = append(row, field)
+			}
+		}
+		out.Write(append(row, record[len(record)-1]))
+		joined++
+	}
+	out.Flush()
+	return len(records), joined, out.Error()
+}
+
+// checkWidth comprueba que todas las filas de d tengan una feature por columna, para
+// que las operaciones que combinan conjuntos no lean fuera de una fila corta
+func (d *Dataset[L]) checkWidth(op, which string) error {
+	for i, example := range d.Examples {
+		if len(example.Features) != len(d.FeatureNames) {
+			return fmt.Errorf("%s: fila %d del conjunto %s: %d features, se esperaban %d",
+				op, i+1, which, len(example.Features), len(d.FeatureNames))
+		}
+	}
+	return nil
+}
+
+// Concat apila los ejemplos de varios conjuntos con las columnas del primero; los demás
+// se alinean por nombre y deben tener todas esas columnas
+func Concat[L comparable](datasets ...*Dataset[L]) (*Dataset[L], error) {
+	if len(datasets) == 0 {
+		return &Dataset[L]{}, nil
+	}
+	for k, data := range datasets {
+		if err := data.checkWidth("concat", strconv.Itoa(k+1)); err != nil {
+			return nil, err
+		}
+	}
+	out := &Dataset[L]{FeatureNames: datasets[0].FeatureNames}
+	out.Examples = append(out.Examples, datasets[0].Examples...)
+	for k, data := range datasets[1:] {
+		aligned, err := data.SelectColumns(out.FeatureNames)
+		if err != nil {
+			return nil, fmt.Errorf("concat: conjunto %d: %w", k+2, err)
+		}
+		out.Examples = append(out.Examples, aligned.Examples...)
+	}
+	return out, nil
+}
+
+// MapClasses devuelve una copia con las clases sustituidas según mapping, que puede
+// renombrar o fundir clases; las que no aparecen en mapping se conservan. También
+// devuelve la correspondencia completa de cada clase de d con su nueva clase.
+func (d *Dataset[L]) MapClasses(mapping map[L]L) (*Dataset[L], map[L]L) {
+	classes, _ := d.byClass()
+	full := make(map[L]L, len(classes))
+	for _, class := range classes {
+		full[class] = class
+		if to, ok := mapping[class]; ok {
+			full[class] = to
+		}
+	}
+	out := &Dataset[L]{FeatureNames: d.FeatureNames, Examples: make([]Example[L], len(d.Examples))}
+	for i, example := range d.Examples {
+		out.Examples[i] = example
+		out.Examples[i].Class = full[example.Class]
+	}
+	return out, full
+}
+
+// MergeRareClasses funde en other las clases con menos de minCount ejemplos
+func (d *Dataset[L]) MergeRareClasses(minCount int, other L) (*Dataset[L], map[L]L) {
+	_, groups := d.byClass()
+	mapping := make(map[L]L)
+	for class, group := range groups {
+		if len(group) < minCount {
+			mapping[class] = other
+		}
+	}
+	return d.MapClasses(mapping)
+}
+
+// OneVsRest convierte el problema en binario: positive frente a rest
+func (d *Dataset[L]) OneVsRest(positive, rest L) (*Dataset[L], map[L]L) {
+	classes, _ := d.byClass()
+	mapping := make(map[L]L)
+	for _, class := range classes {
+		if class != positive {
+			mapping[class] = rest
+		}
+	}
+	return d.MapClasses(mapping)
+}
+
+// ComposeClassMappings encadena dos correspondencias completas; first puede ser nil
+func ComposeClassMappings[L comparable](first, second map[L]L) map[L]L {
+	if first == nil {
+		return second
+	}
+	out := make(map[L]L, len(first))
+	for from, to := range first {
+		out[from] = second[to]
+	}
+	return out
+}
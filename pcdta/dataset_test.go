@@ -0,0 +1,110 @@
+package pcdta
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestJoinRejectsDuplicateColumnsAndRaggedRows(t *testing.T) {
+	left := &Dataset[string]{FeatureNames: []string{"id", "x"}, Examples: []Example[string]{
+		{Features: []float64{1, 10}, Class: "a"},
+		{Features: []float64{2, 20}, Class: "b"},
+	}}
+	right := &Dataset[string]{FeatureNames: []string{"id", "z"}, Examples: []Example[string]{
+		{Features: []float64{2, 0.5}},
+	}}
+	joined, err := left.Join(right, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(joined.Examples) != 1 || strings.Join(joined.FeatureNames, ",") != "id,x,z" || joined.Examples[0].Features[2] != 0.5 {
+		t.Errorf("Join = %v, %v", joined.FeatureNames, joined.Examples)
+	}
+
+	right.FeatureNames[1] = "x"
+	if _, err := left.Join(right, "id"); err == nil || !strings.Contains(err.Error(), "x") {
+		t.Errorf("columna repetida: error %v", err)
+	}
+	right.FeatureNames[1] = "z"
+	right.Examples[0].Features = right.Examples[0].Features[:1]
+	if _, err := left.Join(right, "id"); err == nil {
+		t.Error("se esperaba un error con una fila corta")
+	}
+	if _, err := Concat(left, right); err == nil {
+		t.Error("Concat: se esperaba un error con una fila corta")
+	}
+}
+
+func TestJoinFilesMatchesTextKeys(t *testing.T) {
+	left := writeTemp(t, "clientes.csv", "id,x,class\nu1,1,a\nu2,2,b\nu3,3,a\n")
+	right := writeTemp(t, "extra.csv", "id,region\nu3,EU\nu1,US\n")
+	var out strings.Builder
+	rows, joined, err := JoinFiles(&out, left, right, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "x,region,class\n1,US,a\n3,EU,a\n"
+	if rows != 3 || joined != 2 || out.String() != want {
+		t.Errorf("JoinFiles = %d, %d:\n%s", rows, joined, out.String())
+	}
+
+	duplicated := writeTemp(t, "repetida.csv", "id,region\nu1,EU\nu1,US\n")
+	if _, _, err := JoinFiles(&out, left, duplicated, "id"); err == nil {
+		t.Error("se esperaba un error con una clave repetida")
+	}
+}
+
+func TestSMOTEInterpolatesBetweenNeighboursAndWeights(t *testing.T) {
+	data := &Dataset[string]{FeatureNames: []string{"x"}}
+	for i := 0; i < 20; i++ {
+		data.Examples = append(data.Examples, Example[string]{Features: []float64{float64(i)}, Class: "mayoritaria"})
+	}
+	// Con k = 1 cada sintético queda entre un ejemplo y su vecino más cercano
+	minority := []Example[string]{
+		{Features: []float64{0}, Class: "minoritaria", Weight: 2},
+		{Features: []float64{1}, Class: "minoritaria", Weight: 4},
+		{Features: []float64{100}, Class: "minoritaria", Weight: 2},
+		{Features: []float64{102}, Class: "minoritaria", Weight: 2},
+	}
+	data.Examples = append(data.Examples, minority...)
+
+	out := data.SMOTE(1, rand.New(rand.NewSource(1)))
+	synthetic := 0
+	for _, example := range out.Examples[len(data.Examples):] {
+		if example.Class != "minoritaria" {
+			continue
+		}
+		synthetic++
+		x, w := example.Features[0], example.Weight
+		switch {
+		case x >= 0 && x <= 1:
+			if w < 2 || w > 4 || math.Abs(w-(2+2*x)) > 1e-9 {
+				t.Errorf("x = %v con peso %v, se esperaba %v", x, w, 2+2*x)
+			}
+		case x >= 100 && x <= 102:
+			if w != 2 {
+				t.Errorf("x = %v con peso %v, se esperaba 2", x, w)
+			}
+		default:
+			t.Errorf("x = %v no está entre dos vecinos", x)
+		}
+	}
+	if synthetic != 16 {
+		t.Errorf("%d ejemplos sintéticos, se esperaban 16", synthetic)
+	}
+}
+
+func TestBalanceClassWeights(t *testing.T) {
+	d := &Dataset[string]{Examples: []Example[string]{
+		{Class: "a"}, {Class: "a"}, {Class: "a", Weight: 2}, {Class: "b"},
+	}}
+	weights := ClassWeights(d.BalanceClassWeights().Examples)
+	if math.Abs(weights["a"]-weights["b"]) > 1e-12 || math.Abs(weights["a"]+weights["b"]-5) > 1e-12 {
+		t.Errorf("pesos por clase %v, se esperaban iguales y con el total original", weights)
+	}
+	if d.Examples[0].Weight != 0 {
+		t.Error("BalanceClassWeights modificó los ejemplos originales")
+	}
+}
@@ -0,0 +1,156 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ColumnSummary resume una columna; las estadísticas numéricas solo usan los valores
+// presentes y quedan a NaN si no hay ninguno o la columna es de texto
+type ColumnSummary struct {
+	Name        string
+	Categorical bool
+	Present     int
+	Missing     int
+	Distinct    int // cardinalidad de los valores presentes
+	Min, Max    float64
+	Mean, Std   float64
+	P25, Median float64
+	P75         float64
+}
+
+// ClassShare es el número de ejemplos de una clase y su proporción
+type ClassShare struct {
+	Class string
+	Count int
+	Share float64
+}
+
+// DatasetSummary es el resultado de Describe
+type DatasetSummary struct {
+	Rows      int
+	Columns   []ColumnSummary
+	Classes   []ClassShare // de la más a la menos frecuente
+	Imbalance float64      // peso de la clase mayoritaria por cada unidad de la minoritaria
+}
+
+func (s DatasetSummary) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Filas: %d, columnas: %d\n", s.Rows, len(s.Columns))
+	fmt.Fprintf(&sb, "  %-20s %9s %8s %9s %10s %10s %10s %10s %10s %10s %10s\n",
+		"columna", "presentes", "ausentes", "distintos", "mín", "p25", "mediana", "p75", "máx", "media", "desv.")
+	for _, c := range s.Columns {
+		if c.Categorical {
+			fmt.Fprintf(&sb, "  %-20s %9d %8d %9d %10s\n", c.Name, c.Present, c.Missing, c.Distinct, "(texto)")
+			continue
+		}
+		fmt.Fprintf(&sb, "  %-20s %9d %8d %9d %10.4g %10.4g %10.4g %10.4g %10.4g %10.4g %10.4g\n",
+			c.Name, c.Present, c.Missing, c.Distinct, c.Min, c.P25, c.Median, c.P75, c.Max, c.Mean, c.Std)
+	}
+	fmt.Fprintf(&sb, "Clases: %d (desequilibrio %.2f:1)\n", len(s.Classes), s.Imbalance)
+	for _, c := range s.Classes {
+		fmt.Fprintf(&sb, "  %-20s %8d %6.1f%%\n", c.Class, c.Count, 100*c.Share)
+	}
+	return sb.String()
+}
+
+// quantileSorted interpola linealmente el cuantil q de valores ya ordenados
+func quantileSorted(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	if lo+1 >= len(sorted) {
+		return sorted[lo]
+	}
+	return sorted[lo] + (pos-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+// describeColumn resume los valores de una columna numérica
+func describeColumn(name string, values []float64) ColumnSummary {
+	var present []float64
+	for _, value := range values {
+		if !math.IsNaN(value) {
+			present = append(present, value)
+		}
+	}
+	sort.Float64s(present)
+	c := ColumnSummary{Name: name, Present: len(present), Missing: len(values) - len(present)}
+	c.Min, c.Max, c.Mean, c.Std = math.NaN(), math.NaN(), math.NaN(), math.NaN()
+	c.P25, c.Median, c.P75 = quantileSorted(present, 0.25), quantileSorted(present, 0.5), quantileSorted(present, 0.75)
+	if len(present) == 0 {
+		return c
+	}
+	c.Min, c.Max = present[0], present[len(present)-1]
+	c.Mean = Mean(present)
+	var squares float64
+	for i, value := range present {
+		squares += (value - c.Mean) * (value - c.Mean)
+		if i == 0 || value != present[i-1] {
+			c.Distinct++
+		}
+	}
+	c.Std = math.Sqrt(squares / float64(len(present)))
+	return c
+}
+
+// DescribeText resume una columna de texto: solo presentes, ausentes y cardinalidad
+func DescribeText(name string, values []string) ColumnSummary {
+	c := ColumnSummary{Name: name, Categorical: true}
+	c.Min, c.Max, c.Mean, c.Std = math.NaN(), math.NaN(), math.NaN(), math.NaN()
+	c.P25, c.Median, c.P75 = math.NaN(), math.NaN(), math.NaN()
+	distinct := make(map[string]bool)
+	for _, value := range values {
+		if isMissingField(value) {
+			c.Missing++
+			continue
+		}
+		c.Present++
+		distinct[value] = true
+	}
+	c.Distinct = len(distinct)
+	return c
+}
+
+// Describe resume cada columna del conjunto y el reparto de las clases
+func (d *Dataset[L]) Describe() DatasetSummary {
+	s := DatasetSummary{Rows: len(d.Examples)}
+	values := make([]float64, len(d.Examples))
+	for j, name := range d.FeatureNames {
+		for i, example := range d.Examples {
+			values[i] = example.Features[j]
+		}
+		s.Columns = append(s.Columns, describeColumn(name, values))
+	}
+
+	s.Classes, s.Imbalance = ClassBalance(d.Examples)
+	return s
+}
+
+// ClassBalance devuelve cada clase con su número de ejemplos y su proporción del peso
+// total, de la de más a la de menos peso, y la razón entre el peso de la primera y el
+// de la última
+func ClassBalance[L comparable](examples []Example[L]) ([]ClassShare, float64) {
+	weights := ClassWeights(examples)
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+	var shares []ClassShare
+	for class, count := range ClassCounts(examples) {
+		shares = append(shares, ClassShare{Class: fmt.Sprint(class), Count: count, Share: weights[class] / total})
+	}
+	sort.Slice(shares, func(a, b int) bool {
+		if shares[a].Share != shares[b].Share {
+			return shares[a].Share > shares[b].Share
+		}
+		return shares[a].Class < shares[b].Class
+	})
+	if len(shares) == 0 || shares[len(shares)-1].Share == 0 {
+		return shares, 0
+	}
+	return shares, shares[0].Share / shares[len(shares)-1].Share
+}
@@ -0,0 +1,210 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// LeafKNN envuelve un árbol y, cuando el ejemplo cae en una hoja poco segura
+// (probabilidad máxima por debajo de MinConfidence), predice con un voto ponderado
+// por proximidad entre los K ejemplos de entrenamiento más cercanos de esa misma
+// hoja. Las distancias se miden con cada feature dividida por su desviación típica.
+type LeafKNN[L comparable] struct {
+	Tree          *DecisionTree[L]
+	K             int
+	MinConfidence float64
+
+	leaves map[*DecisionTree[L]][]Example[L]
+	scale  []float64
+}
+
+func NewLeafKNN[L comparable](tree *DecisionTree[L], examples []Example[L], k int, minConfidence float64) *LeafKNN[L] {
+	m := &LeafKNN[L]{
+		Tree:          tree,
+		K:             k,
+		MinConfidence: minConfidence,
+		leaves:        make(map[*DecisionTree[L]][]Example[L]),
+	}
+	for _, example := range examples {
+		leaf := tree.Leaf(example.Features)
+		m.leaves[leaf] = append(m.leaves[leaf], example)
+	}
+
+	if len(examples) > 0 {
+		m.scale = make([]float64, len(examples[0].Features))
+		for j := range m.scale {
+			var sum, sumSq float64
+			n := 0
+			for _, example := range examples {
+				if value := example.Features[j]; !math.IsNaN(value) {
+					sum += value
+					sumSq += value * value
+					n++
+				}
+			}
+			m.scale[j] = 1
+			if n > 0 {
+				mean := sum / float64(n)
+				if std := math.Sqrt(math.Max(sumSq/float64(n)-mean*mean, 0)); std > 0 {
+					m.scale[j] = std
+				}
+			}
+		}
+	}
+	return m
+}
+
+// distance ignora las dimensiones en las que falta alguno de los dos valores
+func (m *LeafKNN[L]) distance(a, b []float64) float64 {
+	var sum float64
+	for j := range a {
+		if math.IsNaN(a[j]) || math.IsNaN(b[j]) {
+			continue
+		}
+		diff := (a[j] - b[j]) / m.scale[j]
+		sum += diff * diff
+	}
+	return math.Sqrt(sum)
+}
+
+func (m *LeafKNN[L]) PredictProba(features []float64) map[L]float64 {
+	probs := m.Tree.PredictProba(features)
+	maxProb := 0.0
+	for _, prob := range probs {
+		maxProb = math.Max(maxProb, prob)
+	}
+	neighbours := m.leaves[m.Tree.Leaf(features)]
+	if maxProb >= m.MinConfidence || len(neighbours) == 0 {
+		return probs
+	}
+
+	type candidate struct {
+		distance float64
+		example  Example[L]
+	}
+	candidates := make([]candidate, len(neighbours))
+	for i, example := range neighbours {
+		candidates[i] = candidate{m.distance(features, example.Features), example}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	if len(candidates) > m.K {
+		candidates = candidates[:m.K]
+	}
+
+	// Cada vecino vota con peso 1/(1+d) multiplicado por su peso de ejemplo
+	votes := make(map[L]float64)
+	var total float64
+	for _, c := range candidates {
+		vote := c.example.SampleWeight() / (1 + c.distance)
+		votes[c.example.Class] += vote
+		total += vote
+	}
+	// Si todos los votos son nulos (distancias infinitas) o no son números, cada
+	// vecino cuenta igual en vez de dividir entre 0
+	if !(total > 0) {
+		clear(votes)
+		for _, c := range candidates {
+			votes[c.example.Class]++
+		}
+		total = float64(len(candidates))
+	}
+	for class := range votes {
+		votes[class] /= total
+	}
+	return votes
+}
+
+func (m *LeafKNN[L]) Predict(features []float64) L {
+	probs := m.PredictProba(features)
+	classes := make([]L, 0, len(probs))
+	for class := range probs {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool { return fmt.Sprint(classes[i]) < fmt.Sprint(classes[j]) })
+	return argmaxClass(classes, probs)
+}
+
+// DistillReport resume el parecido del árbol alumno con el modelo profesor
+type DistillReport struct {
+	Fidelity        float64 // fracción de ejemplos originales en que ambos predicen la misma clase
+	TeacherAccuracy float64
+	StudentAccuracy float64
+	Augmented       int // ejemplos sintéticos añadidos
+	Leaves          int
+}
+
+func (r DistillReport) String() string {
+	return fmt.Sprintf("Fidelidad al profesor: %.4f\nPrecisión: profesor %.3f, alumno %.3f\nHojas del alumno: %d (%d ejemplos sintéticos)",
+		r.Fidelity, r.TeacherAccuracy, r.StudentAccuracy, r.Leaves, r.Augmented)
+}
+
+// Distill entrena un árbol que imita las probabilidades del profesor. Cada ejemplo se
+// reparte en una copia por clase con peso igual a la probabilidad que le da el
+// profesor, así las hojas aprenden la distribución blanda y no solo la clase ganadora.
+// Con augment > 0 se añaden ese número de filas sintéticas: cada una parte de un
+// ejemplo al azar y toma cada feature, con probabilidad 1/2, de otro ejemplo al azar.
+func Distill[L comparable](teacher ProbabilisticClassifier[L], examples []Example[L], opts TrainOptions[L], augment int, rng *rand.Rand) (*DecisionTree[L], DistillReport) {
+	inputs := make([]Example[L], 0, len(examples)+augment)
+	inputs = append(inputs, examples...)
+	if len(examples) > 0 {
+		for i := 0; i < augment; i++ {
+			base := examples[rng.Intn(len(examples))].Features
+			features := make([]float64, len(base))
+			for j := range features {
+				features[j] = base[j]
+				if rng.Intn(2) == 0 {
+					features[j] = examples[rng.Intn(len(examples))].Features[j]
+				}
+			}
+			inputs = append(inputs, Example[L]{Features: features})
+		}
+	}
+
+	var soft []Example[L]
+	for i, input := range inputs {
+		weight := 1.0
+		if i < len(examples) {
+			weight = input.SampleWeight()
+		}
+		probs := teacher.PredictProba(input.Features)
+		classes := make([]L, 0, len(probs))
+		for class := range probs {
+			classes = append(classes, class)
+		}
+		sort.Slice(classes, func(i, j int) bool { return fmt.Sprint(classes[i]) < fmt.Sprint(classes[j]) })
+		for _, class := range classes {
+			if probs[class] > 0 {
+				soft = append(soft, Example[L]{Features: input.Features, Class: class, Weight: weight * probs[class]})
+			}
+		}
+	}
+
+	student, _ := BuildDecisionTreeConcurrent(soft, opts)
+
+	report := EvaluateDistill[L](teacher, student, examples)
+	report.Augmented, report.Leaves = len(inputs)-len(examples), student.Stats().LeafCount
+	return student, report
+}
+
+// EvaluateDistill mide la fidelidad y la precisión de profesor y alumno sobre examples.
+// Distill las mide sobre los ejemplos de entrenamiento, donde un profesor como LeafKNN
+// es su propio vecino más cercano; para cifras fiables examples debe ser una reserva
+// que no se usó para entrenar a ninguno de los dos.
+func EvaluateDistill[L comparable](teacher, student Classifier[L], examples []Example[L]) DistillReport {
+	var report DistillReport
+	if len(examples) == 0 {
+		return report
+	}
+	agree := 0
+	for _, example := range examples {
+		if student.Predict(example.Features) == teacher.Predict(example.Features) {
+			agree++
+		}
+	}
+	report.Fidelity = float64(agree) / float64(len(examples))
+	report.TeacherAccuracy = Accuracy(teacher, examples)
+	report.StudentAccuracy = Accuracy(student, examples)
+	return report
+}
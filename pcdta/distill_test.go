@@ -0,0 +1,46 @@
+package pcdta
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLeafKNNFallsBackToUniformVotes(t *testing.T) {
+	tree := &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 1, "b": 1}}
+	examples := []Example[string]{
+		{Features: []float64{0}, Class: "a"},
+		{Features: []float64{1}, Class: "b"},
+		{Features: []float64{2}, Class: "b"},
+	}
+	knn := NewLeafKNN(tree, examples, 3, 1)
+
+	// A distancia infinita todos los votos 1/(1+d) son 0
+	probs := knn.PredictProba([]float64{math.Inf(1)})
+	if math.Abs(probs["a"]-1.0/3) > 1e-12 || math.Abs(probs["b"]-2.0/3) > 1e-12 {
+		t.Errorf("PredictProba = %v, se esperaba a 1/3 y b 2/3", probs)
+	}
+	if probs = knn.PredictProba([]float64{0}); !(probs["a"] > probs["b"]) {
+		t.Errorf("PredictProba(0) = %v, se esperaba a por delante", probs)
+	}
+}
+
+func TestEvaluateDistillOnHeldOutRows(t *testing.T) {
+	examples := separable(200, 5)
+	train, test := examples[:150], examples[150:]
+	opts := DefaultTrainOptions[string]()
+	tree, _ := BuildDecisionTreeConcurrent(train, opts)
+	teacher := NewLeafKNN(tree, train, 1, 1)
+
+	// Con un solo vecino el profesor se copia a sí mismo en sus filas de entrenamiento
+	student, trainReport := Distill[string](teacher, train, opts, 0, nil)
+	if trainReport.TeacherAccuracy != 1 {
+		t.Fatalf("precisión del profesor en entrenamiento %.3f, se esperaba 1", trainReport.TeacherAccuracy)
+	}
+	heldOut := EvaluateDistill[string](teacher, student, test)
+	if want := Accuracy[string](teacher, test); heldOut.TeacherAccuracy != want {
+		t.Errorf("precisión en la reserva %.3f, se esperaba %.3f", heldOut.TeacherAccuracy, want)
+	}
+	if empty := EvaluateDistill[string](teacher, student, nil); empty != (DistillReport{}) {
+		t.Errorf("sin filas: %+v", empty)
+	}
+}
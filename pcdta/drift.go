@@ -0,0 +1,193 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// psiFloor evita el logaritmo de cero en los intervalos vacíos del PSI
+const psiFloor = 1e-4
+
+// FeatureDrift compara la distribución de una feature entre la referencia y el tráfico
+// reciente: PSI sobre intervalos de cuantiles de la referencia (los ausentes forman su
+// propio intervalo) y estadístico KS de dos muestras sobre los valores presentes
+type FeatureDrift struct {
+	Feature string  `json:"feature"`
+	PSI     float64 `json:"psi"`
+	KS      float64 `json:"ks"`
+	Drifted bool    `json:"drifted"`
+}
+
+// ClassDrift compara las proporciones de cada clase predicha
+type ClassDrift struct {
+	PSI       float64            `json:"psi"`
+	Reference map[string]float64 `json:"reference"`
+	Current   map[string]float64 `json:"current"`
+	Drifted   bool               `json:"drifted"`
+}
+
+// DriftReport es el informe de deriva; Drifted se activa si la distribución de clases
+// predichas o alguna feature supera el umbral de PSI
+type DriftReport struct {
+	ReferenceRows int            `json:"reference_rows"`
+	CurrentRows   int            `json:"current_rows"`
+	Threshold     float64        `json:"psi_threshold"`
+	Predictions   ClassDrift     `json:"predictions"`
+	Features      []FeatureDrift `json:"features"`
+	Drifted       bool           `json:"drifted"`
+}
+
+func (r DriftReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Deriva: %d filas de referencia, %d recientes (umbral PSI %g)\n", r.ReferenceRows, r.CurrentRows, r.Threshold)
+	flag := func(drifted bool) string {
+		if drifted {
+			return "  << deriva"
+		}
+		return ""
+	}
+	fmt.Fprintf(&sb, "  clases predichas: PSI %.4f%s\n", r.Predictions.PSI, flag(r.Predictions.Drifted))
+	classes := make([]string, 0, len(r.Predictions.Reference))
+	for class := range r.Predictions.Reference {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		fmt.Fprintf(&sb, "    %s: %.3f -> %.3f\n", class, r.Predictions.Reference[class], r.Predictions.Current[class])
+	}
+	for _, f := range r.Features {
+		fmt.Fprintf(&sb, "  %s: PSI %.4f, KS %.4f%s\n", f.Feature, f.PSI, f.KS, flag(f.Drifted))
+	}
+	return sb.String()
+}
+
+// psi es el índice de estabilidad poblacional entre dos distribuciones de proporciones
+func psi(reference, current []float64) float64 {
+	var sum float64
+	for i := range reference {
+		p, q := math.Max(reference[i], psiFloor), math.Max(current[i], psiFloor)
+		sum += (q - p) * math.Log(q/p)
+	}
+	return sum
+}
+
+// FeaturePSI reparte ambas muestras en bins intervalos por cuantiles de la referencia,
+// más uno para los valores ausentes, y devuelve su PSI
+func FeaturePSI(reference, current []float64, bins int) float64 {
+	var present []float64
+	for _, value := range reference {
+		if !math.IsNaN(value) {
+			present = append(present, value)
+		}
+	}
+	sort.Float64s(present)
+	var edges []float64
+	for b := 1; b < bins && len(present) > 0; b++ {
+		edge := present[b*len(present)/bins]
+		if len(edges) == 0 || edge > edges[len(edges)-1] {
+			edges = append(edges, edge)
+		}
+	}
+
+	proportions := func(values []float64) []float64 {
+		counts := make([]float64, len(edges)+2)
+		for _, value := range values {
+			if math.IsNaN(value) {
+				counts[len(edges)+1]++
+			} else {
+				counts[sort.Search(len(edges), func(i int) bool { return edges[i] > value })]++
+			}
+		}
+		for i := range counts {
+			counts[i] /= math.Max(float64(len(values)), 1)
+		}
+		return counts
+	}
+	return psi(proportions(reference), proportions(current))
+}
+
+// KSStatistic es la distancia máxima entre las funciones de distribución empíricas
+// de los valores presentes de dos muestras
+func KSStatistic(a, b []float64) float64 {
+	present := func(values []float64) []float64 {
+		var out []float64
+		for _, value := range values {
+			if !math.IsNaN(value) {
+				out = append(out, value)
+			}
+		}
+		sort.Float64s(out)
+		return out
+	}
+	x, y := present(a), present(b)
+	if len(x) == 0 || len(y) == 0 {
+		return 0
+	}
+	var d float64
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		value := math.Min(x[i], y[j])
+		for i < len(x) && x[i] == value {
+			i++
+		}
+		for j < len(y) && y[j] == value {
+			j++
+		}
+		d = math.Max(d, math.Abs(float64(i)/float64(len(x))-float64(j)/float64(len(y))))
+	}
+	return d
+}
+
+// Drift compara el tráfico reciente con la referencia: las features columna a columna
+// y las clases que el modelo predice en cada conjunto
+func Drift[L comparable](model Classifier[L], reference, current []Example[L], featureNames []string, bins int, threshold float64) DriftReport {
+	report := DriftReport{ReferenceRows: len(reference), CurrentRows: len(current), Threshold: threshold}
+
+	shares := func(examples []Example[L]) map[string]float64 {
+		out := make(map[string]float64)
+		for _, example := range examples {
+			out[fmt.Sprint(model.Predict(example.Features))]++
+		}
+		for class := range out {
+			out[class] /= float64(len(examples))
+		}
+		return out
+	}
+	report.Predictions.Reference, report.Predictions.Current = shares(reference), shares(current)
+	var classes []string
+	for class := range report.Predictions.Reference {
+		classes = append(classes, class)
+	}
+	for class := range report.Predictions.Current {
+		if _, ok := report.Predictions.Reference[class]; !ok {
+			classes = append(classes, class)
+			report.Predictions.Reference[class] = 0
+		}
+	}
+	sort.Strings(classes)
+	p, q := make([]float64, len(classes)), make([]float64, len(classes))
+	for i, class := range classes {
+		p[i], q[i] = report.Predictions.Reference[class], report.Predictions.Current[class]
+	}
+	report.Predictions.PSI = psi(p, q)
+	report.Predictions.Drifted = report.Predictions.PSI > threshold
+	report.Drifted = report.Predictions.Drifted
+
+	column := func(examples []Example[L], j int) []float64 {
+		values := make([]float64, len(examples))
+		for i, example := range examples {
+			values[i] = example.Features[j]
+		}
+		return values
+	}
+	for j, name := range featureNames {
+		ref, cur := column(reference, j), column(current, j)
+		f := FeatureDrift{Feature: name, PSI: FeaturePSI(ref, cur, bins), KS: KSStatistic(ref, cur)}
+		f.Drifted = f.PSI > threshold
+		report.Drifted = report.Drifted || f.Drifted
+		report.Features = append(report.Features, f)
+	}
+	return report
+}
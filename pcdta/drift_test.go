@@ -0,0 +1,77 @@
+package pcdta
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFeaturePSI(t *testing.T) {
+	// La referencia {1, 2, 3, 4} en dos intervalos corta en 3: proporciones (1/2, 1/2)
+	// y 0 ausentes, que cuentan como psiFloor
+	floored := (0.5-psiFloor)*math.Log(0.5/psiFloor) + 0.5*math.Log(2)
+	for _, tc := range []struct {
+		name               string
+		reference, current []float64
+		bins               int
+		want               float64
+	}{
+		{"igual", []float64{1, 2, 3, 4}, []float64{4, 3, 2, 1}, 2, 0},
+		// (3/4 - 1/2)·ln(3/2) + (1/4 - 1/2)·ln(1/2) = ln(3)/4
+		{"desplazada", []float64{1, 2, 3, 4}, []float64{1, 1, 1, 4}, 2, math.Log(3) / 4},
+		// Intervalos (1/4, 1/4, 1/2) frente a (1/2, 1/2, 0)
+		{"ausentes nuevos", []float64{1, 2, 3, 4}, []float64{1, math.NaN(), math.NaN(), 4}, 2, floored},
+		// Los cortes repetidos se funden: queda solo el 1, y la referencia entera cae a
+		// su derecha
+		{"referencia constante", []float64{1, 1, 1, 1}, []float64{0, 2}, 4, floored},
+	} {
+		if got := FeaturePSI(tc.reference, tc.current, tc.bins); !near(got, tc.want) {
+			t.Errorf("%s: PSI %v, se esperaba %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestKSStatistic(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"solapadas", []float64{1, 2, 3, 4}, []float64{3, 4, 5, 6}, 0.5},
+		{"disjuntas", []float64{1, 2, 3, 4}, []float64{5, 6}, 1},
+		// En 1: 2/3 frente a 1/3; en 2 las dos llegan a 1
+		{"empates", []float64{1, 1, 2}, []float64{1, 2, 2}, 1.0 / 3},
+		{"ausentes", []float64{1, math.NaN()}, []float64{1}, 0},
+		{"vacía", nil, []float64{1}, 0},
+	} {
+		if got := KSStatistic(tc.a, tc.b); !near(got, tc.want) {
+			t.Errorf("%s: KS %v, se esperaba %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDriftFlagsPredictionShift(t *testing.T) {
+	// echoModel predice sí cuando la feature vale 1: la referencia siempre predice no y
+	// la muestra reciente, la mitad de las veces sí
+	examples := func(values ...float64) []Example[string] {
+		out := make([]Example[string], len(values))
+		for i, value := range values {
+			out[i] = Example[string]{Features: []float64{value}, Class: "no"}
+		}
+		return out
+	}
+	report := Drift[string](echoModel{}, examples(0, 0, 0, 0), examples(0, 0, 1, 1), []string{"x"}, 2, 0.25)
+
+	// Clases (no, sí): (1, 0) frente a (1/2, 1/2)
+	want := 0.5*math.Log(2) + (0.5-psiFloor)*math.Log(0.5/psiFloor)
+	if !near(report.Predictions.PSI, want) || !report.Predictions.Drifted || !report.Drifted {
+		t.Errorf("predicciones: PSI %v (se esperaba %v), deriva %v", report.Predictions.PSI, want, report.Predictions.Drifted)
+	}
+	if share, ok := report.Predictions.Reference["sí"]; !ok || share != 0 || report.Predictions.Current["sí"] != 0.5 {
+		t.Errorf("proporciones %v -> %v", report.Predictions.Reference, report.Predictions.Current)
+	}
+	// Con una referencia constante en 0 el único corte es 0 y todo cae en el mismo
+	// intervalo: el PSI no ve el cambio y el KS sí
+	if f := report.Features[0]; f.PSI != 0 || f.KS != 0.5 || f.Drifted {
+		t.Errorf("feature: %+v", f)
+	}
+}
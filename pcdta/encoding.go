@@ -0,0 +1,219 @@
+package pcdta
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ColumnEncoding convierte una columna categórica de la entrada en las features
+// numéricas Features del modelo
+type ColumnEncoding struct {
+	Column      string               `json:"column"`
+	Method      string               `json:"method"` // "onehot", "target" o "hash"
+	Cardinality int                  `json:"cardinality"`
+	Features    []string             `json:"features"`
+	Values      map[string][]float64 `json:"values,omitempty"` // valores de Features para cada categoría
+	Default     []float64            `json:"default"`          // categorías no vistas al entrenar o ausentes
+}
+
+// Encode devuelve los valores de Features para una categoría. Con hashing no hay
+// vocabulario: la categoría activa el cubo que le toca según su hash FNV-1a.
+func (e *ColumnEncoding) Encode(category string) []float64 {
+	values, _ := e.Lookup(category)
+	return values
+}
+
+// Lookup es Encode indicando además si la categoría se vio al entrenar. Una no vista
+// recibe Default, igual que un valor ausente, pero solo ella devuelve known false:
+// el modelo la trata como si faltara, y quien predice debería saberlo.
+func (e *ColumnEncoding) Lookup(category string) (values []float64, known bool) {
+	if e.Method == "hash" && !isMissingField(category) {
+		values := make([]float64, len(e.Features))
+		h := fnv.New32a()
+		h.Write([]byte(category))
+		values[h.Sum32()%uint32(len(values))] = 1
+		return values, true
+	}
+	if values, ok := e.Values[category]; ok {
+		return values, true
+	}
+	return e.Default, isMissingField(category)
+}
+
+// CategoricalPolicy elige la codificación de cada columna categórica según su número de
+// categorías distintas
+type CategoricalPolicy struct {
+	MaxOneHot       int     // one-hot hasta este número de categorías
+	HighCardinality string  // por encima: "target" o "hash"
+	HashBuckets     int     // features de cada columna codificada con hashing
+	TargetSmoothing float64 // filas equivalentes de la proporción global en "target"
+}
+
+// DefaultCategoricalPolicy devuelve la política de -categorical auto
+func DefaultCategoricalPolicy() CategoricalPolicy {
+	return CategoricalPolicy{
+		MaxOneHot:       10,
+		HighCardinality: "target",
+		HashBuckets:     32,
+		TargetSmoothing: 10,
+	}
+}
+
+// Method devuelve la codificación para una columna con cardinality categorías
+func (p CategoricalPolicy) Method(cardinality int) string {
+	if cardinality <= p.MaxOneHot {
+		return "onehot"
+	}
+	return p.HighCardinality
+}
+
+// fitTargetEncoding calcula, con las filas rows, la proporción suavizada de cada clase
+// de classes en cada categoría: (n_clase + m·prior) / (n + m), con prior la proporción
+// de la clase en rows y m = smoothing
+func fitTargetEncoding(examples []Example[string], values []string, rows []int, classes []string, smoothing float64) (map[string][]float64, []float64) {
+	index := make(map[string]int, len(classes))
+	for c, class := range classes {
+		index[class] = c
+	}
+	prior := make([]float64, len(classes))
+	counts := make(map[string][]float64)
+	totals := make(map[string]float64)
+	for _, i := range rows {
+		c, ok := index[examples[i].Class]
+		if ok {
+			prior[c]++
+		}
+		if isMissingField(values[i]) {
+			continue
+		}
+		if counts[values[i]] == nil {
+			counts[values[i]] = make([]float64, len(classes))
+		}
+		if ok {
+			counts[values[i]][c]++
+		}
+		totals[values[i]]++
+	}
+	for c := range prior {
+		prior[c] /= math.Max(float64(len(rows)), 1)
+	}
+	encoded := make(map[string][]float64, len(counts))
+	for category, count := range counts {
+		encoded[category] = make([]float64, len(classes))
+		for c := range classes {
+			encoded[category][c] = (count[c] + smoothing*prior[c]) / (totals[category] + smoothing)
+		}
+	}
+	return encoded, prior
+}
+
+// TargetEncode codifica todas las columnas categóricas con "target"; ver EncodeCategorical
+func TargetEncode(examples []Example[string], featureNames, columns []string, raw [][]string, folds [][]int, smoothing float64) ([]Example[string], []string, []ColumnEncoding) {
+	return EncodeCategorical(examples, featureNames, columns, raw, folds, CategoricalPolicy{HighCardinality: "target", TargetSmoothing: smoothing})
+}
+
+// EncodeCategorical añade a cada ejemplo las features de cada columna categórica con la
+// codificación que elige policy:
+//   - onehot: una feature 0/1 por categoría vista al entrenar.
+//   - hash: HashBuckets features 0/1; cada categoría activa la de su hash.
+//   - target: la proporción suavizada de cada clase salvo la última, que es redundante
+//     (con dos clases queda una sola feature). Las features de entrenamiento se
+//     calculan fuera de pliegue: las filas de cada uno de folds solo ven las
+//     estadísticas de los demás, así que ninguna fila codifica su propia clase.
+//
+// Las codificaciones devueltas se ajustan con todas las filas y son las que se
+// aplican al puntuar.
+func EncodeCategorical(examples []Example[string], featureNames, columns []string, raw [][]string, folds [][]int, policy CategoricalPolicy) ([]Example[string], []string, []ColumnEncoding) {
+	seen := make(map[string]bool)
+	var classes []string
+	for _, example := range examples {
+		if !seen[example.Class] {
+			seen[example.Class] = true
+			classes = append(classes, example.Class)
+		}
+	}
+	sort.Strings(classes)
+	if len(classes) > 1 {
+		classes = classes[:len(classes)-1]
+	}
+
+	all := make([]int, len(examples))
+	for i := range all {
+		all[i] = i
+	}
+	foldOf := make([]int, len(examples))
+	for f, fold := range folds {
+		for _, i := range fold {
+			foldOf[i] = f
+		}
+	}
+
+	encoded := make([]Example[string], len(examples))
+	for i, example := range examples {
+		encoded[i] = example
+		encoded[i].Features = append([]float64(nil), example.Features...)
+	}
+	names := append([]string(nil), featureNames...)
+	encodings := make([]ColumnEncoding, len(columns))
+	for c, column := range columns {
+		var categories []string
+		distinct := make(map[string]bool)
+		for _, value := range raw[c] {
+			if !isMissingField(value) && !distinct[value] {
+				distinct[value] = true
+				categories = append(categories, value)
+			}
+		}
+		sort.Strings(categories)
+
+		encoding := ColumnEncoding{Column: column, Method: policy.Method(len(categories)), Cardinality: len(categories)}
+		var outOfFold []ColumnEncoding
+		switch encoding.Method {
+		case "onehot":
+			encoding.Values = make(map[string][]float64, len(categories))
+			for k, category := range categories {
+				encoding.Features = append(encoding.Features, column+"="+category)
+				encoding.Values[category] = make([]float64, len(categories))
+				encoding.Values[category][k] = 1
+			}
+			encoding.Default = make([]float64, len(categories))
+		case "hash":
+			for b := 0; b < policy.HashBuckets; b++ {
+				encoding.Features = append(encoding.Features, column+"#"+strconv.Itoa(b))
+			}
+			encoding.Default = make([]float64, policy.HashBuckets)
+		default:
+			for _, class := range classes {
+				encoding.Features = append(encoding.Features, column+":"+class)
+			}
+			encoding.Values, encoding.Default = fitTargetEncoding(examples, raw[c], all, classes, policy.TargetSmoothing)
+
+			// Estadísticas de cada pliegue con las filas de los demás
+			if len(folds) > 1 {
+				outOfFold = make([]ColumnEncoding, len(folds))
+				for f := range folds {
+					var rows []int
+					for i := range examples {
+						if foldOf[i] != f {
+							rows = append(rows, i)
+						}
+					}
+					outOfFold[f].Values, outOfFold[f].Default = fitTargetEncoding(examples, raw[c], rows, classes, policy.TargetSmoothing)
+				}
+			}
+		}
+		encodings[c] = encoding
+		names = append(names, encoding.Features...)
+
+		for i := range encoded {
+			values := encoding.Encode(raw[c][i])
+			if outOfFold != nil {
+				values = outOfFold[foldOf[i]].Encode(raw[c][i])
+			}
+			encoded[i].Features = append(encoded[i].Features, values...)
+		}
+	}
+	return encoded, names, encodings
+}
@@ -0,0 +1,188 @@
+package pcdta
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// VotingEnsemble combina modelos de cualquier tipo por votación. Con voto duro cada
+// modelo vota su clase con su peso; con voto blando (Soft) se promedian, ponderadas,
+// sus probabilidades, y todos los modelos han de ser ProbabilisticClassifier.
+type VotingEnsemble[L comparable] struct {
+	Classes []L // orden de desempate de Predict
+	Models  []Classifier[L]
+	Weights []float64 // peso de cada modelo; vacío equivale a 1
+	Soft    bool
+}
+
+// NewVotingEnsemble comprueba que los pesos correspondan a los modelos y que, con voto
+// blando, todos den probabilidades
+func NewVotingEnsemble[L comparable](classes []L, models []Classifier[L], weights []float64, soft bool) (*VotingEnsemble[L], error) {
+	if len(models) == 0 {
+		return nil, errors.New("la votación necesita al menos un modelo")
+	}
+	if len(weights) > 0 && len(weights) != len(models) {
+		return nil, fmt.Errorf("%d pesos para %d modelos", len(weights), len(models))
+	}
+	total := 0.0
+	for i, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("el peso del modelo %d es negativo: %v", i, w)
+		}
+		total += w
+	}
+	if len(weights) > 0 && total == 0 {
+		return nil, errors.New("todos los pesos son 0")
+	}
+	if soft {
+		for i, model := range models {
+			if _, ok := model.(ProbabilisticClassifier[L]); !ok {
+				return nil, fmt.Errorf("el voto blando necesita probabilidades y el modelo %d (%T) no las da", i, model)
+			}
+		}
+	}
+	return &VotingEnsemble[L]{Classes: classes, Models: models, Weights: weights, Soft: soft}, nil
+}
+
+func (v *VotingEnsemble[L]) weight(i int) float64 {
+	if len(v.Weights) == 0 {
+		return 1
+	}
+	return v.Weights[i]
+}
+
+// PredictProba es, con voto duro, la fracción ponderada de votos de cada clase y, con
+// voto blando, la media ponderada de las probabilidades de los modelos
+func (v *VotingEnsemble[L]) PredictProba(features []float64) map[L]float64 {
+	total := 0.0
+	for i := range v.Models {
+		total += v.weight(i)
+	}
+	probs := make(map[L]float64, len(v.Classes))
+	for i, model := range v.Models {
+		w := v.weight(i) / total
+		if !v.Soft {
+			probs[model.Predict(features)] += w
+			continue
+		}
+		for class, p := range model.(ProbabilisticClassifier[L]).PredictProba(features) {
+			probs[class] += w * p
+		}
+	}
+	return probs
+}
+
+func (v *VotingEnsemble[L]) Predict(features []float64) L {
+	return argmaxClass(v.Classes, v.PredictProba(features))
+}
+
+// VotingEstimator entrena cada estimador con los mismos ejemplos y los combina en un
+// VotingEnsemble con los pesos dados
+func VotingEstimator[L comparable](estimators []Estimator[L], weights []float64, soft bool) Estimator[L] {
+	return EstimatorFunc[L](func(examples []Example[L]) (ProbabilisticClassifier[L], error) {
+		fitted, err := fitAll(estimators, examples)
+		if err != nil {
+			return nil, err
+		}
+		models := make([]Classifier[L], len(fitted))
+		for i, model := range fitted {
+			models[i] = model
+		}
+		ensemble, err := NewVotingEnsemble(sortedClasses(examples), models, weights, soft)
+		if err != nil {
+			return nil, err
+		}
+		return ensemble, nil
+	})
+}
+
+// Stacking combina modelos base con un meta-modelo entrenado sobre sus probabilidades:
+// las features del meta-modelo son la probabilidad que cada modelo base da a cada
+// clase, en el orden de Base y de Classes
+type Stacking[L comparable] struct {
+	Classes []L
+	Base    []ProbabilisticClassifier[L]
+	Meta    ProbabilisticClassifier[L]
+}
+
+// MetaFeatures devuelve las features que ve el meta-modelo para un ejemplo
+func (s *Stacking[L]) MetaFeatures(features []float64) []float64 {
+	return stackFeatures(s.Base, s.Classes, features)
+}
+
+func stackFeatures[L comparable](base []ProbabilisticClassifier[L], classes []L, features []float64) []float64 {
+	out := make([]float64, 0, len(base)*len(classes))
+	for _, model := range base {
+		probs := model.PredictProba(features)
+		for _, class := range classes {
+			out = append(out, probs[class])
+		}
+	}
+	return out
+}
+
+func (s *Stacking[L]) PredictProba(features []float64) map[L]float64 {
+	return s.Meta.PredictProba(s.MetaFeatures(features))
+}
+
+func (s *Stacking[L]) Predict(features []float64) L {
+	return argmaxClass(s.Classes, s.PredictProba(features))
+}
+
+// StackingEstimator entrena un Stacking. El meta-modelo aprende de predicciones fuera
+// de pliegue: en cada uno de los folds pliegues de KFolds los modelos base se entrenan
+// sin esas filas y las predicen, para que el meta-modelo no aprenda a fiarse de
+// modelos que ya vieron los ejemplos. Después los modelos base se reentrenan con todos
+// los ejemplos. rng da los pliegues de todos los entrenamientos sucesivos.
+func StackingEstimator[L comparable](base []Estimator[L], meta Estimator[L], folds int, rng *rand.Rand) Estimator[L] {
+	return EstimatorFunc[L](func(examples []Example[L]) (ProbabilisticClassifier[L], error) {
+		if len(base) == 0 {
+			return nil, errors.New("el stacking necesita al menos un modelo base")
+		}
+		partition, err := KFolds(len(examples), folds, rng)
+		if err != nil {
+			return nil, err
+		}
+		classes := sortedClasses(examples)
+
+		metaExamples := make([]Example[L], len(examples))
+		for f, fold := range partition {
+			train, _ := splitFold(examples, fold)
+			models, err := fitAll(base, train)
+			if err != nil {
+				return nil, fmt.Errorf("pliegue %d: %w", f+1, err)
+			}
+			for _, i := range fold {
+				metaExamples[i] = Example[L]{
+					Features: stackFeatures(models, classes, examples[i].Features),
+					Class:    examples[i].Class,
+					Weight:   examples[i].Weight,
+				}
+			}
+		}
+		metaModel, err := meta.Fit(metaExamples)
+		if err != nil {
+			return nil, fmt.Errorf("meta-modelo: %w", err)
+		}
+
+		models, err := fitAll(base, examples)
+		if err != nil {
+			return nil, err
+		}
+		return &Stacking[L]{Classes: classes, Base: models, Meta: metaModel}, nil
+	})
+}
+
+// fitAll entrena cada estimador con los mismos ejemplos
+func fitAll[L comparable](estimators []Estimator[L], examples []Example[L]) ([]ProbabilisticClassifier[L], error) {
+	models := make([]ProbabilisticClassifier[L], len(estimators))
+	for i, estimator := range estimators {
+		model, err := estimator.Fit(examples)
+		if err != nil {
+			return nil, fmt.Errorf("modelo %d: %w", i, err)
+		}
+		models[i] = model
+	}
+	return models, nil
+}
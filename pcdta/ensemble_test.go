@@ -0,0 +1,132 @@
+package pcdta
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// constantModel predice siempre las mismas probabilidades
+type constantModel map[string]float64
+
+func (m constantModel) PredictProba(features []float64) map[string]float64 { return m }
+
+func (m constantModel) Predict(features []float64) string {
+	return argmaxClass([]string{"a", "b"}, m)
+}
+
+// hardOnly solo sabe votar una clase
+type hardOnly string
+
+func (h hardOnly) Predict(features []float64) string { return string(h) }
+
+func TestVotingEnsemble(t *testing.T) {
+	// Dos modelos dudan por poco hacia a y uno está seguro de b
+	models := []Classifier[string]{
+		constantModel{"a": 0.6, "b": 0.4},
+		constantModel{"a": 0.6, "b": 0.4},
+		constantModel{"a": 0, "b": 1},
+	}
+	classes := []string{"a", "b"}
+
+	hard, err := NewVotingEnsemble(classes, models, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hard.Predict(nil); got != "a" {
+		t.Errorf("voto duro = %s, se esperaba a", got)
+	}
+	if p := hard.PredictProba(nil)["a"]; math.Abs(p-2.0/3) > 1e-9 {
+		t.Errorf("voto duro: P(a) = %v, se esperaba 2/3", p)
+	}
+
+	soft, err := NewVotingEnsemble(classes, models, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := soft.Predict(nil); got != "b" {
+		t.Errorf("voto blando = %s, se esperaba b", got)
+	}
+
+	// Con peso 3 el tercer modelo gana también el voto duro
+	weighted, err := NewVotingEnsemble(classes, models, []float64{1, 1, 3}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := weighted.Predict(nil); got != "b" {
+		t.Errorf("voto duro ponderado = %s, se esperaba b", got)
+	}
+
+	mixed := []Classifier[string]{hardOnly("a"), constantModel{"b": 1}}
+	if _, err := NewVotingEnsemble(classes, mixed, nil, true); err == nil {
+		t.Error("voto blando con un modelo sin probabilidades no devolvió error")
+	}
+	if _, err := NewVotingEnsemble(classes, mixed, nil, false); err != nil {
+		t.Errorf("voto duro con un modelo sin probabilidades: %v", err)
+	}
+	if _, err := NewVotingEnsemble(classes, models, []float64{1, 2}, false); err == nil {
+		t.Error("dos pesos para tres modelos no devolvió error")
+	}
+	if _, err := NewVotingEnsemble(classes, models, []float64{1, -1, 1}, false); err == nil {
+		t.Error("un peso negativo no devolvió error")
+	}
+}
+
+func TestVotingEstimator(t *testing.T) {
+	examples := separable(200, 7)
+	opts := DefaultTrainOptions[string]()
+	opts.MaxDepth = 3
+	estimator := VotingEstimator([]Estimator[string]{
+		TreeEstimator(opts),
+		ForestEstimator(opts, ForestOptions{Trees: 5, MaxFeatures: 2}, rand.New(rand.NewSource(1))),
+		OneVsRestEstimator(opts),
+	}, []float64{1, 2, 1}, true)
+
+	folds, err := KFolds(len(examples), 4, rand.New(rand.NewSource(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores, err := CrossValidateEstimator(estimator, examples, folds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc := Mean(scores); acc < 0.9 {
+		t.Errorf("precisión media %.3f en datos separables", acc)
+	}
+}
+
+func TestStackingEstimator(t *testing.T) {
+	examples := separable(200, 7)
+	opts := DefaultTrainOptions[string]()
+	opts.MaxDepth = 3
+	// Un modelo base que siempre dice a: el meta-modelo debe aprender a ignorarlo
+	wrong := EstimatorFunc[string](func([]Example[string]) (ProbabilisticClassifier[string], error) {
+		return constantModel{"a": 1}, nil
+	})
+	metaOpts := DefaultTrainOptions[string]()
+	metaOpts.MaxDepth = 2
+	estimator := StackingEstimator([]Estimator[string]{
+		wrong,
+		TreeEstimator(opts),
+		ForestEstimator(opts, ForestOptions{Trees: 5, MaxFeatures: 2}, rand.New(rand.NewSource(1))),
+	}, TreeEstimator(metaOpts), 5, rand.New(rand.NewSource(2)))
+
+	model, err := estimator.Fit(examples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stack := model.(*Stacking[string])
+	if n := len(stack.MetaFeatures(examples[0].Features)); n != 6 {
+		t.Errorf("%d features del meta-modelo, se esperaban 3 modelos × 2 clases", n)
+	}
+	if acc := Accuracy[string](stack, separable(100, 8)); acc < 0.9 {
+		t.Errorf("precisión %.3f en datos separables", acc)
+	}
+
+	if _, err := estimator.Fit(examples[:3]); err == nil {
+		t.Error("5 pliegues con 3 filas no devolvió error")
+	}
+	if _, err := StackingEstimator(nil, TreeEstimator(metaOpts), 5, rand.New(rand.NewSource(2))).Fit(examples); err == nil {
+		t.Error("sin modelos base no devolvió error")
+	}
+}
@@ -0,0 +1,50 @@
+package pcdta
+
+// Classifier es la interfaz de predicción común a todos los modelos: el árbol y los
+// meta-clasificadores
+type Classifier[L comparable] interface {
+	Predict(features []float64) L
+}
+
+// ProbabilisticClassifier es un Classifier que además da la probabilidad de cada
+// clase; la cumplen todos los modelos del paquete, y la necesitan el voto blando, el
+// stacking y la destilación
+type ProbabilisticClassifier[L comparable] interface {
+	Classifier[L]
+	PredictProba(features []float64) map[L]float64
+}
+
+// Estimator entrena un modelo sobre unos ejemplos. Con él la validación cruzada, el
+// stacking o el bagging se escriben una vez para cualquier tipo de modelo.
+type Estimator[L comparable] interface {
+	Fit(examples []Example[L]) (ProbabilisticClassifier[L], error)
+}
+
+// EstimatorFunc adapta una función de entrenamiento a Estimator
+type EstimatorFunc[L comparable] func(examples []Example[L]) (ProbabilisticClassifier[L], error)
+
+func (f EstimatorFunc[L]) Fit(examples []Example[L]) (ProbabilisticClassifier[L], error) {
+	return f(examples)
+}
+
+// TreeEstimator entrena un árbol con BuildDecisionTreeConcurrent
+func TreeEstimator[L comparable](opts TrainOptions[L]) Estimator[L] {
+	return EstimatorFunc[L](func(examples []Example[L]) (ProbabilisticClassifier[L], error) {
+		tree, _ := BuildDecisionTreeConcurrent(examples, opts)
+		return tree, nil
+	})
+}
+
+// OneVsRestEstimator entrena un OneVsRest con TrainOneVsRest
+func OneVsRestEstimator[L comparable](opts TrainOptions[L]) Estimator[L] {
+	return EstimatorFunc[L](func(examples []Example[L]) (ProbabilisticClassifier[L], error) {
+		return TrainOneVsRest(examples, opts), nil
+	})
+}
+
+// OneVsOneEstimator entrena un OneVsOne con TrainOneVsOne
+func OneVsOneEstimator[L comparable](opts TrainOptions[L]) Estimator[L] {
+	return EstimatorFunc[L](func(examples []Example[L]) (ProbabilisticClassifier[L], error) {
+		return TrainOneVsOne(examples, opts), nil
+	})
+}
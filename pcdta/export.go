@@ -0,0 +1,934 @@
+package pcdta
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PolicyDocument es la exportación del árbol como política JSON anidada para motores
+// de reglas: cada nodo es una condición con ramas then/else o un resultado final
+type PolicyDocument struct {
+	Format   string      `json:"format"`
+	Version  int         `json:"version"`
+	TreeHash string      `json:"tree_hash,omitempty"`
+	Features []string    `json:"features"`
+	Classes  []string    `json:"classes"`
+	Missing  string      `json:"missing"` // rama que siguen los valores ausentes
+	Policy   *PolicyNode `json:"policy"`
+
+	// Columnas de la entrada y cómo se convierten las categóricas en Features antes de
+	// evaluar las condiciones, si el modelo las codifica o deriva features (como
+	// Model.Preprocess)
+	Inputs    []string         `json:"inputs,omitempty"`
+	Encodings []ColumnEncoding `json:"encodings,omitempty"`
+	Derived   []DerivedFeature `json:"derived,omitempty"` // features calculadas con Expr, en orden
+}
+
+type PolicyNode struct {
+	Condition *PolicyCondition `json:"condition,omitempty"`
+	Then      *PolicyNode      `json:"then,omitempty"`
+	Else      *PolicyNode      `json:"else,omitempty"`
+	Outcome   *PolicyOutcome   `json:"outcome,omitempty"`
+}
+
+// PolicyCondition se cumple si el valor de la feature es menor o igual que el umbral
+type PolicyCondition struct {
+	Feature   string  `json:"feature"`
+	Index     int     `json:"index"`
+	Operator  string  `json:"operator"`
+	Threshold float64 `json:"threshold"`
+}
+
+type PolicyOutcome struct {
+	Class         string             `json:"class"`
+	Probabilities map[string]float64 `json:"probabilities"`
+	Support       float64            `json:"support"`
+}
+
+// originalClasses devuelve las clases del modelo con el nombre que tenían en los datos,
+// en el mismo orden que Classes
+func originalClasses(model *Model[string]) []string {
+	classes := make([]string, len(model.Classes()))
+	for i, class := range model.Classes() {
+		classes[i] = model.OriginalClass(class)
+	}
+	return classes
+}
+
+// ExportPolicy convierte el modelo en una política JSON anidada; las clases se
+// escriben con su nombre original si se renombraron al entrenar
+func ExportPolicy(model *Model[string]) *PolicyDocument {
+	var visit func(node *DecisionTree[string]) *PolicyNode
+	visit = func(node *DecisionTree[string]) *PolicyNode {
+		if node.IsLeaf() {
+			mass := node.classMass()
+			var support float64
+			for _, m := range mass {
+				support += m
+			}
+			probs := make(map[string]float64)
+			if node.Probs != nil {
+				for class, p := range node.Probs {
+					probs[model.OriginalClass(class)] = p
+				}
+			} else if support > 0 {
+				for class, m := range mass {
+					probs[model.OriginalClass(class)] = m / support
+				}
+			} else {
+				probs[model.OriginalClass(node.Class)] = 1
+			}
+			return &PolicyNode{Outcome: &PolicyOutcome{Class: model.OriginalClass(node.Class), Probabilities: probs, Support: support}}
+		}
+		return &PolicyNode{
+			Condition: &PolicyCondition{
+				Feature:   ColumnLabel(model.Meta.FeatureNames, node.Column),
+				Index:     node.Column,
+				Operator:  "<=",
+				Threshold: node.Value,
+			},
+			Then: visit(node.Left),
+			Else: visit(node.Right),
+		}
+	}
+
+	doc := &PolicyDocument{
+		Format:   "pcdta-policy",
+		Version:  1,
+		TreeHash: model.Meta.TreeHash,
+		Features: model.Meta.FeatureNames,
+		Classes:  originalClasses(model),
+		Missing:  "else",
+		Policy:   visit(model.Tree),
+	}
+	if len(model.Meta.Encodings) > 0 || len(model.Meta.Derived) > 0 {
+		doc.Inputs, doc.Encodings, doc.Derived = model.Meta.InputColumns(), model.Meta.Encodings, model.Meta.Derived
+	}
+	return doc
+}
+
+// codeDialect describe la sintaxis de un lenguaje destino para ExportCode
+type codeDialect struct {
+	header     func(w io.Writer, name string, classes []string, in *codeInput)
+	footer     func(w io.Writer)
+	input      func(w io.Writer, name string, in *codeInput) // preprocesado de la entrada
+	trailer    string                                        // cierre tras el preprocesado
+	indent     string                                        // sangría base del cuerpo de la función de predicción
+	step       string                                        // sangría de cada nivel
+	feature    string                                        // formato del acceso a la feature j
+	ifOpen     string
+	elseClause string
+	blockClose string
+	returnStmt string
+	// Con flatElse la rama derecha sigue al if sin else: la izquierda siempre termina
+	// en return (estilo habitual en Go)
+	flatElse bool
+}
+
+func quoteClasses(classes []string) string {
+	quoted := make([]string, len(classes))
+	for i, class := range classes {
+		quoted[i] = strconv.QuoteToASCII(class)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+var codeDialects = map[string]codeDialect{
+	"c": {
+		header: func(w io.Writer, name string, classes []string, in *codeInput) {
+			fmt.Fprintf(w, "/* Código generado por pcdta %s; no editar. */\n\n", PackageVersion)
+			if in != nil {
+				fmt.Fprintf(w, "#include <math.h>\n#include <stdint.h>\n#include <string.h>\n\n")
+			}
+			fmt.Fprintf(w, "static const char *const %s_classes[] = {%s};\n\n", name, quoteClasses(classes))
+			fmt.Fprintf(w, "const char *%s_class_name(int index) {\n    return %s_classes[index];\n}\n\n", name, name)
+			fmt.Fprintf(w, "/* Devuelve el índice de la clase predicha para el vector de features x. */\n")
+			fmt.Fprintf(w, "int %s_predict(const double *x) {\n", name)
+		},
+		footer:     func(w io.Writer) { fmt.Fprintln(w, "}") },
+		input:      writeCInput,
+		indent:     "    ",
+		step:       "    ",
+		feature:    "x[%d]",
+		ifOpen:     "if (%s <= %s) {",
+		elseClause: "} else {",
+		blockClose: "}",
+		returnStmt: "return %d;",
+	},
+	"java": {
+		header: func(w io.Writer, name string, classes []string, in *codeInput) {
+			fmt.Fprintf(w, "// Código generado por pcdta %s; no editar.\n\n", PackageVersion)
+			fmt.Fprintf(w, "public final class %s {\n", name)
+			fmt.Fprintf(w, "    public static final String[] CLASSES = {%s};\n\n", quoteClasses(classes))
+			fmt.Fprintf(w, "    private %s() {}\n\n", name)
+			fmt.Fprintf(w, "    public static String predictClass(double[] x) {\n        return CLASSES[predict(x)];\n    }\n\n")
+			fmt.Fprintf(w, "    /** Devuelve el índice en CLASSES de la clase predicha. */\n")
+			fmt.Fprintf(w, "    public static int predict(double[] x) {\n")
+		},
+		footer:     func(w io.Writer) { fmt.Fprintln(w, "    }") },
+		input:      writeJavaInput,
+		trailer:    "}\n",
+		indent:     "        ",
+		step:       "    ",
+		feature:    "x[%d]",
+		ifOpen:     "if (%s <= %s) {",
+		elseClause: "} else {",
+		blockClose: "}",
+		returnStmt: "return %d;",
+	},
+	"go": {
+		header: func(w io.Writer, name string, classes []string, in *codeInput) {
+			// Marca estándar para que las herramientas de Go lo reconozcan como generado
+			fmt.Fprintf(w, "// Code generated by pcdta %s. DO NOT EDIT.\n\n", PackageVersion)
+			fmt.Fprintf(w, "package %s\n\n", strings.ToLower(name))
+			if in != nil && len(in.derived) > 0 {
+				fmt.Fprintf(w, "import \"math\"\n\n")
+			}
+			fmt.Fprintf(w, "var Classes = []string{%s}\n\n", quoteClasses(classes))
+			fmt.Fprintf(w, "// Predict devuelve el índice en Classes de la clase predicha\n")
+			fmt.Fprintf(w, "func Predict(x []float64) int {\n")
+		},
+		footer:     func(w io.Writer) { fmt.Fprintln(w, "}") },
+		input:      writeGoInput,
+		indent:     "\t",
+		step:       "\t",
+		feature:    "x[%d]",
+		ifOpen:     "if %s <= %s {",
+		elseClause: "}",
+		returnStmt: "return %d",
+		flatElse:   true,
+	},
+}
+
+// ExportCode escribe el árbol como una función de predicción con if/else anidados en
+// C, Java o Go. Las comparaciones con NaN son falsas en los tres lenguajes, así que los
+// valores ausentes siguen la rama derecha igual que en el árbol. Si el modelo codifica
+// columnas categóricas o deriva features se genera además la conversión de una fila de
+// la entrada en el vector de features (input y predict_row), como hace
+// Model.Preprocess. Las clases
+// llevan su nombre original, como en Model.Predict.
+func ExportCode(w io.Writer, model *Model[string], lang, name string) error {
+	dialect, ok := codeDialects[lang]
+	if !ok {
+		return fmt.Errorf("lenguaje desconocido %q (c, java o go)", lang)
+	}
+	in, err := newCodeInput(model.Meta)
+	if err != nil {
+		return err
+	}
+
+	classes := model.Classes()
+	index := make(map[string]int, len(classes))
+	for i, class := range classes {
+		index[class] = i
+	}
+
+	dialect.header(w, name, originalClasses(model), in)
+	var emit func(node *DecisionTree[string], indent string)
+	emit = func(node *DecisionTree[string], indent string) {
+		if node.IsLeaf() {
+			fmt.Fprintf(w, "%s"+dialect.returnStmt+"\n", indent, index[node.Class])
+			return
+		}
+		feature := fmt.Sprintf(dialect.feature, node.Column)
+		threshold := strconv.FormatFloat(node.Value, 'g', -1, 64)
+		comment := ""
+		if node.Column < len(model.Meta.FeatureNames) {
+			comment = " // " + commentText(model.Meta.FeatureNames[node.Column])
+			if lang == "c" {
+				comment = " /* " + commentText(model.Meta.FeatureNames[node.Column]) + " */"
+			}
+		}
+		fmt.Fprintf(w, "%s"+dialect.ifOpen+"%s\n", indent, feature, threshold, comment)
+		emit(node.Left, indent+dialect.step)
+		fmt.Fprintf(w, "%s%s\n", indent, dialect.elseClause)
+		if dialect.flatElse {
+			emit(node.Right, indent)
+			return
+		}
+		emit(node.Right, indent+dialect.step)
+		fmt.Fprintf(w, "%s%s\n", indent, dialect.blockClose)
+	}
+	emit(model.Tree, dialect.indent)
+	dialect.footer(w)
+	if in != nil {
+		dialect.input(w, name, in)
+	}
+	fmt.Fprint(w, dialect.trailer)
+	return nil
+}
+
+// codeInput es el preprocesado que genera ExportCode: la función input recibe num, los
+// valores de las columnas numéricas de la entrada, y cat, los de las categóricas, ambas
+// en el orden de Meta.InputColumns, y rellena el vector de features. Las features que
+// el árbol no consulta (fuera de SelectedFeatures y sin columna) quedan a 0.
+type codeInput struct {
+	size        int
+	numeric     []string
+	categorical []string
+	copies      []inputNumeric // feature <- num[column]
+	encodings   []codeEncoding
+	derived     []codeDerived
+}
+
+// codeDerived es una feature derivada: se guarda en x[feature] o, si el modelo no la
+// usa pero la necesita otra derivada, en la variable local d<local>
+type codeDerived struct {
+	expr    *Expr
+	feature int
+	local   int
+	refs    map[string]string // columna de la expresión -> num[k], x[i] o d<k>
+}
+
+type codeEncoding struct {
+	cat      int
+	encoding *ColumnEncoding
+	features []int // feature de cada salida de la codificación, o -1
+	values   []string
+}
+
+func (e codeEncoding) hashed() bool { return e.encoding.Method == "hash" }
+
+// newCodeInput prepara el preprocesado de meta, o devuelve nil si el árbol recibe las
+// columnas de la entrada tal cual
+func newCodeInput(meta ModelMetadata) (*codeInput, error) {
+	if len(meta.Encodings) == 0 && len(meta.Derived) == 0 {
+		return nil, nil
+	}
+	columns := meta.InputColumns()
+	p, err := meta.NewPreprocessor(columns)
+	if err != nil {
+		return nil, err
+	}
+	encoded := make(map[int]bool)
+	for _, e := range p.encodings {
+		encoded[e.column] = true
+	}
+	in := &codeInput{size: p.size}
+	position := make([]int, len(columns))
+	for col, name := range columns {
+		if encoded[col] {
+			position[col] = len(in.categorical)
+			in.categorical = append(in.categorical, name)
+		} else {
+			position[col] = len(in.numeric)
+			in.numeric = append(in.numeric, name)
+		}
+	}
+	for _, n := range p.numeric {
+		in.copies = append(in.copies, inputNumeric{feature: n.feature, column: position[n.column]})
+	}
+	for _, e := range p.encodings {
+		values := make([]string, 0, len(e.encoding.Values))
+		for category, encoded := range e.encoding.Values {
+			values = append(values, category)
+			for _, value := range encoded {
+				if math.IsNaN(value) || math.IsInf(value, 0) {
+					return nil, fmt.Errorf("la codificación de %s tiene un valor no finito para %q", e.encoding.Column, category)
+				}
+			}
+		}
+		sort.Strings(values)
+		in.encodings = append(in.encodings, codeEncoding{cat: position[e.column], encoding: e.encoding, features: e.features, values: values})
+	}
+
+	// Solo se generan las derivadas que usa el modelo y las que estas necesitan, para
+	// no dejar variables sin usar (un error en Go)
+	needed := make([]bool, len(p.derived))
+	for k := len(p.derived) - 1; k >= 0; k-- {
+		d := p.derived[k]
+		needed[k] = needed[k] || d.feature >= 0
+		for _, ref := range d.refs {
+			if needed[k] && ref.kind == refDerived {
+				needed[ref.index] = true
+			}
+		}
+	}
+	for k, d := range p.derived {
+		if !needed[k] {
+			continue
+		}
+		out := codeDerived{expr: d.expr, feature: d.feature, local: k, refs: make(map[string]string)}
+		for name, ref := range d.refs {
+			switch {
+			case ref.kind == refColumn && encoded[ref.index]:
+				return nil, fmt.Errorf("feature derivada %s: usa la columna categórica %s como número", d.name, name)
+			case ref.kind == refColumn:
+				out.refs[name] = fmt.Sprintf("num[%d]", position[ref.index])
+			case ref.kind == refFeature:
+				out.refs[name] = fmt.Sprintf("x[%d]", ref.index)
+			default:
+				out.refs[name] = fmt.Sprintf("d%d", ref.index)
+			}
+		}
+		// Se comprueba ya que la expresión se puede traducir
+		if _, err := goExprSyntax.translate(d.expr.root, out.refs); err != nil {
+			return nil, fmt.Errorf("feature derivada %s: %w", d.name, err)
+		}
+		in.derived = append(in.derived, out)
+	}
+	return in, nil
+}
+
+// exprSyntax traduce una Expr numérica al lenguaje destino. Cada subexpresión es un
+// double; las comparaciones y operadores lógicos valen 1 o 0 como en Expr.
+type exprSyntax struct {
+	number func(cond string) string // condición -> 1 o 0
+	truth  string                   // formato del valor de verdad de un double
+	mod    string
+	funcs  map[string]string // formato de cada función de Expr
+}
+
+func cExprSyntax(name string) exprSyntax {
+	cond := func(cond string) string { return "(" + cond + " ? 1.0 : 0.0)" }
+	return exprSyntax{
+		number: cond,
+		truth:  name + "_truth(%s)",
+		mod:    "fmod(%s, %s)",
+		funcs: map[string]string{
+			"abs": "fabs(%s)", "sqrt": "sqrt(%s)", "log": "log(%s)", "exp": "exp(%s)",
+			"isna": cond("isnan(%s)"), "min": name + "_min(%s, %s)", "max": name + "_max(%s, %s)",
+		},
+	}
+}
+
+var javaExprSyntax = exprSyntax{
+	number: func(cond string) string { return "(" + cond + " ? 1.0 : 0.0)" },
+	truth:  "truth(%s)",
+	mod:    "(%s %% %s)",
+	funcs: map[string]string{
+		"abs": "Math.abs(%s)", "sqrt": "Math.sqrt(%s)", "log": "Math.log(%s)", "exp": "Math.exp(%s)",
+		"isna": "(Double.isNaN(%s) ? 1.0 : 0.0)", "min": "Math.min(%s, %s)", "max": "Math.max(%s, %s)",
+	},
+}
+
+var goExprSyntax = exprSyntax{
+	number: func(cond string) string { return "b2f(" + cond + ")" },
+	truth:  "truth(%s)",
+	mod:    "math.Mod(%s, %s)",
+	funcs: map[string]string{
+		"abs": "math.Abs(%s)", "sqrt": "math.Sqrt(%s)", "log": "math.Log(%s)", "exp": "math.Exp(%s)",
+		"isna": "b2f(math.IsNaN(%s))", "min": "math.Min(%s, %s)", "max": "math.Max(%s, %s)",
+	},
+}
+
+func (s exprSyntax) translate(node exprNode, refs map[string]string) (string, error) {
+	switch n := node.(type) {
+	case exprLiteral:
+		if n.value.IsString {
+			return "", fmt.Errorf("las cadenas no se pueden exportar")
+		}
+		literal := strconv.FormatFloat(n.value.Num, 'g', -1, 64)
+		if !strings.ContainsAny(literal, ".e") {
+			literal += ".0" // evita la división entera en C y Java
+		}
+		return literal, nil
+	case exprColumn:
+		ref, ok := refs[n.name]
+		if !ok {
+			return "", fmt.Errorf("no existe la columna %s", n.name)
+		}
+		return ref, nil
+	case exprUnary:
+		x, err := s.translate(n.x, refs)
+		if err != nil {
+			return "", err
+		}
+		if n.op == "!" {
+			return s.number("!" + fmt.Sprintf(s.truth, x)), nil
+		}
+		return "(-" + x + ")", nil
+	case exprBinary:
+		l, err := s.translate(n.l, refs)
+		if err != nil {
+			return "", err
+		}
+		r, err := s.translate(n.r, refs)
+		if err != nil {
+			return "", err
+		}
+		switch n.op {
+		case "&&", "||":
+			return s.number(fmt.Sprintf(s.truth, l) + " " + n.op + " " + fmt.Sprintf(s.truth, r)), nil
+		case "%":
+			return fmt.Sprintf(s.mod, l, r), nil
+		case "!=":
+			// Como en Expr, un ausente no es distinto de nada
+			return s.number(fmt.Sprintf("(%s < %s || %s > %s)", l, r, l, r)), nil
+		case "==", "<", "<=", ">", ">=":
+			return s.number(fmt.Sprintf("%s %s %s", l, n.op, r)), nil
+		}
+		return "(" + l + " " + n.op + " " + r + ")", nil
+	case exprCall:
+		args := make([]any, len(n.args))
+		for i, arg := range n.args {
+			var err error
+			if args[i], err = s.translate(arg, refs); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf(s.funcs[n.fn], args...), nil
+	}
+	return "", fmt.Errorf("expresión no soportada")
+}
+
+// derivedCode devuelve el cálculo de las features derivadas; comment, assign y local
+// son los formatos del comentario con la expresión, de la asignación a x[i] y de la
+// declaración de d<k>
+func (in *codeInput) derivedCode(s exprSyntax, indent, comment, assign, local string) string {
+	var code strings.Builder
+	for _, d := range in.derived {
+		// La traducción ya se comprobó en newCodeInput
+		expr, _ := s.translate(d.expr.root, d.refs)
+		fmt.Fprintf(&code, indent+comment+"\n", commentText(d.expr.Source))
+		if d.feature >= 0 {
+			fmt.Fprintf(&code, indent+assign+"\n", d.feature, expr)
+		} else {
+			fmt.Fprintf(&code, indent+local+"\n", d.local, expr)
+		}
+	}
+	return code.String()
+}
+
+func (in *codeInput) hashed() bool {
+	for _, e := range in.encodings {
+		if e.hashed() {
+			return true
+		}
+	}
+	return false
+}
+
+// buckets es la feature de cada cubo de una codificación por hashing, o -1
+func (e codeEncoding) buckets() string {
+	parts := make([]string, len(e.features))
+	for k, i := range e.features {
+		parts[k] = strconv.Itoa(i)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// assign escribe la asignación de los valores de una codificación a sus features
+func (e codeEncoding) assign(w io.Writer, indent, format string, values []float64) {
+	for k, i := range e.features {
+		if i >= 0 {
+			fmt.Fprintf(w, indent+format+"\n", i, strconv.FormatFloat(values[k], 'g', -1, 64))
+		}
+	}
+}
+
+func writeCInput(w io.Writer, name string, in *codeInput) {
+	writeCConvert(w, name, in)
+	fmt.Fprintf(w, "/* Devuelve el índice de la clase predicha para una fila de la entrada. */\n")
+	fmt.Fprintf(w, "int %s_predict_row(const double *num, const char *const *cat) {\n", name)
+	fmt.Fprintf(w, "    double x[%d];\n    %s_input(num, cat, x);\n    return %s_predict(x);\n}\n", max(in.size, 1), name, name)
+}
+
+// writeCConvert escribe la función name_input de C, que rellena x a partir de la fila
+func writeCConvert(w io.Writer, name string, in *codeInput) {
+	fmt.Fprintln(w)
+	if len(in.numeric) > 0 {
+		fmt.Fprintf(w, "/* Columnas numéricas de la entrada, en el orden de num. */\n")
+		fmt.Fprintf(w, "static const char *const %s_numeric_columns[] = {%s};\n", name, quoteClasses(in.numeric))
+	}
+	if len(in.categorical) > 0 {
+		fmt.Fprintf(w, "/* Columnas categóricas de la entrada, en el orden de cat. */\n")
+		fmt.Fprintf(w, "static const char *const %s_categorical_columns[] = {%s};\n", name, quoteClasses(in.categorical))
+	}
+	fmt.Fprintln(w)
+	derived := in.derivedCode(cExprSyntax(name), "    ", "/* %s */", "x[%d] = %s;", "double d%d = %s;")
+	if strings.Contains(derived, name+"_truth(") {
+		fmt.Fprintf(w, "static int %s_truth(double v) {\n    return v != 0 && !isnan(v);\n}\n\n", name)
+	}
+	// fmin y fmax ignoran NaN; en Expr, como en Go, un ausente da ausente
+	for _, fn := range []string{"min", "max"} {
+		if strings.Contains(derived, name+"_"+fn+"(") {
+			op := map[string]string{"min": "<", "max": ">"}[fn]
+			fmt.Fprintf(w, "static double %s_%s(double a, double b) {\n    return isnan(a) || isnan(b) ? NAN : a %s b ? a : b;\n}\n\n", name, fn, op)
+		}
+	}
+	if in.hashed() {
+		// isMissingField: vacío, NA, NaN o ? sin distinguir mayúsculas
+		fmt.Fprintf(w, "static int %s_missing(const char *s) {\n", name)
+		fmt.Fprintf(w, "    char lower[4] = {0};\n    int i;\n")
+		fmt.Fprintf(w, "    for (i = 0; s[i] != '\\0'; i++) {\n")
+		fmt.Fprintf(w, "        if (i == 3) {\n            return 0;\n        }\n")
+		fmt.Fprintf(w, "        lower[i] = (char)(s[i] >= 'A' && s[i] <= 'Z' ? s[i] - 'A' + 'a' : s[i]);\n    }\n")
+		fmt.Fprintf(w, "    return i == 0 || strcmp(lower, \"na\") == 0 || strcmp(lower, \"nan\") == 0 || strcmp(lower, \"?\") == 0;\n}\n\n")
+		for k, e := range in.encodings {
+			if e.hashed() {
+				fmt.Fprintf(w, "static const int %s_buckets_%d[] = {%s};\n\n", name, k, e.buckets())
+			}
+		}
+	}
+	fmt.Fprintf(w, "/* Convierte una fila de la entrada en el vector de features x: num son las columnas\n")
+	fmt.Fprintf(w, "   numéricas (NAN si faltan) y cat las categóricas, sin espacios alrededor. */\n")
+	fmt.Fprintf(w, "void %s_input(const double *num, const char *const *cat, double *x) {\n", name)
+	fmt.Fprintf(w, "    memset(x, 0, %d * sizeof *x);\n", in.size)
+	if len(in.categorical) == 0 {
+		fmt.Fprintf(w, "    (void)cat;\n")
+	}
+	for _, c := range in.copies {
+		fmt.Fprintf(w, "    x[%d] = num[%d];\n", c.feature, c.column)
+	}
+	for k, e := range in.encodings {
+		value := fmt.Sprintf("cat[%d]", e.cat)
+		if e.hashed() {
+			fmt.Fprintf(w, "    if (%s_missing(%s)) {\n", name, value)
+			e.assign(w, "        ", "x[%d] = %s;", e.encoding.Default)
+			fmt.Fprintf(w, "    } else {\n")
+			fmt.Fprintf(w, "        uint32_t h = 2166136261u;\n        const unsigned char *p;\n        int k;\n")
+			fmt.Fprintf(w, "        for (p = (const unsigned char *)%s; *p != '\\0'; p++) {\n            h = (h ^ *p) * 16777619u;\n        }\n", value)
+			fmt.Fprintf(w, "        k = %s_buckets_%d[h %% %du];\n", name, k, len(e.features))
+			fmt.Fprintf(w, "        if (k >= 0) {\n            x[k] = 1;\n        }\n    }\n")
+			continue
+		}
+		fmt.Fprintf(w, "    /* %s */\n    ", commentText(e.encoding.Column))
+		for _, category := range e.values {
+			fmt.Fprintf(w, "if (strcmp(%s, %s) == 0) {\n", value, strconv.QuoteToASCII(category))
+			e.assign(w, "        ", "x[%d] = %s;", e.encoding.Values[category])
+			fmt.Fprintf(w, "    } else ")
+		}
+		fmt.Fprintf(w, "{\n")
+		e.assign(w, "        ", "x[%d] = %s;", e.encoding.Default)
+		fmt.Fprintf(w, "    }\n")
+	}
+	fmt.Fprint(w, derived)
+	fmt.Fprintf(w, "}\n\n")
+}
+
+func writeJavaInput(w io.Writer, name string, in *codeInput) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "    /** Columnas numéricas de la entrada, en el orden de num. */\n")
+	fmt.Fprintf(w, "    public static final String[] NUMERIC_COLUMNS = {%s};\n", quoteClasses(in.numeric))
+	fmt.Fprintf(w, "    /** Columnas categóricas de la entrada, en el orden de cat. */\n")
+	fmt.Fprintf(w, "    public static final String[] CATEGORICAL_COLUMNS = {%s};\n\n", quoteClasses(in.categorical))
+	for k, e := range in.encodings {
+		if e.hashed() {
+			fmt.Fprintf(w, "    private static final int[] BUCKETS_%d = {%s};\n\n", k, e.buckets())
+		}
+	}
+	derived := in.derivedCode(javaExprSyntax, "        ", "// %s", "x[%d] = %s;", "double d%d = %s;")
+	if strings.Contains(derived, "truth(") {
+		fmt.Fprintf(w, "    private static boolean truth(double v) {\n        return v != 0 && !Double.isNaN(v);\n    }\n\n")
+	}
+	if in.hashed() {
+		fmt.Fprintf(w, "    private static boolean isMissing(String s) {\n")
+		fmt.Fprintf(w, "        return s.isEmpty() || s.equalsIgnoreCase(\"NA\") || s.equalsIgnoreCase(\"NaN\") || s.equals(\"?\");\n    }\n\n")
+	}
+	fmt.Fprintf(w, "    /**\n     * Convierte una fila de la entrada en el vector de features: num son las columnas\n")
+	fmt.Fprintf(w, "     * numéricas (NaN si faltan) y cat las categóricas, sin espacios alrededor.\n     */\n")
+	fmt.Fprintf(w, "    public static double[] input(double[] num, String[] cat) {\n")
+	fmt.Fprintf(w, "        double[] x = new double[%d];\n", in.size)
+	for _, c := range in.copies {
+		fmt.Fprintf(w, "        x[%d] = num[%d];\n", c.feature, c.column)
+	}
+	for k, e := range in.encodings {
+		value := fmt.Sprintf("cat[%d]", e.cat)
+		if e.hashed() {
+			fmt.Fprintf(w, "        if (isMissing(%s)) {\n", value)
+			e.assign(w, "            ", "x[%d] = %s;", e.encoding.Default)
+			fmt.Fprintf(w, "        } else {\n")
+			fmt.Fprintf(w, "            int h = 0x811c9dc5;\n")
+			fmt.Fprintf(w, "            for (byte b : %s.getBytes(java.nio.charset.StandardCharsets.UTF_8)) {\n                h = (h ^ (b & 0xff)) * 16777619;\n            }\n", value)
+			fmt.Fprintf(w, "            int k = BUCKETS_%d[Integer.remainderUnsigned(h, %d)];\n", k, len(e.features))
+			fmt.Fprintf(w, "            if (k >= 0) {\n                x[k] = 1;\n            }\n        }\n")
+			continue
+		}
+		fmt.Fprintf(w, "        switch (%s) { // %s\n", value, commentText(e.encoding.Column))
+		for _, category := range e.values {
+			fmt.Fprintf(w, "            case %s:\n", strconv.QuoteToASCII(category))
+			e.assign(w, "                ", "x[%d] = %s;", e.encoding.Values[category])
+			fmt.Fprintf(w, "                break;\n")
+		}
+		fmt.Fprintf(w, "            default:\n")
+		e.assign(w, "                ", "x[%d] = %s;", e.encoding.Default)
+		fmt.Fprintf(w, "        }\n")
+	}
+	fmt.Fprint(w, derived)
+	fmt.Fprintf(w, "        return x;\n    }\n\n")
+	fmt.Fprintf(w, "    /** Devuelve el índice en CLASSES de la clase predicha para una fila de la entrada. */\n")
+	fmt.Fprintf(w, "    public static int predictRow(double[] num, String[] cat) {\n        return predict(input(num, cat));\n    }\n")
+}
+
+func writeGoInput(w io.Writer, name string, in *codeInput) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "// NumericColumns y CategoricalColumns son las columnas de la entrada de Input, en el\n// orden de num y cat\n")
+	fmt.Fprintf(w, "var (\n\tNumericColumns     = []string{%s}\n\tCategoricalColumns = []string{%s}\n)\n\n", quoteClasses(in.numeric), quoteClasses(in.categorical))
+	for k, e := range in.encodings {
+		if e.hashed() {
+			fmt.Fprintf(w, "var buckets%d = [...]int{%s}\n\n", k, e.buckets())
+		}
+	}
+	if len(in.derived) > 0 {
+		// Siempre se escriben para que el import de math se use
+		fmt.Fprintf(w, "func truth(v float64) bool { return v != 0 && !math.IsNaN(v) }\n\n")
+		fmt.Fprintf(w, "func b2f(b bool) float64 {\n\tif b {\n\t\treturn 1\n\t}\n\treturn 0\n}\n\n")
+	}
+	if in.hashed() {
+		fmt.Fprintf(w, "func isMissing(s string) bool {\n")
+		fmt.Fprintf(w, "\tswitch s {\n\tcase \"\", \"?\":\n\t\treturn true\n\t}\n")
+		fmt.Fprintf(w, "\tif len(s) > 3 {\n\t\treturn false\n\t}\n")
+		fmt.Fprintf(w, "\tupper := []byte(s)\n\tfor i, c := range upper {\n\t\tif c >= 'a' && c <= 'z' {\n\t\t\tupper[i] = c - 'a' + 'A'\n\t\t}\n\t}\n")
+		fmt.Fprintf(w, "\treturn string(upper) == \"NA\" || string(upper) == \"NAN\"\n}\n\n")
+	}
+	fmt.Fprintf(w, "// Input convierte una fila de la entrada en el vector de features de Predict: num son\n")
+	fmt.Fprintf(w, "// las columnas numéricas (NaN si faltan) y cat las categóricas, sin espacios alrededor\n")
+	fmt.Fprintf(w, "func Input(num []float64, cat []string) []float64 {\n")
+	fmt.Fprintf(w, "\tx := make([]float64, %d)\n", in.size)
+	for _, c := range in.copies {
+		fmt.Fprintf(w, "\tx[%d] = num[%d]\n", c.feature, c.column)
+	}
+	for k, e := range in.encodings {
+		value := fmt.Sprintf("cat[%d]", e.cat)
+		if e.hashed() {
+			fmt.Fprintf(w, "\tif isMissing(%s) {\n", value)
+			e.assign(w, "\t\t", "x[%d] = %s", e.encoding.Default)
+			fmt.Fprintf(w, "\t} else {\n")
+			fmt.Fprintf(w, "\t\th := uint32(2166136261)\n")
+			fmt.Fprintf(w, "\t\tfor i := 0; i < len(%s); i++ {\n\t\t\th = (h ^ uint32(%s[i])) * 16777619\n\t\t}\n", value, value)
+			fmt.Fprintf(w, "\t\tif k := buckets%d[h%%%d]; k >= 0 {\n\t\t\tx[k] = 1\n\t\t}\n\t}\n", k, len(e.features))
+			continue
+		}
+		fmt.Fprintf(w, "\tswitch %s { // %s\n", value, commentText(e.encoding.Column))
+		for _, category := range e.values {
+			fmt.Fprintf(w, "\tcase %s:\n", strconv.Quote(category))
+			e.assign(w, "\t\t", "x[%d] = %s", e.encoding.Values[category])
+		}
+		fmt.Fprintf(w, "\tdefault:\n")
+		e.assign(w, "\t\t", "x[%d] = %s", e.encoding.Default)
+		fmt.Fprintf(w, "\t}\n")
+	}
+	fmt.Fprint(w, in.derivedCode(goExprSyntax, "\t", "// %s", "x[%d] = %s", "d%d := %s"))
+	fmt.Fprintf(w, "\treturn x\n}\n\n")
+	fmt.Fprintf(w, "// PredictRow devuelve el índice en Classes de la clase predicha para una fila de la entrada\n")
+	fmt.Fprintf(w, "func PredictRow(num []float64, cat []string) int {\n\treturn Predict(Input(num, cat))\n}\n")
+}
+
+// commentText escribe un nombre como literal entrecomillado para los comentarios del
+// código generado: así un salto de línea no termina un comentario de línea ni "*/"
+// uno de bloque, y el comentario no puede inyectar código
+func commentText(name string) string {
+	return strings.ReplaceAll(strconv.QuoteToASCII(name), "*/", `*\/`)
+}
+
+// FixedPointModel es el árbol aplanado en tablas de enteros para microcontroladores
+// sin coma flotante. Cada feature j se representa como x_q = floor(x · 2^Shifts[j])
+// y los umbrales se cuantizan igual, así que x <= t implica x_q <= t_q; solo los
+// valores a menos de un paso de cuantización por encima del umbral pueden cambiar de rama.
+// El máximo del entero queda reservado para los valores ausentes: ningún umbral lo
+// alcanza, así que un NaN va a la derecha como en el árbol en coma flotante.
+type FixedPointModel struct {
+	Bits       int // 16 o 32
+	Classes    []string
+	Shifts     []int
+	Feature    []int   // feature de cada nodo interno
+	Threshold  []int64 // umbral cuantizado de cada nodo interno
+	Left       []int   // hijo izquierdo: >= 0 nodo interno, < 0 hoja -(índice+1)
+	Right      []int
+	LeafClass  []int
+	LeafProbas [][]int64 // probabilidades en Q15 (16 bits) o Q31 (32 bits)
+}
+
+// QuantizeTree elige para cada feature el mayor desplazamiento que hace caber
+// ±maxAbs[j] en el entero con signo de bits bits y aplana el árbol
+func QuantizeTree(tree *DecisionTree[string], maxAbs []float64, bits int) (*FixedPointModel, error) {
+	if bits != 16 && bits != 32 {
+		return nil, fmt.Errorf("solo se admiten 16 o 32 bits, no %d", bits)
+	}
+	limit := float64(int64(1)<<(bits-1) - 1)
+
+	m := &FixedPointModel{Bits: bits, Classes: tree.Classes(), Shifts: make([]int, len(maxAbs))}
+	for j, value := range maxAbs {
+		if value <= 0 {
+			value = 1
+		}
+		m.Shifts[j] = int(math.Floor(math.Log2(limit / value)))
+		if math.Ldexp(value, m.Shifts[j]) >= limit {
+			m.Shifts[j]-- // el máximo queda para los ausentes
+		}
+		// El código generado guarda los desplazamientos en int8_t
+		if m.Shifts[j] > math.MaxInt8 || m.Shifts[j] < math.MinInt8 {
+			return nil, fmt.Errorf("el rango %g de la feature %d necesita un desplazamiento de %d, fuera de int8", maxAbs[j], j, m.Shifts[j])
+		}
+	}
+	// y los índices de feature y de clase en uint16_t
+	if len(maxAbs) > math.MaxUint16+1 {
+		return nil, fmt.Errorf("%d features no caben en los índices de 16 bits del código generado", len(maxAbs))
+	}
+	if len(m.Classes) > math.MaxUint16+1 {
+		return nil, fmt.Errorf("%d clases no caben en los índices de 16 bits del código generado", len(m.Classes))
+	}
+	classIndex := make(map[string]int, len(m.Classes))
+	for i, class := range m.Classes {
+		classIndex[class] = i
+	}
+	one := float64(int64(1)<<(bits-1) - 1)
+
+	var flatten func(node *DecisionTree[string]) (int, error)
+	flatten = func(node *DecisionTree[string]) (int, error) {
+		if node.IsLeaf() {
+			leaf := len(m.LeafClass)
+			m.LeafClass = append(m.LeafClass, classIndex[node.Class])
+			probas := make([]int64, len(m.Classes))
+			// Una hoja se predice a sí misma, así que no hace falta vector de features
+			probs := node.PredictProba(nil)
+			for class, p := range probs {
+				probas[classIndex[class]] = int64(math.Round(p * one))
+			}
+			m.LeafProbas = append(m.LeafProbas, probas)
+			return -(leaf + 1), nil
+		}
+		if node.Column >= len(m.Shifts) {
+			return 0, fmt.Errorf("el árbol usa la feature %d pero solo hay rangos para %d", node.Column, len(m.Shifts))
+		}
+		threshold := math.Floor(node.Value * math.Ldexp(1, m.Shifts[node.Column]))
+		// limit es el valor de los ausentes: el umbral tiene que quedar por debajo
+		if threshold >= limit || threshold < -limit-1 {
+			return 0, fmt.Errorf("el umbral %g de la feature %d no cabe en %d bits", node.Value, node.Column, bits)
+		}
+		index := len(m.Feature)
+		m.Feature = append(m.Feature, node.Column)
+		m.Threshold = append(m.Threshold, int64(threshold))
+		m.Left = append(m.Left, 0)
+		m.Right = append(m.Right, 0)
+		left, err := flatten(node.Left)
+		if err != nil {
+			return 0, err
+		}
+		right, err := flatten(node.Right)
+		if err != nil {
+			return 0, err
+		}
+		m.Left[index], m.Right[index] = left, right
+		return index, nil
+	}
+	if _, err := flatten(tree); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Quantize convierte un vector de features a la representación entera del modelo,
+// saturando en los límites del tipo; un valor ausente (NaN) pasa al máximo
+func (m *FixedPointModel) Quantize(features []float64) []int64 {
+	limit := float64(int64(1)<<(m.Bits-1) - 1)
+	q := make([]int64, len(m.Shifts))
+	for j := range q {
+		value := math.Floor(features[j] * math.Ldexp(1, m.Shifts[j]))
+		if math.IsNaN(value) {
+			value = limit
+		}
+		q[j] = int64(math.Max(-limit-1, math.Min(limit, value)))
+	}
+	return q
+}
+
+// Predict recorre las tablas con aritmética entera, igual que el código generado
+func (m *FixedPointModel) Predict(q []int64) int {
+	if len(m.Feature) == 0 {
+		return m.LeafClass[0]
+	}
+	node := 0
+	for node >= 0 {
+		if q[m.Feature[node]] <= m.Threshold[node] {
+			node = m.Left[node]
+		} else {
+			node = m.Right[node]
+		}
+	}
+	return m.LeafClass[-node-1]
+}
+
+// WriteC emite las tablas y un predictor en C que solo usa enteros
+func (m *FixedPointModel) WriteC(w io.Writer, name string) {
+	typ := fmt.Sprintf("int%d_t", m.Bits)
+	join := func(values []int64) string {
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = strconv.FormatInt(v, 10)
+		}
+		return strings.Join(parts, ", ")
+	}
+	ints := func(values []int) []int64 {
+		out := make([]int64, len(values))
+		for i, v := range values {
+			out[i] = int64(v)
+		}
+		return out
+	}
+	// C no admite arrays vacíos: un árbol de una sola hoja lleva un nodo de relleno
+	internal := len(m.Feature)
+	feature, threshold, left, right := ints(m.Feature), m.Threshold, ints(m.Left), ints(m.Right)
+	if internal == 0 {
+		feature, threshold, left, right = []int64{0}, []int64{0}, []int64{-1}, []int64{-1}
+	}
+
+	fmt.Fprintf(w, "/* Código generado por pcdta %s; no editar. */\n", PackageVersion)
+	fmt.Fprintf(w, "/* Cada feature j se pasa como x_q[j] = floor(x[j] * 2^%s_shift[j]);\n", name)
+	fmt.Fprintf(w, "   un valor ausente se pasa como INT%d_MAX. */\n\n", m.Bits)
+	fmt.Fprintf(w, "#include <stdint.h>\n\n")
+	fmt.Fprintf(w, "#define %s_NUM_FEATURES %d\n", strings.ToUpper(name), len(m.Shifts))
+	fmt.Fprintf(w, "#define %s_NUM_CLASSES %d\n\n", strings.ToUpper(name), len(m.Classes))
+	fmt.Fprintf(w, "static const char *const %s_classes[] = {%s};\n", name, quoteClasses(m.Classes))
+	fmt.Fprintf(w, "const int8_t %s_shift[] = {%s};\n\n", name, join(ints(m.Shifts)))
+	fmt.Fprintf(w, "static const uint16_t %s_feature[] = {%s};\n", name, join(feature))
+	fmt.Fprintf(w, "static const %s %s_threshold[] = {%s};\n", typ, name, join(threshold))
+	indexType := "int16_t"
+	if len(m.LeafClass) > math.MaxInt16 || internal > math.MaxInt16 {
+		indexType = "int32_t"
+	}
+	fmt.Fprintf(w, "static const %s %s_left[] = {%s};\n", indexType, name, join(left))
+	fmt.Fprintf(w, "static const %s %s_right[] = {%s};\n", indexType, name, join(right))
+	fmt.Fprintf(w, "static const uint16_t %s_leaf_class[] = {%s};\n", name, join(ints(m.LeafClass)))
+	fmt.Fprintf(w, "/* Probabilidades por hoja en Q%d */\n", m.Bits-1)
+	fmt.Fprintf(w, "const %s %s_leaf_proba[][%d] = {\n", typ, name, len(m.Classes))
+	for _, probas := range m.LeafProbas {
+		fmt.Fprintf(w, "    {%s},\n", join(probas))
+	}
+	fmt.Fprintf(w, "};\n\n")
+	fmt.Fprintf(w, "const char *%s_class_name(int index) {\n    return %s_classes[index];\n}\n\n", name, name)
+	fmt.Fprintf(w, "/* Devuelve el índice de la hoja alcanzada; su clase es %s_leaf_class[hoja]. */\n", name)
+	fmt.Fprintf(w, "int %s_leaf(const %s *x_q) {\n", name, typ)
+	if internal == 0 {
+		fmt.Fprintf(w, "    (void)x_q;\n    return 0;\n}\n\n")
+	} else {
+		fmt.Fprintf(w, "    int node = 0;\n")
+		fmt.Fprintf(w, "    while (node >= 0) {\n")
+		fmt.Fprintf(w, "        node = x_q[%s_feature[node]] <= %s_threshold[node] ? %s_left[node] : %s_right[node];\n", name, name, name, name)
+		fmt.Fprintf(w, "    }\n    return -node - 1;\n}\n\n")
+	}
+	fmt.Fprintf(w, "int %s_predict(const %s *x_q) {\n    return %s_leaf_class[%s_leaf(x_q)];\n}\n", name, typ, name, name)
+}
+
+// WriteCInput añade a la salida de WriteC la conversión en coma flotante de una fila de
+// la entrada (name_input, como en ExportCode) y name_predict_row, que la cuantiza igual
+// que Quantize. Solo hace falta si meta codifica columnas categóricas o deriva
+// features; si no, no escribe nada.
+func (m *FixedPointModel) WriteCInput(w io.Writer, name string, meta ModelMetadata) error {
+	in, err := newCodeInput(meta)
+	if err != nil || in == nil {
+		return err
+	}
+	if in.size != len(m.Shifts) {
+		return fmt.Errorf("el modelo tiene %d features y las tablas %d", in.size, len(m.Shifts))
+	}
+	typ := fmt.Sprintf("int%d_t", m.Bits)
+	limit := int64(1)<<(m.Bits-1) - 1
+
+	fmt.Fprintf(w, "\n#include <math.h>\n#include <string.h>\n")
+	writeCConvert(w, name, in)
+	fmt.Fprintf(w, "/* Cuantiza una fila de la entrada y devuelve el índice de la clase predicha. */\n")
+	fmt.Fprintf(w, "int %s_predict_row(const double *num, const char *const *cat) {\n", name)
+	fmt.Fprintf(w, "    double x[%d];\n    %s x_q[%d];\n    int j;\n", in.size, typ, in.size)
+	fmt.Fprintf(w, "    %s_input(num, cat, x);\n", name)
+	fmt.Fprintf(w, "    for (j = 0; j < %d; j++) {\n", in.size)
+	fmt.Fprintf(w, "        double v = floor(ldexp(x[j], %s_shift[j]));\n", name)
+	fmt.Fprintf(w, "        x_q[j] = isnan(v) || v > %d.0 ? %d : v < %d.0 ? %d - 1 : (%s)v;\n", limit, limit, -limit-1, -limit, typ)
+	fmt.Fprintf(w, "    }\n    return %s_predict(x_q);\n}\n", name)
+	return nil
+}
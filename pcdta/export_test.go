@@ -0,0 +1,251 @@
+package pcdta
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestExportCodeEscapesFeatureNamesInComments(t *testing.T) {
+	model := &Model[string]{
+		Meta: ModelMetadata{FeatureNames: []string{"a\nint evil;", "b */ int evil2; /*"}},
+		Tree: &DecisionTree[string]{
+			Column: 0, Value: 1,
+			Left:  &DecisionTree[string]{Class: "x"},
+			Right: &DecisionTree[string]{Column: 1, Value: 2, Left: &DecisionTree[string]{Class: "y"}, Right: &DecisionTree[string]{Class: "x"}},
+		},
+	}
+	for _, lang := range []string{"c", "java", "go"} {
+		var out strings.Builder
+		if err := ExportCode(&out, model, lang, "modelo"); err != nil {
+			t.Fatalf("%s: %v", lang, err)
+		}
+		code := out.String()
+		for _, line := range strings.Split(code, "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "int evil") {
+				t.Errorf("%s: un salto de línea del nombre salió del comentario:\n%s", lang, code)
+			}
+		}
+		if strings.Contains(code, "b */") {
+			t.Errorf("%s: el nombre cierra el comentario de bloque:\n%s", lang, code)
+		}
+		if lang == "go" {
+			if _, err := parser.ParseFile(token.NewFileSet(), "modelo.go", code, 0); err != nil {
+				t.Errorf("el código Go generado no compila: %v\n%s", err, code)
+			}
+		}
+	}
+}
+
+// encodedModel usa una columna one-hot (color) y otra con hashing (city)
+func encodedModel() *Model[string] {
+	return &Model[string]{
+		Meta: ModelMetadata{
+			FeatureNames: []string{"x", "color=red", "color=blue", "city#0", "city#1"},
+			Encodings: []ColumnEncoding{
+				{Column: "color", Method: "onehot", Features: []string{"color=red", "color=blue"},
+					Values: map[string][]float64{"red": {1, 0}, "blue": {0, 1}}, Default: []float64{0, 0}},
+				{Column: "city", Method: "hash", Features: []string{"city#0", "city#1"}, Default: []float64{0, 0}},
+			},
+		},
+		Tree: &DecisionTree[string]{
+			Column: 1, Value: 0.5,
+			Left:  &DecisionTree[string]{Column: 4, Value: 0.5, Left: &DecisionTree[string]{Class: "a"}, Right: &DecisionTree[string]{Class: "b"}},
+			Right: &DecisionTree[string]{Class: "c"},
+		},
+	}
+}
+
+func TestExportCodeGeneratesInputConversion(t *testing.T) {
+	model := encodedModel()
+	for _, lang := range []string{"c", "java", "go"} {
+		var out strings.Builder
+		if err := ExportCode(&out, model, lang, "modelo"); err != nil {
+			t.Fatalf("%s: %v", lang, err)
+		}
+		code := out.String()
+		want := map[string]string{"c": "modelo_predict_row(", "java": "predictRow(", "go": "func PredictRow("}[lang]
+		if !strings.Contains(code, want) || !strings.Contains(code, `"red"`) || !strings.Contains(code, "16777619") {
+			t.Errorf("%s: falta la conversión de la entrada:\n%s", lang, code)
+		}
+		if lang == "go" {
+			if _, err := parser.ParseFile(token.NewFileSet(), "modelo.go", code, 0); err != nil {
+				t.Errorf("el código Go generado no compila: %v\n%s", err, code)
+			}
+		}
+	}
+
+	policy := ExportPolicy(model)
+	if strings.Join(policy.Inputs, ",") != "x,color,city" || len(policy.Encodings) != 2 {
+		t.Errorf("la política no describe la entrada: inputs %v, %d codificaciones", policy.Inputs, len(policy.Encodings))
+	}
+}
+
+func TestFixedPointWriteCInput(t *testing.T) {
+	model := encodedModel()
+	fixed, err := QuantizeTree(model.Tree, []float64{10, 1, 1, 1, 1}, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	fixed.WriteC(&out, "m")
+	if err := fixed.WriteCInput(&out, "m", model.Meta); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "int m_predict_row(const double *num, const char *const *cat)") {
+		t.Errorf("falta m_predict_row:\n%s", out.String())
+	}
+	// Sin codificaciones no hace falta conversión
+	out.Reset()
+	if err := fixed.WriteCInput(&out, "m", ModelMetadata{}); err != nil || out.Len() != 0 {
+		t.Errorf("sin codificaciones: error %v, %d bytes escritos", err, out.Len())
+	}
+}
+
+func TestQuantizeTreeMissingValuesAndRanges(t *testing.T) {
+	tree := &DecisionTree[string]{
+		Column: 0, Value: 3,
+		Left:  &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 1}},
+		Right: &DecisionTree[string]{Class: "b", Counts: map[string]int{"b": 1}},
+	}
+	fixed, err := QuantizeTree(tree, []float64{4}, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []float64{math.NaN(), 1, 5, 4} {
+		if got, want := fixed.Classes[fixed.Predict(fixed.Quantize([]float64{value}))], tree.Predict([]float64{value}); got != want {
+			t.Errorf("Predict(%v) en punto fijo = %s, el árbol da %s", value, got, want)
+		}
+	}
+
+	// Un rango diminuto necesita un desplazamiento que no cabe en int8_t
+	if _, err := QuantizeTree(tree, []float64{1e-60}, 32); err == nil {
+		t.Error("un desplazamiento fuera de int8 no devolvió error")
+	}
+	if _, err := QuantizeTree(tree, make([]float64, math.MaxUint16+2), 16); err == nil {
+		t.Error("más features que índices de 16 bits no devolvió error")
+	}
+}
+
+func TestExportUsesOriginalClassNames(t *testing.T) {
+	model := preprocessedModel()
+	model.Meta.Derived, model.Meta.Encodings = nil, nil
+
+	var out strings.Builder
+	if err := ExportCode(&out, model, "go", "modelo"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `var Classes = []string{"alpha", "beta", "gamma"}`) {
+		t.Errorf("las clases exportadas no tienen su nombre original:\n%s", out.String())
+	}
+
+	policy := ExportPolicy(model)
+	if strings.Join(policy.Classes, ",") != "alpha,beta,gamma" || policy.Policy.Else.Outcome.Class != "gamma" {
+		t.Errorf("política con clases %v y hoja derecha %s", policy.Classes, policy.Policy.Else.Outcome.Class)
+	}
+	if p := policy.Policy.Else.Outcome.Probabilities["gamma"]; p != 1 {
+		t.Errorf("probabilidad de gamma en la hoja derecha = %v, se esperaba 1", p)
+	}
+}
+
+func TestExportPolicyJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		model *Model[string]
+		want  string
+	}{
+		{
+			// Las probabilidades salen de Counts, de Weights o, si las hay, de Probs; el
+			// soporte es siempre la masa de la hoja
+			name: "tres hojas y clases renombradas",
+			model: &Model[string]{
+				Meta: ModelMetadata{
+					FeatureNames: []string{"x", "y"},
+					ClassMapping: map[string]string{"alpha": "a", "beta": "b", "gamma": "c"},
+				},
+				Tree: &DecisionTree[string]{
+					Column: 0, Value: 2.5,
+					Left: &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 3, "b": 1}},
+					Right: &DecisionTree[string]{
+						Column: 1, Value: 0.5,
+						Left:  &DecisionTree[string]{Class: "b", Weights: map[string]float64{"b": 1.5, "c": 0.5}},
+						Right: &DecisionTree[string]{Class: "c", Counts: map[string]int{"c": 4, "b": 1}, Probs: map[string]float64{"c": 0.7, "b": 0.3}},
+					},
+				},
+			},
+			want: `{"format":"pcdta-policy","version":1,"features":["x","y"],"classes":["alpha","beta","gamma"],"missing":"else",` +
+				`"policy":{"condition":{"feature":"x","index":0,"operator":"<=","threshold":2.5},` +
+				`"then":{"outcome":{"class":"alpha","probabilities":{"alpha":0.75,"beta":0.25},"support":4}},` +
+				`"else":{"condition":{"feature":"y","index":1,"operator":"<=","threshold":0.5},` +
+				`"then":{"outcome":{"class":"beta","probabilities":{"beta":0.75,"gamma":0.25},"support":2}},` +
+				`"else":{"outcome":{"class":"gamma","probabilities":{"beta":0.3,"gamma":0.7},"support":5}}}}}`,
+		},
+		{
+			// Sin nombres de features la condición usa la posición; una hoja sin masa da
+			// toda la probabilidad a su clase
+			name: "sin nombres y hoja vacía",
+			model: &Model[string]{Tree: &DecisionTree[string]{
+				Column: 0, Value: 1,
+				Left:  &DecisionTree[string]{Class: "a"},
+				Right: &DecisionTree[string]{Class: "b", Counts: map[string]int{"b": 2}},
+			}},
+			want: `{"format":"pcdta-policy","version":1,"features":null,"classes":["a","b"],"missing":"else",` +
+				`"policy":{"condition":{"feature":"feature 0","index":0,"operator":"<=","threshold":1},` +
+				`"then":{"outcome":{"class":"a","probabilities":{"a":1},"support":0}},` +
+				`"else":{"outcome":{"class":"b","probabilities":{"b":1},"support":2}}}}`,
+		},
+	} {
+		// Como en export-policy, sin escapar "<"
+		var out strings.Builder
+		encoder := json.NewEncoder(&out)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(ExportPolicy(tc.model)); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(out.String()); got != tc.want {
+			t.Errorf("%s:\n%s\nse esperaba\n%s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExportCodeComputesDerivedFeatures(t *testing.T) {
+	model := preprocessedModel()
+	// tmp no es una feature del árbol, pero ratio depende de ella
+	model.Meta.Derived = []DerivedFeature{
+		{Name: "tmp", Expr: "(y != 0 && !isna(x)) * x"},
+		{Name: "ratio", Expr: "max(tmp / y, 0) % 3"},
+	}
+	for _, lang := range []string{"c", "java", "go"} {
+		var out strings.Builder
+		if err := ExportCode(&out, model, lang, "modelo"); err != nil {
+			t.Fatalf("%s: %v", lang, err)
+		}
+		code := out.String()
+		want := map[string]string{"c": "x[3] = fmod(modelo_max((d0 / num[1]), 0.0), 3.0);",
+			"java": "x[3] = (Math.max((d0 / num[1]), 0.0) % 3.0);",
+			"go":   "x[3] = math.Mod(math.Max((d0 / num[1]), 0.0), 3.0)"}[lang]
+		if !strings.Contains(code, want) {
+			t.Errorf("%s: falta %q:\n%s", lang, want, code)
+		}
+		if lang == "go" {
+			if _, err := parser.ParseFile(token.NewFileSet(), "modelo.go", code, 0); err != nil {
+				t.Errorf("el código Go generado no compila: %v\n%s", err, code)
+			}
+		}
+	}
+	if policy := ExportPolicy(model); len(policy.Derived) != 2 || strings.Join(policy.Inputs, ",") != "x,region,y" {
+		t.Errorf("la política no describe la entrada: inputs %v, derivadas %v", policy.Inputs, policy.Derived)
+	}
+
+	// Ni literales de texto ni columnas categóricas tienen traducción numérica
+	for _, expr := range []string{"(region == 'EU') * x", "x / 2 + region"} {
+		model.Meta.Derived = []DerivedFeature{{Name: "ratio", Expr: expr}}
+		if err := ExportCode(io.Discard, model, "c", "modelo"); err == nil {
+			t.Errorf("%s: se esperaba un error", expr)
+		}
+	}
+}
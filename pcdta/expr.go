@@ -0,0 +1,559 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Expr es una expresión sobre las columnas de una fila, para filtrar con Where y crear
+// features derivadas con Derive. Admite números, cadenas entre comillas simples o
+// dobles, nombres de columna (class es la clase si no hay una feature con ese nombre;
+// entre acentos graves, `region=EU`, pueden contener cualquier carácter),
+// + - * / %, comparaciones, && || !, paréntesis y las funciones abs, sqrt, log, exp, min,
+// max e isna. Las comparaciones y operadores lógicos valen 1 o 0; un valor ausente
+// (NaN) no cumple ninguna comparación.
+type Expr struct {
+	Source string
+	root   exprNode
+}
+
+// exprValue es el resultado de evaluar un nodo: un número o, si IsString, una cadena
+type exprValue struct {
+	Num      float64
+	Str      string
+	IsString bool
+}
+
+// exprEnv resuelve un nombre de columna en la fila que se evalúa
+type exprEnv func(name string) (exprValue, error)
+
+type exprNode interface {
+	eval(env exprEnv) (exprValue, error)
+}
+
+type (
+	exprLiteral struct{ value exprValue }
+	exprColumn  struct{ name string }
+	exprUnary   struct {
+		op string
+		x  exprNode
+	}
+	exprBinary struct {
+		op   string
+		l, r exprNode
+	}
+	exprCall struct {
+		fn   string
+		args []exprNode
+	}
+)
+
+func (n exprLiteral) eval(exprEnv) (exprValue, error) { return n.value, nil }
+
+func (n exprColumn) eval(env exprEnv) (exprValue, error) { return env(n.name) }
+
+func exprBool(b bool) exprValue {
+	if b {
+		return exprValue{Num: 1}
+	}
+	return exprValue{}
+}
+
+func (v exprValue) truth() bool { return !v.IsString && v.Num != 0 && !math.IsNaN(v.Num) }
+
+func (n exprUnary) eval(env exprEnv) (exprValue, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return x, err
+	}
+	if x.IsString {
+		return x, fmt.Errorf("%s no se aplica a la cadena %q", n.op, x.Str)
+	}
+	if n.op == "!" {
+		return exprBool(!x.truth()), nil
+	}
+	return exprValue{Num: -x.Num}, nil
+}
+
+func (n exprBinary) eval(env exprEnv) (exprValue, error) {
+	l, err := n.l.eval(env)
+	if err != nil {
+		return l, err
+	}
+	// && y || no evalúan el lado derecho si no hace falta
+	switch n.op {
+	case "&&":
+		if !l.truth() {
+			return exprBool(false), nil
+		}
+	case "||":
+		if l.truth() {
+			return exprBool(true), nil
+		}
+	}
+	r, err := n.r.eval(env)
+	if err != nil {
+		return r, err
+	}
+	switch n.op {
+	case "&&", "||":
+		return exprBool(r.truth()), nil
+	}
+
+	if l.IsString || r.IsString {
+		if !l.IsString || !r.IsString {
+			return exprValue{}, fmt.Errorf("%s compara una cadena con un número", n.op)
+		}
+		switch n.op {
+		case "==":
+			return exprBool(l.Str == r.Str), nil
+		case "!=":
+			return exprBool(l.Str != r.Str), nil
+		case "<":
+			return exprBool(l.Str < r.Str), nil
+		case "<=":
+			return exprBool(l.Str <= r.Str), nil
+		case ">":
+			return exprBool(l.Str > r.Str), nil
+		case ">=":
+			return exprBool(l.Str >= r.Str), nil
+		}
+		return exprValue{}, fmt.Errorf("%s no se aplica a cadenas", n.op)
+	}
+
+	a, b := l.Num, r.Num
+	switch n.op {
+	case "+":
+		return exprValue{Num: a + b}, nil
+	case "-":
+		return exprValue{Num: a - b}, nil
+	case "*":
+		return exprValue{Num: a * b}, nil
+	case "/":
+		return exprValue{Num: a / b}, nil
+	case "%":
+		return exprValue{Num: math.Mod(a, b)}, nil
+	case "==":
+		return exprBool(a == b), nil
+	case "!=":
+		// Un ausente tampoco es distinto de nada
+		return exprBool(a != b && !math.IsNaN(a) && !math.IsNaN(b)), nil
+	case "<":
+		return exprBool(a < b), nil
+	case "<=":
+		return exprBool(a <= b), nil
+	case ">":
+		return exprBool(a > b), nil
+	case ">=":
+		return exprBool(a >= b), nil
+	}
+	return exprValue{}, fmt.Errorf("operador desconocido %s", n.op)
+}
+
+// exprFunctions son las funciones de Expr con su número de argumentos
+var exprFunctions = map[string]int{"abs": 1, "sqrt": 1, "log": 1, "exp": 1, "isna": 1, "min": 2, "max": 2}
+
+func (n exprCall) eval(env exprEnv) (exprValue, error) {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(env)
+		if err != nil {
+			return value, err
+		}
+		if value.IsString {
+			return value, fmt.Errorf("%s no admite la cadena %q", n.fn, value.Str)
+		}
+		args[i] = value.Num
+	}
+	switch n.fn {
+	case "abs":
+		return exprValue{Num: math.Abs(args[0])}, nil
+	case "sqrt":
+		return exprValue{Num: math.Sqrt(args[0])}, nil
+	case "log":
+		return exprValue{Num: math.Log(args[0])}, nil
+	case "exp":
+		return exprValue{Num: math.Exp(args[0])}, nil
+	case "isna":
+		return exprBool(math.IsNaN(args[0])), nil
+	case "min":
+		return exprValue{Num: math.Min(args[0], args[1])}, nil
+	default:
+		return exprValue{Num: math.Max(args[0], args[1])}, nil
+	}
+}
+
+// exprParser es un analizador descendente recursivo; la precedencia, de menor a
+// mayor, es || , &&, comparaciones, + -, * / %, y los unarios ! -
+type exprParser struct {
+	src string
+	pos int
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// accept consume el primer operador de ops que aparece en la posición actual
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	p.skipSpace()
+	for _, op := range ops {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			p.pos += len(op)
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("expresión %q, posición %d: %s", p.src, p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) binary(next func() (exprNode, error), ops ...string) (exprNode, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = exprBinary{op, left, right}
+	}
+}
+
+func (p *exprParser) or() (exprNode, error) { return p.binary(p.and, "||") }
+
+func (p *exprParser) and() (exprNode, error) { return p.binary(p.comparison, "&&") }
+
+func (p *exprParser) comparison() (exprNode, error) {
+	// Los operadores de dos caracteres van antes para que < no se coma <=
+	return p.binary(p.sum, "==", "!=", "<=", ">=", "<", ">")
+}
+
+func (p *exprParser) sum() (exprNode, error) { return p.binary(p.product, "+", "-") }
+
+func (p *exprParser) product() (exprNode, error) { return p.binary(p.unary, "*", "/", "%") }
+
+func (p *exprParser) unary() (exprNode, error) {
+	if op, ok := p.accept("-", "!"); ok {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return exprUnary{op, x}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("falta un operando")
+	}
+	c := p.src[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, p.errorf("falta )")
+		}
+		return x, nil
+	case c == '\'' || c == '"':
+		end := strings.IndexByte(p.src[p.pos+1:], c)
+		if end < 0 {
+			return nil, p.errorf("cadena sin cerrar")
+		}
+		value := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return exprLiteral{exprValue{Str: value, IsString: true}}, nil
+	case c == '`':
+		end := strings.IndexByte(p.src[p.pos+1:], '`')
+		if end < 0 {
+			return nil, p.errorf("nombre de columna sin cerrar")
+		}
+		if end == 0 {
+			return nil, p.errorf("nombre de columna vacío")
+		}
+		name := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return exprColumn{name}, nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE", p.src[p.pos]) >= 0 {
+			// Exponente con signo: 1e-3
+			if (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') && p.pos+1 < len(p.src) && (p.src[p.pos+1] == '-' || p.src[p.pos+1] == '+') {
+				p.pos++
+			}
+			p.pos++
+		}
+		value, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, p.errorf("número no válido %q", p.src[start:p.pos])
+		}
+		return exprLiteral{exprValue{Num: value}}, nil
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		start := p.pos
+		for p.pos < len(p.src) {
+			c := p.src[p.pos]
+			if c != '_' && c != '.' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+				break
+			}
+			p.pos++
+		}
+		name := p.src[start:p.pos]
+		if _, ok := p.accept("("); !ok {
+			return exprColumn{name}, nil
+		}
+		arity, ok := exprFunctions[name]
+		if !ok {
+			return nil, p.errorf("función desconocida %s", name)
+		}
+		var args []exprNode
+		for len(args) < arity {
+			if len(args) > 0 {
+				if _, ok := p.accept(","); !ok {
+					return nil, p.errorf("%s espera %d argumentos", name, arity)
+				}
+			}
+			arg, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, p.errorf("falta ) en %s", name)
+		}
+		return exprCall{name, args}, nil
+	}
+	return nil, p.errorf("carácter inesperado %q", c)
+}
+
+// ParseExpr analiza una expresión de Where o Derive
+func ParseExpr(src string) (*Expr, error) {
+	p := &exprParser{src: src}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("sobra %q", p.src[p.pos:])
+	}
+	return &Expr{Source: src, root: root}, nil
+}
+
+// Columns devuelve los nombres de columna que usa la expresión, sin repetir
+func (e *Expr) Columns() []string {
+	var names []string
+	seen := make(map[string]bool)
+	var visit func(node exprNode)
+	visit = func(node exprNode) {
+		switch n := node.(type) {
+		case exprColumn:
+			if !seen[n.name] {
+				seen[n.name] = true
+				names = append(names, n.name)
+			}
+		case exprUnary:
+			visit(n.x)
+		case exprBinary:
+			visit(n.l)
+			visit(n.r)
+		case exprCall:
+			for _, arg := range n.args {
+				visit(arg)
+			}
+		}
+	}
+	visit(e.root)
+	return names
+}
+
+// exprRow prepara la evaluación sobre los ejemplos de d, comprobando antes que existen
+// todas las columnas de e
+func exprRow[L comparable](d *Dataset[L], e *Expr) (func(example Example[L]) (exprValue, error), error) {
+	index := make(map[string]int, len(d.FeatureNames))
+	for j, name := range d.FeatureNames {
+		index[name] = j
+	}
+	for _, name := range e.Columns() {
+		if _, ok := index[name]; !ok && name != "class" {
+			return nil, fmt.Errorf("expresión %q: no existe la columna %s", e.Source, name)
+		}
+	}
+	return func(example Example[L]) (exprValue, error) {
+		return e.root.eval(func(name string) (exprValue, error) {
+			if j, ok := index[name]; ok {
+				return exprValue{Num: example.Features[j]}, nil
+			}
+			return exprValue{Str: fmt.Sprint(example.Class), IsString: true}, nil
+		})
+	}, nil
+}
+
+// Where devuelve los ejemplos para los que la expresión es verdadera, p. ej.
+// d.Where("age > 30 && class == 'EU'")
+func (d *Dataset[L]) Where(expr string) (*Dataset[L], error) {
+	e, err := ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	eval, err := exprRow(d, e)
+	if err != nil {
+		return nil, err
+	}
+	out := &Dataset[L]{FeatureNames: d.FeatureNames}
+	for i, example := range d.Examples {
+		value, err := eval(example)
+		if err != nil {
+			return nil, fmt.Errorf("fila %d: %w", i+1, err)
+		}
+		if value.truth() {
+			out.Examples = append(out.Examples, example)
+		}
+	}
+	return out, nil
+}
+
+// DerivedFeature es una feature calculada a partir de otras columnas con una Expr
+type DerivedFeature struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+}
+
+// Derive devuelve una copia con una columna más, name, calculada con la expresión
+// numérica expr sobre cada fila
+func (d *Dataset[L]) Derive(name, expr string) (*Dataset[L], error) {
+	for _, existing := range d.FeatureNames {
+		if existing == name {
+			return nil, fmt.Errorf("ya existe la columna %s", name)
+		}
+	}
+	e, err := ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	eval, err := exprRow(d, e)
+	if err != nil {
+		return nil, err
+	}
+	out := &Dataset[L]{FeatureNames: append(append([]string{}, d.FeatureNames...), name)}
+	out.Examples = make([]Example[L], len(d.Examples))
+	for i, example := range d.Examples {
+		value, err := eval(example)
+		if err != nil {
+			return nil, fmt.Errorf("fila %d: %w", i+1, err)
+		}
+		if value.IsString {
+			return nil, fmt.Errorf("%s = %s: el resultado es una cadena", name, expr)
+		}
+		out.Examples[i] = example
+		out.Examples[i].Features = append(append([]float64{}, example.Features...), value.Num)
+	}
+	return out, nil
+}
+
+// WhereFile evalúa expr sobre cada fila de un CSV o .pcd tal como está escrito, antes
+// de codificar nada: los campos que no son números son cadenas, así que se puede
+// filtrar por una columna categórica (region == 'EU'), y class, o el nombre de la
+// última columna, es la clase. También puede usar las features derived, que se
+// calculan igual sobre la fila. Devuelve si se cumple para cada fila de datos.
+func WhereFile(filename, expr string, derived []DerivedFeature) ([]bool, error) {
+	e, err := ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	header, records, err := readRawRecords(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	classColumn := len(header) - 1
+	index := map[string]int{"class": classColumn}
+	for j, name := range header {
+		index[name] = j
+	}
+	exprs := make(map[string]*Expr, len(derived))
+	for _, d := range derived {
+		if exprs[d.Name], err = ParseExpr(d.Expr); err != nil {
+			return nil, fmt.Errorf("feature derivada %s: %w", d.Name, err)
+		}
+	}
+	for _, name := range e.Columns() {
+		if _, ok := index[name]; !ok && exprs[name] == nil {
+			return nil, fmt.Errorf("expresión %q: %s no tiene la columna %s", expr, filename, name)
+		}
+	}
+
+	mask := make([]bool, len(records))
+	computed := make(map[string]exprValue, len(derived))
+	pending := make(map[string]bool)
+	for i, record := range records {
+		clear(computed)
+		var env exprEnv
+		env = func(name string) (exprValue, error) {
+			if j, ok := index[name]; ok {
+				field := strings.TrimSpace(record[j])
+				if j == classColumn {
+					return exprValue{Str: record[j], IsString: true}, nil
+				}
+				if value, err := parseFeature(field); err == nil {
+					return exprValue{Num: value}, nil
+				}
+				return exprValue{Str: field, IsString: true}, nil
+			}
+			if value, ok := computed[name]; ok {
+				return value, nil
+			}
+			d, ok := exprs[name]
+			if !ok {
+				return exprValue{}, fmt.Errorf("no existe la columna %s", name)
+			}
+			if pending[name] {
+				return exprValue{}, fmt.Errorf("la feature derivada %s depende de sí misma", name)
+			}
+			pending[name] = true
+			value, err := d.root.eval(env)
+			delete(pending, name)
+			if err != nil {
+				return exprValue{}, fmt.Errorf("feature derivada %s: %w", name, err)
+			}
+			computed[name] = value
+			return value, nil
+		}
+		value, err := e.root.eval(env)
+		if err != nil {
+			return nil, fmt.Errorf("%s: fila %d: %w", filename, i+1, err)
+		}
+		mask[i] = value.truth()
+	}
+	return mask, nil
+}
+
+// Filter devuelve los elementos de items para los que keep es verdadero
+func Filter[T any](items []T, keep []bool) []T {
+	var out []T
+	for i, item := range items {
+		if keep[i] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
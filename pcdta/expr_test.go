@@ -0,0 +1,80 @@
+package pcdta
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseExprQuotedColumnNames(t *testing.T) {
+	e, err := ParseExpr("`region=EU` + `a:b#c` > 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Columns(); !slices.Equal(got, []string{"region=EU", "a:b#c"}) {
+		t.Errorf("Columns = %v", got)
+	}
+	for _, src := range []string{"`sin cerrar > 1", "`` > 1"} {
+		if _, err := ParseExpr(src); err == nil {
+			t.Errorf("ParseExpr(%q) no devolvió error", src)
+		}
+	}
+
+	d := &Dataset[string]{FeatureNames: []string{"region=EU", "x"}, Examples: []Example[string]{
+		{Features: []float64{1, 5}, Class: "a"},
+		{Features: []float64{0, 5}, Class: "b"},
+	}}
+	out, err := d.Where("`region=EU` == 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Examples) != 1 || out.Examples[0].Class != "a" {
+		t.Errorf("Where devolvió %v", out.Examples)
+	}
+}
+
+func TestWhereFileUsesRawValuesAndDerivedFeatures(t *testing.T) {
+	path := writeTemp(t, "raw.csv", "x,region,y,species\n4,EU,2,a\n1,US,2,b\n6,EU,NA,a\n8, EU ,1,b\n")
+	derived := []DerivedFeature{{Name: "ratio", Expr: "x / y"}, {Name: "double", Expr: "ratio * 2"}}
+	cases := map[string][]bool{
+		"region == 'EU'":            {true, false, true, true},
+		"region == 'EU' && x > 5":   {false, false, true, true},
+		"species == 'b'":            {false, true, false, true},
+		"class == 'a'":              {true, false, true, false},
+		"double > 3":                {true, false, false, true}, // NaN no cumple la comparación
+		"`region` != 'US' && y < 2": {false, false, false, true},
+	}
+	for expr, want := range cases {
+		got, err := WhereFile(path, expr, derived)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: %v, se esperaba %v", expr, got, want)
+		}
+	}
+	if _, err := WhereFile(path, "colour == 'EU'", nil); err == nil {
+		t.Error("una columna inexistente no devolvió error")
+	}
+	if _, err := WhereFile(path, "a > 1", []DerivedFeature{{Name: "a", Expr: "b"}, {Name: "b", Expr: "a"}}); err == nil {
+		t.Error("unas derivadas circulares no devolvieron error")
+	}
+}
+
+func TestWhereFilePCD(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "datos.pcd")
+	examples := []Example[string]{{Features: []float64{1, 2}, Class: "a"}, {Features: []float64{3, 4}, Class: "b"}}
+	if err := WritePCD(path, examples, []string{"u", "v"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := WhereFile(path, "v > 3 || class == 'a'", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []bool{true, true}) {
+		t.Errorf("WhereFile = %v", got)
+	}
+	if got := Filter([]string{"p", "q", "r"}, []bool{true, false, true}); !slices.Equal(got, []string{"p", "r"}) {
+		t.Errorf("Filter = %v", got)
+	}
+}
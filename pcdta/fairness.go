@@ -0,0 +1,228 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// GroupRates son las tasas de predicción positiva de un grupo del atributo sensible
+type GroupRates struct {
+	Group         string
+	Count         int
+	SelectionRate float64 // P(ŷ = positiva)
+	TruePositive  float64 // P(ŷ = positiva | y = positiva)
+	FalsePositive float64 // P(ŷ = positiva | y ≠ positiva)
+}
+
+// FairnessReport resume la equidad entre grupos respecto a una clase positiva:
+// diferencia de paridad demográfica (rango de SelectionRate), impacto dispar
+// (mínima / máxima SelectionRate) y diferencia de probabilidades igualadas
+// (el mayor de los rangos de TruePositive y FalsePositive)
+type FairnessReport struct {
+	Positive                    string
+	Groups                      []GroupRates
+	DemographicParityDifference float64
+	DisparateImpact             float64
+	EqualizedOddsDifference     float64
+}
+
+func (r FairnessReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Equidad respecto a la clase %q:\n", r.Positive)
+	for _, g := range r.Groups {
+		fmt.Fprintf(&sb, "  grupo %s (%d filas): selección %.3f, TPR %.3f, FPR %.3f\n",
+			g.Group, g.Count, g.SelectionRate, g.TruePositive, g.FalsePositive)
+	}
+	fmt.Fprintf(&sb, "  diferencia de paridad demográfica: %.3f\n", r.DemographicParityDifference)
+	fmt.Fprintf(&sb, "  impacto dispar: %.3f\n", r.DisparateImpact)
+	fmt.Fprintf(&sb, "  diferencia de probabilidades igualadas: %.3f\n", r.EqualizedOddsDifference)
+	return sb.String()
+}
+
+// Fairness calcula las métricas de equidad del modelo sobre los ejemplos;
+// groups[i] es el valor del atributo sensible del ejemplo i
+func Fairness[L comparable](model Classifier[L], examples []Example[L], groups []string, positive L) FairnessReport {
+	type tally struct{ n, selected, pos, truePos, neg, falsePos int }
+	tallies := make(map[string]*tally)
+	for i, example := range examples {
+		t := tallies[groups[i]]
+		if t == nil {
+			t = &tally{}
+			tallies[groups[i]] = t
+		}
+		predicted := model.Predict(example.Features) == positive
+		actual := example.Class == positive
+		t.n++
+		if predicted {
+			t.selected++
+		}
+		if actual {
+			t.pos++
+			if predicted {
+				t.truePos++
+			}
+		} else {
+			t.neg++
+			if predicted {
+				t.falsePos++
+			}
+		}
+	}
+
+	rate := func(num, den int) float64 {
+		if den == 0 {
+			return 0
+		}
+		return float64(num) / float64(den)
+	}
+
+	report := FairnessReport{Positive: fmt.Sprint(positive)}
+	for group, t := range tallies {
+		report.Groups = append(report.Groups, GroupRates{
+			Group:         group,
+			Count:         t.n,
+			SelectionRate: rate(t.selected, t.n),
+			TruePositive:  rate(t.truePos, t.pos),
+			FalsePositive: rate(t.falsePos, t.neg),
+		})
+	}
+	sort.Slice(report.Groups, func(i, j int) bool { return report.Groups[i].Group < report.Groups[j].Group })
+	if len(report.Groups) == 0 {
+		return report
+	}
+
+	spread := func(value func(GroupRates) float64) (lo, hi float64) {
+		lo, hi = math.Inf(1), math.Inf(-1)
+		for _, g := range report.Groups {
+			lo, hi = math.Min(lo, value(g)), math.Max(hi, value(g))
+		}
+		return lo, hi
+	}
+	lo, hi := spread(func(g GroupRates) float64 { return g.SelectionRate })
+	report.DemographicParityDifference = hi - lo
+	if hi > 0 {
+		report.DisparateImpact = lo / hi
+	}
+	tprLo, tprHi := spread(func(g GroupRates) float64 { return g.TruePositive })
+	fprLo, fprHi := spread(func(g GroupRates) float64 { return g.FalsePositive })
+	report.EqualizedOddsDifference = math.Max(tprHi-tprLo, fprHi-fprLo)
+	return report
+}
+
+// SegmentScore es el rendimiento del modelo en las filas de un segmento. PValue es la
+// probabilidad binomial exacta de acertar como mucho esas filas si el segmento tuviera
+// la precisión global.
+type SegmentScore struct {
+	Segment  string
+	Count    int
+	Accuracy float64
+	MacroF1  float64
+	PValue   float64
+	Below    bool // significativamente por debajo de la precisión global
+}
+
+// SegmentReport desglosa la precisión y el F1 macro por segmentos
+type SegmentReport struct {
+	Column   string
+	Alpha    float64
+	Accuracy float64
+	MacroF1  float64
+	Segments []SegmentScore
+}
+
+func (r SegmentReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Rendimiento por %s: precisión global %.3f, F1 macro %.3f\n", r.Column, r.Accuracy, r.MacroF1)
+	for _, s := range r.Segments {
+		flag := ""
+		if s.Below {
+			flag = fmt.Sprintf("  << por debajo (p = %.4f)", s.PValue)
+		}
+		fmt.Fprintf(&sb, "  %s (%d filas): precisión %.3f, F1 macro %.3f%s\n", s.Segment, s.Count, s.Accuracy, s.MacroF1, flag)
+	}
+	return sb.String()
+}
+
+// macroF1 promedia el F1 de cada clase presente en las clases reales o en las predichas
+func macroF1[L comparable](actual, predicted []L) float64 {
+	truePos := make(map[L]int)
+	actualCount := make(map[L]int)
+	predictedCount := make(map[L]int)
+	for i := range actual {
+		actualCount[actual[i]]++
+		predictedCount[predicted[i]]++
+		if actual[i] == predicted[i] {
+			truePos[actual[i]]++
+		}
+	}
+	classes := make(map[L]bool)
+	for class := range actualCount {
+		classes[class] = true
+	}
+	for class := range predictedCount {
+		classes[class] = true
+	}
+	if len(classes) == 0 {
+		return 0
+	}
+	var sum float64
+	for class := range classes {
+		if denom := actualCount[class] + predictedCount[class]; denom > 0 {
+			sum += 2 * float64(truePos[class]) / float64(denom)
+		}
+	}
+	return sum / float64(len(classes))
+}
+
+// Segments evalúa el modelo por separado en cada segmento; segments[i] es el segmento
+// del ejemplo i. Un segmento se marca si su precisión queda por debajo de la global
+// con p < alpha en un contraste unilateral.
+func Segments[L comparable](model Classifier[L], examples []Example[L], segments []string, column string, alpha float64) SegmentReport {
+	actual := make([]L, len(examples))
+	predicted := make([]L, len(examples))
+	correct := 0
+	for i, example := range examples {
+		actual[i], predicted[i] = example.Class, model.Predict(example.Features)
+		if actual[i] == predicted[i] {
+			correct++
+		}
+	}
+	report := SegmentReport{Column: column, Alpha: alpha, MacroF1: macroF1(actual, predicted)}
+	if len(examples) == 0 {
+		return report
+	}
+	report.Accuracy = float64(correct) / float64(len(examples))
+
+	rows := make(map[string][]int)
+	var names []string
+	for i, segment := range segments {
+		if _, ok := rows[segment]; !ok {
+			names = append(names, segment)
+		}
+		rows[segment] = append(rows[segment], i)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var segActual, segPredicted []L
+		hits := 0
+		for _, i := range rows[name] {
+			segActual = append(segActual, actual[i])
+			segPredicted = append(segPredicted, predicted[i])
+			if actual[i] == predicted[i] {
+				hits++
+			}
+		}
+		n := float64(len(rows[name]))
+		s := SegmentScore{Segment: name, Count: len(rows[name]), Accuracy: float64(hits) / n, MacroF1: macroF1(segActual, segPredicted), PValue: 1}
+		// P(X <= k) con X ~ Bin(n, p) es I_{1-p}(n-k, k+1)
+		if hits < len(rows[name]) && report.Accuracy < 1 {
+			s.PValue = regularizedBeta(1-report.Accuracy, n-float64(hits), float64(hits)+1)
+		}
+		s.Below = s.Accuracy < report.Accuracy && s.PValue < alpha
+		report.Segments = append(report.Segments, s)
+	}
+	return report
+}
@@ -0,0 +1,156 @@
+package pcdta
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// echoModel predice la clase que trae el ejemplo en su primera feature: "sí" si vale 1
+type echoModel struct{}
+
+func (echoModel) Predict(features []float64) string {
+	if features[0] == 1 {
+		return "sí"
+	}
+	return "no"
+}
+
+// fairnessRows convierte filas "grupo predicha real" en ejemplos para echoModel
+func fairnessRows(rows ...string) ([]Example[string], []string) {
+	examples := make([]Example[string], len(rows))
+	groups := make([]string, len(rows))
+	for i, row := range rows {
+		fields := strings.Fields(row)
+		flag := 0.0
+		if fields[1] == "sí" {
+			flag = 1
+		}
+		groups[i] = fields[0]
+		examples[i] = Example[string]{Features: []float64{flag}, Class: fields[2]}
+	}
+	return examples, groups
+}
+
+func TestFairness(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		rows         []string
+		groups       []GroupRates
+		parity       float64
+		impact       float64
+		equalizedOdd float64
+	}{
+		{
+			// A: selección 2/4, TPR 1/2, FPR 1/2; B: selección 2/5, TPR 2/3, FPR 0/2
+			name: "dos grupos",
+			rows: []string{
+				"A sí sí", "A sí no", "A no sí", "A no no",
+				"B sí sí", "B sí sí", "B no sí", "B no no", "B no no",
+			},
+			groups: []GroupRates{
+				{Group: "A", Count: 4, SelectionRate: 0.5, TruePositive: 0.5, FalsePositive: 0.5},
+				{Group: "B", Count: 5, SelectionRate: 0.4, TruePositive: 2.0 / 3, FalsePositive: 0},
+			},
+			parity: 0.1, impact: 0.8, equalizedOdd: 0.5,
+		},
+		{
+			// Sin positivos reales en C su TPR es 0, no NaN
+			name:   "grupo sin positivos",
+			rows:   []string{"C sí no", "C no no", "D sí sí", "D sí no"},
+			groups: []GroupRates{{Group: "C", Count: 2, SelectionRate: 0.5, FalsePositive: 0.5}, {Group: "D", Count: 2, SelectionRate: 1, TruePositive: 1, FalsePositive: 1}},
+			parity: 0.5, impact: 0.5, equalizedOdd: 1,
+		},
+		{
+			// Sin ninguna selección el impacto dispar se queda en 0 en lugar de 0/0
+			name:   "nadie seleccionado",
+			rows:   []string{"A no sí", "B no no"},
+			groups: []GroupRates{{Group: "A", Count: 1}, {Group: "B", Count: 1}},
+		},
+	} {
+		examples, groups := fairnessRows(tc.rows...)
+		report := Fairness[string](echoModel{}, examples, groups, "sí")
+		if len(report.Groups) != len(tc.groups) {
+			t.Fatalf("%s: grupos %+v", tc.name, report.Groups)
+		}
+		for i, want := range tc.groups {
+			got := report.Groups[i]
+			if got.Group != want.Group || got.Count != want.Count || !near(got.SelectionRate, want.SelectionRate) ||
+				!near(got.TruePositive, want.TruePositive) || !near(got.FalsePositive, want.FalsePositive) {
+				t.Errorf("%s: grupo %+v, se esperaba %+v", tc.name, got, want)
+			}
+		}
+		if !near(report.DemographicParityDifference, tc.parity) || !near(report.DisparateImpact, tc.impact) ||
+			!near(report.EqualizedOddsDifference, tc.equalizedOdd) {
+			t.Errorf("%s: paridad %v, impacto %v, probabilidades igualadas %v; se esperaban %v, %v, %v", tc.name,
+				report.DemographicParityDifference, report.DisparateImpact, report.EqualizedOddsDifference,
+				tc.parity, tc.impact, tc.equalizedOdd)
+		}
+	}
+}
+
+// near compara valores calculados a mano con los del paquete, salvo redondeo
+func near(got, want float64) bool {
+	return math.Abs(got-want) < 1e-12
+}
+
+func TestSegments(t *testing.T) {
+	// Global: 5 de 8 aciertos. F1 macro global (6/9 + 4/7) / 2 = 13/21; en Y acierta 1
+	// de 4 y P(X <= 1) con X ~ Bin(4, 5/8) es (3/8)^4 + 4·(5/8)·(3/8)^3
+	mixed := []string{
+		"X sí sí", "X no no", "X sí sí", "X no no",
+		"Y sí no", "Y no sí", "Y sí no", "Y sí sí",
+	}
+	yPValue := math.Pow(0.375, 4) + 4*0.625*math.Pow(0.375, 3)
+	for _, tc := range []struct {
+		name     string
+		rows     []string
+		alpha    float64
+		accuracy float64
+		macroF1  float64
+		segments []SegmentScore
+	}{
+		{
+			name: "un segmento por debajo", rows: mixed, alpha: 0.2,
+			accuracy: 0.625, macroF1: 13.0 / 21,
+			segments: []SegmentScore{
+				{Segment: "X", Count: 4, Accuracy: 1, MacroF1: 1, PValue: 1},
+				{Segment: "Y", Count: 4, Accuracy: 0.25, MacroF1: 0.2, PValue: yPValue, Below: true},
+			},
+		},
+		{
+			// p = 0.1516 no llega a alpha = 0.1
+			name: "por debajo sin significación", rows: mixed, alpha: 0.1,
+			accuracy: 0.625, macroF1: 13.0 / 21,
+			segments: []SegmentScore{
+				{Segment: "X", Count: 4, Accuracy: 1, MacroF1: 1, PValue: 1},
+				{Segment: "Y", Count: 4, Accuracy: 0.25, MacroF1: 0.2, PValue: yPValue},
+			},
+		},
+		{
+			// Con precisión global 1 ningún segmento puede quedar por debajo
+			name: "todo acertado", rows: []string{"B sí sí", "A no no", "B no no"}, alpha: 0.5,
+			accuracy: 1, macroF1: 1,
+			segments: []SegmentScore{
+				{Segment: "A", Count: 1, Accuracy: 1, MacroF1: 1, PValue: 1},
+				{Segment: "B", Count: 2, Accuracy: 1, MacroF1: 1, PValue: 1},
+			},
+		},
+	} {
+		examples, segments := fairnessRows(tc.rows...)
+		report := Segments[string](echoModel{}, examples, segments, "grupo", tc.alpha)
+		if !near(report.Accuracy, tc.accuracy) || !near(report.MacroF1, tc.macroF1) {
+			t.Errorf("%s: precisión %v, F1 macro %v; se esperaban %v y %v", tc.name, report.Accuracy, report.MacroF1, tc.accuracy, tc.macroF1)
+		}
+		if len(report.Segments) != len(tc.segments) {
+			t.Fatalf("%s: segmentos %+v", tc.name, report.Segments)
+		}
+		for i, want := range tc.segments {
+			got := report.Segments[i]
+			if got.Segment != want.Segment || got.Count != want.Count || got.Below != want.Below || !near(got.Accuracy, want.Accuracy) ||
+				!near(got.MacroF1, want.MacroF1) || !near(got.PValue, want.PValue) {
+				t.Errorf("%s: segmento %+v, se esperaba %+v", tc.name, got, want)
+			}
+		}
+	}
+}
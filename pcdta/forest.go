@@ -0,0 +1,235 @@
+package pcdta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// Forest es un bosque aleatorio: árboles entrenados sobre muestras bootstrap y, si se
+// pide, cada uno con un subconjunto al azar de las features. Predice por la media de
+// las probabilidades de sus árboles, ponderada por Weights si CompressForest fundió
+// algunos.
+type Forest[L comparable] struct {
+	Classes []L                `json:"classes"`
+	Trees   []*DecisionTree[L] `json:"trees"`
+	Weights []float64          `json:"weights,omitempty"` // peso de cada árbol; vacío equivale a 1
+
+	FeatureNames []string `json:"feature_names,omitempty"`
+}
+
+// SaveForest guarda el bosque como JSON
+func SaveForest[L comparable](forest *Forest[L], filename string) error {
+	data, err := json.MarshalIndent(forest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+// LoadForest lee un bosque guardado con SaveForest
+func LoadForest[L comparable](filename string) (*Forest[L], error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var forest Forest[L]
+	if err := json.Unmarshal(data, &forest); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(forest.Trees) == 0 {
+		return nil, fmt.Errorf("%s: el bosque no tiene árboles", filename)
+	}
+	if len(forest.Weights) > 0 && len(forest.Weights) != len(forest.Trees) {
+		return nil, fmt.Errorf("%s: %d pesos para %d árboles", filename, len(forest.Weights), len(forest.Trees))
+	}
+	return &forest, nil
+}
+
+// Qué hace TrainForest cuando falla un árbol
+const (
+	TreeFailFast = "fail"  // devuelve el error sin entrenar el resto
+	TreeSkip     = "skip"  // sigue sin ese árbol
+	TreeRetry    = "retry" // lo repite con otra muestra, hasta Retries veces, y si no falla
+)
+
+// ErrTreeBudget indica que MemoryBudget tuvo que submuestrear o recortar un árbol del
+// bosque y ForestOptions.StrictBudget lo cuenta como fallo
+var ErrTreeBudget = errors.New("el árbol no cabe en MemoryBudget")
+
+// ForestOptions configura TrainForest
+type ForestOptions struct {
+	Trees       int // número de árboles
+	MaxFeatures int // features al azar que puede usar cada árbol (0 = todas)
+
+	// Fallos de un árbol: se acaba su TreeTimeout (0 = sin límite) o, con StrictBudget,
+	// MemoryBudget lo recorta. OnFailure dice qué hacer entonces (TreeFailFast si está
+	// vacío) y Retries cuántas veces se repite con TreeRetry.
+	OnFailure    string
+	Retries      int
+	TreeTimeout  time.Duration
+	StrictBudget bool
+}
+
+// TreeFailure es un intento fallido de entrenar un árbol del bosque
+type TreeFailure struct {
+	Tree    int    // posición del árbol entre los pedidos
+	Attempt int    // intento, desde 1
+	Err     string // causa del fallo
+	Outcome string // lo que se hizo: TreeFailFast, TreeSkip o TreeRetry
+}
+
+// ForestReport es el informe de TrainForest: el de cada árbol que entró en el bosque y
+// los intentos que fallaron
+type ForestReport struct {
+	Trees    []TrainReport
+	Failures []TreeFailure
+}
+
+// bootstrapSample devuelve len(examples) ejemplos elegidos al azar con reemplazo
+func bootstrapSample[L comparable](examples []Example[L], rng *rand.Rand) []Example[L] {
+	sample := make([]Example[L], len(examples))
+	for i := range sample {
+		sample[i] = examples[rng.Intn(len(examples))]
+	}
+	return sample
+}
+
+// TrainForest entrena forest.Trees árboles con opts, cada uno sobre su muestra
+// bootstrap. Con MaxFeatures > 0 cada árbol elige esas features al azar entre las que
+// permite opts.Features (todas si está vacío). Un árbol que falla se trata según
+// forest.OnFailure y queda en el informe; cancelar ctx detiene siempre el bosque.
+func TrainForest[L comparable](ctx context.Context, examples []Example[L], opts TrainOptions[L], forest ForestOptions, rng *rand.Rand) (*Forest[L], ForestReport, error) {
+	f := &Forest[L]{Classes: sortedClasses(examples)}
+	var report ForestReport
+	if forest.Trees < 1 {
+		return nil, report, fmt.Errorf("el bosque necesita al menos un árbol, no %d", forest.Trees)
+	}
+	if len(examples) == 0 {
+		return f, report, nil
+	}
+	switch forest.OnFailure {
+	case "", TreeFailFast, TreeSkip, TreeRetry:
+	default:
+		return nil, report, fmt.Errorf("OnFailure desconocido %q: use %s, %s o %s", forest.OnFailure, TreeFailFast, TreeSkip, TreeRetry)
+	}
+
+	for t := 0; t < forest.Trees; t++ {
+		for attempt := 1; ; attempt++ {
+			sample := bootstrapSample(examples, rng)
+			treeOpts := opts
+			treeOpts.Features = forestFeatures(opts.Features, len(examples[0].Features), forest.MaxFeatures, rng)
+			tree, treeReport, err := trainForestTree(ctx, sample, treeOpts, forest)
+			if err == nil {
+				f.Trees = append(f.Trees, tree)
+				report.Trees = append(report.Trees, treeReport)
+				break
+			}
+			if ctx.Err() != nil {
+				return nil, report, ctx.Err()
+			}
+
+			outcome := forest.OnFailure
+			if outcome == TreeRetry && attempt > forest.Retries {
+				outcome = TreeFailFast
+			}
+			if outcome == "" {
+				outcome = TreeFailFast
+			}
+			report.Failures = append(report.Failures, TreeFailure{Tree: t, Attempt: attempt, Err: err.Error(), Outcome: outcome})
+			if outcome == TreeFailFast {
+				return nil, report, fmt.Errorf("árbol %d, intento %d: %w", t, attempt, err)
+			}
+			if outcome == TreeSkip {
+				break
+			}
+		}
+	}
+	if len(f.Trees) == 0 {
+		return nil, report, fmt.Errorf("fallaron los %d árboles del bosque", forest.Trees)
+	}
+	return f, report, nil
+}
+
+// trainForestTree entrena un árbol del bosque con el límite de tiempo de forest y
+// devuelve ErrTreeBudget si StrictBudget no admite el recorte de MemoryBudget
+func trainForestTree[L comparable](ctx context.Context, sample []Example[L], opts TrainOptions[L], forest ForestOptions) (*DecisionTree[L], TrainReport, error) {
+	if forest.TreeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, forest.TreeTimeout)
+		defer cancel()
+	}
+	tree, report, err := BuildDecisionTreeContext(ctx, sample, opts)
+	if err != nil {
+		return nil, report, err
+	}
+	if forest.StrictBudget && (report.SampledRows > 0 || report.BudgetLeaves > 0) {
+		return nil, report, fmt.Errorf("%w: %d filas muestreadas, %d hojas cerradas", ErrTreeBudget, report.SampledRows, report.BudgetLeaves)
+	}
+	return tree, report, nil
+}
+
+// forestFeatures elige maxFeatures de las features permitidas (allowed, o todas si
+// está vacío); con maxFeatures <= 0 o mayor que las permitidas devuelve allowed
+func forestFeatures(allowed []int, numFeatures, maxFeatures int, rng *rand.Rand) []int {
+	pool := allowed
+	if len(pool) == 0 {
+		pool = make([]int, numFeatures)
+		for j := range pool {
+			pool[j] = j
+		}
+	}
+	if maxFeatures <= 0 || maxFeatures >= len(pool) {
+		return allowed
+	}
+	chosen := make([]int, maxFeatures)
+	for i, k := range rng.Perm(len(pool))[:maxFeatures] {
+		chosen[i] = pool[k]
+	}
+	sort.Ints(chosen)
+	return chosen
+}
+
+// weight devuelve el peso del árbol t
+func (f *Forest[L]) weight(t int) float64 {
+	if len(f.Weights) == 0 {
+		return 1
+	}
+	return f.Weights[t]
+}
+
+// PredictProba es la media, ponderada por Weights, de las probabilidades de los árboles
+func (f *Forest[L]) PredictProba(features []float64) map[L]float64 {
+	total := 0.0
+	for t := range f.Trees {
+		total += f.weight(t)
+	}
+	probs := make(map[L]float64, len(f.Classes))
+	for t, tree := range f.Trees {
+		for class, p := range tree.PredictProba(features) {
+			probs[class] += p * f.weight(t) / total
+		}
+	}
+	return probs
+}
+
+func (f *Forest[L]) Predict(features []float64) L {
+	return argmaxClass(f.Classes, f.PredictProba(features))
+}
+
+// ForestEstimator entrena un Forest con TrainForest; rng da las muestras de todos los
+// entrenamientos sucesivos
+func ForestEstimator[L comparable](opts TrainOptions[L], forest ForestOptions, rng *rand.Rand) Estimator[L] {
+	return EstimatorFunc[L](func(examples []Example[L]) (ProbabilisticClassifier[L], error) {
+		f, _, err := TrainForest(context.Background(), examples, opts, forest, rng)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	})
+}
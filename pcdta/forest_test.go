@@ -0,0 +1,168 @@
+package pcdta
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrainForest(t *testing.T) {
+	examples := separable(200, 7)
+	opts := DefaultTrainOptions[string]()
+	opts.MaxDepth = 3
+	forest, report, err := TrainForest(context.Background(), examples, opts, ForestOptions{Trees: 10, MaxFeatures: 2}, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Trees) != 10 || len(report.Failures) != 0 {
+		t.Errorf("informe con %d árboles y %d fallos", len(report.Trees), len(report.Failures))
+	}
+	if len(forest.Trees) != 10 {
+		t.Fatalf("%d árboles, se esperaban 10", len(forest.Trees))
+	}
+	for i, tree := range forest.Trees {
+		used := make(map[int]bool)
+		tree.Walk(func(node *DecisionTree[string], depth int) {
+			if !node.IsLeaf() {
+				used[node.Column] = true
+			}
+		})
+		if len(used) > 2 {
+			t.Errorf("el árbol %d usa %d features con MaxFeatures 2", i, len(used))
+		}
+	}
+
+	probs := forest.PredictProba(examples[0].Features)
+	if sum := probs["a"] + probs["b"]; math.Abs(sum-1) > 1e-9 {
+		t.Errorf("las probabilidades suman %v", sum)
+	}
+	if acc := Accuracy[string](forest, examples); acc < 0.9 {
+		t.Errorf("precisión %.3f en datos separables", acc)
+	}
+
+	again, _, _ := TrainForest(context.Background(), examples, opts, ForestOptions{Trees: 10, MaxFeatures: 2}, rand.New(rand.NewSource(1)))
+	for i := range forest.Trees {
+		if TreeHash(forest.Trees[i]) != TreeHash(again.Trees[i]) {
+			t.Fatal("con la misma semilla el bosque cambia")
+		}
+	}
+}
+
+func TestTrainForestFailures(t *testing.T) {
+	examples := separable(200, 7)
+	opts := DefaultTrainOptions[string]()
+	// Con un presupuesto de un byte MemoryBudget recorta todos los árboles
+	opts.MemoryBudget = 1
+	strict := ForestOptions{Trees: 3, StrictBudget: true}
+
+	for _, trees := range []int{0, -1} {
+		_, _, err := TrainForest(context.Background(), examples, opts, ForestOptions{Trees: trees}, rand.New(rand.NewSource(1)))
+		if err == nil || !strings.Contains(err.Error(), "al menos un árbol") {
+			t.Errorf("Trees=%d: error %v, se esperaba el de parámetros", trees, err)
+		}
+	}
+
+	_, report, err := TrainForest(context.Background(), examples, opts, strict, rand.New(rand.NewSource(1)))
+	if !errors.Is(err, ErrTreeBudget) || len(report.Failures) != 1 || report.Failures[0].Outcome != TreeFailFast {
+		t.Errorf("TreeFailFast: error %v, fallos %+v", err, report.Failures)
+	}
+
+	strict.OnFailure, strict.Retries = TreeRetry, 2
+	_, report, err = TrainForest(context.Background(), examples, opts, strict, rand.New(rand.NewSource(1)))
+	if err == nil || len(report.Failures) != 3 || report.Failures[2].Attempt != 3 || report.Failures[1].Outcome != TreeRetry {
+		t.Errorf("TreeRetry: error %v, fallos %+v", err, report.Failures)
+	}
+
+	strict.OnFailure = TreeSkip
+	if _, report, err = TrainForest(context.Background(), examples, opts, strict, rand.New(rand.NewSource(1))); err == nil || len(report.Failures) != 3 {
+		t.Errorf("TreeSkip sin ningún árbol: error %v, fallos %+v", err, report.Failures)
+	}
+
+	// Sin presupuesto no falla ninguno; con un nanosegundo por árbol fallan todos
+	opts.MemoryBudget = 0
+	forest, report, err := TrainForest(context.Background(), examples, opts, strict, rand.New(rand.NewSource(1)))
+	if err != nil || len(forest.Trees) != 3 || len(report.Trees) != 3 {
+		t.Errorf("sin recortes: error %v, %d árboles", err, len(report.Trees))
+	}
+	strict.TreeTimeout = time.Nanosecond
+	if _, report, _ = TrainForest(context.Background(), examples, opts, strict, rand.New(rand.NewSource(1))); len(report.Failures) != 3 || !strings.Contains(report.Failures[0].Err, "deadline") {
+		t.Errorf("TreeTimeout: fallos %+v", report.Failures)
+	}
+	strict.TreeTimeout = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	strict.OnFailure = TreeSkip
+	if _, _, err := TrainForest(ctx, examples, opts, strict, rand.New(rand.NewSource(1))); !errors.Is(err, context.Canceled) {
+		t.Errorf("con el contexto cancelado: error %v, se esperaba context.Canceled", err)
+	}
+}
+
+func TestCompressForest(t *testing.T) {
+	opts := DefaultTrainOptions[string]()
+	opts.MaxDepth = 3
+	forest, _, err := TrainForest(context.Background(), separable(200, 7), opts, ForestOptions{Trees: 12, MaxFeatures: 2}, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	validation := separable(100, 8)
+
+	compressed, report, err := CompressForest(forest, validation, CompressOptions{MaxAccuracyLoss: 0.02, MergeTolerance: 0.05, ThresholdBits: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Trees >= report.OriginalTrees || len(compressed.Trees) != report.Trees || report.Nodes >= report.OriginalNodes {
+		t.Errorf("no se comprimió: %+v", report)
+	}
+	if report.Accuracy < report.OriginalAccuracy-0.02 {
+		t.Errorf("precisión %.3f, la original era %.3f", report.Accuracy, report.OriginalAccuracy)
+	}
+	if len(forest.Trees) != 12 {
+		t.Error("CompressForest cambió el bosque original")
+	}
+
+	// Con MaxTrees se para en el tamaño pedido aunque el presupuesto permita más
+	compressed, report, err = CompressForest(forest, validation, CompressOptions{MaxTrees: 5, MaxAccuracyLoss: 1})
+	if err != nil || len(compressed.Trees) != 5 || report.Quantized {
+		t.Errorf("MaxTrees 5: %d árboles, %+v, error %v", len(compressed.Trees), report, err)
+	}
+
+	if _, _, err := CompressForest(forest, nil, CompressOptions{}); err == nil {
+		t.Error("sin ejemplos de validación no devolvió error")
+	}
+}
+
+func TestCompressForestMergesDuplicates(t *testing.T) {
+	examples := separable(100, 3)
+	opts := DefaultTrainOptions[string]()
+	opts.MaxDepth = 1
+	stump, _ := BuildDecisionTreeConcurrent(examples, opts)
+	constant := &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 1}}
+	forest := &Forest[string]{Classes: []string{"a", "b"}, Trees: []*DecisionTree[string]{stump, constant, stump.Clone()}}
+
+	// MaxTrees 2 impide podar después de fundir las dos copias
+	compressed, report, err := CompressForest(forest, examples, CompressOptions{MaxTrees: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Merged != 1 || len(compressed.Trees) != 2 || compressed.Weights[0] != 2 {
+		t.Errorf("%d fundidos, pesos %v; se esperaba fundir las dos copias con peso 2", report.Merged, compressed.Weights)
+	}
+	for _, example := range examples {
+		if got, want := compressed.PredictProba(example.Features), forest.PredictProba(example.Features); math.Abs(got["a"]-want["a"]) > 1e-9 {
+			t.Fatalf("fundir copias cambió las probabilidades: %v frente a %v", got, want)
+		}
+	}
+}
+
+func TestRoundMantissa(t *testing.T) {
+	for _, c := range []struct{ v, want float64 }{{0.3, 0.3125}, {-6, -6}, {1000.5, 1024}, {0, 0}} {
+		if got := roundMantissa(c.v, 4); got != c.want {
+			t.Errorf("roundMantissa(%v, 4) = %v, se esperaba %v", c.v, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FeatureImportance devuelve la reducción media de impureza (MDI) de cada feature,
+// normalizada para sumar 1: la impureza de Gini de cada nodo ponderada por su peso,
+// menos la de sus dos hijos. El peso por clase de los nodos internos se reconstruye
+// sumando el de sus hojas, así que sirve también para modelos cargados.
+func FeatureImportance[L comparable](tree *DecisionTree[L], numFeatures int) []float64 {
+	importance := make([]float64, numFeatures)
+	var visit func(node *DecisionTree[L]) map[L]float64
+	visit = func(node *DecisionTree[L]) map[L]float64 {
+		if node.IsLeaf() {
+			return node.classMass()
+		}
+		left, right := visit(node.Left), visit(node.Right)
+		mass := make(map[L]float64, len(left)+len(right))
+		for class, weight := range left {
+			mass[class] += weight
+		}
+		for class, weight := range right {
+			mass[class] += weight
+		}
+		if node.Column < numFeatures {
+			importance[node.Column] += weightedGini(mass) - weightedGini(left) - weightedGini(right)
+		}
+		return mass
+	}
+	visit(tree)
+
+	var total float64
+	for _, value := range importance {
+		total += value
+	}
+	if total > 0 {
+		for j := range importance {
+			importance[j] /= total
+		}
+	}
+	return importance
+}
+
+// weightedGini es la impureza de Gini de un reparto por clase multiplicada por su peso total
+func weightedGini[L comparable](mass map[L]float64) float64 {
+	var total, sumSq float64
+	for _, weight := range mass {
+		total += weight
+		sumSq += weight * weight
+	}
+	if total == 0 {
+		return 0
+	}
+	return total - sumSq/total
+}
+
+// importanceRanks devuelve la posición de cada feature (0 = la más importante); los
+// empates se ordenan por columna
+func importanceRanks(importance []float64) []int {
+	order := make([]int, len(importance))
+	for j := range order {
+		order[j] = j
+	}
+	sort.SliceStable(order, func(a, b int) bool { return importance[order[a]] > importance[order[b]] })
+	ranks := make([]int, len(importance))
+	for rank, j := range order {
+		ranks[j] = rank
+	}
+	return ranks
+}
+
+// averageRanks devuelve la posición de cada valor de mayor a menor, desde 1; los
+// empatados reciben la media de las posiciones que ocupan
+func averageRanks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for j := range order {
+		order[j] = j
+	}
+	sort.Slice(order, func(a, b int) bool { return values[order[a]] > values[order[b]] })
+	ranks := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+		for _, j := range order[start:end] {
+			ranks[j] = float64(start+end+1) / 2
+		}
+		start = end
+	}
+	return ranks
+}
+
+// spearman es la correlación de rangos de Spearman entre a y b, con rangos medios
+// para los empates. Si alguno no varía se toma 1 cuando los rangos coinciden y 0 si no.
+func spearman(a, b []float64) float64 {
+	ra, rb := averageRanks(a), averageRanks(b)
+	n := float64(len(ra))
+	var meanA, meanB float64
+	for j := range ra {
+		meanA += ra[j] / n
+		meanB += rb[j] / n
+	}
+	var cov, varA, varB float64
+	for j := range ra {
+		cov += (ra[j] - meanA) * (rb[j] - meanB)
+		varA += (ra[j] - meanA) * (ra[j] - meanA)
+		varB += (rb[j] - meanB) * (rb[j] - meanB)
+	}
+	if varA == 0 || varB == 0 {
+		if slices.Equal(ra, rb) {
+			return 1
+		}
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
+
+// ImportanceStability resume la variación de la importancia de cada feature entre
+// reentrenamientos sobre muestras bootstrap
+type ImportanceStability struct {
+	FeatureNames []string
+	Full         []float64 // importancia con todos los datos
+	Mean, Std    []float64
+	MeanRank     []float64 // posición media, desde 1
+	RankStd      []float64
+	TopK         int
+	TopKShare    []float64 // fracción de muestras en que la feature queda entre las TopK primeras
+	Spearman     float64   // correlación de rangos media entre cada muestra y los datos completos, con empates
+	Samples      int
+}
+
+func (s ImportanceStability) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Estabilidad de la importancia (%d muestras bootstrap, Spearman medio %.3f):\n", s.Samples, s.Spearman)
+	order := make([]int, len(s.Full))
+	for j := range order {
+		order[j] = j
+	}
+	sort.SliceStable(order, func(a, b int) bool { return s.MeanRank[order[a]] < s.MeanRank[order[b]] })
+	fmt.Fprintf(&sb, "  %-20s %8s %8s %8s %10s %7s\n", "feature", "total", "media", "desv.", "rango", fmt.Sprintf("top-%d", s.TopK))
+	for _, j := range order {
+		fmt.Fprintf(&sb, "  %-20s %8.4f %8.4f %8.4f %5.2f±%-4.2f %6.0f%%\n",
+			ColumnLabel(s.FeatureNames, j), s.Full[j], s.Mean[j], s.Std[j], s.MeanRank[j], s.RankStd[j], 100*s.TopKShare[j])
+	}
+	return sb.String()
+}
+
+// FeatureImportanceStability entrena un árbol con todos los datos y otro por cada
+// una de samples muestras bootstrap, y compara sus importancias y rangos
+func FeatureImportanceStability[L comparable](examples []Example[L], featureNames []string, opts TrainOptions[L], samples, topK int, rng *rand.Rand) ImportanceStability {
+	numFeatures := 0
+	if len(examples) > 0 {
+		numFeatures = len(examples[0].Features)
+	}
+	if topK > numFeatures {
+		topK = numFeatures
+	}
+	full, _ := BuildDecisionTreeConcurrent(examples, opts)
+	s := ImportanceStability{
+		FeatureNames: featureNames,
+		Full:         FeatureImportance(full, numFeatures),
+		Mean:         make([]float64, numFeatures),
+		Std:          make([]float64, numFeatures),
+		MeanRank:     make([]float64, numFeatures),
+		RankStd:      make([]float64, numFeatures),
+		TopK:         topK,
+		TopKShare:    make([]float64, numFeatures),
+		Samples:      samples,
+	}
+	if samples <= 0 || len(examples) == 0 {
+		return s
+	}
+
+	sumSq := make([]float64, numFeatures)
+	rankSumSq := make([]float64, numFeatures)
+	for k := 0; k < samples; k++ {
+		tree, _ := BuildDecisionTreeConcurrent(bootstrapSample(examples, rng), opts)
+		importance := FeatureImportance(tree, numFeatures)
+		ranks := importanceRanks(importance)
+
+		for j := range importance {
+			s.Mean[j] += importance[j]
+			sumSq[j] += importance[j] * importance[j]
+			rank := float64(ranks[j] + 1)
+			s.MeanRank[j] += rank
+			rankSumSq[j] += rank * rank
+			if ranks[j] < topK {
+				s.TopKShare[j]++
+			}
+		}
+		// Las features sin importancia empatan; con rangos ordinales, el desempate por
+		// índice haría que coincidieran siempre e inflaría la estabilidad
+		s.Spearman += spearman(importance, s.Full)
+	}
+
+	n := float64(samples)
+	for j := 0; j < numFeatures; j++ {
+		s.Mean[j] /= n
+		s.Std[j] = math.Sqrt(math.Max(sumSq[j]/n-s.Mean[j]*s.Mean[j], 0))
+		s.MeanRank[j] /= n
+		s.RankStd[j] = math.Sqrt(math.Max(rankSumSq[j]/n-s.MeanRank[j]*s.MeanRank[j], 0))
+		s.TopKShare[j] /= n
+	}
+	s.Spearman /= n
+	return s
+}
+
+// Interaction es la fuerza de la interacción entre dos features según el estadístico
+// H² de Friedman: la fracción de la varianza de la dependencia parcial conjunta que no
+// explica la suma de las dos dependencias parciales individuales (0 = aditivas)
+type Interaction struct {
+	A, B int
+	H2   float64
+}
+
+// partialDependence devuelve, para cada fila de grid, la probabilidad media de cada
+// clase sobre todas las filas de background cuando las columnas cols toman los
+// valores de esa fila de grid; los valores quedan centrados en media cero por clase
+func partialDependence[L comparable](tree *DecisionTree[L], classes []L, rows [][]float64, cols []int) [][]float64 {
+	pd := make([][]float64, len(rows))
+	features := make([]float64, len(rows[0]))
+	probs := make([]float64, len(classes))
+	for g, grid := range rows {
+		pd[g] = make([]float64, len(classes))
+		for _, background := range rows {
+			copy(features, background)
+			for _, col := range cols {
+				features[col] = grid[col]
+			}
+			probs = tree.PredictProbaInto(features, classes, probs)
+			for c, prob := range probs {
+				pd[g][c] += prob / float64(len(rows))
+			}
+		}
+	}
+	for c := range classes {
+		var sum float64
+		for g := range pd {
+			sum += pd[g][c]
+		}
+		for g := range pd {
+			pd[g][c] -= sum / float64(len(pd))
+		}
+	}
+	return pd
+}
+
+// PairwiseInteractions calcula H² para cada par de las features que usa el árbol,
+// evaluando las dependencias parciales sobre rows (que conviene limitar a unos cientos
+// de filas: el coste crece con su cuadrado). En multiclase se suman numerador y
+// denominador de todas las clases. Devuelve los pares de mayor a menor interacción.
+func PairwiseInteractions[L comparable](tree *DecisionTree[L], rows [][]float64) []Interaction {
+	if len(rows) == 0 {
+		return nil
+	}
+	classes := tree.Classes()
+	used := make(map[int]bool)
+	tree.Walk(func(node *DecisionTree[L], depth int) {
+		if !node.IsLeaf() && node.Column < len(rows[0]) {
+			used[node.Column] = true
+		}
+	})
+	var features []int
+	for col := range used {
+		features = append(features, col)
+	}
+	sort.Ints(features)
+
+	single := make(map[int][][]float64, len(features))
+	for _, col := range features {
+		single[col] = partialDependence(tree, classes, rows, []int{col})
+	}
+
+	var interactions []Interaction
+	for a := 0; a < len(features); a++ {
+		for b := a + 1; b < len(features); b++ {
+			j, k := features[a], features[b]
+			joint := partialDependence(tree, classes, rows, []int{j, k})
+			var num, den float64
+			for g := range rows {
+				for c := range classes {
+					diff := joint[g][c] - single[j][g][c] - single[k][g][c]
+					num += diff * diff
+					den += joint[g][c] * joint[g][c]
+				}
+			}
+			h2 := 0.0
+			if den > 0 {
+				h2 = num / den
+			}
+			interactions = append(interactions, Interaction{A: j, B: k, H2: h2})
+		}
+	}
+	sort.SliceStable(interactions, func(i, j int) bool { return interactions[i].H2 > interactions[j].H2 })
+	return interactions
+}
@@ -0,0 +1,81 @@
+package pcdta
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestAverageRanksSharesTies(t *testing.T) {
+	got := averageRanks([]float64{0, 5, 0, 2, 0})
+	if want := []float64{4, 1, 4, 2, 4}; !slices.Equal(got, want) {
+		t.Errorf("averageRanks = %v, se esperaba %v", got, want)
+	}
+}
+
+func TestSpearmanWithTies(t *testing.T) {
+	// Con rangos ordinales los dos ceros de la primera desempatarían por índice y la
+	// correlación saldría más alta
+	if got := spearman([]float64{2, 1, 0, 0}, []float64{2, 0, 0, 0}); math.Abs(got-0.816497) > 1e-6 {
+		t.Errorf("spearman = %v, se esperaba 0.8165", got)
+	}
+	if got := spearman([]float64{0, 0, 0}, []float64{0, 0, 0}); got != 1 {
+		t.Errorf("spearman sin variación = %v, se esperaba 1", got)
+	}
+	if got := spearman([]float64{1, 2, 3}, []float64{3, 2, 1}); math.Abs(got+1) > 1e-12 {
+		t.Errorf("spearman invertido = %v, se esperaba -1", got)
+	}
+}
+
+func TestPairwiseInteractions(t *testing.T) {
+	leaf := func(pb float64) *DecisionTree[string] {
+		class := "a"
+		if pb > 0.5 {
+			class = "b"
+		}
+		return &DecisionTree[string]{Class: class, Counts: map[string]int{"a": 1, "b": 1}, Probs: map[string]float64{"a": 1 - pb, "b": pb}}
+	}
+	split := func(column int, left, right *DecisionTree[string]) *DecisionTree[string] {
+		return &DecisionTree[string]{Column: column, Value: 0.5, Left: left, Right: right}
+	}
+	// Las cuatro combinaciones de x0 y x1; x2 es constante y el árbol no la usa
+	rows := [][]float64{{0, 0, 7}, {0, 1, 7}, {1, 0, 7}, {1, 1, 7}}
+	for _, tc := range []struct {
+		name string
+		tree *DecisionTree[string]
+		want []Interaction
+	}{
+		{
+			// P(b) = x0·x1. Centradas, la conjunta es (-1, -1, -1, 3)/4 y las
+			// individuales (-1, -1, 1, 1)/4 y (-1, 1, -1, 1)/4: el residuo es ±1/4,
+			// H² = (4/16) / (12/16) por clase
+			name: "producto",
+			tree: split(0, leaf(0), split(1, leaf(0), leaf(1))),
+			want: []Interaction{{A: 0, B: 1, H2: 1.0 / 3}},
+		},
+		{
+			// P(b) = x0/2 + x1/4 es aditiva
+			name: "suma",
+			tree: split(0, split(1, leaf(0), leaf(0.25)), split(1, leaf(0.5), leaf(0.75))),
+			want: []Interaction{{A: 0, B: 1, H2: 0}},
+		},
+		{
+			name: "una sola feature",
+			tree: split(1, leaf(0), leaf(1)),
+		},
+	} {
+		got := PairwiseInteractions(tc.tree, rows)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: %+v, se esperaba %+v", tc.name, got, tc.want)
+			continue
+		}
+		for i, want := range tc.want {
+			if got[i].A != want.A || got[i].B != want.B || math.Abs(got[i].H2-want.H2) > 1e-12 {
+				t.Errorf("%s: %+v, se esperaba %+v", tc.name, got[i], want)
+			}
+		}
+	}
+	if got := PairwiseInteractions(split(0, leaf(0), leaf(1)), nil); got != nil {
+		t.Errorf("sin filas: %+v", got)
+	}
+}
@@ -0,0 +1,426 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Preprocessor convierte filas de la entrada, con las columnas que se le indicaron al
+// crearlo, en el vector de features del árbol: copia las columnas numéricas, codifica
+// las categóricas con Encodings y calcula las features de Derived que la entrada no
+// trae. Las features que no son obligatorias (fuera de SelectedFeatures) y no se
+// pueden obtener quedan ausentes, ya que el árbol nunca las consulta.
+type Preprocessor struct {
+	columns   []string
+	size      int // features del modelo
+	numeric   []inputNumeric
+	encodings []inputEncoding
+	derived   []inputDerived
+	absent    []int
+}
+
+type inputNumeric struct{ feature, column int }
+
+type inputEncoding struct {
+	encoding *ColumnEncoding
+	column   int
+	features []int // feature del modelo de cada salida de la codificación, o -1
+}
+
+type inputDerived struct {
+	name    string
+	expr    *Expr
+	feature int // -1 si el modelo no la usa y solo la necesita otra derivada
+	refs    map[string]inputRef
+}
+
+// inputRef dice de dónde sale una columna que usa una feature derivada
+type inputRef struct {
+	kind  int
+	index int
+}
+
+const (
+	refColumn  = iota // columna de la entrada
+	refFeature        // feature del modelo ya calculada (p. ej. de una codificación)
+	refDerived        // derivada anterior que el modelo no usa
+)
+
+// InputColumns devuelve las columnas que lee el modelo, en el orden en que las esperan
+// Model.Preprocess y Model.Predict: las features numéricas, la columna de origen de
+// cada codificación y las columnas que usan las features derivadas
+func (m ModelMetadata) InputColumns() []string {
+	encodedBy := make(map[string]*ColumnEncoding)
+	for e := range m.Encodings {
+		for _, name := range m.Encodings[e].Features {
+			encodedBy[name] = &m.Encodings[e]
+		}
+	}
+	isDerived := make(map[string]bool, len(m.Derived))
+	for _, d := range m.Derived {
+		isDerived[d.Name] = true
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			columns = append(columns, name)
+		}
+	}
+	for _, name := range m.FeatureNames {
+		switch {
+		case encodedBy[name] != nil:
+			add(encodedBy[name].Column)
+		case !isDerived[name]:
+			add(name)
+		}
+	}
+	for _, d := range m.Derived {
+		e, err := ParseExpr(d.Expr)
+		if err != nil {
+			continue
+		}
+		for _, name := range e.Columns() {
+			if !isDerived[name] && encodedBy[name] == nil {
+				add(name)
+			}
+		}
+	}
+	return columns
+}
+
+// NewPreprocessor prepara la conversión de filas con las columnas indicadas; devuelve
+// un error con las columnas que faltan para calcular alguna feature obligatoria
+func (m ModelMetadata) NewPreprocessor(columns []string) (*Preprocessor, error) {
+	index := make(map[string]int, len(columns))
+	for col, name := range columns {
+		if _, ok := index[name]; !ok {
+			index[name] = col
+		}
+	}
+	feature := make(map[string]int, len(m.FeatureNames))
+	for i, name := range m.FeatureNames {
+		feature[name] = i
+	}
+	required := make(map[string]bool)
+	if len(m.SelectedFeatures) > 0 {
+		for _, name := range m.SelectedFeatures {
+			required[name] = true
+		}
+	} else {
+		for _, name := range m.FeatureNames {
+			required[name] = true
+		}
+	}
+
+	p := &Preprocessor{columns: columns, size: len(m.FeatureNames)}
+	filled := make([]bool, len(m.FeatureNames))
+	encodedBy := make(map[string]*ColumnEncoding)
+
+	// Las features codificadas salen de su columna de origen si la entrada la trae
+	for e := range m.Encodings {
+		encoding := &m.Encodings[e]
+		for _, name := range encoding.Features {
+			encodedBy[name] = encoding
+		}
+		col, ok := index[encoding.Column]
+		if !ok {
+			continue
+		}
+		in := inputEncoding{encoding: encoding, column: col, features: make([]int, len(encoding.Features))}
+		used := false
+		for k, name := range encoding.Features {
+			in.features[k] = -1
+			if i, ok := feature[name]; ok && !filled[i] {
+				in.features[k], filled[i], used = i, true, true
+			}
+		}
+		if used {
+			p.encodings = append(p.encodings, in)
+		}
+	}
+
+	for i, name := range m.FeatureNames {
+		if col, ok := index[name]; ok && !filled[i] {
+			p.numeric = append(p.numeric, inputNumeric{feature: i, column: col})
+			filled[i] = true
+		}
+	}
+
+	// Las derivadas se calculan en el orden en que se crearon, así que cada una puede
+	// usar las anteriores
+	derivedAt := make(map[string]int)
+	unresolved := make(map[string]string)
+	for _, d := range m.Derived {
+		i, isFeature := feature[d.Name]
+		if _, ok := index[d.Name]; ok || (isFeature && filled[i]) {
+			continue
+		}
+		e, err := ParseExpr(d.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("feature derivada %s: %w", d.Name, err)
+		}
+		in := inputDerived{name: d.Name, expr: e, feature: -1, refs: make(map[string]inputRef)}
+		for _, name := range e.Columns() {
+			if k, ok := derivedAt[name]; ok {
+				in.refs[name] = inputRef{refDerived, k}
+			} else if col, ok := index[name]; ok {
+				in.refs[name] = inputRef{refColumn, col}
+			} else if j, ok := feature[name]; ok && filled[j] {
+				in.refs[name] = inputRef{refFeature, j}
+			} else {
+				unresolved[d.Name] = name
+				break
+			}
+		}
+		if _, ok := unresolved[d.Name]; ok {
+			continue
+		}
+		if isFeature {
+			in.feature, filled[i] = i, true
+		}
+		derivedAt[d.Name] = len(p.derived)
+		p.derived = append(p.derived, in)
+	}
+
+	var missing []string
+	reported := make(map[string]bool)
+	for i, name := range m.FeatureNames {
+		if filled[i] {
+			continue
+		}
+		if !required[name] {
+			p.absent = append(p.absent, i)
+			continue
+		}
+		if encoding := encodedBy[name]; encoding != nil {
+			name = encoding.Column
+		} else if ref, ok := unresolved[name]; ok {
+			name = ref
+		}
+		if !reported[name] {
+			reported[name] = true
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("faltan columnas usadas por el modelo: %s", strings.Join(missing, ", "))
+	}
+	return p, nil
+}
+
+// inputSource da los valores de una fila por posición de columna
+type inputSource interface {
+	number(col int) (float64, error)
+	text(col int) (string, error)
+}
+
+// recordSource es una fila de texto, como las de un CSV
+type recordSource []string
+
+func (r recordSource) field(col int) (string, error) {
+	if col >= len(r) {
+		return "", fmt.Errorf("la fila tiene %d columnas, se esperaban al menos %d", len(r), col+1)
+	}
+	return r[col], nil
+}
+
+func (r recordSource) number(col int) (float64, error) {
+	field, err := r.field(col)
+	if err != nil {
+		return 0, err
+	}
+	return parseFeature(field)
+}
+
+func (r recordSource) text(col int) (string, error) {
+	field, err := r.field(col)
+	return strings.TrimSpace(field), err
+}
+
+// vectorSource es una fila ya numérica, como las de un .pcd
+type vectorSource []float64
+
+func (v vectorSource) number(col int) (float64, error) {
+	if col >= len(v) {
+		return 0, fmt.Errorf("la fila tiene %d columnas, se esperaban al menos %d", len(v), col+1)
+	}
+	return v[col], nil
+}
+
+func (v vectorSource) text(col int) (string, error) {
+	value, err := v.number(col)
+	if err != nil || math.IsNaN(value) {
+		return "", err
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64), nil
+}
+
+// Record convierte una fila de texto y escribe el resultado en features, que se
+// reutiliza si tiene capacidad suficiente
+func (p *Preprocessor) Record(record []string, features []float64) ([]float64, error) {
+	return p.apply(recordSource(record), features)
+}
+
+// Unseen devuelve un *InputError por cada columna categórica de la fila con una
+// categoría que no se vio al entrenar; Record la codifica con Default, como si faltara
+func (p *Preprocessor) Unseen(record []string) []*InputError {
+	var out []*InputError
+	for _, e := range p.encodings {
+		category, err := recordSource(record).text(e.column)
+		if err != nil {
+			continue
+		}
+		if _, known := e.encoding.Lookup(category); !known {
+			out = append(out, &InputError{Feature: -1, Column: e.encoding.Column, Category: category})
+		}
+	}
+	return out
+}
+
+// Values es Record para una fila numérica; las columnas categóricas se codifican con
+// el número escrito como texto
+func (p *Preprocessor) Values(values []float64, features []float64) ([]float64, error) {
+	return p.apply(vectorSource(values), features)
+}
+
+func (p *Preprocessor) apply(src inputSource, features []float64) ([]float64, error) {
+	if cap(features) < p.size {
+		features = make([]float64, p.size)
+	}
+	features = features[:p.size]
+
+	for _, n := range p.numeric {
+		value, err := src.number(n.column)
+		if err != nil {
+			return nil, fmt.Errorf("columna %s: %w", p.columns[n.column], err)
+		}
+		features[n.feature] = value
+	}
+	for _, e := range p.encodings {
+		category, err := src.text(e.column)
+		if err != nil {
+			return nil, fmt.Errorf("columna %s: %w", p.columns[e.column], err)
+		}
+		values := e.encoding.Encode(category)
+		for k, i := range e.features {
+			if i >= 0 {
+				features[i] = values[k]
+			}
+		}
+	}
+	for _, i := range p.absent {
+		features[i] = math.NaN()
+	}
+
+	if len(p.derived) == 0 {
+		return features, nil
+	}
+	computed := make([]float64, len(p.derived))
+	for k, d := range p.derived {
+		value, err := d.expr.root.eval(func(name string) (exprValue, error) {
+			ref := d.refs[name]
+			switch ref.kind {
+			case refColumn:
+				value, err := src.number(ref.index)
+				if err != nil {
+					err = fmt.Errorf("columna %s: %w", p.columns[ref.index], err)
+				}
+				return exprValue{Num: value}, err
+			case refFeature:
+				return exprValue{Num: features[ref.index]}, nil
+			default:
+				return exprValue{Num: computed[ref.index]}, nil
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("feature derivada %s: %w", d.name, err)
+		}
+		if value.IsString {
+			return nil, fmt.Errorf("feature derivada %s: el resultado es una cadena", d.name)
+		}
+		computed[k] = value.Num
+		if d.feature >= 0 {
+			features[d.feature] = value.Num
+		}
+	}
+	return features, nil
+}
+
+// LoadForModel lee un CSV o .pcd con la clase real en la última columna y lo convierte
+// al esquema del modelo con su Preprocessor: las features quedan en el orden de
+// Meta.FeatureNames, codificadas y con las derivadas calculadas, y las clases
+// renombradas al entrenar pasan a su nombre nuevo, para comparar con Tree.Predict. Las
+// columnas se buscan por nombre; un archivo sin los nombres del modelo se lee por
+// posición si tiene tantas features como el modelo y este no codifica ni deriva.
+func LoadForModel(filename string, model *Model[string]) (*Dataset[string], error) {
+	meta := model.Meta
+	input := func(names []string) (*Preprocessor, error) {
+		p, err := meta.NewPreprocessor(names)
+		if err != nil && len(names) == len(meta.FeatureNames) && len(meta.Encodings) == 0 && len(meta.Derived) == 0 {
+			return meta.NewPreprocessor(meta.FeatureNames)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		return p, nil
+	}
+	class := func(name string) string {
+		if to, ok := meta.ClassMapping[name]; ok {
+			return to
+		}
+		return name
+	}
+	out := &Dataset[string]{FeatureNames: meta.FeatureNames}
+
+	if strings.HasSuffix(filename, ".pcd") {
+		examples, names, err := LoadPCD(filename)
+		if err != nil {
+			return nil, err
+		}
+		p, err := input(names)
+		if err != nil {
+			return nil, err
+		}
+		out.Examples = make([]Example[string], len(examples))
+		for i, example := range examples {
+			features, err := p.Values(example.Features, nil)
+			if err != nil {
+				return nil, fmt.Errorf("%s: fila %d: %w", filename, i+1, err)
+			}
+			out.Examples[i] = Example[string]{Features: features, Class: class(example.Class)}
+		}
+		return out, nil
+	}
+
+	data, err := readCSVRecords(filename)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(data[0])-1)
+	for j := range names {
+		names[j] = fmt.Sprintf("feature_%d", j)
+	}
+	line := 1
+	if _, err := strconv.ParseFloat(strings.TrimSpace(data[0][0]), 64); err != nil {
+		copy(names, data[0])
+		data, line = data[1:], 2
+	}
+	p, err := input(names)
+	if err != nil {
+		return nil, err
+	}
+	out.Examples = make([]Example[string], len(data))
+	for i, d := range data {
+		features, err := p.Record(d[:len(d)-1], nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: fila %d: %w", filename, i+line, err)
+		}
+		out.Examples[i] = Example[string]{Features: features, Class: class(d[len(d)-1])}
+	}
+	return out, nil
+}
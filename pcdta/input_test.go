@@ -0,0 +1,199 @@
+package pcdta
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// preprocessedModel predice sobre x, la columna categórica region (one-hot) y la
+// derivada ratio = x / y, con las clases renombradas al entrenar
+func preprocessedModel() *Model[string] {
+	leaf := func(class string) *DecisionTree[string] {
+		return &DecisionTree[string]{Class: class, Counts: map[string]int{class: 1}}
+	}
+	return &Model[string]{
+		Meta: ModelMetadata{
+			FeatureNames: []string{"x", "region=EU", "region=US", "ratio"},
+			Encodings: []ColumnEncoding{{
+				Column:   "region",
+				Method:   "onehot",
+				Features: []string{"region=EU", "region=US"},
+				Values:   map[string][]float64{"EU": {1, 0}, "US": {0, 1}},
+				Default:  []float64{0, 0},
+			}},
+			Derived:      []DerivedFeature{{Name: "ratio", Expr: "x / y"}},
+			ClassMapping: map[string]string{"alpha": "a", "beta": "b", "gamma": "c"},
+		},
+		Tree: &DecisionTree[string]{
+			Column: 3, Value: 1,
+			Left:  &DecisionTree[string]{Column: 1, Value: 0.5, Left: leaf("b"), Right: leaf("a")},
+			Right: leaf("c"),
+		},
+	}
+}
+
+func TestModelInputColumns(t *testing.T) {
+	got := strings.Join(preprocessedModel().Meta.InputColumns(), ",")
+	if got != "x,region,y" {
+		t.Errorf("InputColumns = %s, se esperaba x,region,y", got)
+	}
+}
+
+func TestModelPredictAppliesEncodingsDerivedAndClassNames(t *testing.T) {
+	model := preprocessedModel()
+	cases := []struct {
+		record []string
+		want   string
+	}{
+		{[]string{"4", "EU", "8"}, "alpha"},
+		{[]string{"4", "US", "8"}, "beta"},
+		{[]string{"4", "??", "8"}, "beta"}, // categoría no vista: Default
+		{[]string{"9", "EU", "3"}, "gamma"},
+		{[]string{"4", "EU", "NA"}, "gamma"}, // ratio ausente va a la derecha
+	}
+	for _, c := range cases {
+		got, err := model.Predict(c.record)
+		if err != nil {
+			t.Fatalf("Predict(%v): %v", c.record, err)
+		}
+		if got != c.want {
+			t.Errorf("Predict(%v) = %s, se esperaba %s", c.record, got, c.want)
+		}
+	}
+
+	probs, err := model.PredictProba([]string{"4", "EU", "8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probs["alpha"] != 1 || len(probs) != 1 {
+		t.Errorf("PredictProba = %v, se esperaba alpha con probabilidad 1", probs)
+	}
+}
+
+func TestModelPreprocessRejectsWrongLength(t *testing.T) {
+	if _, err := preprocessedModel().Preprocess([]string{"4", "EU"}); err == nil {
+		t.Error("Preprocess con una columna de menos no devolvió error")
+	}
+	if _, err := preprocessedModel().Predict([]string{"x", "EU", "8"}); err == nil {
+		t.Error("Predict con un número no válido no devolvió error")
+	}
+}
+
+func TestPreprocessorReadsColumnsByName(t *testing.T) {
+	meta := preprocessedModel().Meta
+
+	// Una entrada que ya trae la derivada la usa en lugar de recalcularla
+	p, err := meta.NewPreprocessor([]string{"ratio", "extra", "region", "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	features, err := p.Record([]string{"0.5", "zzz", "US", "4"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{4, 0, 1, 0.5}; !slices.Equal(features, want) {
+		t.Errorf("features = %v, se esperaba %v", features, want)
+	}
+
+	_, err = meta.NewPreprocessor([]string{"x", "y"})
+	if err == nil || !strings.Contains(err.Error(), "region") {
+		t.Errorf("sin la columna categórica: error %v, se esperaba que nombrara region", err)
+	}
+}
+
+func TestLoadForModel(t *testing.T) {
+	path := writeTemp(t, "eval.csv", "y,region,x,class\n8,EU,4,alpha\n3,EU,9,gamma\n")
+	data, err := LoadForModel(path, preprocessedModel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Examples) != 2 {
+		t.Fatalf("%d filas, se esperaban 2", len(data.Examples))
+	}
+	if want := []float64{4, 1, 0, 0.5}; !slices.Equal(data.Examples[0].Features, want) {
+		t.Errorf("features = %v, se esperaba %v", data.Examples[0].Features, want)
+	}
+	// Las clases pasan al nombre con el que se entrenó, el que predice el árbol
+	if data.Examples[0].Class != "a" || data.Examples[1].Class != "c" {
+		t.Errorf("clases %s y %s, se esperaban a y c", data.Examples[0].Class, data.Examples[1].Class)
+	}
+}
+
+func TestSaveModelWritesRequiredFormat(t *testing.T) {
+	dir := t.TempDir()
+	plain := preprocessedModel()
+	plain.Meta = ModelMetadata{FeatureNames: []string{"x", "region=EU", "region=US", "ratio"}}
+	for _, tc := range []struct {
+		name  string
+		model *Model[string]
+		want  string
+	}{
+		{"plain", plain, `"format_version": 2`},
+		{"preprocessed", preprocessedModel(), `"format_version": 3`},
+	} {
+		path := filepath.Join(dir, tc.name+".json")
+		if err := SaveModel(tc.model, path); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), tc.want) {
+			t.Errorf("%s: falta %s en el fichero", tc.name, tc.want)
+		}
+		loaded, err := LoadModel[string](path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if loaded.FormatVersion != ModelFormatVersion || len(loaded.Meta.Encodings) != len(tc.model.Meta.Encodings) {
+			t.Errorf("%s: cargado con formato %d y %d codificaciones", tc.name, loaded.FormatVersion, len(loaded.Meta.Encodings))
+		}
+	}
+
+	// Un formato posterior al que conoce el lector se rechaza en lugar de ignorar sus campos
+	newer := filepath.Join(dir, "newer.json")
+	if err := os.WriteFile(newer, []byte(`{"format_version": 4, "metadata": {}, "tree": {"class": "a"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadModel[string](newer); err == nil {
+		t.Error("un modelo de formato 4 se cargó sin error")
+	}
+}
+
+func TestPredictWarningsReportUnseenCategoriesAndLength(t *testing.T) {
+	model := preprocessedModel()
+	class, warnings, err := model.PredictRecordWithWarnings([]string{"4", "ASIA", "8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if class != "beta" || len(warnings) != 1 || warnings[0].Column != "region" || warnings[0].Category != "ASIA" {
+		t.Errorf("clase %s, avisos %v; se esperaba beta con region = ASIA no vista", class, warnings)
+	}
+	// Un ausente también recibe Default, pero no es una categoría nueva
+	if _, warnings, _ := model.PredictRecordWithWarnings([]string{"4", "NA", "8"}); len(warnings) != 0 {
+		t.Errorf("avisos %v para una categoría ausente", warnings)
+	}
+
+	var inputErr *InputError
+	if _, _, err := model.PredictWithWarnings([]float64{4, 1}); !errors.As(err, &inputErr) || inputErr.Want != 4 {
+		t.Errorf("vector corto: error %v, se esperaba un *InputError de longitud", err)
+	}
+}
+
+func TestScoreStreamFlagsUnseenCategories(t *testing.T) {
+	var out strings.Builder
+	in := "x,region,y\n4,EU,8\n4,ASIA,8\n"
+	if err := ScoreStreamWith(context.Background(), preprocessedModel(), strings.NewReader(in), &out, ScoreOptions{FlagUnseen: true}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !strings.HasSuffix(lines[0], ",unseen_categories") || !strings.HasSuffix(lines[1], ",") || !strings.HasSuffix(lines[2], ",region=ASIA") {
+		t.Errorf("salida:\n%s", out.String())
+	}
+}
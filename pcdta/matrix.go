@@ -0,0 +1,96 @@
+package pcdta
+
+import (
+	"fmt"
+)
+
+// Matrix es la parte de mat.Matrix de gonum que hace falta para leer features: un
+// *mat.Dense (o cualquier mat.Matrix) la cumple sin que este paquete dependa de gonum
+type Matrix interface {
+	Dims() (r, c int)
+	At(i, j int) float64
+}
+
+// rowViewer lo cumple *mat.Dense; permite usar sus filas directamente en lugar de
+// leerlas celda a celda
+type rowViewer interface {
+	RawRowView(i int) []float64
+}
+
+// ExamplesFromMatrix convierte las filas de m en ejemplos con las clases labels. Si m
+// cumple rowViewer (un *mat.Dense) los ejemplos no copian nada: sus Features son las
+// filas de m, así que comparten su memoria y modificar una afecta a la otra. Con otras
+// matrices las filas se copian.
+func ExamplesFromMatrix[L comparable](m Matrix, labels []L) ([]Example[L], error) {
+	rows, cols := m.Dims()
+	if len(labels) != rows {
+		return nil, fmt.Errorf("la matriz tiene %d filas y hay %d clases", rows, len(labels))
+	}
+	examples := make([]Example[L], rows)
+	if view, ok := m.(rowViewer); ok {
+		for i := range examples {
+			// Sin capacidad extra, un append a las features no pisa la fila siguiente
+			row := view.RawRowView(i)
+			examples[i] = Example[L]{Features: row[:cols:cols], Class: labels[i]}
+		}
+		return examples, nil
+	}
+	data := make([]float64, rows*cols)
+	for i := range examples {
+		features := data[i*cols : (i+1)*cols : (i+1)*cols]
+		for j := range features {
+			features[j] = m.At(i, j)
+		}
+		examples[i] = Example[L]{Features: features, Class: labels[i]}
+	}
+	return examples, nil
+}
+
+// BuildDecisionTreeMatrix entrena con las filas de m como features y labels como clases
+func BuildDecisionTreeMatrix[L comparable](m Matrix, labels []L, opts TrainOptions[L]) (*DecisionTree[L], TrainReport, error) {
+	examples, err := ExamplesFromMatrix(m, labels)
+	if err != nil {
+		return nil, TrainReport{}, err
+	}
+	tree, report := BuildDecisionTreeConcurrent(examples, opts)
+	return tree, report, nil
+}
+
+// PredictMatrix predice la clase de cada fila de m. numFeatures es la longitud del
+// vector de features que espera el modelo (len(Meta.FeatureNames) en uno guardado); una
+// matriz con otro número de columnas es un error.
+func PredictMatrix[L comparable](model Classifier[L], numFeatures int, m Matrix) ([]L, error) {
+	rows, cols := m.Dims()
+	if cols != numFeatures {
+		return nil, &InputError{Feature: -1, Got: cols, Want: numFeatures}
+	}
+	predictions := make([]L, rows)
+	features := make([]float64, cols)
+	for i := range predictions {
+		for j := range features {
+			features[j] = m.At(i, j)
+		}
+		predictions[i] = model.Predict(features)
+	}
+	return predictions, nil
+}
+
+// MatrixData devuelve las features de examples por filas y las clases, en la forma que
+// espera mat.NewDense(rows, cols, data). Todos los ejemplos deben tener el mismo
+// número de features.
+func MatrixData[L comparable](examples []Example[L]) (rows, cols int, data []float64, labels []L, err error) {
+	if len(examples) == 0 {
+		return 0, 0, nil, nil, nil
+	}
+	rows, cols = len(examples), len(examples[0].Features)
+	data = make([]float64, 0, rows*cols)
+	labels = make([]L, rows)
+	for i, example := range examples {
+		if len(example.Features) != cols {
+			return 0, 0, nil, nil, fmt.Errorf("el ejemplo %d tiene %d features y el primero %d", i, len(example.Features), cols)
+		}
+		data = append(data, example.Features...)
+		labels[i] = example.Class
+	}
+	return rows, cols, data, labels, nil
+}
@@ -0,0 +1,47 @@
+package pcdta
+
+import "testing"
+
+// dense imita a *mat.Dense: filas contiguas en data
+type dense struct {
+	rows, cols int
+	data       []float64
+}
+
+func (d *dense) Dims() (int, int)           { return d.rows, d.cols }
+func (d *dense) At(i, j int) float64        { return d.data[i*d.cols+j] }
+func (d *dense) RawRowView(i int) []float64 { return d.data[i*d.cols : (i+1)*d.cols] }
+
+func TestExamplesFromMatrixSharesRows(t *testing.T) {
+	m := &dense{rows: 2, cols: 2, data: []float64{1, 2, 3, 4}}
+	examples, err := ExamplesFromMatrix(m, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.data[2] = 30
+	if examples[1].Features[0] != 30 {
+		t.Error("los ejemplos no comparten la memoria de la matriz")
+	}
+	// Un append no debe pisar la fila siguiente
+	_ = append(examples[0].Features, 99)
+	if m.data[2] != 30 {
+		t.Error("un append a las features de un ejemplo modificó la fila siguiente")
+	}
+}
+
+func TestPredictMatrixAndMatrixDataCheckShapes(t *testing.T) {
+	tree := &DecisionTree[string]{Column: 1, Value: 2.5, Left: &DecisionTree[string]{Class: "a"}, Right: &DecisionTree[string]{Class: "b"}}
+	m := &dense{rows: 2, cols: 2, data: []float64{1, 2, 3, 4}}
+	if _, err := PredictMatrix[string](tree, 3, m); err == nil {
+		t.Error("PredictMatrix con menos columnas de las que espera el modelo no devolvió error")
+	}
+	predictions, err := PredictMatrix[string](tree, 2, m)
+	if err != nil || predictions[0] != "a" || predictions[1] != "b" {
+		t.Errorf("PredictMatrix = %v, %v", predictions, err)
+	}
+
+	ragged := []Example[string]{{Features: []float64{1, 2}}, {Features: []float64{3}}}
+	if _, _, _, _, err := MatrixData(ragged); err == nil {
+		t.Error("MatrixData con ejemplos de distinta longitud no devolvió error")
+	}
+}
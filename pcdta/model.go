@@ -0,0 +1,550 @@
+package pcdta
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ModelMetadata struct {
+	PackageVersion  string         `json:"package_version"`
+	TrainedAt       time.Time      `json:"trained_at"`
+	Rows            int            `json:"rows"`
+	DatasetHash     string         `json:"dataset_hash"`
+	Hyperparameters map[string]any `json:"hyperparameters"`
+	FeatureNames    []string       `json:"feature_names"`
+	TreeHash        string         `json:"tree_hash"`
+
+	// Columnas a las que se restringió el entrenamiento; al puntuar solo estas son
+	// obligatorias en la entrada
+	SelectedFeatures []string `json:"selected_features,omitempty"`
+
+	// Rango de cada feature en los datos de entrenamiento, para validar las entradas
+	FeatureRanges []FeatureRange `json:"feature_ranges,omitempty"`
+
+	// Cortes con los que se discretizaron las features, si se entrenó con -bin; los
+	// umbrales del árbol ya están en la escala original
+	Binning *Binning `json:"binning,omitempty"`
+
+	// Columnas categóricas de la entrada y cómo se convierten en features del modelo
+	Encodings []ColumnEncoding `json:"encodings,omitempty"`
+
+	// Clase original de los datos -> clase con la que se entrenó, si se renombraron o
+	// fundieron clases
+	ClassMapping map[string]string `json:"class_mapping,omitempty"`
+
+	// Features derivadas con -derive, que se recalculan al puntuar si la entrada no las trae
+	Derived []DerivedFeature `json:"derived,omitempty"`
+
+	// Grupos de features dispersas con los que se entrenó con -bundle; FeatureNames son
+	// las columnas agrupadas y las filas nuevas se traducen con NewBundler
+	FeatureBundles []FeatureBundle `json:"feature_bundles,omitempty"`
+}
+
+// OriginalClasses invierte ClassMapping para las clases entrenadas que provienen de una
+// sola clase original distinta; las fundidas se predicen con su nombre nuevo
+func (m ModelMetadata) OriginalClasses() map[string]string {
+	sources := make(map[string][]string)
+	for from, to := range m.ClassMapping {
+		sources[to] = append(sources[to], from)
+	}
+	original := make(map[string]string)
+	for to, from := range sources {
+		if len(from) == 1 && from[0] != to {
+			original[to] = from[0]
+		}
+	}
+	return original
+}
+
+// FeatureRange es el mínimo y el máximo de los valores presentes de una feature;
+// Empty indica que la columna no tenía ningún valor presente
+type FeatureRange struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Empty bool    `json:"empty,omitempty"`
+}
+
+// FeatureRanges calcula el rango de cada columna ignorando los valores ausentes
+func FeatureRanges[L comparable](examples []Example[L]) []FeatureRange {
+	if len(examples) == 0 {
+		return nil
+	}
+	ranges := make([]FeatureRange, len(examples[0].Features))
+	for j := range ranges {
+		ranges[j].Empty = true
+		for _, example := range examples {
+			value := example.Features[j]
+			if math.IsNaN(value) {
+				continue
+			}
+			if ranges[j].Empty {
+				ranges[j] = FeatureRange{Min: value, Max: value}
+			}
+			ranges[j].Min = math.Min(ranges[j].Min, value)
+			ranges[j].Max = math.Max(ranges[j].Max, value)
+		}
+		if ranges[j].Empty {
+			ranges[j] = FeatureRange{Empty: true}
+		}
+	}
+	return ranges
+}
+
+type Model[L comparable] struct {
+	FormatVersion int              `json:"format_version"`
+	Meta          ModelMetadata    `json:"metadata"`
+	Tree          *DecisionTree[L] `json:"tree"`
+
+	cache modelCache[L]
+}
+
+// modelCache guarda estructuras derivadas del árbol que se calculan la primera vez que
+// se piden. sync.Once permite compartir entre goroutines un modelo recién cargado sin
+// carreras; si después se modifica el árbol, el caché ya no es válido.
+type modelCache[L comparable] struct {
+	once        sync.Once
+	classes     []L
+	leafIndex   map[*DecisionTree[L]]int
+	numFeatures int // longitud esperada del vector de features
+	input       *Preprocessor
+	inputErr    error
+	original    map[string]string
+	bundler     *Bundler
+}
+
+func (m *Model[L]) derived() *modelCache[L] {
+	m.cache.once.Do(func() {
+		m.cache.classes = m.Tree.Classes()
+		m.cache.leafIndex = make(map[*DecisionTree[L]]int)
+		m.cache.numFeatures = len(m.Meta.FeatureNames)
+		m.Tree.Walk(func(node *DecisionTree[L], depth int) {
+			if node.IsLeaf() {
+				m.cache.leafIndex[node] = len(m.cache.leafIndex)
+			} else if node.Column >= m.cache.numFeatures {
+				// Sin nombres de features (o con menos de los usados), la longitud la fija el árbol
+				m.cache.numFeatures = node.Column + 1
+			}
+		})
+		m.cache.input, m.cache.inputErr = m.Meta.NewPreprocessor(m.Meta.InputColumns())
+		m.cache.original = m.Meta.OriginalClasses()
+		if len(m.Meta.FeatureBundles) > 0 {
+			m.cache.bundler = NewBundler(m.Meta.FeatureBundles)
+		}
+	})
+	return &m.cache
+}
+
+// InputError describe una entrada que no cumple el esquema del modelo: una longitud
+// distinta de la esperada (Feature == -1), un valor fuera del rango de entrenamiento
+// de la feature Feature o, con Column, una categoría que no se vio al entrenar
+type InputError struct {
+	Feature  int
+	Name     string
+	Value    float64
+	Min, Max float64
+	Got      int // longitud recibida
+	Want     int // longitud esperada
+
+	Column   string // columna categórica de la entrada
+	Category string // categoría no vista, codificada con el Default de la columna
+}
+
+func (e *InputError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("%s = %q no se vio al entrenar; se codifica como ausente", e.Column, e.Category)
+	}
+	if e.Feature < 0 {
+		return fmt.Sprintf("el vector tiene %d features, el modelo espera %d", e.Got, e.Want)
+	}
+	name := e.Name
+	if name == "" {
+		name = fmt.Sprintf("feature %d", e.Feature)
+	}
+	return fmt.Sprintf("%s = %v fuera del rango de entrenamiento [%v, %v]", name, e.Value, e.Min, e.Max)
+}
+
+// CheckInput comprueba que el vector tenga la longitud que espera el modelo y, con
+// checkRanges, que cada valor presente esté dentro del rango visto al entrenar. Las
+// features sin rango registrado no se comprueban.
+func (m *Model[L]) CheckInput(features []float64, checkRanges bool) error {
+	if want := m.derived().numFeatures; len(features) != want {
+		return &InputError{Feature: -1, Got: len(features), Want: want}
+	}
+	if !checkRanges {
+		return nil
+	}
+	if out := m.OutOfRange(features); len(out) > 0 {
+		return out[0]
+	}
+	return nil
+}
+
+// OutOfRange devuelve un *InputError por cada valor presente fuera del rango de
+// entrenamiento de su feature. Las features sin rango registrado no se comprueban.
+func (m *Model[L]) OutOfRange(features []float64) []*InputError {
+	var out []*InputError
+	for j, value := range features {
+		if j >= len(m.Meta.FeatureRanges) || math.IsNaN(value) || m.Meta.FeatureRanges[j].Empty {
+			continue
+		}
+		r := m.Meta.FeatureRanges[j]
+		if value < r.Min || value > r.Max {
+			err := &InputError{Feature: j, Value: value, Min: r.Min, Max: r.Max}
+			if j < len(m.Meta.FeatureNames) {
+				err.Name = m.Meta.FeatureNames[j]
+			}
+			out = append(out, err)
+		}
+	}
+	return out
+}
+
+// PredictWithWarnings predice aunque la entrada salga del rango de entrenamiento y
+// devuelve además esos avisos, para vigilar la extrapolación sin rechazar filas. Un
+// vector de otra longitud sí es un error, el *InputError de CheckInput.
+func (m *Model[L]) PredictWithWarnings(features []float64) (L, []*InputError, error) {
+	if err := m.CheckInput(features, false); err != nil {
+		var zero L
+		return zero, nil, err
+	}
+	return m.Tree.Predict(features), m.OutOfRange(features), nil
+}
+
+// PredictRecordWithWarnings es Predict con los avisos de PredictWithWarnings y, además,
+// uno por cada columna categórica con una categoría no vista al entrenar
+func (m *Model[L]) PredictRecordWithWarnings(record []string) (L, []*InputError, error) {
+	var zero L
+	features, err := m.Preprocess(record)
+	if err != nil {
+		return zero, nil, err
+	}
+	class, warnings, err := m.PredictWithWarnings(features)
+	if err != nil {
+		return zero, nil, err
+	}
+	return m.OriginalClass(class), append(m.derived().input.Unseen(record), warnings...), nil
+}
+
+// PredictChecked es Predict precedido de CheckInput: en lugar de salirse del vector o
+// extrapolar sin aviso devuelve un *InputError
+func (m *Model[L]) PredictChecked(features []float64, checkRanges bool) (L, error) {
+	if err := m.CheckInput(features, checkRanges); err != nil {
+		var zero L
+		return zero, err
+	}
+	return m.Tree.Predict(features), nil
+}
+
+// Preprocess convierte una fila de la entrada, con las columnas de Meta.InputColumns en
+// ese orden, en el vector de features del árbol: codifica las columnas categóricas y
+// calcula las features derivadas. Los campos vacíos, NA, NaN o ? son ausentes.
+func (m *Model[L]) Preprocess(record []string) ([]float64, error) {
+	cache := m.derived()
+	if cache.inputErr != nil {
+		return nil, cache.inputErr
+	}
+	if want := len(cache.input.columns); len(record) != want {
+		return nil, fmt.Errorf("la fila tiene %d columnas, el modelo espera %d (%s)", len(record), want, strings.Join(cache.input.columns, ", "))
+	}
+	return cache.input.Record(record, nil)
+}
+
+// Predict es la predicción de extremo a extremo sobre una fila de la entrada:
+// Preprocess, PredictChecked y la clase con su nombre original si se renombró al
+// entrenar. Es el camino que deben seguir las fachadas y la CLI en lugar de
+// Tree.Predict, que espera el vector ya preprocesado.
+func (m *Model[L]) Predict(record []string) (L, error) {
+	features, err := m.Preprocess(record)
+	if err != nil {
+		var zero L
+		return zero, err
+	}
+	class, err := m.PredictChecked(features, false)
+	return m.OriginalClass(class), err
+}
+
+// PredictProba es Predict con la probabilidad de cada clase, con los nombres originales
+func (m *Model[L]) PredictProba(record []string) (map[L]float64, error) {
+	features, err := m.Preprocess(record)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.CheckInput(features, false); err != nil {
+		return nil, err
+	}
+	probs := make(map[L]float64)
+	for class, p := range m.Tree.PredictProba(features) {
+		probs[m.OriginalClass(class)] += p
+	}
+	return probs, nil
+}
+
+// PredictSparse predice una fila dispersa con las columnas de los datos originales en
+// orden creciente; si el modelo se entrenó con grupos de features, la traduce antes a
+// las columnas agrupadas
+func (m *Model[L]) PredictSparse(cols []int, values []float64) L {
+	if bundler := m.derived().bundler; bundler != nil {
+		cols, values = bundler.Row(cols, values)
+	}
+	return m.OriginalClass(m.Tree.PredictSparse(cols, values))
+}
+
+// OriginalClass devuelve el nombre de la clase en los datos originales si se renombró
+// con ClassMapping; las clases fundidas y los modelos sin clases de texto no cambian
+func (m *Model[L]) OriginalClass(class L) L {
+	if name, ok := any(class).(string); ok {
+		if original, ok := m.derived().original[name]; ok {
+			return any(original).(L)
+		}
+	}
+	return class
+}
+
+// Classes devuelve las clases del árbol ordenadas; el slice es compartido y no debe modificarse
+func (m *Model[L]) Classes() []L {
+	return m.derived().classes
+}
+
+// LeafIndex es DecisionTree.LeafIndex sin recorrer el árbol en cada llamada
+func (m *Model[L]) LeafIndex(features []float64) int {
+	return m.derived().leafIndex[m.Tree.Leaf(features)]
+}
+
+func NewModel[L comparable](tree *DecisionTree[L], examples []Example[L], featureNames []string, hyperparameters map[string]any) *Model[L] {
+	return &Model[L]{
+		FormatVersion: ModelFormatVersion,
+		Meta: ModelMetadata{
+			PackageVersion:  PackageVersion,
+			TrainedAt:       time.Now().UTC(),
+			Rows:            len(examples),
+			DatasetHash:     DatasetHash(examples),
+			Hyperparameters: hyperparameters,
+			FeatureNames:    featureNames,
+			TreeHash:        TreeHash(tree),
+			FeatureRanges:   FeatureRanges(examples),
+		},
+		Tree: tree,
+	}
+}
+
+func (m *Model[L]) Metadata() ModelMetadata {
+	return m.Meta
+}
+
+// DatasetHash calcula un SHA-256 sobre las features, pesos y clases de los ejemplos en orden
+func DatasetHash[L comparable](examples []Example[L]) string {
+	h := sha256.New()
+	var buf [8]byte
+	for _, example := range examples {
+		for _, value := range example.Features {
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(value))
+			h.Write(buf[:])
+		}
+		// El peso solo entra en el hash si se indicó, para no cambiar los hashes sin pesos
+		if example.Weight != 0 {
+			fmt.Fprintf(h, "|w%v", example.Weight)
+		}
+		fmt.Fprintf(h, "|%v\n", example.Class)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// TreeHash calcula un SHA-256 del contenido del árbol serializado
+func TreeHash[L comparable](tree *DecisionTree[L]) string {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// requiredFormat es el formato más antiguo que interpreta bien el modelo: 3 si tiene
+// metadatos que transforman la entrada o la salida, 2 si no
+func (m ModelMetadata) requiredFormat() int {
+	if m.Binning != nil || len(m.Encodings) > 0 || len(m.ClassMapping) > 0 || len(m.Derived) > 0 || len(m.FeatureBundles) > 0 {
+		return 3
+	}
+	return 2
+}
+
+// MarshalJSON escribe el modelo con el formato que necesita en lugar del actual, para
+// que los lectores anteriores carguen los modelos que saben interpretar y rechacen los
+// demás
+func (m *Model[L]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		FormatVersion int              `json:"format_version"`
+		Meta          ModelMetadata    `json:"metadata"`
+		Tree          *DecisionTree[L] `json:"tree"`
+	}{m.Meta.requiredFormat(), m.Meta, m.Tree})
+}
+
+func SaveModel[L comparable](model *Model[L], filename string) error {
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+func LoadModel[L comparable](filename string) (*Model[L], error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	model, err := ParseModel[L](data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return model, nil
+}
+
+// ParseModel es LoadModel sobre el contenido del archivo, para las fachadas que no
+// leen del disco
+func ParseModel[L comparable](data []byte) (*Model[L], error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	raw, err := upgradeModel(raw)
+	if err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var model Model[L]
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, err
+	}
+	if err := model.Tree.Validate(); err != nil {
+		return nil, err
+	}
+	if model.Meta.TreeHash != "" && model.Meta.TreeHash != TreeHash(model.Tree) {
+		return nil, fmt.Errorf("el hash del árbol no coincide con los metadatos")
+	}
+
+	return &model, nil
+}
+
+// modelUpgrades[v] convierte un modelo en formato v al formato v+1
+var modelUpgrades = []func(raw map[string]json.RawMessage) (map[string]json.RawMessage, error){
+	// 0 -> 1: el archivo es el árbol sin envoltorio; se envuelve con metadatos vacíos
+	func(raw map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+		tree, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]json.RawMessage{
+			"metadata": json.RawMessage("{}"),
+			"tree":     tree,
+		}, nil
+	},
+	// 1 -> 2: se añade el número de versión explícito
+	func(raw map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+		return raw, nil
+	},
+	// 2 -> 3: un modelo 2 no tiene metadatos de la versión 3, que se quedan vacíos
+	func(raw map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+		return raw, nil
+	},
+}
+
+func detectModelFormat(raw map[string]json.RawMessage) (int, error) {
+	if v, ok := raw["format_version"]; ok {
+		var version int
+		if err := json.Unmarshal(v, &version); err != nil {
+			return 0, fmt.Errorf("format_version inválido: %w", err)
+		}
+		return version, nil
+	}
+	if _, ok := raw["tree"]; ok {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// upgradeModel aplica en orden las conversiones necesarias hasta el formato actual
+func upgradeModel(raw map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	version, err := detectModelFormat(raw)
+	if err != nil {
+		return nil, err
+	}
+	if version < 0 || version > ModelFormatVersion {
+		return nil, fmt.Errorf("formato de modelo %d no soportado (máximo %d)", version, ModelFormatVersion)
+	}
+
+	for ; version < ModelFormatVersion; version++ {
+		raw, err = modelUpgrades[version](raw)
+		if err != nil {
+			return nil, fmt.Errorf("actualizando formato %d: %w", version, err)
+		}
+	}
+	raw["format_version"] = json.RawMessage(fmt.Sprint(ModelFormatVersion))
+
+	return raw, nil
+}
+
+type TreeDiff struct {
+	Path   string // camino desde la raíz, p. ej. "LR" (izquierda y luego derecha)
+	Kind   string // "feature", "threshold", "class", "added" o "removed"
+	Before string
+	After  string
+}
+
+func (d TreeDiff) String() string {
+	path := d.Path
+	if path == "" {
+		path = "(raíz)"
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", path, d.Kind, d.Before, d.After)
+}
+
+// CompareTrees recorre ambos árboles en paralelo y devuelve las diferencias estructurales
+func CompareTrees[L comparable](a, b *DecisionTree[L]) []TreeDiff {
+	var diffs []TreeDiff
+
+	describe := func(node *DecisionTree[L]) string {
+		if node.IsLeaf() {
+			return fmt.Sprintf("Class: %v", node.Class)
+		}
+		return fmt.Sprintf("Feature %d <= %.4f", node.Column, node.Value)
+	}
+
+	var compare func(a, b *DecisionTree[L], path string)
+	compare = func(a, b *DecisionTree[L], path string) {
+		switch {
+		case a == nil || b == nil:
+			return
+		case a.IsLeaf() && b.IsLeaf():
+			if a.Class != b.Class {
+				diffs = append(diffs, TreeDiff{path, "class", describe(a), describe(b)})
+			}
+		case a.IsLeaf():
+			diffs = append(diffs, TreeDiff{path, "added", describe(a), describe(b)})
+		case b.IsLeaf():
+			diffs = append(diffs, TreeDiff{path, "removed", describe(a), describe(b)})
+		default:
+			if a.Column != b.Column {
+				diffs = append(diffs, TreeDiff{path, "feature", describe(a), describe(b)})
+			} else if a.Value != b.Value {
+				diffs = append(diffs, TreeDiff{path, "threshold", describe(a), describe(b)})
+			}
+			compare(a.Left, b.Left, path+"L")
+			compare(a.Right, b.Right, path+"R")
+		}
+	}
+
+	compare(a, b, "")
+	return diffs
+}
@@ -0,0 +1,253 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// binaryOptions adapta las opciones de entrenamiento a un submodelo binario;
+// la matriz de costes se refiere a las clases originales y no se traslada
+func binaryOptions[L comparable](opts TrainOptions[L]) TrainOptions[bool] {
+	return TrainOptions[bool]{
+		MaxDepth:           opts.MaxDepth,
+		NumWorkers:         opts.NumWorkers,
+		Smoothing:          opts.Smoothing,
+		SmoothingMethod:    opts.SmoothingMethod,
+		ForbiddenFeatures:  opts.ForbiddenFeatures,
+		RootFeature:        opts.RootFeature,
+		Features:           opts.Features,
+		TieBreak:           opts.TieBreak,
+		TieTolerance:       opts.TieTolerance,
+		MinFeatureVariance: opts.MinFeatureVariance,
+		MemoryBudget:       opts.MemoryBudget,
+	}
+}
+
+// sortedClasses devuelve las clases de los ejemplos ordenadas por su representación textual
+func sortedClasses[L comparable](examples []Example[L]) []L {
+	var classes []L
+	for class := range ClassCounts(examples) {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return fmt.Sprint(classes[i]) < fmt.Sprint(classes[j])
+	})
+	return classes
+}
+
+// OneVsRest descompone un problema multiclase en un árbol binario por clase
+// que separa esa clase del resto
+type OneVsRest[L comparable] struct {
+	Classes []L
+	Models  []*DecisionTree[bool]
+}
+
+func TrainOneVsRest[L comparable](examples []Example[L], opts TrainOptions[L]) *OneVsRest[L] {
+	ovr := &OneVsRest[L]{Classes: sortedClasses(examples)}
+	for _, class := range ovr.Classes {
+		binary := make([]Example[bool], len(examples))
+		for i, example := range examples {
+			binary[i] = Example[bool]{Features: example.Features, Class: example.Class == class, Weight: example.Weight}
+		}
+		model, _ := BuildDecisionTreeConcurrent(binary, binaryOptions(opts))
+		ovr.Models = append(ovr.Models, model)
+	}
+	return ovr
+}
+
+// PredictProba normaliza la probabilidad positiva de cada submodelo
+func (m *OneVsRest[L]) PredictProba(features []float64) map[L]float64 {
+	probs := make(map[L]float64, len(m.Classes))
+	var total float64
+	for i, class := range m.Classes {
+		probs[class] = m.Models[i].PredictProba(features)[true]
+		total += probs[class]
+	}
+	for _, class := range m.Classes {
+		if total > 0 {
+			probs[class] /= total
+		} else {
+			probs[class] = 1 / float64(len(m.Classes))
+		}
+	}
+	return probs
+}
+
+func (m *OneVsRest[L]) Predict(features []float64) L {
+	return argmaxClass(m.Classes, m.PredictProba(features))
+}
+
+// Apply devuelve la hoja de cada submodelo a la que llega el ejemplo
+func (m *OneVsRest[L]) Apply(features []float64) []int {
+	return Apply(m.Models, features)
+}
+
+// OneVsOne entrena un árbol por cada par de clases con solo los ejemplos de ese par
+type OneVsOne[L comparable] struct {
+	Classes []L
+	Pairs   [][2]int // índices en Classes de cada par
+	Models  []*DecisionTree[L]
+}
+
+func TrainOneVsOne[L comparable](examples []Example[L], opts TrainOptions[L]) *OneVsOne[L] {
+	ovo := &OneVsOne[L]{Classes: sortedClasses(examples)}
+	for i := range ovo.Classes {
+		for j := i + 1; j < len(ovo.Classes); j++ {
+			var pair []Example[L]
+			for _, example := range examples {
+				if example.Class == ovo.Classes[i] || example.Class == ovo.Classes[j] {
+					pair = append(pair, example)
+				}
+			}
+			model, _ := BuildDecisionTreeConcurrent(pair, opts)
+			ovo.Pairs = append(ovo.Pairs, [2]int{i, j})
+			ovo.Models = append(ovo.Models, model)
+		}
+	}
+	return ovo
+}
+
+// PredictProba promedia las probabilidades por pares: cada submodelo reparte su voto
+// entre sus dos clases según su probabilidad relativa
+func (m *OneVsOne[L]) PredictProba(features []float64) map[L]float64 {
+	probs := make(map[L]float64, len(m.Classes))
+	for _, class := range m.Classes {
+		probs[class] = 0
+	}
+	if len(m.Models) == 0 {
+		for _, class := range m.Classes {
+			probs[class] = 1
+		}
+		return probs
+	}
+
+	for k, model := range m.Models {
+		a, b := m.Classes[m.Pairs[k][0]], m.Classes[m.Pairs[k][1]]
+		pair := model.PredictProba(features)
+		if total := pair[a] + pair[b]; total > 0 {
+			probs[a] += pair[a] / total
+			probs[b] += pair[b] / total
+		} else {
+			probs[a] += 0.5
+			probs[b] += 0.5
+		}
+	}
+	for _, class := range m.Classes {
+		probs[class] /= float64(len(m.Models))
+	}
+	return probs
+}
+
+func (m *OneVsOne[L]) Predict(features []float64) L {
+	return argmaxClass(m.Classes, m.PredictProba(features))
+}
+
+// Apply devuelve la hoja de cada submodelo a la que llega el ejemplo
+func (m *OneVsOne[L]) Apply(features []float64) []int {
+	return Apply(m.Models, features)
+}
+
+// argmaxClass devuelve la clase de mayor probabilidad; los empates se quedan con la primera
+func argmaxClass[L comparable](classes []L, probs map[L]float64) L {
+	var best L
+	bestProb := math.Inf(-1)
+	for _, class := range classes {
+		if probs[class] > bestProb {
+			bestProb = probs[class]
+			best = class
+		}
+	}
+	return best
+}
+
+// Ordinal clasifica clases con orden natural (p. ej. valoraciones) según la
+// descomposición acumulada de Frank y Hall: un árbol binario por umbral k estima
+// P(y > Order[k]) y las probabilidades de cada clase salen de las diferencias
+type Ordinal[L comparable] struct {
+	Order  []L
+	Models []*DecisionTree[bool] // Models[k] estima P(y > Order[k])
+}
+
+func TrainOrdinal[L comparable](examples []Example[L], order []L, opts TrainOptions[L]) (*Ordinal[L], error) {
+	rank := make(map[L]int, len(order))
+	for i, class := range order {
+		rank[class] = i
+	}
+	for _, example := range examples {
+		if _, ok := rank[example.Class]; !ok {
+			return nil, fmt.Errorf("la clase %v no está en el orden indicado", example.Class)
+		}
+	}
+
+	m := &Ordinal[L]{Order: order}
+	for k := 0; k < len(order)-1; k++ {
+		binary := make([]Example[bool], len(examples))
+		for i, example := range examples {
+			binary[i] = Example[bool]{Features: example.Features, Class: rank[example.Class] > k, Weight: example.Weight}
+		}
+		model, _ := BuildDecisionTreeConcurrent(binary, binaryOptions(opts))
+		m.Models = append(m.Models, model)
+	}
+	return m, nil
+}
+
+// PredictProba convierte las probabilidades acumuladas en probabilidades por clase;
+// las acumuladas se fuerzan a ser no crecientes para que ninguna diferencia sea negativa
+func (m *Ordinal[L]) PredictProba(features []float64) map[L]float64 {
+	greater := make([]float64, len(m.Models))
+	for k, model := range m.Models {
+		greater[k] = model.PredictProba(features)[true]
+		if k > 0 && greater[k] > greater[k-1] {
+			greater[k] = greater[k-1]
+		}
+	}
+
+	probs := make(map[L]float64, len(m.Order))
+	above := 1.0
+	for k, class := range m.Order {
+		next := 0.0
+		if k < len(greater) {
+			next = greater[k]
+		}
+		probs[class] = above - next
+		above = next
+	}
+	return probs
+}
+
+// Predict devuelve la mediana de la distribución predicha, que respeta el orden
+// de las clases mejor que la moda
+func (m *Ordinal[L]) Predict(features []float64) L {
+	probs := m.PredictProba(features)
+	var cumulative float64
+	for _, class := range m.Order {
+		cumulative += probs[class]
+		if cumulative >= 0.5 {
+			return class
+		}
+	}
+	return m.Order[len(m.Order)-1]
+}
+
+// Apply devuelve la hoja de cada árbol de umbral a la que llega el ejemplo
+func (m *Ordinal[L]) Apply(features []float64) []int {
+	return Apply(m.Models, features)
+}
+
+// OrdinalMAE es el error absoluto medio en posiciones del orden entre la clase
+// predicha y la real
+func OrdinalMAE[L comparable](model Classifier[L], examples []Example[L], order []L) float64 {
+	if len(examples) == 0 {
+		return 0
+	}
+	rank := make(map[L]int, len(order))
+	for i, class := range order {
+		rank[class] = i
+	}
+	var total float64
+	for _, example := range examples {
+		total += math.Abs(float64(rank[model.Predict(example.Features)] - rank[example.Class]))
+	}
+	return total / float64(len(examples))
+}
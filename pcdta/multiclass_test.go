@@ -0,0 +1,176 @@
+package pcdta
+
+import (
+	"slices"
+	"testing"
+)
+
+// binaryLeaf es un árbol de una hoja que da probabilidad p a la clase positiva
+func binaryLeaf(p float64) *DecisionTree[bool] {
+	return &DecisionTree[bool]{Class: p > 0.5, Probs: map[bool]float64{true: p, false: 1 - p}}
+}
+
+// leafWith es un árbol de una hoja con las probabilidades dadas
+func leafWith(class string, probs map[string]float64) *DecisionTree[string] {
+	return &DecisionTree[string]{Class: class, Probs: probs}
+}
+
+func TestOneVsRestNormalizesPositiveProbabilities(t *testing.T) {
+	classes := []string{"a", "b", "c"}
+	for _, tc := range []struct {
+		name     string
+		positive []float64
+		want     []float64
+		class    string
+	}{
+		{"ya suman 1", []float64{0.6, 0.3, 0.1}, []float64{0.6, 0.3, 0.1}, "a"},
+		// Suman 1.6: se dividen entre ese total
+		{"se normalizan", []float64{0.4, 0.8, 0.4}, []float64{0.25, 0.5, 0.25}, "b"},
+		// Ningún submodelo reclama el ejemplo: uniforme, y el empate es de la primera
+		{"todas cero", []float64{0, 0, 0}, []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}, "a"},
+	} {
+		ovr := &OneVsRest[string]{Classes: classes}
+		for _, p := range tc.positive {
+			ovr.Models = append(ovr.Models, binaryLeaf(p))
+		}
+		probs := ovr.PredictProba(nil)
+		for i, class := range classes {
+			if !near(probs[class], tc.want[i]) {
+				t.Errorf("%s: P(%s) = %v, se esperaba %v", tc.name, class, probs[class], tc.want[i])
+			}
+		}
+		if got := ovr.Predict(nil); got != tc.class {
+			t.Errorf("%s: Predict = %s, se esperaba %s", tc.name, got, tc.class)
+		}
+	}
+}
+
+func TestOneVsOneAveragesPairVotes(t *testing.T) {
+	classes := []string{"a", "b", "c"}
+	pairs := [][2]int{{0, 1}, {0, 2}, {1, 2}}
+	for _, tc := range []struct {
+		name   string
+		models []*DecisionTree[string]
+		want   []float64
+		class  string
+	}{
+		{
+			// a-b reparte 0.6 : 0.2 como 3/4 : 1/4 aunque le quede masa para c; b-c no
+			// da probabilidad a ninguna y vota mitad y mitad. a = (3/4 + 1/2) / 3
+			name: "votos relativos",
+			models: []*DecisionTree[string]{
+				leafWith("a", map[string]float64{"a": 0.6, "b": 0.2, "c": 0.2}),
+				leafWith("a", map[string]float64{"a": 0.5, "c": 0.5}),
+				leafWith("b", map[string]float64{}),
+			},
+			want:  []float64{5.0 / 12, 1.0 / 4, 1.0 / 3},
+			class: "a",
+		},
+		{
+			name: "votos seguros",
+			models: []*DecisionTree[string]{
+				leafWith("b", map[string]float64{"b": 1}),
+				leafWith("c", map[string]float64{"c": 1}),
+				leafWith("c", map[string]float64{"c": 1}),
+			},
+			want:  []float64{0, 1.0 / 3, 2.0 / 3},
+			class: "c",
+		},
+	} {
+		ovo := &OneVsOne[string]{Classes: classes, Pairs: pairs, Models: tc.models}
+		probs := ovo.PredictProba(nil)
+		for i, class := range classes {
+			if !near(probs[class], tc.want[i]) {
+				t.Errorf("%s: P(%s) = %v, se esperaba %v", tc.name, class, probs[class], tc.want[i])
+			}
+		}
+		if got := ovo.Predict(nil); got != tc.class {
+			t.Errorf("%s: Predict = %s, se esperaba %s", tc.name, got, tc.class)
+		}
+	}
+}
+
+func TestTrainMulticlassDecompositions(t *testing.T) {
+	// Cada clase ocupa su tramo de x: los submodelos separan sin error
+	var examples []Example[string]
+	for i, class := range []string{"c", "a", "b"} {
+		for _, offset := range []float64{0, 1} {
+			examples = append(examples, Example[string]{Features: []float64{float64(10*i) + offset}, Class: class})
+		}
+	}
+	opts := DefaultTrainOptions[string]()
+
+	ovr := TrainOneVsRest(examples, opts)
+	if !slices.Equal(ovr.Classes, []string{"a", "b", "c"}) || len(ovr.Models) != 3 {
+		t.Fatalf("OvR con clases %v y %d modelos", ovr.Classes, len(ovr.Models))
+	}
+	ovo := TrainOneVsOne(examples, opts)
+	if !slices.Equal(ovo.Pairs, [][2]int{{0, 1}, {0, 2}, {1, 2}}) {
+		t.Fatalf("OvO con pares %v", ovo.Pairs)
+	}
+	for _, model := range []Classifier[string]{ovr, ovo} {
+		if acc := Accuracy(model, examples); acc != 1 {
+			t.Errorf("%T: precisión %v sobre datos separables", model, acc)
+		}
+	}
+	// El submodelo b-c de OvO solo vio ejemplos de b y c
+	if classes := ovo.Models[2].Classes(); !slices.Equal(classes, []string{"b", "c"}) {
+		t.Errorf("el par b-c conoce las clases %v", classes)
+	}
+}
+
+func TestOrdinalCumulativeProbabilities(t *testing.T) {
+	order := []string{"baja", "media", "alta"}
+	for _, tc := range []struct {
+		name    string
+		greater []float64 // P(y > baja), P(y > media)
+		want    []float64
+		median  string
+	}{
+		{"monótonas", []float64{0.7, 0.2}, []float64{0.3, 0.5, 0.2}, "media"},
+		// P(y > media) = 0.6 supera a P(y > baja) = 0.4 y se rebaja a 0.4
+		{"cruzadas", []float64{0.4, 0.6}, []float64{0.6, 0, 0.4}, "baja"},
+		// La moda es alta (0.48) pero la mediana es media: 0.42 + 0.1 pasa de 1/2
+		{"mediana frente a moda", []float64{0.58, 0.48}, []float64{0.42, 0.1, 0.48}, "media"},
+		{"acumulada justo 1/2", []float64{0.5, 0.5}, []float64{0.5, 0, 0.5}, "baja"},
+	} {
+		m := &Ordinal[string]{Order: order}
+		for _, p := range tc.greater {
+			m.Models = append(m.Models, binaryLeaf(p))
+		}
+		probs := m.PredictProba(nil)
+		for i, class := range order {
+			if !near(probs[class], tc.want[i]) {
+				t.Errorf("%s: P(%s) = %v, se esperaba %v", tc.name, class, probs[class], tc.want[i])
+			}
+		}
+		if got := m.Predict(nil); got != tc.median {
+			t.Errorf("%s: Predict = %s, se esperaba %s", tc.name, got, tc.median)
+		}
+	}
+}
+
+func TestTrainOrdinalAndMAE(t *testing.T) {
+	order := []string{"baja", "media", "alta"}
+	examples := []Example[string]{
+		{Features: []float64{0}, Class: "baja"},
+		{Features: []float64{10}, Class: "media"},
+		{Features: []float64{20}, Class: "alta"},
+		{Features: []float64{21}, Class: "alta"},
+	}
+	if _, err := TrainOrdinal(append(examples, Example[string]{Features: []float64{5}, Class: "nula"}), order, DefaultTrainOptions[string]()); err == nil {
+		t.Error("se esperaba un error con una clase fuera del orden")
+	}
+
+	m, err := TrainOrdinal(examples, order, DefaultTrainOptions[string]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Models) != len(order)-1 || OrdinalMAE[string](m, examples, order) != 0 {
+		t.Errorf("%d umbrales, MAE %v sobre datos separables", len(m.Models), OrdinalMAE[string](m, examples, order))
+	}
+	// Predecir siempre media falla por una posición en baja y en las dos altas
+	if got := OrdinalMAE[string](hardOnly("media"), examples, order); got != 0.75 {
+		t.Errorf("MAE = %v, se esperaba 3/4", got)
+	}
+}
@@ -0,0 +1,199 @@
+package pcdta
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"unsafe"
+)
+
+// Formato binario .pcd (little-endian):
+//
+//	"PCD1" | filas uint64 | columnas uint32 | clases uint32
+//	nombres de columnas y de clases (longitud uint16 + bytes)
+//	relleno hasta múltiplo de 8 | features float64 fila a fila | índice de clase uint32 por fila
+const pcdMagic = "PCD1"
+
+type pcdHeader struct {
+	Rows       uint64
+	Cols       uint32
+	NumClasses uint32
+}
+
+func WritePCD(filename string, examples []Example[string], featureNames []string) error {
+	classIndex := make(map[string]uint32)
+	var classes []string
+	for _, example := range examples {
+		if _, ok := classIndex[example.Class]; !ok {
+			classIndex[example.Class] = uint32(len(classes))
+			classes = append(classes, example.Class)
+		}
+		if len(example.Features) != len(featureNames) {
+			return fmt.Errorf("fila con %d features, se esperaban %d", len(example.Features), len(featureNames))
+		}
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+
+	written := 0
+	write := func(v any) {
+		binary.Write(w, binary.LittleEndian, v)
+		written += binary.Size(v)
+	}
+	writeString := func(value string) {
+		write(uint16(len(value)))
+		w.WriteString(value)
+		written += len(value)
+	}
+
+	w.WriteString(pcdMagic)
+	written += len(pcdMagic)
+	write(pcdHeader{Rows: uint64(len(examples)), Cols: uint32(len(featureNames)), NumClasses: uint32(len(classes))})
+	for _, name := range featureNames {
+		writeString(name)
+	}
+	for _, class := range classes {
+		writeString(class)
+	}
+	for written%8 != 0 {
+		write(uint8(0))
+	}
+
+	for _, example := range examples {
+		write(example.Features)
+	}
+	for _, example := range examples {
+		write(classIndex[example.Class])
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// LoadPCD carga un archivo .pcd. Donde hay mmap el archivo se proyecta en memoria y,
+// en máquinas little-endian, las Features de los ejemplos apuntan directamente a la
+// proyección: no se copia ni se analiza nada, y varios procesos que cargan el mismo
+// archivo comparten sus páginas. La proyección dura lo que el proceso.
+func LoadPCD(filename string) ([]Example[string], []string, error) {
+	data, mapped, err := mapFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	examples, featureNames, aliased, err := parsePCD(filename, data, mapped && littleEndian())
+	if mapped && !aliased {
+		unmapFile(data)
+	}
+	return examples, featureNames, err
+}
+
+// littleEndian indica si la máquina guarda los float64 en el orden del archivo
+func littleEndian() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}
+
+// parsePCD interpreta el contenido de un .pcd. Con alias, las features apuntan a data
+// en lugar de copiarse; aliased indica si alguna lo hace, y entonces data no puede
+// liberarse.
+func parsePCD(filename string, data []byte, alias bool) (examples []Example[string], featureNames []string, aliased bool, err error) {
+	corrupt := fmt.Errorf("%s: archivo .pcd truncado o corrupto", filename)
+
+	if len(data) < len(pcdMagic)+16 || string(data[:len(pcdMagic)]) != pcdMagic {
+		return nil, nil, false, fmt.Errorf("%s: no es un archivo .pcd", filename)
+	}
+	off := len(pcdMagic)
+	var header pcdHeader
+	header.Rows = binary.LittleEndian.Uint64(data[off:])
+	header.Cols = binary.LittleEndian.Uint32(data[off+8:])
+	header.NumClasses = binary.LittleEndian.Uint32(data[off+12:])
+	off += 16
+
+	// Antes de reservar nada, la cabecera tiene que caber en el archivo: cada nombre
+	// ocupa al menos sus 2 bytes de longitud y cada fila cols*8+4 bytes
+	rest := uint64(len(data) - off)
+	if (uint64(header.Cols)+uint64(header.NumClasses))*2 > rest {
+		return nil, nil, false, corrupt
+	}
+	rowBytes := uint64(header.Cols)*8 + 4
+	if header.Rows > rest/rowBytes {
+		return nil, nil, false, corrupt
+	}
+
+	readString := func() (string, bool) {
+		if off+2 > len(data) {
+			return "", false
+		}
+		n := int(binary.LittleEndian.Uint16(data[off:]))
+		off += 2
+		if off+n > len(data) {
+			return "", false
+		}
+		value := string(data[off : off+n])
+		off += n
+		return value, true
+	}
+
+	featureNames = make([]string, header.Cols)
+	for j := range featureNames {
+		name, ok := readString()
+		if !ok {
+			return nil, nil, false, corrupt
+		}
+		featureNames[j] = name
+	}
+	classes := make([]string, header.NumClasses)
+	for k := range classes {
+		class, ok := readString()
+		if !ok {
+			return nil, nil, false, corrupt
+		}
+		classes[k] = class
+	}
+	off = (off + 7) &^ 7
+
+	rows, cols := int(header.Rows), int(header.Cols)
+	if off > len(data) || uint64(len(data)-off) != header.Rows*rowBytes {
+		return nil, nil, false, corrupt
+	}
+
+	// Las clases se comprueban antes de entregar ningún alias a la proyección
+	labels := off + rows*cols*8
+	for i := 0; i < rows; i++ {
+		if binary.LittleEndian.Uint32(data[labels+i*4:]) >= header.NumClasses {
+			return nil, nil, false, corrupt
+		}
+	}
+
+	// Todas las features en un único bloque; cada ejemplo apunta a su fila. El bloque
+	// empieza en un múltiplo de 8 del archivo, y la proyección en un inicio de página,
+	// así que los float64 quedan alineados.
+	var values []float64
+	if alias && rows*cols > 0 {
+		values = unsafe.Slice((*float64)(unsafe.Pointer(&data[off])), rows*cols)
+		aliased = true
+	} else {
+		values = make([]float64, rows*cols)
+		for i := range values {
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[off+i*8:]))
+		}
+	}
+
+	examples = make([]Example[string], rows)
+	for i := range examples {
+		examples[i] = Example[string]{
+			Features: values[i*cols : (i+1)*cols : (i+1)*cols],
+			Class:    classes[binary.LittleEndian.Uint32(data[labels+i*4:])],
+		}
+	}
+
+	return examples, featureNames, aliased, nil
+}
@@ -0,0 +1,37 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package pcdta
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile proyecta el archivo en memoria. La proyección es privada y con escritura:
+// las páginas se comparten con los demás procesos que leen el mismo archivo hasta que
+// alguien modifica un valor, y esa página pasa a ser una copia suya.
+func mapFile(filename string) (data []byte, mapped bool, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	if info.Size() == 0 {
+		return nil, false, nil
+	}
+	data, err = syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE)
+	if err != nil {
+		// Sistemas de archivos que no admiten mmap: se lee entero
+		data, err = os.ReadFile(filename)
+		return data, false, err
+	}
+	return data, true, nil
+}
+
+func unmapFile(data []byte) {
+	syscall.Munmap(data)
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package pcdta
+
+import "os"
+
+// mapFile lee el archivo entero donde no hay mmap (js/wasm, Windows)
+func mapFile(filename string) (data []byte, mapped bool, err error) {
+	data, err = os.ReadFile(filename)
+	return data, false, err
+}
+
+func unmapFile(data []byte) {}
@@ -0,0 +1,65 @@
+package pcdta
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPCDRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "datos.pcd")
+	examples := []Example[string]{
+		{Features: []float64{1, 2.5}, Class: "a"},
+		{Features: []float64{-3, 4}, Class: "b"},
+		{Features: []float64{5, 6}, Class: "a"},
+	}
+	if err := WritePCD(path, examples, []string{"x", "col y"}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, names, err := LoadPCD(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"x", "col y"}) || len(loaded) != len(examples) {
+		t.Fatalf("%d filas con columnas %v", len(loaded), names)
+	}
+	for i := range examples {
+		if !slices.Equal(loaded[i].Features, examples[i].Features) || loaded[i].Class != examples[i].Class {
+			t.Errorf("fila %d = %v, se esperaba %v", i, loaded[i], examples[i])
+		}
+	}
+	// La proyección es privada: modificar un ejemplo no cambia el archivo
+	loaded[0].Features[0] = 100
+	again, _, err := LoadPCD(path)
+	if err != nil || again[0].Features[0] != 1 {
+		t.Errorf("tras modificar un ejemplo el archivo da %v, %v", again[0].Features, err)
+	}
+}
+
+func TestLoadPCDRejectsHeaderLargerThanFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "datos.pcd")
+	if err := WritePCD(path, []Example[string]{{Features: []float64{1}, Class: "a"}}, []string{"x"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, corrupt := range []func(h []byte){
+		func(h []byte) { binary.LittleEndian.PutUint32(h[12:], 1<<31) },      // columnas
+		func(h []byte) { binary.LittleEndian.PutUint64(h[4:], 1<<62) },       // filas
+		func(h []byte) { binary.LittleEndian.PutUint32(h[16:], 0xffffffff) }, // clases
+		func(h []byte) { binary.LittleEndian.PutUint64(h[4:], 1<<61+1) },     // filas*(cols*8+4) desborda
+	} {
+		c := slices.Clone(data)
+		corrupt(c)
+		if err := os.WriteFile(path, c, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := LoadPCD(path); err == nil {
+			t.Error("una cabecera que no cabe en el archivo no devolvió error")
+		}
+	}
+}
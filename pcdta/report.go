@@ -0,0 +1,64 @@
+package pcdta
+
+import (
+	"html/template"
+	"io"
+)
+
+var reportTemplate = template.Must(template.New("informe").Funcs(template.FuncMap{
+	"percent": func(share float64) float64 { return 100 * share },
+	"mib":     func(bytes uint64) float64 { return float64(bytes) / (1 << 20) },
+}).Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+td, th { padding: 0.25em 0.75em; text-align: left; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.bar { background: #4a7ab5; height: 1em; }
+.track { width: 24em; background: #eee; }
+.warning { background: #fff4d6; border-left: 4px solid #e0a800; padding: 0.5em 1em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with .Report}}
+<h2>Entrenamiento</h2>
+<table>
+<tr><th>Tiempo total</th><td class="num">{{.Total}}</td></tr>
+<tr><th>Carga</th><td class="num">{{.Load}}</td></tr>
+<tr><th>Ordenación</th><td class="num">{{.Sort}}</td></tr>
+<tr><th>Búsqueda de divisiones</th><td class="num">{{.SplitSearch}}</td></tr>
+<tr><th>Partición</th><td class="num">{{.Partition}}</td></tr>
+<tr><th>Nodos (hojas)</th><td class="num">{{.Nodes}} ({{.Leaves}})</td></tr>
+<tr><th>Filas procesadas</th><td class="num">{{.RowsProcessed}} ({{printf "%.0f" .RowsPerSecond}} filas/s)</td></tr>
+{{if .PeakHeapBytes}}<tr><th>Memoria máxima en heap</th><td class="num">{{printf "%.1f" (mib .PeakHeapBytes)}} MiB</td></tr>{{end}}
+{{if .SkippedFeatures}}<tr><th>Features constantes omitidas</th><td>{{.SkippedFeatures}}</td></tr>{{end}}
+{{if .SampledRows}}<tr><th>Presupuesto de memoria</th><td>entrenado con una muestra de {{.SampledRows}} filas</td></tr>{{end}}
+{{if .BudgetLeaves}}<tr><th>Presupuesto de memoria</th><td>{{.BudgetLeaves}} nodos cerrados como hoja</td></tr>{{end}}
+</table>
+{{if .Classes}}
+<h2>Reparto de clases</h2>
+{{if .ImbalanceWarning}}<p class="warning">Desequilibrio de clases {{printf "%.1f" .Imbalance}}:1, por encima del umbral {{.ImbalanceThreshold}}. Para compensarlo, entrene con <code>-class-weight balanced</code>, <code>-resample over|under|smote</code> o <code>-costs</code>.</p>
+{{else}}<p>Desequilibrio {{printf "%.1f" .Imbalance}}:1</p>{{end}}
+<table>
+<tr><th>Clase</th><th>Ejemplos</th><th></th><th>Peso</th></tr>
+{{range .Classes}}<tr><td>{{.Class}}</td><td class="num">{{.Count}}</td><td class="track"><div class="bar" style="width: {{printf "%.1f" (percent .Share)}}%"></div></td><td class="num">{{printf "%.1f" (percent .Share)}}%</td></tr>
+{{end}}</table>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML escribe el informe como una página HTML autónoma, con el reparto de
+// clases en un gráfico de barras y el aviso de desequilibrio si lo hay
+func (r TrainReport) WriteHTML(w io.Writer, title string) error {
+	return reportTemplate.Execute(w, struct {
+		Title  string
+		Report TrainReport
+	}{title, r})
+}
@@ -0,0 +1,203 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// RobustnessReport mide con qué frecuencia cambia la predicción al perturbar cada
+// feature dentro de ±Epsilons[j]; una fila cuenta como volteada si algún punto del
+// intervalo cambia la clase predicha
+type RobustnessReport struct {
+	FeatureNames []string
+	Epsilons     []float64
+	Rows         int
+	FeatureFlips []float64            // tasa de volteo por feature
+	ClassFlips   map[string][]float64 // tasa de volteo por clase real y feature
+	AnyFlip      float64              // filas que se voltean con alguna feature
+}
+
+func (r RobustnessReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Robustez sobre %d filas (volteadas por alguna feature: %.3f)\n", r.Rows, r.AnyFlip)
+	classes := make([]string, 0, len(r.ClassFlips))
+	for class := range r.ClassFlips {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for j, name := range r.FeatureNames {
+		fmt.Fprintf(&sb, "  %s ±%g: %.3f", name, r.Epsilons[j], r.FeatureFlips[j])
+		for _, class := range classes {
+			fmt.Fprintf(&sb, ", %s %.3f", class, r.ClassFlips[class][j])
+		}
+		fmt.Fprintln(&sb)
+	}
+	return sb.String()
+}
+
+// Robustness perturba cada feature por separado en steps puntos a cada lado del
+// valor original, hasta ±epsilons[j]; los valores ausentes no se perturban
+func Robustness[L comparable](model Classifier[L], examples []Example[L], featureNames []string, epsilons []float64, steps int) RobustnessReport {
+	numFeatures := len(epsilons)
+	report := RobustnessReport{
+		FeatureNames: featureNames,
+		Epsilons:     epsilons,
+		Rows:         len(examples),
+		FeatureFlips: make([]float64, numFeatures),
+		ClassFlips:   make(map[string][]float64),
+	}
+	if len(examples) == 0 {
+		return report
+	}
+
+	classRows := make(map[string]int)
+	anyFlips := 0
+	features := make([]float64, numFeatures)
+	for _, example := range examples {
+		class := fmt.Sprint(example.Class)
+		classRows[class]++
+		if report.ClassFlips[class] == nil {
+			report.ClassFlips[class] = make([]float64, numFeatures)
+		}
+
+		original := model.Predict(example.Features)
+		copy(features, example.Features)
+		flipped := false
+		for j := 0; j < numFeatures; j++ {
+			value := example.Features[j]
+			if math.IsNaN(value) || epsilons[j] <= 0 {
+				continue
+			}
+			for k := 1; k <= steps; k++ {
+				delta := epsilons[j] * float64(k) / float64(steps)
+				features[j] = value - delta
+				changed := model.Predict(features) != original
+				if !changed {
+					features[j] = value + delta
+					changed = model.Predict(features) != original
+				}
+				if changed {
+					report.FeatureFlips[j]++
+					report.ClassFlips[class][j]++
+					flipped = true
+					break
+				}
+			}
+			features[j] = value
+		}
+		if flipped {
+			anyFlips++
+		}
+	}
+
+	for j := range report.FeatureFlips {
+		report.FeatureFlips[j] /= float64(len(examples))
+	}
+	for class, flips := range report.ClassFlips {
+		for j := range flips {
+			flips[j] /= float64(classRows[class])
+		}
+	}
+	report.AnyFlip = float64(anyFlips) / float64(len(examples))
+	return report
+}
+
+// WithFeatureNoise devuelve una copia con ruido gaussiano en cada feature, de
+// desviación scale veces la desviación típica de la columna
+func (d *Dataset[L]) WithFeatureNoise(scale float64, rng *rand.Rand) *Dataset[L] {
+	out := &Dataset[L]{FeatureNames: d.FeatureNames, Examples: make([]Example[L], len(d.Examples))}
+	if len(d.Examples) == 0 {
+		return out
+	}
+
+	numFeatures := len(d.Examples[0].Features)
+	stddev := make([]float64, numFeatures)
+	for j := range stddev {
+		var sum, sumSq float64
+		n := 0
+		for _, example := range d.Examples {
+			if value := example.Features[j]; !math.IsNaN(value) {
+				sum += value
+				sumSq += value * value
+				n++
+			}
+		}
+		if n > 0 {
+			mean := sum / float64(n)
+			stddev[j] = math.Sqrt(math.Max(sumSq/float64(n)-mean*mean, 0))
+		}
+	}
+
+	for i, example := range d.Examples {
+		features := make([]float64, numFeatures)
+		for j, value := range example.Features {
+			features[j] = value + rng.NormFloat64()*scale*stddev[j]
+		}
+		out.Examples[i] = Example[L]{Features: features, Class: example.Class, Weight: example.Weight}
+	}
+	return out
+}
+
+// WithLabelNoise devuelve una copia en la que cada etiqueta se cambia, con
+// probabilidad rate, por otra clase elegida al azar
+func (d *Dataset[L]) WithLabelNoise(rate float64, rng *rand.Rand) *Dataset[L] {
+	classes, _ := d.byClass()
+	out := &Dataset[L]{FeatureNames: d.FeatureNames, Examples: append([]Example[L]{}, d.Examples...)}
+	if len(classes) < 2 {
+		return out
+	}
+	for i, example := range out.Examples {
+		if rng.Float64() >= rate {
+			continue
+		}
+		other := classes[rng.Intn(len(classes)-1)]
+		if other == example.Class {
+			other = classes[len(classes)-1]
+		}
+		out.Examples[i].Class = other
+	}
+	return out
+}
+
+// Split baraja los ejemplos y separa una fracción testFraction para evaluación
+func (d *Dataset[L]) Split(testFraction float64, rng *rand.Rand) (train, test *Dataset[L]) {
+	shuffled := append([]Example[L]{}, d.Examples...)
+	rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	n := int(float64(len(shuffled)) * testFraction)
+	test = &Dataset[L]{FeatureNames: d.FeatureNames, Examples: shuffled[:n]}
+	train = &Dataset[L]{FeatureNames: d.FeatureNames, Examples: shuffled[n:]}
+	return train, test
+}
+
+// NoisePoint es la precisión sobre datos limpios de un árbol entrenado con ruido
+type NoisePoint struct {
+	Depth        int
+	FeatureNoise float64
+	LabelNoise   float64
+	Accuracy     float64
+}
+
+// NoiseCurves entrena un árbol por cada profundidad y nivel de ruido sobre train
+// con ruido de features y de etiquetas y lo evalúa sobre test sin ruido
+func NoiseCurves[L comparable](train, test *Dataset[L], opts TrainOptions[L], depths []int, featureNoise, labelNoise []float64, rng *rand.Rand) []NoisePoint {
+	var points []NoisePoint
+	for _, depth := range depths {
+		opts.MaxDepth = depth
+		for _, fNoise := range featureNoise {
+			for _, lNoise := range labelNoise {
+				noisy := train.WithFeatureNoise(fNoise, rng).WithLabelNoise(lNoise, rng)
+				tree, _ := BuildDecisionTreeConcurrent(noisy.Examples, opts)
+				points = append(points, NoisePoint{
+					Depth:        depth,
+					FeatureNoise: fNoise,
+					LabelNoise:   lNoise,
+					Accuracy:     Accuracy(tree, test.Examples),
+				})
+			}
+		}
+	}
+	return points
+}
@@ -0,0 +1,143 @@
+package pcdta
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestRobustness(t *testing.T) {
+	// x0 <= 5 es a y el resto b; x1 no se usa
+	stump := &DecisionTree[string]{
+		Column: 0, Value: 5,
+		Left:  &DecisionTree[string]{Class: "a"},
+		Right: &DecisionTree[string]{Class: "b"},
+	}
+	examples := []Example[string]{
+		{Features: []float64{4.5, 0}, Class: "a"},
+		{Features: []float64{3, 0}, Class: "a"},
+		{Features: []float64{5.8, 0}, Class: "b"},
+		{Features: []float64{math.NaN(), 0}, Class: "b"}, // ausente: no se perturba
+		{Features: []float64{7, 0}, Class: "a"},          // mal clasificada
+	}
+	for _, tc := range []struct {
+		name     string
+		epsilons []float64
+		steps    int
+		features []float64
+		classes  map[string][]float64
+		any      float64
+	}{
+		{
+			// ±0.5 no basta (4.5+0.5 = 5 sigue a la izquierda); ±1 voltea 4.5 y 5.8
+			name: "dos pasos", epsilons: []float64{1, 1}, steps: 2,
+			features: []float64{2.0 / 5, 0},
+			classes:  map[string][]float64{"a": {1.0 / 3, 0}, "b": {1.0 / 2, 0}},
+			any:      2.0 / 5,
+		},
+		{
+			name: "umbral exacto", epsilons: []float64{0.5, 1}, steps: 1,
+			features: []float64{0, 0},
+			classes:  map[string][]float64{"a": {0, 0}, "b": {0, 0}},
+		},
+		{
+			// ±2 voltea además la fila 7 -> 5, pero no 3 -> 5
+			name: "radio hasta el umbral", epsilons: []float64{2, 0}, steps: 4,
+			features: []float64{3.0 / 5, 0},
+			classes:  map[string][]float64{"a": {2.0 / 3, 0}, "b": {1.0 / 2, 0}},
+			any:      3.0 / 5,
+		},
+	} {
+		report := Robustness[string](stump, examples, []string{"x0", "x1"}, tc.epsilons, tc.steps)
+		if report.Rows != len(examples) || !near(report.AnyFlip, tc.any) {
+			t.Errorf("%s: %d filas, volteo %v; se esperaba %v", tc.name, report.Rows, report.AnyFlip, tc.any)
+		}
+		for j, want := range tc.features {
+			if !near(report.FeatureFlips[j], want) {
+				t.Errorf("%s: feature %d voltea %v, se esperaba %v", tc.name, j, report.FeatureFlips[j], want)
+			}
+		}
+		for class, want := range tc.classes {
+			for j := range want {
+				if !near(report.ClassFlips[class][j], want[j]) {
+					t.Errorf("%s: clase %s, feature %d voltea %v, se esperaba %v", tc.name, class, j, report.ClassFlips[class][j], want[j])
+				}
+			}
+		}
+	}
+}
+
+func TestWithFeatureNoiseScalesByColumnDeviation(t *testing.T) {
+	// Desviaciones típicas: 1 en x0 ({1, 3}), 0 en la constante x1 y en x2, que solo
+	// tiene un valor presente
+	d := &Dataset[string]{FeatureNames: []string{"x0", "x1", "x2"}, Examples: []Example[string]{
+		{Features: []float64{1, 5, 2}, Class: "a", Weight: 2},
+		{Features: []float64{3, 5, math.NaN()}, Class: "b"},
+	}}
+	noisy := d.WithFeatureNoise(0.5, rand.New(rand.NewSource(4)))
+
+	// El ruido sale de una normal por feature, en orden de filas y columnas
+	twin := rand.New(rand.NewSource(4))
+	for i, example := range d.Examples {
+		got := noisy.Examples[i]
+		for j, value := range example.Features {
+			want := value + twin.NormFloat64()*0.5*[]float64{1, 0, 0}[j]
+			if !(got.Features[j] == want || math.IsNaN(want) && math.IsNaN(got.Features[j])) {
+				t.Errorf("fila %d, feature %d: %v, se esperaba %v", i, j, got.Features[j], want)
+			}
+		}
+		if got.Class != example.Class || got.Weight != example.Weight {
+			t.Errorf("fila %d: clase %s peso %v, se esperaba %s %v", i, got.Class, got.Weight, example.Class, example.Weight)
+		}
+	}
+	if d.Examples[0].Features[0] != 1 {
+		t.Error("WithFeatureNoise cambió el conjunto original")
+	}
+}
+
+func TestWithLabelNoise(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		classes []string
+		rate    float64
+		want    []string // nil: cualquier clase distinta de la original
+	}{
+		{"sin ruido", []string{"a", "b", "a"}, 0, []string{"a", "b", "a"}},
+		{"dos clases, todas cambian", []string{"a", "b", "a"}, 1, []string{"b", "a", "b"}},
+		{"una sola clase", []string{"a", "a"}, 1, []string{"a", "a"}},
+		{"tres clases, todas cambian", []string{"a", "b", "c", "a", "b", "c"}, 1, nil},
+	} {
+		d := &Dataset[string]{}
+		for _, class := range tc.classes {
+			d.Examples = append(d.Examples, Example[string]{Features: []float64{0}, Class: class})
+		}
+		noisy := d.WithLabelNoise(tc.rate, rand.New(rand.NewSource(1)))
+		for i, example := range noisy.Examples {
+			if tc.want != nil && example.Class != tc.want[i] || tc.want == nil && example.Class == tc.classes[i] {
+				t.Errorf("%s: fila %d con clase %s (original %s)", tc.name, i, example.Class, tc.classes[i])
+			}
+		}
+	}
+}
+
+func TestNoiseCurves(t *testing.T) {
+	// La columna 1 separa las clases con un solo corte: sin ruido se acierta todo y con
+	// todas las etiquetas cambiadas el árbol aprende justo lo contrario
+	train := &Dataset[string]{Examples: separable(60, 1)}
+	test := &Dataset[string]{Examples: separable(40, 2)}
+	points := NoiseCurves(train, test, DefaultTrainOptions[string](), []int{1, 2}, []float64{0}, []float64{0, 1}, rand.New(rand.NewSource(1)))
+	want := []NoisePoint{
+		{Depth: 1, LabelNoise: 0, Accuracy: 1},
+		{Depth: 1, LabelNoise: 1, Accuracy: 0},
+		{Depth: 2, LabelNoise: 0, Accuracy: 1},
+		{Depth: 2, LabelNoise: 1, Accuracy: 0},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("%d puntos, se esperaban %d", len(points), len(want))
+	}
+	for i := range want {
+		if points[i] != want[i] {
+			t.Errorf("punto %d = %+v, se esperaba %+v", i, points[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Condition es una comparación de una feature con un umbral: x[Feature] <= Threshold,
+// o x[Feature] > Threshold si Greater
+type Condition struct {
+	Feature   int     `json:"feature"`
+	Greater   bool    `json:"greater"`
+	Threshold float64 `json:"threshold"`
+}
+
+func (c Condition) Holds(features []float64) bool {
+	if c.Greater {
+		// Los valores ausentes siguen la rama derecha, como en el árbol
+		return features[c.Feature] > c.Threshold || math.IsNaN(features[c.Feature])
+	}
+	return features[c.Feature] <= c.Threshold
+}
+
+// Rule predice Class cuando se cumplen todas sus condiciones. Support es el peso de
+// entrenamiento que la cumple y Confidence la fracción de ese peso de la clase Class.
+type Rule[L comparable] struct {
+	Conditions []Condition `json:"conditions"`
+	Class      L           `json:"class"`
+	Support    float64     `json:"support"`
+	Confidence float64     `json:"confidence"`
+}
+
+func (r Rule[L]) Matches(features []float64) bool {
+	for _, c := range r.Conditions {
+		if !c.Holds(features) {
+			return false
+		}
+	}
+	return true
+}
+
+// Format escribe la regla usando los nombres de las features si se conocen
+func (r Rule[L]) Format(featureNames []string) string {
+	parts := make([]string, len(r.Conditions))
+	for i, c := range r.Conditions {
+		op := "<="
+		if c.Greater {
+			op = ">"
+		}
+		parts[i] = fmt.Sprintf("%s %s %.4g", ColumnLabel(featureNames, c.Feature), op, c.Threshold)
+	}
+	condition := "siempre"
+	if len(parts) > 0 {
+		condition = strings.Join(parts, " Y ")
+	}
+	return fmt.Sprintf("SI %s ENTONCES %v (soporte %.0f, confianza %.3f)", condition, r.Class, r.Support, r.Confidence)
+}
+
+func ColumnLabel(featureNames []string, j int) string {
+	if j < len(featureNames) {
+		return featureNames[j]
+	}
+	return fmt.Sprintf("feature %d", j)
+}
+
+// ExtractRules convierte cada camino de la raíz a una hoja en una regla, con el
+// soporte y la confianza que guarda la hoja
+func ExtractRules[L comparable](tree *DecisionTree[L]) []Rule[L] {
+	var rules []Rule[L]
+	var visit func(node *DecisionTree[L], path []Condition)
+	visit = func(node *DecisionTree[L], path []Condition) {
+		if node.IsLeaf() {
+			mass := node.classMass()
+			var total float64
+			for _, m := range mass {
+				total += m
+			}
+			rule := Rule[L]{Conditions: append([]Condition{}, path...), Class: node.Class, Support: total}
+			if total > 0 {
+				rule.Confidence = mass[node.Class] / total
+			}
+			rules = append(rules, rule)
+			return
+		}
+		visit(node.Left, append(path, Condition{Feature: node.Column, Threshold: node.Value}))
+		visit(node.Right, append(path, Condition{Feature: node.Column, Greater: true, Threshold: node.Value}))
+	}
+	visit(tree, nil)
+	return rules
+}
+
+// mergeConditions deja una sola condición por feature y sentido: la cota superior
+// más baja y la inferior más alta; las demás son redundantes
+func mergeConditions(conditions []Condition) []Condition {
+	type key struct {
+		feature int
+		greater bool
+	}
+	best := make(map[key]int)
+	var merged []Condition
+	for _, c := range conditions {
+		k := key{c.Feature, c.Greater}
+		i, ok := best[k]
+		if !ok {
+			best[k] = len(merged)
+			merged = append(merged, c)
+			continue
+		}
+		if (c.Greater && c.Threshold > merged[i].Threshold) || (!c.Greater && c.Threshold < merged[i].Threshold) {
+			merged[i].Threshold = c.Threshold
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Feature != merged[j].Feature {
+			return merged[i].Feature < merged[j].Feature
+		}
+		return merged[i].Greater && !merged[j].Greater
+	})
+	return merged
+}
+
+// ruleStats calcula el soporte y la confianza de una regla sobre los ejemplos
+func ruleStats[L comparable](conditions []Condition, class L, examples []Example[L]) (support, confidence float64) {
+	rule := Rule[L]{Conditions: conditions}
+	var hits float64
+	for _, example := range examples {
+		if rule.Matches(example.Features) {
+			support += example.SampleWeight()
+			if example.Class == class {
+				hits += example.SampleWeight()
+			}
+		}
+	}
+	if support > 0 {
+		confidence = hits / support
+	}
+	return support, confidence
+}
+
+// RuleList es una lista de decisión: gana la primera regla que se cumple y, si
+// ninguna se cumple, se predice Default
+type RuleList[L comparable] struct {
+	Rules   []Rule[L]
+	Default L
+}
+
+func (r *RuleList[L]) Predict(features []float64) L {
+	for _, rule := range r.Rules {
+		if rule.Matches(features) {
+			return rule.Class
+		}
+	}
+	return r.Default
+}
+
+// PredictProba da toda la probabilidad a la clase de la regla que se cumple: las
+// reglas no guardan conteos por clase con los que repartirla
+func (r *RuleList[L]) PredictProba(features []float64) map[L]float64 {
+	for _, rule := range r.Rules {
+		if rule.Matches(features) {
+			return map[L]float64{rule.Class: 1}
+		}
+	}
+	return map[L]float64{r.Default: 1}
+}
+
+// SimplifyRules compacta las reglas extraídas de un árbol:
+//   - fusiona los umbrales de una misma feature y sentido en la cota más estricta;
+//   - si hay ejemplos, elimina cada condición cuya ausencia no baja la confianza de la
+//     regla más de tolerance (generalización al estilo de C4.5rules);
+//   - funde las reglas que quedan idénticas y descarta las que no cubren ningún ejemplo;
+//   - ordena por confianza y después por soporte, de modo que los solapamientos que
+//     introduce la generalización se resuelven a favor de la regla más fiable.
+//
+// La clase por defecto es la de mayor peso total de las reglas.
+func SimplifyRules[L comparable](rules []Rule[L], examples []Example[L], tolerance float64) *RuleList[L] {
+	var simplified []Rule[L]
+	for _, rule := range rules {
+		rule.Conditions = mergeConditions(rule.Conditions)
+
+		if len(examples) > 0 {
+			// Una regla que no cubre nada se descarta antes de generalizarla: sin
+			// condiciones pasaría a cubrirlo todo
+			support, confidence := ruleStats(rule.Conditions, rule.Class, examples)
+			if support == 0 {
+				continue
+			}
+			for i := 0; i < len(rule.Conditions); {
+				without := append(append([]Condition{}, rule.Conditions[:i]...), rule.Conditions[i+1:]...)
+				_, c := ruleStats(without, rule.Class, examples)
+				if c >= confidence-tolerance {
+					rule.Conditions = without
+					continue
+				}
+				i++
+			}
+			rule.Support, rule.Confidence = ruleStats(rule.Conditions, rule.Class, examples)
+		}
+		simplified = append(simplified, rule)
+	}
+
+	// Fundir reglas idénticas sumando su soporte
+	var unique []Rule[L]
+	seen := make(map[string]int)
+	for _, rule := range simplified {
+		key := fmt.Sprint(rule.Conditions, rule.Class)
+		if i, ok := seen[key]; ok {
+			if len(examples) == 0 {
+				total := unique[i].Support + rule.Support
+				if total > 0 {
+					unique[i].Confidence = (unique[i].Confidence*unique[i].Support + rule.Confidence*rule.Support) / total
+				}
+				unique[i].Support = total
+			}
+			continue
+		}
+		seen[key] = len(unique)
+		unique = append(unique, rule)
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		if unique[i].Confidence != unique[j].Confidence {
+			return unique[i].Confidence > unique[j].Confidence
+		}
+		return unique[i].Support > unique[j].Support
+	})
+
+	list := &RuleList[L]{Rules: unique}
+	weights := make(map[L]float64)
+	var classes []L
+	for _, rule := range unique {
+		if _, ok := weights[rule.Class]; !ok {
+			classes = append(classes, rule.Class)
+		}
+		weights[rule.Class] += rule.Support * rule.Confidence
+	}
+	list.Default = argmaxClass(classes, weights)
+	return list
+}
@@ -0,0 +1,113 @@
+package pcdta
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSimplifyRules(t *testing.T) {
+	le := func(feature int, threshold float64) Condition {
+		return Condition{Feature: feature, Threshold: threshold}
+	}
+	gt := func(feature int, threshold float64) Condition {
+		return Condition{Feature: feature, Greater: true, Threshold: threshold}
+	}
+	// En la columna 0 la clase es a hasta 2; la columna 1 no informa
+	examples := []Example[string]{
+		{Features: []float64{1, 0}, Class: "a"},
+		{Features: []float64{2, 10}, Class: "a"},
+		{Features: []float64{3, 0}, Class: "b"},
+		{Features: []float64{4, 10}, Class: "b"},
+	}
+	for _, tc := range []struct {
+		name     string
+		rules    []Rule[string]
+		examples []Example[string]
+		want     []Rule[string]
+		fallback string
+	}{
+		{
+			name:     "cotas más estrictas",
+			rules:    []Rule[string]{{Conditions: []Condition{le(0, 5), gt(1, 1), le(0, 3), gt(1, 2)}, Class: "a", Support: 4, Confidence: 1}},
+			want:     []Rule[string]{{Conditions: []Condition{le(0, 3), gt(1, 2)}, Class: "a", Support: 4, Confidence: 1}},
+			fallback: "a",
+		},
+		{
+			// Sin ejemplos las reglas iguales suman soporte: (0.9·10 + 0.5·30) / 40
+			name: "reglas iguales tras fundir cotas",
+			rules: []Rule[string]{
+				{Conditions: []Condition{le(0, 3), le(0, 5)}, Class: "a", Support: 10, Confidence: 0.9},
+				{Conditions: []Condition{le(0, 3)}, Class: "a", Support: 30, Confidence: 0.5},
+			},
+			want:     []Rule[string]{{Conditions: []Condition{le(0, 3)}, Class: "a", Support: 40, Confidence: 0.6}},
+			fallback: "a",
+		},
+		{
+			// Gana la confianza y, a igualdad, el soporte; la clase por defecto es la de
+			// mayor soporte·confianza: a 4.5, b 7, c 7.2
+			name: "orden de los conflictos",
+			rules: []Rule[string]{
+				{Conditions: []Condition{gt(0, 3)}, Class: "b", Support: 10, Confidence: 0.7},
+				{Conditions: []Condition{le(0, 3)}, Class: "a", Support: 5, Confidence: 0.9},
+				{Conditions: []Condition{le(0, 5)}, Class: "c", Support: 8, Confidence: 0.9},
+			},
+			want: []Rule[string]{
+				{Conditions: []Condition{le(0, 5)}, Class: "c", Support: 8, Confidence: 0.9},
+				{Conditions: []Condition{le(0, 3)}, Class: "a", Support: 5, Confidence: 0.9},
+				{Conditions: []Condition{gt(0, 3)}, Class: "b", Support: 10, Confidence: 0.7},
+			},
+			fallback: "c",
+		},
+		{
+			// Quitar x1 <= 5 mantiene la confianza en 1 y quitar x0 <= 2 la baja a 1/2;
+			// la regla de x0 > 10 no cubre nada y las dos de a acaban iguales sin sumar
+			// soporte, que se vuelve a medir
+			name: "generalización con ejemplos",
+			rules: []Rule[string]{
+				{Conditions: []Condition{le(0, 2), le(1, 5)}, Class: "a", Support: 1, Confidence: 1},
+				{Conditions: []Condition{le(1, 20), le(0, 2)}, Class: "a", Support: 1, Confidence: 1},
+				{Conditions: []Condition{gt(0, 2), gt(1, 5)}, Class: "b", Support: 1, Confidence: 1},
+				{Conditions: []Condition{gt(0, 10)}, Class: "b", Support: 7, Confidence: 1},
+			},
+			examples: examples,
+			want: []Rule[string]{
+				{Conditions: []Condition{le(0, 2)}, Class: "a", Support: 2, Confidence: 1},
+				{Conditions: []Condition{gt(0, 2)}, Class: "b", Support: 2, Confidence: 1},
+			},
+			fallback: "a",
+		},
+	} {
+		list := SimplifyRules(tc.rules, tc.examples, 0)
+		if len(list.Rules) != len(tc.want) {
+			t.Errorf("%s: reglas %+v, se esperaban %+v", tc.name, list.Rules, tc.want)
+			continue
+		}
+		for i, want := range tc.want {
+			got := list.Rules[i]
+			if fmt.Sprint(got.Conditions) != fmt.Sprint(want.Conditions) || got.Class != want.Class ||
+				!near(got.Support, want.Support) || !near(got.Confidence, want.Confidence) {
+				t.Errorf("%s: regla %d = %+v, se esperaba %+v", tc.name, i, got, want)
+			}
+		}
+		if list.Default != tc.fallback {
+			t.Errorf("%s: clase por defecto %s, se esperaba %s", tc.name, list.Default, tc.fallback)
+		}
+	}
+}
+
+func TestRuleListResolvesOverlapsInOrder(t *testing.T) {
+	list := SimplifyRules([]Rule[string]{
+		{Conditions: []Condition{{Feature: 0, Threshold: 3}}, Class: "a", Support: 5, Confidence: 0.9},
+		{Conditions: []Condition{{Feature: 0, Threshold: 5}}, Class: "c", Support: 8, Confidence: 0.95},
+	}, nil, 0)
+	// x0 = 1 cumple las dos y gana la más fiable, aunque vaya después en la entrada
+	for features, want := range map[float64]string{1: "c", 4: "c"} {
+		if got := list.Predict([]float64{features}); got != want {
+			t.Errorf("Predict(%v) = %s, se esperaba %s", features, got, want)
+		}
+	}
+	list.Default = "b"
+	if got := list.Predict([]float64{9}); got != "b" {
+		t.Errorf("sin regla que se cumpla: %s, se esperaba la clase por defecto b", got)
+	}
+}
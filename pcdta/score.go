@@ -0,0 +1,283 @@
+package pcdta
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Filas leídas por adelantado en ScoreStream mientras se puntúan las anteriores
+const scoreBuffer = 256
+
+// ScoreOptions añade columnas opcionales a la salida de ScoreStreamWith
+type ScoreOptions struct {
+	// FlagOutOfRange añade la columna out_of_range con las features de la fila que
+	// quedan fuera del rango de entrenamiento, separadas por ';' (vacía si ninguna)
+	FlagOutOfRange bool
+
+	// FlagUnseen añade la columna unseen_categories con columna=categoría por cada
+	// categoría que no se vio al entrenar y se codificó como ausente, separadas por ';'
+	FlagUnseen bool
+
+	// Confidence añade las columnas confidence y margin. Con Abstain > 0 también se
+	// añaden, y las filas con confianza menor que Abstain predicen AbstainClass.
+	Confidence bool
+	Abstain    float64
+
+	// CheckRanges rechaza, con su *InputError, la primera fila con un valor fuera del
+	// rango de entrenamiento, en vez de extrapolar
+	CheckRanges bool
+}
+
+// Clase que escribe ScoreStreamWith cuando el modelo se abstiene
+const AbstainClass = "unknown"
+
+// ScoreCSV lee filas CSV de r, añade la clase predicha y las probabilidades por clase
+// y escribe cada fila en w según se procesa, sin cargar el archivo completo en memoria
+func ScoreCSV(model *Model[string], r io.Reader, w io.Writer) error {
+	return ScoreStream(context.Background(), model, r, w)
+}
+
+// ScoreStream es ScoreCSV con cancelación: la lectura va en otra goroutine que se
+// adelanta como mucho scoreBuffer filas, así que si w se atasca la lectura también
+// se detiene y la memoria queda acotada. Cada bloque de filas se vuelca a w en
+// cuanto se puntúa. Al cancelar ctx escribe las filas ya puntuadas y devuelve
+// ctx.Err().
+func ScoreStream(ctx context.Context, model *Model[string], r io.Reader, w io.Writer) error {
+	return ScoreStreamWith(ctx, model, r, w, ScoreOptions{})
+}
+
+// ScoreStreamWith es ScoreStream con las columnas opcionales de opts
+func ScoreStreamWith(ctx context.Context, model *Model[string], r io.Reader, w io.Writer, opts ScoreOptions) error {
+	// Sin un nombre por cada feature que usa el árbol el vector quedaría corto, tanto
+	// al leer por nombre como por posición
+	if model.Tree == nil {
+		return errors.New("el modelo no tiene árbol")
+	}
+	if err := model.CheckInput(make([]float64, len(model.Meta.FeatureNames)), false); err != nil {
+		return fmt.Errorf("el modelo no nombra todas sus features: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reader := csv.NewReader(r)
+	writer := csv.NewWriter(w)
+	classes := model.Classes()
+
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+
+	// Columnas de la entrada: por nombre si la cabecera contiene las que necesita el
+	// modelo, si no por posición. Si se entrenó con una selección de features solo
+	// hacen falta esas; las demás pueden faltar y se leen como ausentes, ya que el
+	// árbol nunca las consulta. Las columnas categóricas y las features derivadas que
+	// no trae la entrada solo se pueden obtener por nombre.
+	required := model.Meta.FeatureNames
+	if len(model.Meta.SelectedFeatures) > 0 {
+		required = model.Meta.SelectedFeatures
+	}
+	needsNames := len(model.Meta.Encodings) > 0 || len(model.Meta.Derived) > 0
+	input, missingErr := model.Meta.NewPreprocessor(header)
+	byName := len(required) > 0 && missingErr == nil
+	if !byName {
+		if needsNames {
+			return fmt.Errorf("cabecera: %w", missingErr)
+		}
+		if input, err = model.Meta.NewPreprocessor(model.Meta.FeatureNames); err != nil {
+			return err
+		}
+	}
+
+	features := make([]float64, len(model.Meta.FeatureNames))
+	parse := func(record []string) error {
+		_, err := input.Record(record, features)
+		return err
+	}
+	// La longitud ya la garantiza el preprocesado; CheckInput es el mismo control que
+	// hace Model.Predict, con los rangos si se piden
+	check := func(line int) error {
+		if err := model.CheckInput(features, opts.CheckRanges); err != nil {
+			return fmt.Errorf("fila %d: %w", line, err)
+		}
+		return nil
+	}
+
+	// Las clases renombradas al entrenar se escriben con su nombre original
+	original := model.Meta.OriginalClasses()
+	classNames := make([]string, len(classes))
+	for i, class := range classes {
+		classNames[i] = class
+		if name, ok := original[class]; ok {
+			classNames[i] = name
+		}
+	}
+
+	// Una clase del modelo que se llame como AbstainClass no se distinguiría de una
+	// abstención en la salida
+	withConfidence := opts.Confidence || opts.Abstain > 0
+	if opts.Abstain > 0 && (slices.Contains(classes, AbstainClass) || slices.Contains(classNames, AbstainClass)) {
+		return fmt.Errorf("el modelo tiene una clase %q, que es la que indica las abstenciones", AbstainClass)
+	}
+	classIndex := make(map[string]int, len(classes))
+	for i, class := range classes {
+		classIndex[class] = i
+	}
+
+	probs := make([]float64, len(classes))
+	score := func(record []string) error {
+		// La hoja se busca una vez: PredictProbaInto sobre la propia hoja no recorre el
+		// árbol, y la confianza sale del mismo buffer de probabilidades
+		leaf := model.Tree.Leaf(features)
+		probs = leaf.PredictProbaInto(features, classes, probs)
+		class := leaf.Class
+		var probability, margin float64
+		if withConfidence {
+			i := classIndex[class]
+			runnerUp := 0.0
+			for j, prob := range probs {
+				if j != i && prob > runnerUp {
+					runnerUp = prob
+				}
+			}
+			probability, margin = probs[i], probs[i]-runnerUp
+		}
+		if name, renamed := original[class]; renamed {
+			class = name
+		}
+		if probability < opts.Abstain {
+			class = AbstainClass
+		}
+		out := append(record, class)
+		for i := range classes {
+			out = append(out, strconv.FormatFloat(probs[i], 'f', 6, 64))
+		}
+		if withConfidence {
+			out = append(out,
+				strconv.FormatFloat(probability, 'f', 6, 64),
+				strconv.FormatFloat(margin, 'f', 6, 64))
+		}
+		if opts.FlagOutOfRange {
+			var names []string
+			for _, warning := range model.OutOfRange(features) {
+				if warning.Name != "" {
+					names = append(names, warning.Name)
+				} else {
+					names = append(names, strconv.Itoa(warning.Feature))
+				}
+			}
+			out = append(out, strings.Join(names, ";"))
+		}
+		if opts.FlagUnseen {
+			var unseen []string
+			for _, warning := range input.Unseen(record) {
+				unseen = append(unseen, warning.Column+"="+warning.Category)
+			}
+			out = append(out, strings.Join(unseen, ";"))
+		}
+		return writer.Write(out)
+	}
+
+	// Si la primera fila no es numérica se trata como cabecera
+	headerIsData := !needsNames && parse(header) == nil
+	if !headerIsData && !byName && len(model.Meta.SelectedFeatures) > 0 {
+		return fmt.Errorf("cabecera: %w", missingErr)
+	}
+	outHeader := append([]string{}, header...)
+	if headerIsData {
+		for i := range outHeader {
+			outHeader[i] = fmt.Sprintf("col_%d", i)
+		}
+	}
+	outHeader = append(outHeader, "predicted_class")
+	for _, class := range classNames {
+		outHeader = append(outHeader, "prob_"+class)
+	}
+	if withConfidence {
+		outHeader = append(outHeader, "confidence", "margin")
+	}
+	if opts.FlagOutOfRange {
+		outHeader = append(outHeader, "out_of_range")
+	}
+	if opts.FlagUnseen {
+		outHeader = append(outHeader, "unseen_categories")
+	}
+	if err := writer.Write(outHeader); err != nil {
+		return err
+	}
+	if headerIsData {
+		if err := check(1); err != nil {
+			return err
+		}
+		if err := score(header); err != nil {
+			return err
+		}
+	}
+
+	type row struct {
+		record []string
+		err    error
+	}
+	rows := make(chan row, scoreBuffer)
+	go func() {
+		defer close(rows)
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			select {
+			case rows <- row{record, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for line := 2; ; line++ {
+		var next row
+		var ok bool
+		select {
+		case next, ok = <-rows:
+		case <-ctx.Done():
+			// Las filas ya puntuadas se escriben antes de parar
+			writer.Flush()
+			return errors.Join(ctx.Err(), writer.Error())
+		}
+		if !ok {
+			break
+		}
+		if next.err != nil {
+			return next.err
+		}
+		if err := parse(next.record); err != nil {
+			return fmt.Errorf("fila %d: %w", line, err)
+		}
+		if err := check(line); err != nil {
+			return err
+		}
+		if err := score(next.record); err != nil {
+			return err
+		}
+		// Volcar cuando no quedan filas leídas: la salida avanza al ritmo de la entrada
+		if len(rows) == 0 {
+			writer.Flush()
+			if err := writer.Error(); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,101 @@
+package pcdta
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestScoreCSVRejectsModelWithoutFeatureNames(t *testing.T) {
+	model := &Model[string]{Tree: &DecisionTree[string]{
+		Column: 1, Value: 0.5,
+		Left:  &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 1}},
+		Right: &DecisionTree[string]{Class: "b", Counts: map[string]int{"b": 1}},
+	}}
+	var out strings.Builder
+	if err := ScoreCSV(model, strings.NewReader("x,y\n1,2\n"), &out); err == nil {
+		t.Fatal("se esperaba un error con un modelo sin nombres de features")
+	}
+	if err := ScoreCSV(&Model[string]{}, strings.NewReader("x,y\n1,2\n"), &out); err == nil {
+		t.Fatal("se esperaba un error con un modelo sin árbol")
+	}
+
+	model.Meta.FeatureNames = []string{"x", "y"}
+	if err := ScoreCSV(model, strings.NewReader("x,y\n1,2\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "1,2,b,") {
+		t.Errorf("salida inesperada:\n%s", out.String())
+	}
+}
+
+func TestScoreStreamCheckRanges(t *testing.T) {
+	model := &Model[string]{
+		Meta: ModelMetadata{FeatureNames: []string{"x"}, FeatureRanges: []FeatureRange{{Min: 0, Max: 10}}},
+		Tree: &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 1}},
+	}
+	var out strings.Builder
+	err := ScoreStreamWith(context.Background(), model, strings.NewReader("x\n5\n50\n"), &out, ScoreOptions{CheckRanges: true})
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) || inputErr.Value != 50 || !strings.Contains(err.Error(), "fila 3") {
+		t.Fatalf("ScoreStreamWith = %v; se esperaba un *InputError en la fila 3", err)
+	}
+	out.Reset()
+	if err := ScoreCSV(model, strings.NewReader("x\n5\n50\n"), &out); err != nil {
+		t.Fatalf("sin CheckRanges: %v", err)
+	}
+}
+
+func TestScoreStreamConfidenceAndAbstain(t *testing.T) {
+	model := &Model[string]{
+		Meta: ModelMetadata{FeatureNames: []string{"x"}},
+		Tree: &DecisionTree[string]{
+			Column: 0, Value: 0.5,
+			Left:  &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 9, "b": 1}},
+			Right: &DecisionTree[string]{Class: "b", Counts: map[string]int{"a": 4, "b": 6}},
+		},
+	}
+	var out strings.Builder
+	err := ScoreStreamWith(context.Background(), model, strings.NewReader("x\n0\n1\n"), &out, ScoreOptions{Abstain: 0.7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "x,predicted_class,prob_a,prob_b,confidence,margin\n" +
+		"0,a,0.900000,0.100000,0.900000,0.800000\n" +
+		"1,unknown,0.400000,0.600000,0.600000,0.200000\n"
+	if out.String() != want {
+		t.Errorf("salida:\n%s\nse esperaba:\n%s", out.String(), want)
+	}
+
+	model.Tree.Right.Class = AbstainClass
+	model.Tree.Right.Counts = map[string]int{AbstainClass: 1}
+	model = &Model[string]{Meta: model.Meta, Tree: model.Tree}
+	if err := ScoreStreamWith(context.Background(), model, strings.NewReader("x\n0\n"), &out, ScoreOptions{Abstain: 0.7}); err == nil {
+		t.Errorf("se esperaba un error con una clase %q en el modelo", AbstainClass)
+	}
+}
+
+func TestScoreStreamFlushesOnCancel(t *testing.T) {
+	model := &Model[string]{
+		Meta: ModelMetadata{FeatureNames: []string{"x"}},
+		Tree: &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 1}},
+	}
+	// La entrada se queda esperando tras la primera fila, como un tubo sin más datos
+	r, w := io.Pipe()
+	defer w.Close()
+	go w.Write([]byte("x\n1\n"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out strings.Builder
+	done := make(chan error)
+	go func() { done <- ScoreStream(ctx, model, r, &out) }()
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScoreStream = %v; se esperaba context.Canceled", err)
+	}
+	if !strings.HasPrefix(out.String(), "x,predicted_class,prob_a\n") {
+		t.Errorf("no se escribió lo ya procesado: %q", out.String())
+	}
+}
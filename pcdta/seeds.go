@@ -0,0 +1,39 @@
+package pcdta
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+)
+
+// Nombres de los flujos aleatorios de cada subsistema
+const (
+	StreamGenerate  = "generate"  // datos sintéticos
+	StreamResample  = "resample"  // remuestreo de clases y SMOTE
+	StreamNoise     = "noise"     // inyección de ruido
+	StreamFolds     = "folds"     // particiones y pliegues
+	StreamBootstrap = "bootstrap" // muestras bootstrap
+	StreamFeatures  = "features"  // submuestreo de features
+)
+
+// SeedStreams deriva de una semilla raíz un generador independiente por subsistema,
+// de modo que cambiar cuántos números consume uno no altera los demás
+type SeedStreams struct {
+	Root int64
+}
+
+// Seed devuelve la semilla del flujo: los primeros 8 bytes de SHA-256(raíz, nombre)
+func (s SeedStreams) Seed(name string) int64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(s.Root))
+	h := sha256.New()
+	h.Write(buf[:])
+	h.Write([]byte(name))
+	return int64(binary.LittleEndian.Uint64(h.Sum(nil)))
+}
+
+// Stream devuelve un generador nuevo para el subsistema; dos llamadas con el mismo
+// nombre producen la misma secuencia
+func (s SeedStreams) Stream(name string) *rand.Rand {
+	return rand.New(rand.NewSource(s.Seed(name)))
+}
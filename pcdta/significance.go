@@ -0,0 +1,153 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+)
+
+// SignificanceResult es el resultado de un contraste entre dos modelos
+type SignificanceResult struct {
+	Test      string
+	Statistic float64
+	PValue    float64
+	Detail    string
+}
+
+func (r SignificanceResult) String() string {
+	return fmt.Sprintf("%s: estadístico %.4f, p-valor %.4f (%s)", r.Test, r.Statistic, r.PValue, r.Detail)
+}
+
+// McNemar compara dos clasificadores sobre los mismos ejemplos usando solo los
+// desacuerdos: b filas que acierta A y falla B, c filas al revés. Con pocos
+// desacuerdos (b+c < 25) usa la prueba binomial exacta; si no, chi² con corrección
+// de continuidad
+func McNemar[L comparable](a, b Classifier[L], examples []Example[L]) SignificanceResult {
+	onlyA, onlyB := 0, 0
+	for _, example := range examples {
+		rightA := a.Predict(example.Features) == example.Class
+		rightB := b.Predict(example.Features) == example.Class
+		if rightA && !rightB {
+			onlyA++
+		} else if rightB && !rightA {
+			onlyB++
+		}
+	}
+	return McNemarCounts(onlyA, onlyB)
+}
+
+func McNemarCounts(onlyA, onlyB int) SignificanceResult {
+	detail := fmt.Sprintf("solo A acierta: %d, solo B acierta: %d", onlyA, onlyB)
+	n := onlyA + onlyB
+	if n == 0 {
+		return SignificanceResult{Test: "McNemar", PValue: 1, Detail: detail}
+	}
+
+	if n < 25 {
+		// Binomial exacta de dos colas con p = 1/2
+		k := onlyA
+		if onlyB < k {
+			k = onlyB
+		}
+		var p float64
+		for i := 0; i <= k; i++ {
+			lg, _ := math.Lgamma(float64(n + 1))
+			li, _ := math.Lgamma(float64(i + 1))
+			lr, _ := math.Lgamma(float64(n - i + 1))
+			p += math.Exp(lg - li - lr - float64(n)*math.Ln2)
+		}
+		return SignificanceResult{Test: "McNemar exacta", Statistic: float64(k), PValue: math.Min(1, 2*p), Detail: detail}
+	}
+
+	// La corrección de continuidad no puede volver negativa la diferencia: con b = c
+	// el estadístico es 0
+	diff := math.Max(0, math.Abs(float64(onlyA-onlyB))-1)
+	chi2 := diff * diff / float64(n)
+	// Cola superior de chi² con 1 grado de libertad
+	return SignificanceResult{Test: "McNemar", Statistic: chi2, PValue: math.Erfc(math.Sqrt(chi2 / 2)), Detail: detail}
+}
+
+// PairedTTest contrasta si la media de las diferencias por pliegue entre dos
+// configuraciones es cero (t de Student con k-1 grados de libertad); las dos listas
+// han de tener una puntuación por pliegue
+func PairedTTest(scoresA, scoresB []float64) (SignificanceResult, error) {
+	if len(scoresA) != len(scoresB) {
+		return SignificanceResult{}, fmt.Errorf("%d puntuaciones de A y %d de B: hace falta una de cada por pliegue", len(scoresA), len(scoresB))
+	}
+	k := len(scoresA)
+	diffs := make([]float64, k)
+	for i := range diffs {
+		diffs[i] = scoresA[i] - scoresB[i]
+	}
+	m := Mean(diffs)
+	var ss float64
+	for _, d := range diffs {
+		ss += (d - m) * (d - m)
+	}
+	detail := fmt.Sprintf("diferencia media A-B: %.4f en %d pliegues", m, k)
+	if k < 2 {
+		return SignificanceResult{Test: "t pareada", PValue: 1, Detail: detail}, nil
+	}
+
+	se := math.Sqrt(ss / float64(k-1) / float64(k))
+	if se == 0 {
+		p := 1.0
+		if m != 0 {
+			p = 0
+		}
+		return SignificanceResult{Test: "t pareada", Statistic: math.Copysign(math.Inf(1), m), PValue: p, Detail: detail}, nil
+	}
+	t := m / se
+	df := float64(k - 1)
+	// P(|T| > t) = I_{df/(df+t²)}(df/2, 1/2)
+	p := regularizedBeta(df/(df+t*t), df/2, 0.5)
+	return SignificanceResult{Test: "t pareada", Statistic: t, PValue: p, Detail: detail}, nil
+}
+
+// regularizedBeta calcula la función beta incompleta regularizada I_x(a, b)
+// con la fracción continua de Lentz
+func regularizedBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	if x > (a+1)/(a+b+2) {
+		return 1 - regularizedBeta(1-x, b, a)
+	}
+
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab-la-lb+a*math.Log(x)+b*math.Log(1-x)) / a
+
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	f := d
+	for m := 1; m <= 200; m++ {
+		fm := float64(m)
+		for _, num := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			f *= c * d
+		}
+		if math.Abs(c*d-1) < 1e-12 {
+			break
+		}
+	}
+	return front * f
+}
@@ -0,0 +1,67 @@
+package pcdta
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMcNemarCounts(t *testing.T) {
+	cases := []struct {
+		onlyA, onlyB int
+		test         string
+		statistic    float64
+		pValue       float64
+	}{
+		{0, 0, "McNemar", 0, 1},
+		// Binomial exacta: 2·(C(10,0) + C(10,1))/2¹⁰
+		{1, 9, "McNemar exacta", 1, 22.0 / 1024},
+		{12, 12, "McNemar exacta", 12, 1},
+		// Chi² con corrección: (|10-30| - 1)²/40
+		{10, 30, "McNemar", 361.0 / 40, 0.002663119259138554},
+		// La corrección no vuelve el estadístico positivo cuando b = c
+		{15, 15, "McNemar", 0, 1},
+		{15, 16, "McNemar", 0, 1},
+	}
+	for _, c := range cases {
+		got := McNemarCounts(c.onlyA, c.onlyB)
+		if got.Test != c.test || math.Abs(got.Statistic-c.statistic) > 1e-9 || math.Abs(got.PValue-c.pValue) > 1e-9 {
+			t.Errorf("McNemarCounts(%d, %d) = %s %v p=%v, se esperaba %s %v p=%v",
+				c.onlyA, c.onlyB, got.Test, got.Statistic, got.PValue, c.test, c.statistic, c.pValue)
+		}
+	}
+}
+
+func TestMcNemarCountsDisagreements(t *testing.T) {
+	examples := []Example[string]{
+		{Features: []float64{0}, Class: "a"},
+		{Features: []float64{0}, Class: "a"},
+		{Features: []float64{0}, Class: "b"},
+	}
+	got := McNemar[string](hardOnly("a"), hardOnly("b"), examples)
+	if want := McNemarCounts(2, 1); got != want {
+		t.Errorf("McNemar = %v, se esperaba %v", got, want)
+	}
+}
+
+func TestPairedTTest(t *testing.T) {
+	// Diferencias 0.05, 0.1, 0.05, 0.1: media 0.075, t = 3·√3 con 3 grados de
+	// libertad, cuya cola doble es 1 - (2/π)·(x/(1+x²) + atan x) con x = 3
+	got, err := PairedTTest([]float64{0.8, 0.9, 0.85, 0.95}, []float64{0.75, 0.8, 0.8, 0.85})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got.Statistic-3*math.Sqrt(3)) > 1e-9 || math.Abs(got.PValue-0.013846832988859137) > 1e-9 {
+		t.Errorf("t = %v, p = %v; se esperaba t = %v, p = 0.013847", got.Statistic, got.PValue, 3*math.Sqrt(3))
+	}
+
+	// Misma diferencia en todos los pliegues: sin varianza, p 0
+	if got, _ := PairedTTest([]float64{0.75, 0.5}, []float64{0.5, 0.25}); got.PValue != 0 || !math.IsInf(got.Statistic, 1) {
+		t.Errorf("diferencia constante: t = %v, p = %v", got.Statistic, got.PValue)
+	}
+	if got, _ := PairedTTest([]float64{0.9}, []float64{0.8}); got.PValue != 1 {
+		t.Errorf("un solo pliegue: p = %v, se esperaba 1", got.PValue)
+	}
+	if _, err := PairedTTest([]float64{0.9, 0.8, 0.7}, []float64{0.8, 0.7}); err == nil {
+		t.Error("listas de distinta longitud no devolvieron error")
+	}
+}
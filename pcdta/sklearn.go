@@ -0,0 +1,166 @@
+package pcdta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// SklearnTree es el volcado JSON de DecisionTreeClassifier.tree_ junto con classes_:
+//
+//	{"classes": [...], "feature_names": [...], "tree_": {"children_left": [...], ...}}
+//
+// Un RandomForestClassifier se vuelca igual, con un volcado por árbol en estimators
+// (sin classes, que se toman del bosque) en lugar de tree_.
+type SklearnTree struct {
+	Classes      []any         `json:"classes"`
+	FeatureNames []string      `json:"feature_names"`
+	Estimators   []SklearnTree `json:"estimators"`
+	Tree         struct {
+		ChildrenLeft  []int         `json:"children_left"`
+		ChildrenRight []int         `json:"children_right"`
+		Feature       []int         `json:"feature"`
+		Threshold     []float64     `json:"threshold"`
+		Value         [][][]float64 `json:"value"`
+		NodeSamples   []int         `json:"n_node_samples"`
+	} `json:"tree_"`
+}
+
+// ErrSklearnForest indica que el volcado es un bosque, que importa ImportSklearnForest
+var ErrSklearnForest = errors.New("el volcado es un RandomForestClassifier: use ImportSklearnForest")
+
+// ImportSklearn convierte un DecisionTreeClassifier exportado a JSON en un modelo propio
+func ImportSklearn(data []byte) (*Model[string], error) {
+	var sk SklearnTree
+	if err := json.Unmarshal(data, &sk); err != nil {
+		return nil, err
+	}
+	if len(sk.Estimators) > 0 {
+		return nil, ErrSklearnForest
+	}
+	tree, err := sk.convert(sklearnClasses(sk.Classes))
+	if err != nil {
+		return nil, err
+	}
+
+	rows := 0
+	if len(sk.Tree.NodeSamples) > 0 {
+		rows = sk.Tree.NodeSamples[0]
+	}
+	model := NewModel(tree, nil, sk.FeatureNames, map[string]any{"source": "sklearn"})
+	model.Meta.Rows = rows
+	model.Meta.DatasetHash = ""
+
+	return model, nil
+}
+
+// ImportSklearnForest convierte un RandomForestClassifier exportado a JSON en un
+// Forest. Como sklearn, el bosque predice por la media de las probabilidades de sus
+// árboles.
+func ImportSklearnForest(data []byte) (*Forest[string], error) {
+	var sk SklearnTree
+	if err := json.Unmarshal(data, &sk); err != nil {
+		return nil, err
+	}
+	if len(sk.Estimators) == 0 {
+		return nil, errors.New("el volcado no tiene estimators: importe el árbol con ImportSklearn")
+	}
+	classes := sklearnClasses(sk.Classes)
+	forest := &Forest[string]{Classes: classes, FeatureNames: sk.FeatureNames}
+	for i, estimator := range sk.Estimators {
+		tree, err := estimator.convert(classes)
+		if err != nil {
+			return nil, fmt.Errorf("árbol %d: %w", i, err)
+		}
+		forest.Trees = append(forest.Trees, tree)
+	}
+	return forest, nil
+}
+
+func sklearnClasses(raw []any) []string {
+	classes := make([]string, len(raw))
+	for i, c := range raw {
+		classes[i] = fmt.Sprint(c)
+	}
+	return classes
+}
+
+// convert construye el árbol de tree_ con las clases dadas
+func (sk SklearnTree) convert(classes []string) (*DecisionTree[string], error) {
+	t := sk.Tree
+	n := len(t.ChildrenLeft)
+	if n == 0 {
+		return nil, errors.New("tree_ vacío")
+	}
+	if len(t.ChildrenRight) != n || len(t.Feature) != n || len(t.Threshold) != n || len(t.Value) != n {
+		return nil, errors.New("los arrays de tree_ tienen longitudes distintas")
+	}
+
+	var build func(i, depth int) (*DecisionTree[string], error)
+	build = func(i, depth int) (*DecisionTree[string], error) {
+		if i < 0 || i >= n || depth > n {
+			return nil, fmt.Errorf("índice de nodo inválido %d", i)
+		}
+
+		// sklearn marca las hojas con children_left == -1
+		if t.ChildrenLeft[i] == -1 {
+			if len(t.Value[i]) == 0 || len(t.Value[i][0]) != len(classes) {
+				return nil, fmt.Errorf("nodo %d: value no coincide con el número de clases", i)
+			}
+			values := t.Value[i][0]
+
+			// Las versiones recientes guardan fracciones en lugar de conteos
+			sum := 0.0
+			for _, v := range values {
+				sum += v
+			}
+			scale := 1.0
+			if i < len(t.NodeSamples) && sum > 0 && sum <= 1+1e-9 {
+				scale = float64(t.NodeSamples[i]) / sum
+			}
+
+			leaf := &DecisionTree[string]{Counts: make(map[string]int)}
+			weights := make(map[string]float64)
+			integral := true
+			best := -1.0
+			for k, v := range values {
+				mass := v * scale
+				if mass > 0 {
+					weights[classes[k]] = mass
+				}
+				if count := int(math.Round(mass)); count > 0 {
+					leaf.Counts[classes[k]] = count
+				}
+				integral = integral && math.Abs(mass-math.Round(mass)) < 1e-6
+				if v > best {
+					best = v
+					leaf.Class = classes[k]
+				}
+			}
+			// Con class_weight o muestras bootstrap los valores son masas ponderadas:
+			// redondearlas a conteos cambiaría las probabilidades, así que se guardan
+			// en Weights, que PredictProba usa antes que Counts
+			if !integral {
+				leaf.Weights = weights
+			}
+			return leaf, nil
+		}
+
+		left, err := build(t.ChildrenLeft[i], depth+1)
+		if err != nil {
+			return nil, err
+		}
+		right, err := build(t.ChildrenRight[i], depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return &DecisionTree[string]{
+			Left:   left,
+			Right:  right,
+			Column: t.Feature[i],
+			Value:  t.Threshold[i],
+		}, nil
+	}
+	return build(0, 0)
+}
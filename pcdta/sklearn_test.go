@@ -0,0 +1,90 @@
+package pcdta
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// Raíz x0 <= 2.5; la hoja izquierda es pura de 0 y la derecha tiene 1 de 0 y 3 de 1
+const sklearnStump = `{"children_left": [1, -1, -1], "children_right": [2, -1, -1],
+	"feature": [0, -2, -2], "threshold": [2.5, -2, -2],
+	"value": [[[5, 3]], [[4, 0]], [[1, 3]]], "n_node_samples": [8, 4, 4]}`
+
+func TestImportSklearnTree(t *testing.T) {
+	model, err := ImportSklearn([]byte(`{"classes": [0, 1], "feature_names": ["x0"], "tree_": ` + sklearnStump + `}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Meta.Rows != 8 || model.Meta.FeatureNames[0] != "x0" {
+		t.Errorf("metadatos %+v", model.Meta)
+	}
+	if got := model.Tree.Predict([]float64{2.5}); got != "0" {
+		t.Errorf("x0 = 2.5 (<= umbral, como sklearn) predice %s, se esperaba 0", got)
+	}
+	if probs := model.Tree.PredictProba([]float64{3}); probs["1"] != 0.75 {
+		t.Errorf("PredictProba(3) = %v, se esperaba P(1) = 0.75", probs)
+	}
+
+	if _, err := ImportSklearn([]byte(`{"classes": [0, 1], "tree_": {"children_left": [-1], "children_right": []}}`)); err == nil {
+		t.Error("arrays de longitudes distintas no devolvieron error")
+	}
+}
+
+func TestImportSklearnWeightedLeaves(t *testing.T) {
+	// Con class_weight value guarda masas ponderadas y, en versiones recientes,
+	// fracciones: 0.25·4 = 1 y 0.75·4 = 3 son conteos, 2.5 y 0.75 no
+	for _, c := range []struct {
+		value string
+		want  float64
+	}{
+		{`[[2.5, 0.75]]`, 0.75 / 3.25},
+		{`[[0.25, 0.75]]`, 0.75},
+	} {
+		tree := `{"children_left": [-1], "children_right": [-1], "feature": [-2], "threshold": [-2], "value": [` + c.value + `], "n_node_samples": [4]}`
+		model, err := ImportSklearn([]byte(`{"classes": ["a", "b"], "tree_": ` + tree + `}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := model.Tree.PredictProba([]float64{0})["b"]; math.Abs(p-c.want) > 1e-9 {
+			t.Errorf("value %s: P(b) = %v, se esperaba %v", c.value, p, c.want)
+		}
+	}
+}
+
+func TestImportSklearnForest(t *testing.T) {
+	leafB := `{"children_left": [-1], "children_right": [-1], "feature": [-2], "threshold": [-2], "value": [[[0, 2]]], "n_node_samples": [2]}`
+	data := []byte(`{"classes": [0, 1], "feature_names": ["x0"], "estimators": [{"tree_": ` + sklearnStump + `}, {"tree_": ` + leafB + `}]}`)
+
+	if _, err := ImportSklearn(data); !errors.Is(err, ErrSklearnForest) {
+		t.Errorf("ImportSklearn de un bosque: error %v, se esperaba ErrSklearnForest", err)
+	}
+	forest, err := ImportSklearnForest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forest.Trees) != 2 || forest.FeatureNames[0] != "x0" {
+		t.Fatalf("%d árboles, features %v", len(forest.Trees), forest.FeatureNames)
+	}
+	// Media de (1, 0) y (0, 1) a la izquierda
+	if probs := forest.PredictProba([]float64{1}); probs["0"] != 0.5 || probs["1"] != 0.5 {
+		t.Errorf("PredictProba(1) = %v, se esperaba 0.5 y 0.5", probs)
+	}
+	if got := forest.Predict([]float64{3}); got != "1" {
+		t.Errorf("Predict(3) = %s, se esperaba 1", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "bosque.json")
+	if err := SaveForest(forest, path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadForest[string](path)
+	if err != nil || len(loaded.Trees) != 2 || loaded.Predict([]float64{3}) != "1" {
+		t.Errorf("LoadForest: error %v", err)
+	}
+
+	if _, err := ImportSklearnForest([]byte(`{"classes": [0, 1], "estimators": [{"tree_": {}}]}`)); err == nil {
+		t.Error("un árbol vacío en estimators no devolvió error")
+	}
+}
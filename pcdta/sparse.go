@@ -0,0 +1,633 @@
+package pcdta
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SparseDataset guarda features dispersas en formato CSR: las entradas no nulas de
+// la fila i son ColIndex[RowPtr[i]:RowPtr[i+1]] con sus Values, con las columnas
+// en orden creciente. Las entradas ausentes valen 0; no admite valores ausentes (NaN).
+type SparseDataset[L comparable] struct {
+	NumFeatures  int
+	FeatureNames []string
+	RowPtr       []int
+	ColIndex     []int
+	Values       []float64
+	Classes      []L
+	Weights      []float64 // opcional; nil equivale a peso 1 en todas las filas
+}
+
+func NewSparseDataset[L comparable](numFeatures int) *SparseDataset[L] {
+	return &SparseDataset[L]{NumFeatures: numFeatures, RowPtr: []int{0}}
+}
+
+func (d *SparseDataset[L]) Rows() int {
+	return len(d.Classes)
+}
+
+// AddRow añade una fila a partir de sus entradas no nulas, en cualquier orden;
+// los ceros explícitos se descartan
+func (d *SparseDataset[L]) AddRow(cols []int, values []float64, class L) error {
+	order := make([]int, len(cols))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return cols[order[i]] < cols[order[j]] })
+
+	start := len(d.ColIndex)
+	for k, i := range order {
+		col, value := cols[i], values[i]
+		if col < 0 || col >= d.NumFeatures {
+			d.ColIndex, d.Values = d.ColIndex[:start], d.Values[:start]
+			return fmt.Errorf("columna %d fuera de rango (%d features)", col, d.NumFeatures)
+		}
+		if math.IsNaN(value) {
+			d.ColIndex, d.Values = d.ColIndex[:start], d.Values[:start]
+			return fmt.Errorf("columna %d: el formato disperso no admite valores ausentes", col)
+		}
+		if k > 0 && cols[order[k-1]] == col {
+			d.ColIndex, d.Values = d.ColIndex[:start], d.Values[:start]
+			return fmt.Errorf("columna %d repetida", col)
+		}
+		if value != 0 {
+			d.ColIndex = append(d.ColIndex, col)
+			d.Values = append(d.Values, value)
+		}
+	}
+	d.RowPtr = append(d.RowPtr, len(d.ColIndex))
+	d.Classes = append(d.Classes, class)
+	return nil
+}
+
+// Value devuelve el valor de la celda (row, col) buscando en las entradas de la fila
+func (d *SparseDataset[L]) Value(row, col int) float64 {
+	return sparseValue(d.ColIndex[d.RowPtr[row]:d.RowPtr[row+1]], d.Values[d.RowPtr[row]:d.RowPtr[row+1]], col)
+}
+
+func sparseValue(cols []int, values []float64, col int) float64 {
+	i := sort.SearchInts(cols, col)
+	if i < len(cols) && cols[i] == col {
+		return values[i]
+	}
+	return 0
+}
+
+func (d *SparseDataset[L]) weight(row int) float64 {
+	if d.Weights == nil || d.Weights[row] == 0 {
+		return 1
+	}
+	return d.Weights[row]
+}
+
+// Hash calcula un SHA-256 sobre las entradas, pesos y clases de las filas en orden
+func (d *SparseDataset[L]) Hash() string {
+	h := sha256.New()
+	var buf [8]byte
+	for row := 0; row < d.Rows(); row++ {
+		for k := d.RowPtr[row]; k < d.RowPtr[row+1]; k++ {
+			binary.LittleEndian.PutUint64(buf[:], uint64(d.ColIndex[k]))
+			h.Write(buf[:])
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(d.Values[k]))
+			h.Write(buf[:])
+		}
+		if d.Weights != nil {
+			fmt.Fprintf(h, "|w%v", d.Weights[row])
+		}
+		fmt.Fprintf(h, "|%v\n", d.Classes[row])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// PredictSparse recorre el árbol con una fila dispersa (columnas crecientes)
+func (tree *DecisionTree[L]) PredictSparse(cols []int, values []float64) L {
+	node := tree
+	for !node.IsLeaf() {
+		if sparseValue(cols, values, node.Column) <= node.Value {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+	}
+	return node.Class
+}
+
+// BuildSparseTree entrena un árbol sobre datos dispersos con las mismas reglas que
+// BuildDecisionTreeConcurrent. Cada nodo solo recorre sus entradas no nulas: los
+// ceros de una columna se tratan como un único bloque cuyo peso por clase es el total
+// del nodo menos el de las entradas no nulas, así que el coste depende de las
+// entradas no nulas y no del número de columnas.
+func BuildSparseTree[L comparable](d *SparseDataset[L], opts TrainOptions[L]) (*DecisionTree[L], TrainReport) {
+	rows := make([]int, d.Rows())
+	for i := range rows {
+		rows[i] = i
+	}
+	skipped := sparseLowVarianceFeatures(d, opts.MinFeatureVariance)
+	b := newBuilder(opts, d.Weights != nil, skipped, sparseLabels(d, rows))
+	opts = b.opts
+
+	stopSampling := sampleHeap()
+	start := time.Now()
+	tree := b.buildSparse(d, rows, 0)
+	if opts.Smoothing > 0 {
+		SmoothLeaves(tree, ClassWeights(sparseLabels(d, rows)), opts.Smoothing, opts.SmoothingMethod)
+	}
+	total := time.Since(start)
+	peak := stopSampling()
+
+	c := &b.counters
+	report := TrainReport{
+		Sort:          time.Duration(c.sort.Load()),
+		SplitSearch:   time.Duration(c.splitSearch.Load()),
+		Partition:     time.Duration(c.partition.Load()),
+		Total:         total,
+		Nodes:         int(c.nodes.Load()),
+		Leaves:        int(c.leaves.Load()),
+		RowsProcessed: int(c.rows.Load()),
+		PeakHeapBytes: peak,
+
+		SkippedFeatures: skipped,
+
+		Hyperparameters: opts.Hyperparameters(),
+		Rows:            d.Rows(),
+	}
+	if total > 0 {
+		report.RowsPerSecond = float64(report.RowsProcessed) / total.Seconds()
+	}
+	return tree, report
+}
+
+// sparseLowVarianceFeatures es lowVarianceFeatures sobre las entradas de d, contando
+// los ceros implícitos. Las columnas sin ninguna entrada no se listan: no aportan
+// entradas a la búsqueda, así que nunca dividen, y con millones de columnas la lista
+// no cabría en el informe.
+func sparseLowVarianceFeatures[L comparable](d *SparseDataset[L], minVariance float64) []int {
+	type moments struct {
+		sum, sumSq, first float64
+		n                 int
+		constant          bool
+	}
+	columns := make(map[int]*moments)
+	for k, col := range d.ColIndex {
+		value := d.Values[k]
+		m := columns[col]
+		if m == nil {
+			m = &moments{first: value, constant: true}
+			columns[col] = m
+		} else if value != m.first {
+			m.constant = false
+		}
+		m.sum += value
+		m.sumSq += value * value
+		m.n++
+	}
+
+	rows := float64(d.Rows())
+	var low []int
+	for col, m := range columns {
+		// Con algún cero implícito la columna ya no es constante
+		if m.constant && m.n == d.Rows() {
+			low = append(low, col)
+			continue
+		}
+		mean := m.sum / rows
+		if m.sumSq/rows-mean*mean <= minVariance {
+			low = append(low, col)
+		}
+	}
+	sort.Ints(low)
+	return low
+}
+
+// sparseLabels devuelve ejemplos sin features con la clase y el peso de cada fila,
+// que es todo lo que necesitan las hojas
+func sparseLabels[L comparable](d *SparseDataset[L], rows []int) []Example[L] {
+	examples := make([]Example[L], len(rows))
+	for i, row := range rows {
+		examples[i] = Example[L]{Class: d.Classes[row]}
+		if d.Weights != nil {
+			examples[i].Weight = d.Weights[row]
+		}
+	}
+	return examples
+}
+
+func (b *treeBuilder[L]) buildSparse(d *SparseDataset[L], rows []int, depth int) *DecisionTree[L] {
+	if len(rows) == 0 || depth >= b.opts.MaxDepth {
+		return b.leaf(sparseLabels(d, rows))
+	}
+
+	b.counters.rows.Add(int64(len(rows)))
+	bestSplit := b.findBestSparseSplit(d, rows, depth)
+	if bestSplit == nil {
+		return b.leaf(sparseLabels(d, rows))
+	}
+	b.counters.nodes.Add(1)
+
+	partitionStart := time.Now()
+	var leftRows, rightRows []int
+	for _, row := range rows {
+		if d.Value(row, bestSplit.Column) <= bestSplit.Value {
+			leftRows = append(leftRows, row)
+		} else {
+			rightRows = append(rightRows, row)
+		}
+	}
+	b.counters.partition.Add(int64(time.Since(partitionStart)))
+
+	var wg sync.WaitGroup
+	var left *DecisionTree[L]
+	b.spawn(&wg, func() {
+		left = b.buildSparse(d, leftRows, depth+1)
+	})
+	right := b.buildSparse(d, rightRows, depth+1)
+	wg.Wait()
+
+	return &DecisionTree[L]{
+		Left:   left,
+		Right:  right,
+		Column: bestSplit.Column,
+		Value:  bestSplit.Value,
+	}
+}
+
+func (b *treeBuilder[L]) findBestSparseSplit(d *SparseDataset[L], rows []int, depth int) *DecisionTree[L] {
+	classIndex := make(map[L]int)
+	for _, row := range rows {
+		if _, ok := classIndex[d.Classes[row]]; !ok {
+			classIndex[d.Classes[row]] = len(classIndex)
+		}
+	}
+	numClasses := len(classIndex)
+	costs := b.costMatrix(classIndex)
+
+	// Peso total por clase del nodo
+	totals := make([]float64, numClasses)
+	var totalWeight float64
+	for _, row := range rows {
+		w := d.weight(row)
+		totals[classIndex[d.Classes[row]]] += w
+		totalWeight += w
+	}
+
+	// Entradas no nulas del nodo agrupadas por columna y ordenadas por valor
+	sortStart := time.Now()
+	type entry struct {
+		col    int
+		value  float64
+		label  int
+		weight float64
+	}
+	var entries []entry
+	for _, row := range rows {
+		label, w := classIndex[d.Classes[row]], d.weight(row)
+		for k := d.RowPtr[row]; k < d.RowPtr[row+1]; k++ {
+			if b.allowed(d.ColIndex[k], depth) {
+				entries = append(entries, entry{d.ColIndex[k], d.Values[k], label, w})
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].col != entries[j].col {
+			return entries[i].col < entries[j].col
+		}
+		return entries[i].value < entries[j].value
+	})
+	var groups [][]entry
+	for start := 0; start < len(entries); {
+		end := start
+		for end < len(entries) && entries[end].col == entries[start].col {
+			end++
+		}
+		groups = append(groups, entries[start:end])
+		start = end
+	}
+	searchStart := time.Now()
+	b.counters.sort.Add(int64(searchStart.Sub(sortStart)))
+
+	type sparseResult struct {
+		gini, balance float64
+		value         float64
+		found         bool
+	}
+	results := make([]sparseResult, len(groups))
+
+	b.parallel(len(groups), func(g int) {
+		group := groups[g]
+		best := sparseResult{gini: math.Inf(1)}
+
+		// Peso por clase del bloque de ceros
+		zeros := append([]float64{}, totals...)
+		zeroWeight := totalWeight
+		for _, e := range group {
+			zeros[e.label] -= e.weight
+			zeroWeight -= e.weight
+		}
+
+		left := make([]float64, numClasses)
+		right := make([]float64, numClasses)
+		var leftWeight float64
+		prev, started := 0.0, false
+
+		// step prueba el umbral entre el valor anterior y value y después mueve a la
+		// izquierda las filas con ese valor
+		step := func(value float64, move func()) {
+			if started && value != prev {
+				for k := range right {
+					right[k] = totals[k] - left[k]
+				}
+				rightWeight := totalWeight - leftWeight
+				var gini float64
+				if costs != nil {
+					gini = CalculateCostGini(left, right, leftWeight, rightWeight, costs)
+				} else {
+					gini = CalculateGini(left, right, leftWeight, rightWeight)
+				}
+				balance := math.Abs(leftWeight-rightWeight) / totalWeight
+				if b.better(gini, balance, best.gini, best.balance) {
+					best = sparseResult{gini: gini, balance: balance, value: (prev + value) / 2.0, found: true}
+				}
+			}
+			move()
+			prev, started = value, true
+		}
+
+		zeroDone := zeroWeight <= 1e-12*totalWeight
+		moveZeros := func() {
+			for k := range left {
+				left[k] += zeros[k]
+			}
+			leftWeight += zeroWeight
+		}
+		for _, e := range group {
+			if !zeroDone && e.value > 0 {
+				step(0, moveZeros)
+				zeroDone = true
+			}
+			e := e
+			step(e.value, func() {
+				left[e.label] += e.weight
+				leftWeight += e.weight
+			})
+		}
+		if !zeroDone {
+			step(0, moveZeros)
+		}
+
+		results[g] = best
+	})
+	b.counters.splitSearch.Add(int64(time.Since(searchStart)))
+
+	var bestSplit *DecisionTree[L]
+	bestGini, bestBalance := math.Inf(1), 0.0
+	for g, result := range results {
+		if result.found && b.better(result.gini, result.balance, bestGini, bestBalance) {
+			bestGini, bestBalance = result.gini, result.balance
+			bestSplit = &DecisionTree[L]{Column: groups[g][0].col, Value: result.value}
+		}
+	}
+	return bestSplit
+}
+
+// LoadSVMLight lee un archivo en formato SVMlight/LIBSVM: "clase índice:valor ..."
+// con índices desde 1; lo que sigue a '#' es un comentario
+func LoadSVMLight(filename string) (*SparseDataset[string], error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	type row struct {
+		cols   []int
+		values []float64
+		class  string
+	}
+	var rows []row
+	numFeatures := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1<<20), 1<<30)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		r := row{class: fields[0]}
+		for _, field := range fields[1:] {
+			index, value, ok := strings.Cut(field, ":")
+			col, err := strconv.Atoi(index)
+			if !ok || err != nil || col < 1 {
+				return nil, fmt.Errorf("%s:%d: entrada no válida %q", filename, line, field)
+			}
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", filename, line, err)
+			}
+			r.cols = append(r.cols, col-1)
+			r.values = append(r.values, v)
+			if col > numFeatures {
+				numFeatures = col
+			}
+		}
+		rows = append(rows, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	d := NewSparseDataset[string](numFeatures)
+	for j := 0; j < numFeatures; j++ {
+		d.FeatureNames = append(d.FeatureNames, fmt.Sprintf("f%d", j+1))
+	}
+	for i, r := range rows {
+		if err := d.AddRow(r.cols, r.values, r.class); err != nil {
+			return nil, fmt.Errorf("%s: fila %d: %w", filename, i+1, err)
+		}
+	}
+	return d, nil
+}
+
+// FeatureBundle agrupa features dispersas mutuamente excluyentes en una sola columna:
+// el valor de la feature Features[k] se guarda desplazado en Offsets[k], de modo que
+// los rangos de cada feature no se solapan dentro de la columna. Max[k] es el mayor
+// valor de la feature al entrenar; los valores mayores se recortan a él para que no
+// invadan el rango de la feature siguiente.
+type FeatureBundle struct {
+	Features []int     `json:"features"`
+	Offsets  []float64 `json:"offsets"`
+	Max      []float64 `json:"max,omitempty"`
+}
+
+// ExclusiveBundles agrupa de forma voraz las features con valores positivos, de la más
+// a la menos poblada, en el primer grupo con el que comparten a lo sumo maxConflicts
+// filas no nulas. Las features con valores negativos quedan en su propio grupo.
+func (d *SparseDataset[L]) ExclusiveBundles(maxConflicts int) []FeatureBundle {
+	nnz := make([]int, d.NumFeatures)
+	maxValue := make([]float64, d.NumFeatures)
+	negative := make([]bool, d.NumFeatures)
+	rowsOf := make([][]int, d.NumFeatures)
+	for row := 0; row < d.Rows(); row++ {
+		for k := d.RowPtr[row]; k < d.RowPtr[row+1]; k++ {
+			col, value := d.ColIndex[k], d.Values[k]
+			nnz[col]++
+			rowsOf[col] = append(rowsOf[col], row)
+			if value < 0 {
+				negative[col] = true
+			}
+			maxValue[col] = math.Max(maxValue[col], value)
+		}
+	}
+
+	order := make([]int, d.NumFeatures)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return nnz[order[i]] > nnz[order[j]] })
+
+	var bundles []FeatureBundle
+	var used []map[int]bool // filas no nulas de cada grupo
+	var conflicts []int
+	var width []float64
+	for _, col := range order {
+		placed := false
+		if !negative[col] {
+			for g := range bundles {
+				if used[g] == nil {
+					continue // grupo de una feature negativa
+				}
+				overlap := 0
+				for _, row := range rowsOf[col] {
+					if used[g][row] {
+						overlap++
+					}
+				}
+				if conflicts[g]+overlap > maxConflicts {
+					continue
+				}
+				bundles[g].Features = append(bundles[g].Features, col)
+				bundles[g].Offsets = append(bundles[g].Offsets, width[g])
+				bundles[g].Max = append(bundles[g].Max, maxValue[col])
+				width[g] += maxValue[col]
+				conflicts[g] += overlap
+				for _, row := range rowsOf[col] {
+					used[g][row] = true
+				}
+				placed = true
+				break
+			}
+		}
+		if placed {
+			continue
+		}
+
+		bundles = append(bundles, FeatureBundle{Features: []int{col}, Offsets: []float64{0}, Max: []float64{maxValue[col]}})
+		conflicts = append(conflicts, 0)
+		width = append(width, maxValue[col])
+		if negative[col] {
+			used = append(used, nil)
+			continue
+		}
+		rows := make(map[int]bool, len(rowsOf[col]))
+		for _, row := range rowsOf[col] {
+			rows[row] = true
+		}
+		used = append(used, rows)
+	}
+	return bundles
+}
+
+// Bundler traduce filas dispersas a las columnas agrupadas de unos FeatureBundle. El
+// mapa de cada feature a su grupo se construye una sola vez, así que traducir una fila
+// solo cuesta sus entradas no nulas.
+type Bundler struct {
+	slots map[int]bundleSlot
+}
+
+type bundleSlot struct {
+	bundle      int
+	offset, max float64
+	clamp       bool // false en los modelos guardados sin Max
+}
+
+func NewBundler(bundles []FeatureBundle) *Bundler {
+	b := &Bundler{slots: make(map[int]bundleSlot)}
+	for g, bundle := range bundles {
+		for k, col := range bundle.Features {
+			s := bundleSlot{bundle: g, offset: bundle.Offsets[k]}
+			if k < len(bundle.Max) {
+				s.max, s.clamp = bundle.Max[k], true
+			}
+			b.slots[col] = s
+		}
+	}
+	return b
+}
+
+// Row traduce una fila dispersa a las columnas agrupadas, en orden creciente. Si dos
+// features del mismo grupo están presentes (un conflicto) se conserva la primera; las
+// features que no están en ningún grupo se descartan.
+func (b *Bundler) Row(cols []int, values []float64) ([]int, []float64) {
+	type entry struct {
+		col   int
+		value float64
+	}
+	var entries []entry
+	seen := make(map[int]bool)
+	for i, col := range cols {
+		s, ok := b.slots[col]
+		if !ok || seen[s.bundle] {
+			continue
+		}
+		seen[s.bundle] = true
+		value := values[i]
+		if s.clamp && value > s.max {
+			value = s.max
+		}
+		entries = append(entries, entry{s.bundle, s.offset + value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].col < entries[j].col })
+
+	outCols := make([]int, len(entries))
+	outValues := make([]float64, len(entries))
+	for i, e := range entries {
+		outCols[i], outValues[i] = e.col, e.value
+	}
+	return outCols, outValues
+}
+
+// BundleRow traduce una sola fila como Bundler.Row; para muchas filas conviene crear
+// el Bundler una vez
+func BundleRow(bundles []FeatureBundle, cols []int, values []float64) ([]int, []float64) {
+	return NewBundler(bundles).Row(cols, values)
+}
+
+// Bundle devuelve una copia del conjunto de datos con una columna por grupo
+func (d *SparseDataset[L]) Bundle(bundles []FeatureBundle) *SparseDataset[L] {
+	out := NewSparseDataset[L](len(bundles))
+	for _, bundle := range bundles {
+		names := make([]string, len(bundle.Features))
+		for k, col := range bundle.Features {
+			names[k] = d.FeatureNames[col]
+		}
+		out.FeatureNames = append(out.FeatureNames, strings.Join(names, "|"))
+	}
+	bundler := NewBundler(bundles)
+	for row := 0; row < d.Rows(); row++ {
+		cols, values := bundler.Row(d.ColIndex[d.RowPtr[row]:d.RowPtr[row+1]], d.Values[d.RowPtr[row]:d.RowPtr[row+1]])
+		// Las columnas de BundleRow son grupos distintos, así que AddRow no puede fallar
+		_ = out.AddRow(cols, values, d.Classes[row])
+	}
+	out.Weights = d.Weights
+	return out
+}
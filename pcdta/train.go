@@ -0,0 +1,1033 @@
+package pcdta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+type TrainOptions[L comparable] struct {
+	MaxDepth   int
+	NumWorkers int // goroutines de entrenamiento simultáneas; 0 = GOMAXPROCS
+
+	// CostMatrix[real][predicha] es el coste de predecir "predicha" cuando la clase es
+	// "real". Si se indica, las divisiones minimizan la impureza de Gini ponderada por
+	// costes y las hojas predicen la clase de menor coste esperado. Los pares que
+	// faltan cuestan 1 (0 en la diagonal).
+	CostMatrix map[L]map[L]float64
+
+	// Smoothing > 0 suaviza las probabilidades de las hojas para que las hojas pequeñas
+	// no den 0% o 100%. Con SmoothingMethod "laplace": (n_k + α) / (N + α·K); con
+	// "m-estimate": (n_k + m·prior_k) / (N + m), usando las frecuencias globales como prior.
+	// Cualquier otro valor se trata como "laplace".
+	Smoothing       float64
+	SmoothingMethod string
+
+	// ForbiddenFeatures son columnas que nunca se usan para dividir (p. ej. atributos
+	// protegidos). RootFeature, si no es nil, fuerza la división de la raíz sobre esa
+	// columna. Si la columna forzada no admite ningún umbral la raíz queda como hoja.
+	ForbiddenFeatures []int
+	RootFeature       *int
+
+	// Features, si no está vacío, es la lista de las únicas columnas que pueden usarse
+	// para dividir; el resto se ignora como si estuviera prohibido
+	Features []int
+
+	// Dos divisiones empatan si su impureza difiere menos de TieTolerance. Con TieBreak
+	// "lowest" gana la de menor columna y, dentro de ella, el menor umbral; con
+	// "balanced" gana la que reparte el peso de forma más equilibrada y, si sigue
+	// habiendo empate, se aplica "lowest". El árbol no depende del orden de los workers.
+	TieBreak     string
+	TieTolerance float64
+
+	// Las features cuya varianza en el conjunto de entrenamiento no supera
+	// MinFeatureVariance se omiten en toda la búsqueda de divisiones. Con 0 solo se
+	// omiten las columnas constantes.
+	MinFeatureVariance float64
+
+	// MemoryBudget limita en bytes la memoria estimada del entrenamiento (0 = sin
+	// límite). Si los datos no caben se entrena con una muestra equiespaciada de las
+	// filas, y la profundidad se limita a la que cabe en lo que queda según el tamaño de
+	// un nodo. La estimación depende solo de los datos y las opciones, no del heap, así
+	// que el árbol es reproducible.
+	MemoryBudget uint64
+}
+
+func DefaultTrainOptions[L comparable]() TrainOptions[L] {
+	return TrainOptions[L]{
+		MaxDepth:        MaxDepth,
+		NumWorkers:      runtime.GOMAXPROCS(0),
+		SmoothingMethod: "laplace",
+		TieBreak:        "lowest",
+		TieTolerance:    1e-12,
+	}
+}
+
+// Hyperparameters devuelve las opciones que afectan al modelo, para sus metadatos
+func (o TrainOptions[L]) Hyperparameters() map[string]any {
+	params := map[string]any{"max_depth": o.MaxDepth}
+	if o.CostMatrix != nil {
+		costs := make(map[string]map[string]float64)
+		for actual, row := range o.CostMatrix {
+			costs[fmt.Sprint(actual)] = make(map[string]float64)
+			for predicted, cost := range row {
+				costs[fmt.Sprint(actual)][fmt.Sprint(predicted)] = cost
+			}
+		}
+		params["cost_matrix"] = costs
+	}
+	if o.Smoothing > 0 {
+		params["smoothing"] = o.Smoothing
+		params["smoothing_method"] = o.SmoothingMethod
+	}
+	if len(o.ForbiddenFeatures) > 0 {
+		params["forbidden_features"] = o.ForbiddenFeatures
+	}
+	if o.RootFeature != nil {
+		params["root_feature"] = *o.RootFeature
+	}
+	if len(o.Features) > 0 {
+		params["features"] = o.Features
+	}
+	if o.TieBreak == "balanced" {
+		params["tie_break"] = o.TieBreak
+	}
+	if o.MinFeatureVariance > 0 {
+		params["min_feature_variance"] = o.MinFeatureVariance
+	}
+	if o.MemoryBudget > 0 {
+		params["memory_budget"] = o.MemoryBudget
+	}
+	return params
+}
+
+// cost devuelve el coste de predecir predicted cuando la clase real es actual
+func (o TrainOptions[L]) cost(actual, predicted L) float64 {
+	if c, ok := o.CostMatrix[actual][predicted]; ok {
+		return c
+	}
+	if actual == predicted {
+		return 0
+	}
+	return 1
+}
+
+// Por debajo de este número de filas no compensa repartir la partición entre workers
+const minRowsPerWorker = 4096
+
+// TrainReport resume un entrenamiento. Las fases Sort, SplitSearch y Partition
+// suman el tiempo de todos los workers, por lo que pueden superar a Total.
+type TrainReport struct {
+	Load          time.Duration
+	Sort          time.Duration
+	SplitSearch   time.Duration
+	Partition     time.Duration
+	Total         time.Duration
+	Nodes         int
+	Leaves        int
+	RowsProcessed int
+	RowsPerSecond float64
+	PeakHeapBytes uint64
+
+	// Features constantes o casi constantes que no se consideraron para dividir
+	SkippedFeatures []int
+
+	// Efecto de MemoryBudget: filas de la muestra usada (0 si se usaron todas) y
+	// nodos que quedaron como hoja por no caber en el presupuesto
+	SampledRows  int
+	BudgetLeaves int
+
+	// Lo que identifica al modelo entrenado, para Manifest. Rows y DatasetHash se
+	// refieren a los ejemplos con los que se entrenó, tras la muestra si la hubo.
+	Hyperparameters map[string]any
+	Rows            int
+	DatasetHash     string
+	TreeHash        string
+
+	// Reparto de las clases en los datos y su desequilibrio, antes de remuestrear o
+	// reponderar para que el aviso no desaparezca justo al corregirlo; el informe
+	// avisa si Imbalance supera ImbalanceThreshold (0 = sin aviso)
+	Classes            []ClassShare
+	Imbalance          float64
+	ImbalanceThreshold float64
+}
+
+// ImbalanceWarning indica si el desequilibrio de clases supera el umbral configurado
+func (r TrainReport) ImbalanceWarning() bool {
+	return r.ImbalanceThreshold > 0 && r.Imbalance > r.ImbalanceThreshold
+}
+
+// RunManifest recoge lo necesario para reproducir un entrenamiento exactamente: con
+// los mismos datos, opciones y semillas el árbol resultante debe tener TreeHash
+type RunManifest struct {
+	PackageVersion  string           `json:"package_version"`
+	GoVersion       string           `json:"go_version"`
+	OS              string           `json:"os"`
+	Arch            string           `json:"arch"`
+	NumCPU          int              `json:"num_cpu"`
+	CreatedAt       time.Time        `json:"created_at"`
+	Args            []string         `json:"args,omitempty"`
+	Data            string           `json:"data,omitempty"`
+	Seed            int64            `json:"seed,omitempty"`
+	Streams         map[string]int64 `json:"streams,omitempty"` // semilla derivada de cada flujo
+	Hyperparameters map[string]any   `json:"hyperparameters"`
+	DatasetHash     string           `json:"dataset_hash"`
+	Rows            int              `json:"rows"`
+	TreeHash        string           `json:"tree_hash"`
+
+	// Efecto de MemoryBudget en el árbol, como en TrainReport
+	SampledRows  int `json:"sampled_rows,omitempty"`
+	BudgetLeaves int `json:"budget_leaves,omitempty"`
+}
+
+// Manifest devuelve el manifiesto del entrenamiento con el entorno actual; quien
+// llama completa los campos que el entrenador no conoce (Args, Data, Seed, Streams)
+func (r TrainReport) Manifest() RunManifest {
+	return RunManifest{
+		PackageVersion:  PackageVersion,
+		GoVersion:       runtime.Version(),
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		NumCPU:          runtime.NumCPU(),
+		CreatedAt:       time.Now().UTC(),
+		Hyperparameters: r.Hyperparameters,
+		DatasetHash:     r.DatasetHash,
+		Rows:            r.Rows,
+		TreeHash:        r.TreeHash,
+		SampledRows:     r.SampledRows,
+		BudgetLeaves:    r.BudgetLeaves,
+	}
+}
+
+func (r TrainReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintln(&sb, "Tiempo de entrenamiento:", r.Total)
+	fmt.Fprintf(&sb, "  carga: %v, ordenación: %v, búsqueda de divisiones: %v, partición: %v\n",
+		r.Load, r.Sort, r.SplitSearch, r.Partition)
+	fmt.Fprintf(&sb, "  nodos: %d (hojas: %d), filas procesadas: %d (%.0f filas/s)\n",
+		r.Nodes, r.Leaves, r.RowsProcessed, r.RowsPerSecond)
+	if r.PeakHeapBytes > 0 {
+		fmt.Fprintf(&sb, "  memoria máxima en heap: %.1f MiB\n", float64(r.PeakHeapBytes)/(1<<20))
+	}
+	if len(r.SkippedFeatures) > 0 {
+		fmt.Fprintf(&sb, "  features constantes omitidas: %v\n", r.SkippedFeatures)
+	}
+	if r.SampledRows > 0 {
+		fmt.Fprintf(&sb, "  presupuesto de memoria: entrenado con una muestra de %d filas\n", r.SampledRows)
+	}
+	if r.BudgetLeaves > 0 {
+		fmt.Fprintf(&sb, "  presupuesto de memoria: %d nodos cerrados como hoja\n", r.BudgetLeaves)
+	}
+	if r.ImbalanceWarning() {
+		fmt.Fprintf(&sb, "  aviso: desequilibrio de clases %.1f:1 (umbral %g); para compensarlo, -class-weight balanced, -resample over|under|smote o -costs\n",
+			r.Imbalance, r.ImbalanceThreshold)
+		for _, c := range r.Classes {
+			bar := strings.Repeat("#", int(math.Round(40*c.Share)))
+			fmt.Fprintf(&sb, "    %-20s %-40s %6.1f%%\n", c.Class, bar, 100*c.Share)
+		}
+	}
+	return sb.String()
+}
+
+// trainCounters acumula las métricas de TrainReport desde varios workers
+type trainCounters struct {
+	sort, splitSearch, partition atomic.Int64
+	nodes, leaves, rows          atomic.Int64
+	budgetLeaves                 atomic.Int64
+}
+
+// treeBuilder limita la concurrencia del entrenamiento a NumWorkers goroutines.
+// Cada tarea nueva intenta tomar un token; si no hay ninguno libre se ejecuta en la
+// goroutine actual, así que el trabajo anidado nunca se bloquea esperando tokens.
+type treeBuilder[L comparable] struct {
+	ctx         context.Context // al cancelarse, los nodos pendientes quedan como hoja
+	opts        TrainOptions[L]
+	tokens      chan struct{}
+	counters    trainCounters
+	weighted    bool         // si los ejemplos tienen pesos, las hojas guardan Weights
+	skip        map[int]bool // features constantes o casi constantes
+	classes     []L          // clases de todos los ejemplos, candidatas de MinCostClass
+	budgetDepth int          // profundidad a partir de la cual MemoryBudget cierra los nodos; -1 sin límite
+
+	// Construcción densa: los ejemplos de la raíz con el índice de clase y el peso de
+	// cada fila precalculados; los nodos se refieren a las filas por su índice
+	examples   []Example[L]
+	labels     []int
+	weights    []float64
+	numClasses int
+	costs      [][]float64
+	goesLeft   []bool // lado de cada fila en la última partición de su nodo
+}
+
+// nodeRows son las filas de un nodo en su orden original y, para cada feature,
+// ordenadas por su valor con los ausentes al final (nil si la feature se omite)
+type nodeRows struct {
+	rows   []int
+	sorted [][]int
+}
+
+// newTreeBuilder crea el constructor y marca las features que se omiten
+func newTreeBuilder[L comparable](examples []Example[L], opts TrainOptions[L]) (*treeBuilder[L], []int) {
+	skipped := lowVarianceFeatures(examples, opts.MinFeatureVariance)
+	return newBuilder(opts, weighted(examples), skipped, examples), skipped
+}
+
+// newBuilder es la parte común de los constructores denso y disperso: skipped son las
+// features que se omiten y labels los ejemplos de la raíz, de los que solo se usan la
+// clase y el peso
+func newBuilder[L comparable](opts TrainOptions[L], weighted bool, skipped []int, labels []Example[L]) *treeBuilder[L] {
+	if opts.NumWorkers <= 0 {
+		opts.NumWorkers = runtime.GOMAXPROCS(0)
+	}
+
+	// La goroutine que llama cuenta como uno de los workers
+	b := &treeBuilder[L]{
+		ctx:         context.Background(),
+		opts:        opts,
+		tokens:      make(chan struct{}, opts.NumWorkers-1),
+		weighted:    weighted,
+		skip:        make(map[int]bool, len(skipped)),
+		budgetDepth: -1,
+	}
+	for _, col := range skipped {
+		b.skip[col] = true
+	}
+	if opts.CostMatrix != nil {
+		for class := range ClassWeights(labels) {
+			b.classes = append(b.classes, class)
+		}
+	}
+	return b
+}
+
+// sampleHeap muestrea la memoria en uso cada 10 ms hasta que se llama a la función
+// devuelta, que da el máximo observado; solo sirve para el informe
+func sampleHeap() func() uint64 {
+	var peak uint64
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		var mem runtime.MemStats
+		for {
+			runtime.ReadMemStats(&mem)
+			if mem.HeapAlloc > peak {
+				peak = mem.HeapAlloc
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() uint64 {
+		close(done)
+		<-sampled
+		return peak
+	}
+}
+
+func BuildDecisionTreeConcurrent[L comparable](examples []Example[L], opts TrainOptions[L]) (*DecisionTree[L], TrainReport) {
+	return buildDecisionTree(context.Background(), examples, opts)
+}
+
+// BuildDecisionTreeContext es BuildDecisionTreeConcurrent con cancelación: si ctx
+// termina antes que el árbol, deja de dividir nodos y devuelve ctx.Err()
+func BuildDecisionTreeContext[L comparable](ctx context.Context, examples []Example[L], opts TrainOptions[L]) (*DecisionTree[L], TrainReport, error) {
+	tree, report := buildDecisionTree(ctx, examples, opts)
+	if err := ctx.Err(); err != nil {
+		return nil, report, err
+	}
+	return tree, report, nil
+}
+
+func buildDecisionTree[L comparable](ctx context.Context, examples []Example[L], opts TrainOptions[L]) (*DecisionTree[L], TrainReport) {
+	b, skipped := newTreeBuilder(examples, opts)
+	b.ctx = ctx
+	opts = b.opts
+
+	sampledRows := 0
+	if opts.MemoryBudget > 0 {
+		if sample := budgetSample(examples, opts.MemoryBudget); len(sample) < len(examples) {
+			examples, sampledRows = sample, len(sample)
+		}
+		b.budgetDepth = budgetDepth(examples, opts.MemoryBudget, opts.MaxDepth)
+	}
+
+	stopSampling := sampleHeap()
+	start := time.Now()
+	tree := b.build(b.index(examples), 0)
+	if opts.Smoothing > 0 {
+		SmoothLeaves(tree, ClassWeights(examples), opts.Smoothing, opts.SmoothingMethod)
+	}
+	total := time.Since(start)
+	peak := stopSampling()
+
+	c := &b.counters
+	report := TrainReport{
+		Sort:          time.Duration(c.sort.Load()),
+		SplitSearch:   time.Duration(c.splitSearch.Load()),
+		Partition:     time.Duration(c.partition.Load()),
+		Total:         total,
+		Nodes:         int(c.nodes.Load()),
+		Leaves:        int(c.leaves.Load()),
+		RowsProcessed: int(c.rows.Load()),
+		PeakHeapBytes: peak,
+
+		SkippedFeatures: skipped,
+		SampledRows:     sampledRows,
+		BudgetLeaves:    int(c.budgetLeaves.Load()),
+
+		Hyperparameters: opts.Hyperparameters(),
+		Rows:            len(examples),
+		DatasetHash:     DatasetHash(examples),
+		TreeHash:        TreeHash(tree),
+	}
+	if total > 0 {
+		report.RowsPerSecond = float64(report.RowsProcessed) / total.Seconds()
+	}
+
+	return tree, report
+}
+
+func (b *treeBuilder[L]) leaf(examples []Example[L]) *DecisionTree[L] {
+	b.counters.nodes.Add(1)
+	b.counters.leaves.Add(1)
+
+	leaf := NewLeaf(examples)
+	if b.weighted {
+		leaf.Weights = ClassWeights(examples)
+	}
+	if b.opts.CostMatrix != nil && len(examples) > 0 {
+		leaf.Class = MinCostClass(ClassWeights(examples), b.classes, b.opts.cost)
+	}
+	return leaf
+}
+
+// spawn ejecuta fn en otra goroutine si hay un worker libre, o en la actual si no
+func (b *treeBuilder[L]) spawn(wg *sync.WaitGroup, fn func()) {
+	wg.Add(1)
+	select {
+	case b.tokens <- struct{}{}:
+		go func() {
+			defer wg.Done()
+			defer func() { <-b.tokens }()
+			fn()
+		}()
+	default:
+		fn()
+		wg.Done()
+	}
+}
+
+// parallel divide [0, n) en bloques contiguos, uno por worker, y llama a fn(i) para cada i
+func (b *treeBuilder[L]) parallel(n int, fn func(i int)) {
+	blocks := b.opts.NumWorkers
+	if blocks > n {
+		blocks = n
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < blocks; w++ {
+		start, end := w*n/blocks, (w+1)*n/blocks
+		b.spawn(&wg, func() {
+			for i := start; i < end; i++ {
+				fn(i)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// Filas mínimas de la muestra de budgetSample, aunque no quepan en el presupuesto
+const minBudgetRows = 1000
+
+// budgetRowBytes estima la memoria de entrenamiento por fila: las listas ordenadas de
+// la raíz y de un nivel de hijos, más el índice de clase, el peso y el lado de la fila
+func budgetRowBytes[L comparable](examples []Example[L]) uint64 {
+	return uint64(2*len(examples[0].Features)*int(unsafe.Sizeof(int(0))) + 8 + 8 + 1 + int(unsafe.Sizeof(int(0))))
+}
+
+// budgetSample devuelve, si las filas no caben en el presupuesto según budgetRowBytes,
+// una muestra equiespaciada de las que sí caben. Equiespaciada conserva las
+// proporciones de datos ordenados por clase.
+func budgetSample[L comparable](examples []Example[L], budget uint64) []Example[L] {
+	if len(examples) == 0 {
+		return examples
+	}
+	rows := len(examples)
+	if fit := budget / budgetRowBytes(examples); fit < uint64(rows) {
+		rows = int(fit)
+	}
+	if rows < minBudgetRows {
+		rows = minBudgetRows
+	}
+	if rows >= len(examples) {
+		return examples
+	}
+
+	sample := make([]Example[L], rows)
+	for i := range sample {
+		sample[i] = examples[i*len(examples)/rows]
+	}
+	return sample
+}
+
+// Bytes de la cabecera de un mapa de conteos, aparte de sus entradas
+const budgetMapBytes = 48
+
+// budgetDepth devuelve la mayor profundidad con la que cualquier árbol cabe en lo que
+// queda de budget tras las filas: uno completo de profundidad d tiene 2^(d+1)-1 nodos,
+// cada uno con su mapa de conteos. Como no depende del orden en que los workers crean
+// los nodos, limita igual en cada ejecución. Devuelve -1 si no limita maxDepth.
+func budgetDepth[L comparable](examples []Example[L], budget uint64, maxDepth int) int {
+	var data uint64
+	if len(examples) > 0 {
+		data = uint64(len(examples)) * budgetRowBytes(examples)
+	}
+	if data >= budget {
+		return 0
+	}
+	var label L
+	entry := uint64(unsafe.Sizeof(label) + unsafe.Sizeof(int(0)))
+	node := uint64(unsafe.Sizeof(DecisionTree[L]{})) + budgetMapBytes + uint64(len(ClassCounts(examples)))*entry
+	nodes := (budget - data) / node
+
+	depth := 0
+	for depth < maxDepth && depth < 62 && uint64(1)<<(depth+2)-1 <= nodes {
+		depth++
+	}
+	if depth >= maxDepth {
+		return -1
+	}
+	return depth
+}
+
+// index prepara la raíz: asigna un índice entero a cada clase para contar en slices
+// planos y ordena una sola vez las filas por cada feature. Los hijos heredan esas
+// listas ya ordenadas al partir, así que ningún nodo vuelve a ordenar.
+func (b *treeBuilder[L]) index(examples []Example[L]) nodeRows {
+	b.examples = examples
+	b.labels = make([]int, len(examples))
+	b.weights = make([]float64, len(examples))
+	b.goesLeft = make([]bool, len(examples))
+	classIndex := make(map[L]int)
+	for i, example := range examples {
+		index, ok := classIndex[example.Class]
+		if !ok {
+			index = len(classIndex)
+			classIndex[example.Class] = index
+		}
+		b.labels[i] = index
+		b.weights[i] = example.SampleWeight()
+	}
+	b.numClasses = len(classIndex)
+	b.costs = b.costMatrix(classIndex)
+
+	node := nodeRows{rows: make([]int, len(examples))}
+	for i := range node.rows {
+		node.rows[i] = i
+	}
+	if len(examples) == 0 {
+		return node
+	}
+
+	sortStart := time.Now()
+	node.sorted = make([][]int, len(examples[0].Features))
+	b.parallel(len(node.sorted), func(col int) {
+		if b.skip[col] {
+			return
+		}
+		order := append([]int(nil), node.rows...)
+		// Los valores ausentes (NaN) quedan al final y nunca van a la izquierda
+		sort.Slice(order, func(i, j int) bool {
+			a, b := examples[order[i]].Features[col], examples[order[j]].Features[col]
+			return a < b || (!math.IsNaN(a) && math.IsNaN(b))
+		})
+		node.sorted[col] = order
+	})
+	b.counters.sort.Add(int64(time.Since(sortStart)))
+	return node
+}
+
+// rowExamples devuelve los ejemplos de las filas indicadas, para las hojas
+func (b *treeBuilder[L]) rowExamples(rows []int) []Example[L] {
+	examples := make([]Example[L], len(rows))
+	for i, row := range rows {
+		examples[i] = b.examples[row]
+	}
+	return examples
+}
+
+func (b *treeBuilder[L]) build(node nodeRows, depth int) *DecisionTree[L] {
+	// Si no hay ejemplos, se alcanza la profundidad máxima o se canceló el entrenamiento, devuelve un nodo hoja con la clase mayoritaria
+	if len(node.rows) == 0 || depth >= b.opts.MaxDepth || b.ctx.Err() != nil {
+		return b.leaf(b.rowExamples(node.rows))
+	}
+
+	// Más allá de la profundidad que cabe en el presupuesto no se sigue creciendo
+	if b.budgetDepth >= 0 && depth >= b.budgetDepth {
+		b.counters.budgetLeaves.Add(1)
+		return b.leaf(b.rowExamples(node.rows))
+	}
+
+	// Encontrar la mejor división de forma concurrente
+	b.counters.rows.Add(int64(len(node.rows)))
+	bestSplit := b.findBestSplit(node, depth)
+
+	// Si no se encuentra la mejor división, devuelve un nodo hoja con la clase mayoritaria
+	if bestSplit == nil {
+		return b.leaf(b.rowExamples(node.rows))
+	}
+	b.counters.nodes.Add(1)
+
+	// Dividir las filas
+	partitionStart := time.Now()
+	leftRows, rightRows := b.partition(node, bestSplit.Column, bestSplit.Value)
+	b.counters.partition.Add(int64(time.Since(partitionStart)))
+
+	// Construir recursivamente los subárboles; el izquierdo en otro worker si hay uno libre
+	var wg sync.WaitGroup
+	var left *DecisionTree[L]
+
+	b.spawn(&wg, func() {
+		left = b.build(leftRows, depth+1)
+	})
+	right := b.build(rightRows, depth+1)
+
+	wg.Wait()
+
+	return &DecisionTree[L]{
+		Left:   left,
+		Right:  right,
+		Column: bestSplit.Column,
+		Value:  bestSplit.Value,
+	}
+}
+
+// partition separa las filas según el umbral en tiempo lineal: marca el lado de cada
+// fila y reparte cada lista ordenada conservando su orden, de modo que los hijos ya
+// reciben sus filas ordenadas por cada feature. En nodos grandes cada worker parte
+// las listas de un bloque de features.
+func (b *treeBuilder[L]) partition(node nodeRows, column int, value float64) (nodeRows, nodeRows) {
+	numLeft := 0
+	for _, row := range node.rows {
+		b.goesLeft[row] = b.examples[row].Features[column] <= value
+		if b.goesLeft[row] {
+			numLeft++
+		}
+	}
+
+	split := func(rows []int) (left, right []int) {
+		left, right = make([]int, 0, numLeft), make([]int, 0, len(rows)-numLeft)
+		for _, row := range rows {
+			if b.goesLeft[row] {
+				left = append(left, row)
+			} else {
+				right = append(right, row)
+			}
+		}
+		return left, right
+	}
+
+	var left, right nodeRows
+	left.rows, right.rows = split(node.rows)
+	left.sorted = make([][]int, len(node.sorted))
+	right.sorted = make([][]int, len(node.sorted))
+	splitColumn := func(col int) {
+		if node.sorted[col] != nil {
+			left.sorted[col], right.sorted[col] = split(node.sorted[col])
+		}
+	}
+	if len(node.rows) < minRowsPerWorker {
+		for col := range node.sorted {
+			splitColumn(col)
+		}
+	} else {
+		b.parallel(len(node.sorted), splitColumn)
+	}
+	return left, right
+}
+
+// TreeCheckpoint es un árbol a medio construir: Tree ya es utilizable, con los nodos
+// pendientes como hojas provisionales, y Pending guarda lo necesario para seguir
+// dividiéndolos. El hash de los datos y los hiperparámetros impiden reanudar con
+// otros datos u otras opciones.
+type TreeCheckpoint[L comparable] struct {
+	DatasetHash     string           `json:"dataset_hash"`
+	Hyperparameters json.RawMessage  `json:"hyperparameters"`
+	Tree            *DecisionTree[L] `json:"tree"`
+	Pending         []PendingNode    `json:"pending"`
+}
+
+// PendingNode identifica un nodo por su camino desde la raíz ("L" y "R"; "" es la
+// raíz) y guarda su profundidad y sus filas, como índices de los ejemplos
+type PendingNode struct {
+	Path  string `json:"path"`
+	Depth int    `json:"depth"`
+	Rows  []int  `json:"rows"`
+}
+
+// BuildDecisionTreeCheckpointed construye el árbol nodo a nodo en anchura y guarda
+// un TreeCheckpoint en checkpointFile cada vez que pasa every. Si el archivo existe
+// al empezar, reanuda desde él. Al cancelar ctx guarda el punto de control y devuelve
+// ctx.Err(); al terminar lo borra. La búsqueda de cada división sigue repartida
+// entre workers, pero los nodos se dividen de uno en uno. MemoryBudget no submuestrea
+// aquí, porque las filas pendientes se refieren a los índices de todos los ejemplos.
+func BuildDecisionTreeCheckpointed[L comparable](ctx context.Context, examples []Example[L], opts TrainOptions[L], checkpointFile string, every time.Duration) (*DecisionTree[L], TrainReport, error) {
+	b, skipped := newTreeBuilder(examples, opts)
+	hyperparameters, err := json.Marshal(opts.Hyperparameters())
+	if err != nil {
+		return nil, TrainReport{}, err
+	}
+	hash := DatasetHash(examples)
+
+	start := time.Now()
+	root := b.index(examples)
+
+	type pending struct {
+		node  *DecisionTree[L]
+		path  string
+		depth int
+		rows  nodeRows
+	}
+	var queue []pending
+	var tree *DecisionTree[L]
+
+	data, err := os.ReadFile(checkpointFile)
+	switch {
+	case err == nil:
+		var checkpoint TreeCheckpoint[L]
+		if err := json.Unmarshal(data, &checkpoint); err != nil {
+			return nil, TrainReport{}, fmt.Errorf("%s: %w", checkpointFile, err)
+		}
+		if checkpoint.DatasetHash != hash {
+			return nil, TrainReport{}, fmt.Errorf("%s: el punto de control es de otros datos", checkpointFile)
+		}
+		if !bytes.Equal(checkpoint.Hyperparameters, hyperparameters) {
+			return nil, TrainReport{}, fmt.Errorf("%s: el punto de control usa otras opciones: %s", checkpointFile, checkpoint.Hyperparameters)
+		}
+		tree = checkpoint.Tree
+
+		// Repartir las listas ordenadas de la raíz entre los nodos pendientes conserva
+		// su orden sin volver a ordenar
+		owner := make([]int, len(examples))
+		for i := range owner {
+			owner[i] = -1
+		}
+		for k, p := range checkpoint.Pending {
+			node := tree
+			for _, step := range p.Path {
+				if node == nil {
+					break
+				}
+				if step == 'L' {
+					node = node.Left
+				} else {
+					node = node.Right
+				}
+			}
+			if node == nil || !node.IsLeaf() {
+				return nil, TrainReport{}, fmt.Errorf("%s: el camino %q no lleva a una hoja", checkpointFile, p.Path)
+			}
+			for _, row := range p.Rows {
+				if row < 0 || row >= len(examples) || owner[row] >= 0 {
+					return nil, TrainReport{}, fmt.Errorf("%s: fila %d no válida en %q", checkpointFile, row, p.Path)
+				}
+				owner[row] = k
+			}
+			queue = append(queue, pending{node: node, path: p.Path, depth: p.Depth,
+				rows: nodeRows{rows: p.Rows, sorted: make([][]int, len(root.sorted))}})
+		}
+		for col, order := range root.sorted {
+			if order == nil {
+				continue
+			}
+			for _, row := range order {
+				if k := owner[row]; k >= 0 {
+					queue[k].rows.sorted[col] = append(queue[k].rows.sorted[col], row)
+				}
+			}
+		}
+	case errors.Is(err, os.ErrNotExist):
+		tree = NewLeaf(examples)
+		queue = append(queue, pending{node: tree, depth: 0, rows: root})
+	default:
+		return nil, TrainReport{}, err
+	}
+
+	save := func() error {
+		checkpoint := TreeCheckpoint[L]{DatasetHash: hash, Hyperparameters: hyperparameters, Tree: tree}
+		for _, p := range queue {
+			checkpoint.Pending = append(checkpoint.Pending, PendingNode{Path: p.path, Depth: p.depth, Rows: p.rows.rows})
+		}
+		data, err := json.Marshal(checkpoint)
+		if err != nil {
+			return err
+		}
+		// Escribir aparte y renombrar, para no dejar un punto de control a medias
+		tmp := checkpointFile + ".tmp"
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			return err
+		}
+		return os.Rename(tmp, checkpointFile)
+	}
+
+	lastSave := time.Now()
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			if saveErr := save(); saveErr != nil {
+				return nil, TrainReport{}, saveErr
+			}
+			return tree, TrainReport{}, err
+		}
+		if every > 0 && time.Since(lastSave) >= every {
+			if err := save(); err != nil {
+				return nil, TrainReport{}, err
+			}
+			lastSave = time.Now()
+		}
+
+		p := queue[0]
+		queue = queue[1:]
+		var split *DecisionTree[L]
+		if len(p.rows.rows) > 0 && p.depth < b.opts.MaxDepth {
+			b.counters.rows.Add(int64(len(p.rows.rows)))
+			split = b.findBestSplit(p.rows, p.depth)
+		}
+		if split == nil {
+			*p.node = *b.leaf(b.rowExamples(p.rows.rows))
+			continue
+		}
+		b.counters.nodes.Add(1)
+
+		partitionStart := time.Now()
+		leftRows, rightRows := b.partition(p.rows, split.Column, split.Value)
+		b.counters.partition.Add(int64(time.Since(partitionStart)))
+
+		// Los hijos son hojas provisionales hasta que les toque el turno
+		left, right := NewLeaf(b.rowExamples(leftRows.rows)), NewLeaf(b.rowExamples(rightRows.rows))
+		*p.node = DecisionTree[L]{Left: left, Right: right, Column: split.Column, Value: split.Value}
+		queue = append(queue,
+			pending{node: left, path: p.path + "L", depth: p.depth + 1, rows: leftRows},
+			pending{node: right, path: p.path + "R", depth: p.depth + 1, rows: rightRows})
+	}
+
+	if opts.Smoothing > 0 {
+		SmoothLeaves(tree, ClassWeights(examples), opts.Smoothing, opts.SmoothingMethod)
+	}
+	if err := os.Remove(checkpointFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, TrainReport{}, err
+	}
+
+	c := &b.counters
+	report := TrainReport{
+		Sort:            time.Duration(c.sort.Load()),
+		SplitSearch:     time.Duration(c.splitSearch.Load()),
+		Partition:       time.Duration(c.partition.Load()),
+		Total:           time.Since(start),
+		Nodes:           int(c.nodes.Load()),
+		Leaves:          int(c.leaves.Load()),
+		RowsProcessed:   int(c.rows.Load()),
+		SkippedFeatures: skipped,
+
+		Hyperparameters: opts.Hyperparameters(),
+		Rows:            len(examples),
+		DatasetHash:     hash,
+		TreeHash:        TreeHash(tree),
+	}
+	if report.Total > 0 {
+		report.RowsPerSecond = float64(report.RowsProcessed) / report.Total.Seconds()
+	}
+	return tree, report, nil
+}
+
+// lowVarianceFeatures devuelve las columnas cuya varianza (sin contar valores ausentes)
+// no supera minVariance; una columna sin ningún valor presente también cuenta
+func lowVarianceFeatures[L comparable](examples []Example[L], minVariance float64) []int {
+	if len(examples) == 0 {
+		return nil
+	}
+
+	var low []int
+	for col := range examples[0].Features {
+		var sum, sumSq float64
+		n := 0
+		first, constant := math.NaN(), true
+		for _, example := range examples {
+			value := example.Features[col]
+			if math.IsNaN(value) {
+				continue
+			}
+			if n == 0 {
+				first = value
+			} else if value != first {
+				constant = false
+			}
+			sum += value
+			sumSq += value * value
+			n++
+		}
+		// Las columnas exactamente constantes se detectan sin depender del redondeo de la varianza
+		if constant {
+			low = append(low, col)
+			continue
+		}
+		mean := sum / float64(n)
+		if sumSq/float64(n)-mean*mean <= minVariance {
+			low = append(low, col)
+		}
+	}
+	return low
+}
+
+// allowed indica si la columna puede usarse para dividir a esa profundidad
+func (b *treeBuilder[L]) allowed(col, depth int) bool {
+	if b.skip[col] {
+		return false
+	}
+	for _, forbidden := range b.opts.ForbiddenFeatures {
+		if col == forbidden {
+			return false
+		}
+	}
+	if len(b.opts.Features) > 0 && !slices.Contains(b.opts.Features, col) {
+		return false
+	}
+	// La raíz forzada no salta la selección ni las prohibiciones: si no está
+	// permitida, la raíz queda como hoja
+	if depth == 0 && b.opts.RootFeature != nil {
+		return col == *b.opts.RootFeature
+	}
+	return true
+}
+
+// better decide si un candidato supera al mejor actual. Los candidatos llegan en
+// orden de umbral y de columna crecientes, así que ante un empate conservar el
+// actual equivale a quedarse con el menor.
+func (b *treeBuilder[L]) better(gini, balance, bestGini, bestBalance float64) bool {
+	if gini < bestGini-b.opts.TieTolerance {
+		return true
+	}
+	if gini > bestGini+b.opts.TieTolerance {
+		return false
+	}
+	return b.opts.TieBreak == "balanced" && balance < bestBalance
+}
+
+// costMatrix devuelve la matriz de costes indexada por los índices de clase de un
+// nodo, o nil si se entrena sin costes
+func (b *treeBuilder[L]) costMatrix(classIndex map[L]int) [][]float64 {
+	if b.opts.CostMatrix == nil {
+		return nil
+	}
+	costs := make([][]float64, len(classIndex))
+	for actual, a := range classIndex {
+		costs[a] = make([]float64, len(classIndex))
+		for predicted, p := range classIndex {
+			costs[a][p] = b.opts.cost(actual, predicted)
+		}
+	}
+	return costs
+}
+
+// findBestSplit recorre cada feature en el orden heredado del nodo padre
+func (b *treeBuilder[L]) findBestSplit(node nodeRows, depth int) *DecisionTree[L] {
+	numExamples := len(node.rows)
+	if numExamples == 0 {
+		return nil
+	}
+
+	type SplitResult struct {
+		Split   *DecisionTree[L]
+		Gini    float64
+		Balance float64 // |peso izquierdo - peso derecho| / peso total
+	}
+
+	results := make([]SplitResult, len(node.sorted))
+
+	b.parallel(len(node.sorted), func(col int) {
+		if node.sorted[col] == nil || !b.allowed(col, depth) {
+			results[col] = SplitResult{Gini: math.Inf(1)}
+			return
+		}
+		searchStart := time.Now()
+		order := node.sorted[col]
+
+		// Todos los ejemplos empiezan a la derecha y se mueven uno a uno a la izquierda,
+		// acumulando sus pesos por clase
+		leftClasses := make([]float64, b.numClasses)
+		rightClasses := make([]float64, b.numClasses)
+		var leftWeight, rightWeight float64
+		for _, row := range order {
+			rightClasses[b.labels[row]] += b.weights[row]
+			rightWeight += b.weights[row]
+		}
+
+		best := SplitResult{Gini: math.Inf(1)}
+		for i := 1; i < numExamples; i++ {
+			prev, value := b.examples[order[i-1]].Features[col], b.examples[order[i]].Features[col]
+			if math.IsNaN(value) {
+				break
+			}
+
+			label, weight := b.labels[order[i-1]], b.weights[order[i-1]]
+			leftClasses[label] += weight
+			rightClasses[label] -= weight
+			leftWeight += weight
+			rightWeight -= weight
+
+			// Solo hay un umbral válido entre valores distintos
+			if prev == value {
+				continue
+			}
+
+			// Calcular impureza de Gini, ponderada por costes si hay matriz
+			var gini float64
+			if b.costs != nil {
+				gini = CalculateCostGini(leftClasses, rightClasses, leftWeight, rightWeight, b.costs)
+			} else {
+				gini = CalculateGini(leftClasses, rightClasses, leftWeight, rightWeight)
+			}
+
+			// Actualizar mejor división si es mejor, probando en el punto medio
+			balance := math.Abs(leftWeight-rightWeight) / (leftWeight + rightWeight)
+			if b.better(gini, balance, best.Gini, best.Balance) {
+				best.Gini = gini
+				best.Balance = balance
+				best.Split = &DecisionTree[L]{
+					Column: col,
+					Value:  (prev + value) / 2.0,
+				}
+			}
+		}
+
+		results[col] = best
+		b.counters.splitSearch.Add(int64(time.Since(searchStart)))
+	})
+
+	// Quedarse con el mejor resultado de todas las features
+	best := SplitResult{Gini: math.Inf(1)}
+	for _, result := range results {
+		if result.Split != nil && b.better(result.Gini, result.Balance, best.Gini, best.Balance) {
+			best = result
+		}
+	}
+
+	return best.Split
+}
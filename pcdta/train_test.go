@@ -0,0 +1,263 @@
+package pcdta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// separable devuelve ejemplos en los que la columna 1 separa las clases y las
+// columnas 0 y 2 son ruido
+func separable(n int, seed int64) []Example[string] {
+	rng := rand.New(rand.NewSource(seed))
+	examples := make([]Example[string], n)
+	for i := range examples {
+		class := "a"
+		if i%2 == 1 {
+			class = "b"
+		}
+		signal := rng.Float64()
+		if class == "b" {
+			signal += 2
+		}
+		examples[i] = Example[string]{Features: []float64{rng.Float64(), signal, rng.Float64()}, Class: class}
+	}
+	return examples
+}
+
+func TestTrainOptionsLiteralLeavesRootFree(t *testing.T) {
+	examples := separable(200, 1)
+	tree, _ := BuildDecisionTreeConcurrent(examples, TrainOptions[string]{MaxDepth: 2})
+	if tree.Column != 1 {
+		t.Fatalf("la raíz divide por la columna %d; un TrainOptions literal no debería forzarla", tree.Column)
+	}
+
+	root := 0
+	tree, _ = BuildDecisionTreeConcurrent(examples, TrainOptions[string]{MaxDepth: 2, RootFeature: &root})
+	if tree.Column != 0 {
+		t.Fatalf("la raíz forzada a la columna 0 divide por la %d", tree.Column)
+	}
+	if tree.Left.IsLeaf() && tree.Right.IsLeaf() {
+		t.Fatal("por debajo de la raíz forzada el árbol debería seguir dividiendo")
+	}
+}
+
+func TestBuildDecisionTreeIsDeterministicAcrossWorkers(t *testing.T) {
+	examples := separable(300, 2)
+	opts := DefaultTrainOptions[string]()
+	opts.MaxDepth = 5
+	opts.NumWorkers = 1
+	single, _ := BuildDecisionTreeConcurrent(examples, opts)
+	opts.NumWorkers = 8
+	parallel, _ := BuildDecisionTreeConcurrent(examples, opts)
+	if TreeHash(single) != TreeHash(parallel) {
+		t.Fatal("el árbol depende del número de workers")
+	}
+	if Accuracy(single, examples) != 1 {
+		t.Errorf("precisión de entrenamiento %.3f en datos separables", Accuracy(single, examples))
+	}
+}
+
+func TestForbiddenFeaturesAreNeverUsed(t *testing.T) {
+	examples := separable(200, 3)
+	opts := DefaultTrainOptions[string]()
+	opts.ForbiddenFeatures = []int{1}
+	tree, _ := BuildDecisionTreeConcurrent(examples, opts)
+	tree.Walk(func(node *DecisionTree[string], depth int) {
+		if !node.IsLeaf() && node.Column == 1 {
+			t.Fatalf("nodo de profundidad %d divide por una columna prohibida", depth)
+		}
+	})
+}
+
+func TestForcedRootRespectsForbiddenAndSelection(t *testing.T) {
+	examples := separable(200, 4)
+	root := 0
+
+	forbidden := TrainOptions[string]{MaxDepth: 2, RootFeature: &root, ForbiddenFeatures: []int{0}}
+	if tree, _ := BuildDecisionTreeConcurrent(examples, forbidden); !tree.IsLeaf() {
+		t.Errorf("una raíz forzada sobre una columna prohibida divide por la columna %d", tree.Column)
+	}
+
+	unselected := TrainOptions[string]{MaxDepth: 2, RootFeature: &root, Features: []int{1, 2}}
+	if tree, _ := BuildDecisionTreeConcurrent(examples, unselected); !tree.IsLeaf() {
+		t.Errorf("una raíz forzada fuera de la selección divide por la columna %d", tree.Column)
+	}
+}
+
+func TestMemoryBudgetLimitsDepthDeterministically(t *testing.T) {
+	// Clases al azar: sin presupuesto el árbol llega a la profundidad máxima
+	rng := rand.New(rand.NewSource(3))
+	examples := make([]Example[string], 1000)
+	for i := range examples {
+		examples[i] = Example[string]{Features: []float64{rng.Float64(), rng.Float64()}, Class: []string{"a", "b"}[rng.Intn(2)]}
+	}
+	opts := DefaultTrainOptions[string]()
+	opts.MaxDepth = 8
+
+	// Lo justo para los datos y un árbol completo de profundidad 2
+	var label string
+	node := uint64(unsafe.Sizeof(DecisionTree[string]{})) + budgetMapBytes + 2*uint64(unsafe.Sizeof(label)+unsafe.Sizeof(int(0)))
+	opts.MemoryBudget = uint64(len(examples))*budgetRowBytes(examples) + 7*node
+	if depth := budgetDepth(examples, opts.MemoryBudget, opts.MaxDepth); depth != 2 {
+		t.Fatalf("budgetDepth = %d, se esperaba 2", depth)
+	}
+
+	first, report := BuildDecisionTreeConcurrent(examples, opts)
+	if report.BudgetLeaves == 0 || report.Manifest().BudgetLeaves != report.BudgetLeaves {
+		t.Errorf("BudgetLeaves = %d, manifiesto %d", report.BudgetLeaves, report.Manifest().BudgetLeaves)
+	}
+	first.Walk(func(n *DecisionTree[string], depth int) {
+		if depth > 2 {
+			t.Fatalf("nodo a profundidad %d con presupuesto para 2", depth)
+		}
+	})
+	opts.NumWorkers = 8
+	if second, _ := BuildDecisionTreeConcurrent(examples, opts); TreeHash(first) != TreeHash(second) {
+		t.Error("con presupuesto el árbol cambia entre ejecuciones")
+	}
+}
+
+func TestBuildSparseTreeSkipsLowVarianceFeatures(t *testing.T) {
+	examples := separable(200, 4)
+	sparse := NewSparseDataset[string](4)
+	for i := range examples {
+		// La columna 3 vale 7 en todas las filas
+		examples[i].Features = append(examples[i].Features, 7)
+		if err := sparse.AddRow([]int{0, 1, 2, 3}, examples[i].Features, examples[i].Class); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := DefaultTrainOptions[string]()
+	opts.MinFeatureVariance = 0.1 // el ruido uniforme tiene varianza 1/12
+	_, dense := BuildDecisionTreeConcurrent(examples, opts)
+	tree, report := BuildSparseTree(sparse, opts)
+	if !slices.Equal(report.SkippedFeatures, []int{0, 2, 3}) || !slices.Equal(report.SkippedFeatures, dense.SkippedFeatures) {
+		t.Errorf("features omitidas %v, el constructor denso omite %v", report.SkippedFeatures, dense.SkippedFeatures)
+	}
+	tree.Walk(func(node *DecisionTree[string], depth int) {
+		if !node.IsLeaf() && node.Column != 1 {
+			t.Errorf("el árbol disperso divide por la feature omitida %d", node.Column)
+		}
+	})
+	if report.PeakHeapBytes == 0 {
+		t.Error("el informe disperso no muestrea la memoria")
+	}
+}
+
+func TestBundlerClampsAndModelScoresRawRows(t *testing.T) {
+	// Las features 0 y 1 nunca coinciden: van al mismo grupo
+	d := NewSparseDataset[string](2)
+	for i := 0; i < 20; i++ {
+		col, class := i%2, []string{"a", "b"}[i%2]
+		if err := d.AddRow([]int{col}, []float64{1 + float64(i%3)}, class); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.FeatureNames = []string{"f1", "f2"}
+	bundles := d.ExclusiveBundles(0)
+	if len(bundles) != 1 {
+		t.Fatalf("%d grupos, se esperaba 1", len(bundles))
+	}
+	bundled := d.Bundle(bundles)
+
+	// Un valor por encima del máximo de entrenamiento no llega al rango de la otra feature
+	first := bundles[0].Features[0]
+	cols, values := NewBundler(bundles).Row([]int{first}, []float64{100})
+	if len(cols) != 1 || values[0] != bundles[0].Max[0] {
+		t.Errorf("Row = %v %v, se esperaba el valor recortado a %v", cols, values, bundles[0].Max[0])
+	}
+
+	tree, _ := BuildSparseTree(bundled, DefaultTrainOptions[string]())
+	model := NewModel(tree, nil, bundled.FeatureNames, nil)
+	model.Meta.FeatureBundles = bundles
+	for col, want := range []string{"a", "b"} {
+		if got := model.PredictSparse([]int{col}, []float64{100}); got != want {
+			t.Errorf("PredictSparse(f%d = 100) = %s, se esperaba %s", col+1, got, want)
+		}
+	}
+}
+
+func TestTrainReportWriteHTML(t *testing.T) {
+	report := TrainReport{ImbalanceThreshold: 5}
+	report.Classes, report.Imbalance = ClassBalance([]Example[string]{
+		{Class: "<común>"}, {Class: "<común>"}, {Class: "<común>"}, {Class: "<común>"},
+		{Class: "<común>"}, {Class: "<común>"}, {Class: "rara"},
+	})
+	var out strings.Builder
+	if err := report.WriteHTML(&out, "Informe"); err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+	for _, want := range []string{`style="width: 85.7%"`, `style="width: 14.3%"`, "-class-weight balanced", "&lt;común&gt;"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("falta %q en el informe:\n%s", want, html)
+		}
+	}
+}
+
+// countdownContext se cancela después de que Err se consulte n veces, para cortar la
+// construcción tras un número fijo de nodos
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (c *countdownContext) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestCheckpointedBuildResumesToTheSameTree(t *testing.T) {
+	examples := separable(120, 9)
+	// Pesos desiguales y ruido en las clases para que el árbol tenga varios niveles
+	for i := range examples {
+		examples[i].Weight = float64(1 + i%3)
+		if i%7 == 0 {
+			examples[i].Class = "a"
+		}
+	}
+	smoothed := DefaultTrainOptions[string]()
+	smoothed.Smoothing = 1
+	for name, opts := range map[string]TrainOptions[string]{"por defecto": DefaultTrainOptions[string](), "suavizado": smoothed} {
+		dir := t.TempDir()
+		full, _, err := BuildDecisionTreeCheckpointed(context.Background(), examples, opts, filepath.Join(dir, "full.json"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := json.Marshal(full)
+		if full.Stats().NodeCount < 7 {
+			t.Fatalf("%s: el árbol tiene %d nodos, demasiado pocos para cortarlo", name, full.Stats().NodeCount)
+		}
+
+		for _, cuts := range [][]int{{1}, {3}, {2, 4}, {full.Stats().NodeCount - 1}} {
+			checkpoint := filepath.Join(dir, "resumed.json")
+			for _, n := range cuts {
+				ctx := &countdownContext{Context: context.Background(), n: n}
+				if _, _, err := BuildDecisionTreeCheckpointed(ctx, examples, opts, checkpoint, 0); !errors.Is(err, context.Canceled) {
+					t.Fatalf("%s, cortes %v: error %v, se esperaba context.Canceled", name, cuts, err)
+				}
+			}
+			resumed, report, err := BuildDecisionTreeCheckpointed(context.Background(), examples, opts, checkpoint, 0)
+			if err != nil {
+				t.Fatalf("%s, cortes %v: %v", name, cuts, err)
+			}
+			if got, _ := json.Marshal(resumed); string(got) != string(want) || report.TreeHash != TreeHash(full) {
+				t.Errorf("%s, cortes %v: el árbol reanudado difiere del construido de una vez", name, cuts)
+			}
+			if _, err := os.Stat(checkpoint); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("%s, cortes %v: el punto de control sigue en disco: %v", name, cuts, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,717 @@
+// Package pcdta entrena árboles de decisión de forma concurrente y reúne la inferencia,
+// la evaluación y las utilidades de datos que usa la herramienta de línea de comandos.
+package pcdta
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"sort"
+	"unsafe"
+)
+
+type DecisionTree[L comparable] struct {
+	Left    *DecisionTree[L]
+	Right   *DecisionTree[L]
+	Column  int
+	Value   float64
+	Class   L
+	Counts  map[L]int
+	Weights map[L]float64 `json:",omitempty"` // peso total por clase, si los ejemplos tenían pesos
+	Probs   map[L]float64 `json:",omitempty"` // probabilidades suavizadas, si se entrenó con Smoothing
+}
+
+type Example[L comparable] struct {
+	Features []float64
+	Class    L
+	Weight   float64 // peso del ejemplo en los criterios; 0 equivale a 1
+}
+
+// SampleWeight devuelve el peso efectivo del ejemplo
+func (e Example[L]) SampleWeight() float64 {
+	if e.Weight == 0 {
+		return 1
+	}
+	return e.Weight
+}
+
+// weighted indica si algún ejemplo tiene un peso distinto de 1
+func weighted[L comparable](examples []Example[L]) bool {
+	for _, example := range examples {
+		if example.SampleWeight() != 1 {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	PackageVersion = "0.2.0"
+	MaxDepth       = 3
+
+	// Versión del formato de modelo serializado:
+	// 0 = árbol JSON sin envoltorio, 1 = árbol con metadatos, 2 = versión explícita,
+	// 3 = metadatos que cambian el significado de la entrada o de la salida
+	// (codificaciones, derivadas, binning, grupos de features, clases renombradas).
+	// Un lector de la versión 2 los ignoraría y predeciría mal sin avisar, así que
+	// SaveModel solo escribe 3 cuando el modelo los usa.
+	ModelFormatVersion = 3
+)
+
+func GenerateExamples(numExamples int, rng *rand.Rand) []Example[string] {
+	examples := make([]Example[string], numExamples)
+	for i := 0; i < numExamples; i++ {
+		features := make([]float64, 4) // 4 features para IRIS dataset
+		for j := range features {
+			features[j] = rng.Float64() * 10 // Números aleatorios entre 0 y 10
+		}
+		class := "ClassA"
+		if i%2 == 0 {
+			class = "ClassB"
+		}
+		examples[i] = Example[string]{
+			Features: features,
+			Class:    class,
+		}
+	}
+	return examples
+}
+
+// CalculateGini pondera la impureza de cada lado por su peso total; sin pesos
+// de ejemplo, los pesos son simplemente los conteos
+func CalculateGini(leftClasses, rightClasses []float64, leftWeight, rightWeight float64) float64 {
+	total := leftWeight + rightWeight
+	giniLeft := GiniImpurity(leftClasses, leftWeight)
+	giniRight := GiniImpurity(rightClasses, rightWeight)
+	gini := (leftWeight/total)*giniLeft + (rightWeight/total)*giniRight
+	return gini
+}
+
+func GiniImpurity(classWeights []float64, totalWeight float64) float64 {
+	if totalWeight <= 0 {
+		return 0.0
+	}
+
+	var impurity float64
+	for _, weight := range classWeights {
+		prob := weight / totalWeight
+		impurity += prob * (1 - prob)
+	}
+
+	return impurity
+}
+
+func CalculateCostGini(leftClasses, rightClasses []float64, leftWeight, rightWeight float64, costs [][]float64) float64 {
+	total := leftWeight + rightWeight
+	giniLeft := CostGiniImpurity(leftClasses, leftWeight, costs)
+	giniRight := CostGiniImpurity(rightClasses, rightWeight, costs)
+	return (leftWeight/total)*giniLeft + (rightWeight/total)*giniRight
+}
+
+// CostGiniImpurity es la generalización de Gini con costes de CART:
+// suma de costs[i][j] * p_i * p_j; con costes unitarios coincide con GiniImpurity
+func CostGiniImpurity(classWeights []float64, totalWeight float64, costs [][]float64) float64 {
+	if totalWeight <= 0 {
+		return 0.0
+	}
+
+	var impurity float64
+	for i, weightI := range classWeights {
+		if weightI == 0 {
+			continue
+		}
+		probI := weightI / totalWeight
+		for j, weightJ := range classWeights {
+			if i != j {
+				impurity += costs[i][j] * probI * weightJ / totalWeight
+			}
+		}
+	}
+
+	return impurity
+}
+
+// MinCostClass devuelve la clase cuya predicción tiene menor coste esperado. Se
+// consideran todas las classes del modelo, además de las que tienen peso en la hoja:
+// una clase sin ejemplos en la hoja también puede ser la más barata.
+func MinCostClass[L comparable](weights map[L]float64, classes []L, cost func(actual, predicted L) float64) L {
+	var best L
+	bestCost := math.Inf(1)
+	consider := func(predicted L) {
+		var expected float64
+		for actual, weight := range weights {
+			expected += weight * cost(actual, predicted)
+		}
+		if expected < bestCost || (expected == bestCost && fmt.Sprint(predicted) < fmt.Sprint(best)) {
+			bestCost = expected
+			best = predicted
+		}
+	}
+	for _, predicted := range classes {
+		consider(predicted)
+	}
+	for predicted := range weights {
+		consider(predicted)
+	}
+	return best
+}
+
+func NewLeaf[L comparable](examples []Example[L]) *DecisionTree[L] {
+	return &DecisionTree[L]{
+		Class:  MajorityClass(examples),
+		Counts: ClassCounts(examples),
+	}
+}
+
+func ClassCounts[L comparable](examples []Example[L]) map[L]int {
+	counts := make(map[L]int)
+	for _, example := range examples {
+		counts[example.Class]++
+	}
+	return counts
+}
+
+// ClassWeights suma el peso de los ejemplos de cada clase
+func ClassWeights[L comparable](examples []Example[L]) map[L]float64 {
+	weights := make(map[L]float64)
+	for _, example := range examples {
+		weights[example.Class] += example.SampleWeight()
+	}
+	return weights
+}
+
+// MajorityClass devuelve la clase con mayor peso total
+func MajorityClass[L comparable](examples []Example[L]) L {
+	var none L
+	return heaviestClass(ClassWeights(examples), none)
+}
+
+// heaviestClass devuelve la clase de más peso, o fallback si ninguna tiene peso
+func heaviestClass[L comparable](mass map[L]float64, fallback L) L {
+	maxWeight := 0.0
+	class := fallback
+	for c, weight := range mass {
+		// Los empates se resuelven por la representación textual y no por el orden
+		// del mapa, para que el mismo entrenamiento dé siempre el mismo árbol
+		if weight > maxWeight || (weight > 0 && weight == maxWeight && fmt.Sprint(c) < fmt.Sprint(class)) {
+			maxWeight = weight
+			class = c
+		}
+	}
+	return class
+}
+
+func PrintDecisionTree[L comparable](tree *DecisionTree[L], indent int) {
+	if tree == nil {
+		return
+	}
+
+	for i := 0; i < indent; i++ {
+		fmt.Print("  ")
+	}
+
+	if tree.Left == nil && tree.Right == nil {
+		fmt.Println("Class:", tree.Class)
+	} else {
+		fmt.Printf("Feature %d <= %.2f\n", tree.Column, tree.Value)
+		PrintDecisionTree(tree.Left, indent+1)
+
+		for i := 0; i < indent; i++ {
+			fmt.Print("  ")
+		}
+		fmt.Println("else")
+		PrintDecisionTree(tree.Right, indent+1)
+	}
+}
+
+func (tree *DecisionTree[L]) IsLeaf() bool {
+	return tree.Left == nil && tree.Right == nil
+}
+
+// Accuracy devuelve la fracción de ejemplos clasificados correctamente
+func Accuracy[L comparable](model Classifier[L], examples []Example[L]) float64 {
+	if len(examples) == 0 {
+		return 0
+	}
+	correct := 0
+	for _, example := range examples {
+		if model.Predict(example.Features) == example.Class {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(examples))
+}
+
+// Leaf devuelve la hoja a la que llega el vector de features
+func (tree *DecisionTree[L]) Leaf(features []float64) *DecisionTree[L] {
+	node := tree
+	for !node.IsLeaf() {
+		if features[node.Column] <= node.Value {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+	}
+	return node
+}
+
+func (tree *DecisionTree[L]) Predict(features []float64) L {
+	return tree.Leaf(features).Class
+}
+
+// LeafIndex devuelve la posición, en el orden de Walk, de la hoja a la que llega el
+// vector de features; las hojas se numeran desde 0
+func (tree *DecisionTree[L]) LeafIndex(features []float64) int {
+	target := tree.Leaf(features)
+	index, found := 0, false
+	tree.Walk(func(node *DecisionTree[L], depth int) {
+		if found || !node.IsLeaf() {
+			return
+		}
+		if node == target {
+			found = true
+			return
+		}
+		index++
+	})
+	return index
+}
+
+// Apply devuelve, para cada árbol, el índice de la hoja a la que llega el ejemplo.
+// Junto con el número de hojas de cada árbol sirve como codificación one-hot para
+// modelos lineales posteriores.
+func Apply[L comparable](trees []*DecisionTree[L], features []float64) []int {
+	leaves := make([]int, len(trees))
+	for i, tree := range trees {
+		leaves[i] = tree.LeafIndex(features)
+	}
+	return leaves
+}
+
+// LeafEmbedding concatena la codificación one-hot de las hojas de cada árbol
+func LeafEmbedding[L comparable](trees []*DecisionTree[L], features []float64) []float64 {
+	var embedding []float64
+	for _, tree := range trees {
+		block := make([]float64, tree.Stats().LeafCount)
+		block[tree.LeafIndex(features)] = 1
+		embedding = append(embedding, block...)
+	}
+	return embedding
+}
+
+// PredictProba devuelve la proporción de cada clase en la hoja alcanzada
+func (tree *DecisionTree[L]) PredictProba(features []float64) map[L]float64 {
+	leaf := tree.Leaf(features)
+
+	if leaf.Probs != nil {
+		probs := make(map[L]float64, len(leaf.Probs))
+		for class, prob := range leaf.Probs {
+			probs[class] = prob
+		}
+		return probs
+	}
+
+	mass := leaf.classMass()
+	total := 0.0
+	for _, weight := range mass {
+		total += weight
+	}
+
+	probs := make(map[L]float64)
+	if total == 0 {
+		probs[leaf.Class] = 1
+		return probs
+	}
+	for class, weight := range mass {
+		probs[class] = weight / total
+	}
+	return probs
+}
+
+// PredictProbaInto escribe en out la probabilidad de cada una de las clases indicadas
+// (normalmente las de Classes(), calculadas una sola vez) y devuelve out[:len(classes)].
+// Si out tiene capacidad suficiente no reserva memoria, a diferencia de PredictProba,
+// que crea un mapa en cada llamada.
+func (tree *DecisionTree[L]) PredictProbaInto(features []float64, classes []L, out []float64) []float64 {
+	if cap(out) < len(classes) {
+		out = make([]float64, len(classes))
+	}
+	out = out[:len(classes)]
+	leaf := tree.Leaf(features)
+
+	if leaf.Probs != nil {
+		for i, class := range classes {
+			out[i] = leaf.Probs[class]
+		}
+		return out
+	}
+
+	// Mismo reparto que classMass, leyendo los mapas de la hoja sin copiarlos
+	total := 0.0
+	if leaf.Weights != nil {
+		for _, weight := range leaf.Weights {
+			total += weight
+		}
+	} else {
+		for _, count := range leaf.Counts {
+			total += float64(count)
+		}
+	}
+	for i, class := range classes {
+		switch {
+		case total == 0 && class == leaf.Class:
+			out[i] = 1
+		case total == 0:
+			out[i] = 0
+		case leaf.Weights != nil:
+			out[i] = leaf.Weights[class] / total
+		default:
+			out[i] = float64(leaf.Counts[class]) / total
+		}
+	}
+	return out
+}
+
+// Confidence resume la seguridad de una predicción
+type Confidence struct {
+	Probability float64 // probabilidad de la clase predicha (suavizada si la hoja tiene Probs)
+	Margin      float64 // Probability menos la probabilidad de la siguiente clase más probable
+	Purity      float64 // fracción del peso de la hoja que pertenece a la clase predicha
+}
+
+// PredictWithConfidence devuelve la clase de la hoja con su confianza; ok es false si
+// la probabilidad queda por debajo de minProbability, en cuyo caso quien llama debe
+// tratar la fila como desconocida (p. ej. derivarla a revisión manual)
+func (tree *DecisionTree[L]) PredictWithConfidence(features []float64, minProbability float64) (class L, confidence Confidence, ok bool) {
+	leaf := tree.Leaf(features)
+	class = leaf.Class
+	probs := tree.PredictProba(features)
+
+	confidence.Probability = probs[class]
+	runnerUp := 0.0
+	for other, prob := range probs {
+		if other != class && prob > runnerUp {
+			runnerUp = prob
+		}
+	}
+	confidence.Margin = confidence.Probability - runnerUp
+
+	mass := leaf.classMass()
+	total := 0.0
+	for _, weight := range mass {
+		total += weight
+	}
+	confidence.Purity = 1
+	if total > 0 {
+		confidence.Purity = mass[class] / total
+	}
+	return class, confidence, confidence.Probability >= minProbability
+}
+
+// classMass devuelve el peso por clase de una hoja: Weights si se entrenó con pesos,
+// si no los conteos
+func (tree *DecisionTree[L]) classMass() map[L]float64 {
+	if tree.Weights != nil {
+		return tree.Weights
+	}
+	mass := make(map[L]float64, len(tree.Counts))
+	for class, count := range tree.Counts {
+		mass[class] = float64(count)
+	}
+	return mass
+}
+
+// SmoothLeaves calcula Probs en cada hoja a partir de su peso por clase y del peso
+// global de entrenamiento, que da el número de clases y el prior del m-estimate
+func SmoothLeaves[L comparable](tree *DecisionTree[L], global map[L]float64, smoothing float64, method string) {
+	total := 0.0
+	for _, weight := range global {
+		total += weight
+	}
+
+	tree.Walk(func(node *DecisionTree[L], depth int) {
+		if !node.IsLeaf() {
+			return
+		}
+
+		mass := node.classMass()
+		n := 0.0
+		for _, weight := range mass {
+			n += weight
+		}
+
+		node.Probs = make(map[L]float64, len(global))
+		for class, globalWeight := range global {
+			if method == "m-estimate" {
+				prior := globalWeight / total
+				node.Probs[class] = (mass[class] + smoothing*prior) / (n + smoothing)
+			} else {
+				node.Probs[class] = (mass[class] + smoothing) / (n + smoothing*float64(len(global)))
+			}
+		}
+	})
+}
+
+// Classes devuelve las clases vistas en las hojas, ordenadas por su representación textual
+func (tree *DecisionTree[L]) Classes() []L {
+	seen := make(map[L]bool)
+	var classes []L
+	tree.Walk(func(node *DecisionTree[L], depth int) {
+		if !node.IsLeaf() {
+			return
+		}
+		if !seen[node.Class] {
+			seen[node.Class] = true
+			classes = append(classes, node.Class)
+		}
+		for class := range node.Counts {
+			if !seen[class] {
+				seen[class] = true
+				classes = append(classes, class)
+			}
+		}
+	})
+	sort.Slice(classes, func(i, j int) bool {
+		return fmt.Sprint(classes[i]) < fmt.Sprint(classes[j])
+	})
+	return classes
+}
+
+// Walk recorre el árbol en preorden llamando a fn con cada nodo y su profundidad
+func (tree *DecisionTree[L]) Walk(fn func(node *DecisionTree[L], depth int)) {
+	var walk func(node *DecisionTree[L], depth int)
+	walk = func(node *DecisionTree[L], depth int) {
+		if node == nil {
+			return
+		}
+		fn(node, depth)
+		walk(node.Left, depth+1)
+		walk(node.Right, depth+1)
+	}
+	walk(tree, 0)
+}
+
+type TreeStats struct {
+	Depth         int
+	NodeCount     int
+	LeafCount     int
+	NodesPerDepth []int
+	AvgLeafPurity float64
+	MemoryBytes   int
+}
+
+func (tree *DecisionTree[L]) Stats() TreeStats {
+	var stats TreeStats
+	var label L
+	var purityLeaves int
+	var puritySum float64
+
+	nodeSize := int(unsafe.Sizeof(*tree))
+	entrySize := int(unsafe.Sizeof(label)) + int(unsafe.Sizeof(int(0)))
+
+	tree.Walk(func(node *DecisionTree[L], depth int) {
+		stats.NodeCount++
+		if depth > stats.Depth {
+			stats.Depth = depth
+		}
+		for len(stats.NodesPerDepth) <= depth {
+			stats.NodesPerDepth = append(stats.NodesPerDepth, 0)
+		}
+		stats.NodesPerDepth[depth]++
+
+		// Tamaño aproximado: el nodo más las entradas del mapa de conteos
+		stats.MemoryBytes += nodeSize + len(node.Counts)*entrySize
+
+		if !node.IsLeaf() {
+			return
+		}
+		stats.LeafCount++
+
+		// La pureza de una hoja es la fracción (ponderada) de ejemplos de la clase mayoritaria
+		total, maxMass := 0.0, 0.0
+		for _, m := range node.classMass() {
+			total += m
+			if m > maxMass {
+				maxMass = m
+			}
+		}
+		if total > 0 {
+			puritySum += maxMass / total
+			purityLeaves++
+		}
+	})
+
+	if purityLeaves > 0 {
+		stats.AvgLeafPurity = puritySum / float64(purityLeaves)
+	}
+
+	return stats
+}
+
+var ErrNodeNotInTree = errors.New("el nodo no pertenece al árbol")
+
+// Validate comprueba las invariantes estructurales del árbol: cada nodo interno
+// tiene exactamente dos hijos, ningún nodo aparece dos veces y las columnas son válidas
+func (tree *DecisionTree[L]) Validate() error {
+	seen := make(map[*DecisionTree[L]]bool)
+	var check func(node *DecisionTree[L]) error
+	check = func(node *DecisionTree[L]) error {
+		if seen[node] {
+			return errors.New("el árbol contiene un nodo repetido o un ciclo")
+		}
+		seen[node] = true
+
+		if node.IsLeaf() {
+			return nil
+		}
+		if node.Left == nil || node.Right == nil {
+			return fmt.Errorf("el nodo sobre la feature %d tiene un solo hijo", node.Column)
+		}
+		if node.Column < 0 {
+			return fmt.Errorf("columna inválida %d", node.Column)
+		}
+		if err := check(node.Left); err != nil {
+			return err
+		}
+		return check(node.Right)
+	}
+
+	if tree == nil {
+		return errors.New("árbol vacío")
+	}
+	return check(tree)
+}
+
+func (tree *DecisionTree[L]) contains(target *DecisionTree[L]) bool {
+	found := false
+	tree.Walk(func(node *DecisionTree[L], depth int) {
+		if node == target {
+			found = true
+		}
+	})
+	return found
+}
+
+// Prune convierte el subárbol node en una hoja cuyos conteos son la suma de sus hojas.
+// La clase es la de más peso, con los empates resueltos como en MajorityClass; si las
+// hojas tenían Probs, la nueva hoja las recalcula como la proporción de cada clase en
+// la masa sumada.
+func (tree *DecisionTree[L]) Prune(node *DecisionTree[L]) error {
+	if !tree.contains(node) {
+		return ErrNodeNotInTree
+	}
+
+	counts := make(map[L]int)
+	var weights map[L]float64
+	var probClasses map[L]bool
+	node.Walk(func(n *DecisionTree[L], depth int) {
+		if n.IsLeaf() {
+			for class, count := range n.Counts {
+				counts[class] += count
+			}
+			if n.Weights != nil {
+				if weights == nil {
+					weights = make(map[L]float64)
+				}
+				for class, weight := range n.Weights {
+					weights[class] += weight
+				}
+			}
+			if n.Probs != nil {
+				if probClasses == nil {
+					probClasses = make(map[L]bool)
+				}
+				for class := range n.Probs {
+					probClasses[class] = true
+				}
+			}
+		}
+	})
+
+	// La clase mayoritaria se decide por peso si las hojas tenían pesos
+	mass := weights
+	if mass == nil {
+		mass = (&DecisionTree[L]{Counts: counts}).classMass()
+	}
+	leaf := DecisionTree[L]{
+		Class:   heaviestClass(mass, node.Class),
+		Counts:  counts,
+		Weights: weights,
+	}
+
+	total := 0.0
+	for _, m := range mass {
+		total += m
+	}
+	if probClasses != nil && total > 0 {
+		for class := range mass {
+			probClasses[class] = true
+		}
+		leaf.Probs = make(map[L]float64, len(probClasses))
+		for class := range probClasses {
+			leaf.Probs[class] = mass[class] / total
+		}
+	}
+	*node = leaf
+	return nil
+}
+
+// Clone devuelve una copia profunda del árbol, con sus propios nodos y mapas
+func (tree *DecisionTree[L]) Clone() *DecisionTree[L] {
+	if tree == nil {
+		return nil
+	}
+	out := *tree
+	out.Left, out.Right = tree.Left.Clone(), tree.Right.Clone()
+	out.Counts, out.Weights, out.Probs = maps.Clone(tree.Counts), maps.Clone(tree.Weights), maps.Clone(tree.Probs)
+	return &out
+}
+
+// Graft reemplaza la hoja leaf del árbol por una copia del subárbol dado, para que
+// editar después cualquiera de los dos árboles no cambie el otro
+func (tree *DecisionTree[L]) Graft(leaf, subtree *DecisionTree[L]) error {
+	if !tree.contains(leaf) {
+		return ErrNodeNotInTree
+	}
+	if !leaf.IsLeaf() {
+		return errors.New("solo se puede injertar sobre una hoja")
+	}
+	if err := subtree.Validate(); err != nil {
+		return fmt.Errorf("subárbol inválido: %w", err)
+	}
+	if subtree.contains(leaf) || tree.contains(subtree) {
+		return errors.New("el subárbol ya forma parte del árbol")
+	}
+
+	*leaf = *subtree.Clone()
+	return nil
+}
+
+// CollapsePure fusiona de abajo hacia arriba los nodos cuyas dos hojas predicen
+// la misma clase y devuelve cuántos nodos se colapsaron
+func (tree *DecisionTree[L]) CollapsePure() (int, error) {
+	if tree == nil || tree.IsLeaf() {
+		return 0, nil
+	}
+
+	left, err := tree.Left.CollapsePure()
+	if err != nil {
+		return left, err
+	}
+	right, err := tree.Right.CollapsePure()
+	collapsed := left + right
+	if err != nil {
+		return collapsed, err
+	}
+
+	if tree.Left.IsLeaf() && tree.Right.IsLeaf() && tree.Left.Class == tree.Right.Class {
+		if err := tree.Prune(tree); err != nil {
+			return collapsed, err
+		}
+		collapsed++
+	}
+
+	return collapsed, nil
+}
@@ -0,0 +1,153 @@
+package pcdta
+
+import "testing"
+
+func TestGraftCopiesTheSubtree(t *testing.T) {
+	tree := &DecisionTree[string]{
+		Column: 0, Value: 1,
+		Left:  &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 3}},
+		Right: &DecisionTree[string]{Class: "b", Counts: map[string]int{"b": 2}},
+	}
+	donor := &DecisionTree[string]{
+		Column: 1, Value: 5,
+		Left:  &DecisionTree[string]{Class: "c", Counts: map[string]int{"c": 1}},
+		Right: &DecisionTree[string]{Class: "d", Counts: map[string]int{"d": 1}},
+	}
+	if err := tree.Graft(tree.Right, donor); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Right.Left == donor.Left {
+		t.Fatal("el injerto comparte nodos con el árbol donante")
+	}
+
+	// Podar y editar el injerto no debe tocar el donante
+	if err := tree.Prune(tree.Right); err != nil {
+		t.Fatal(err)
+	}
+	tree.Right.Counts["c"] = 100
+	if donor.IsLeaf() || donor.Left.Counts["c"] != 1 || donor.Right.Class != "d" {
+		t.Errorf("el donante cambió: %+v", donor)
+	}
+}
+
+func TestPruneBreaksTiesByClassNameAndRecomputesProbs(t *testing.T) {
+	// La masa sumada empata entre a y c; el orden de los mapas cambia en cada vuelta y
+	// la clase tiene que ser siempre a, como en MajorityClass
+	for i := 0; i < 50; i++ {
+		tree := &DecisionTree[string]{
+			Column: 0, Value: 1,
+			Left: &DecisionTree[string]{Class: "c", Counts: map[string]int{"c": 2, "b": 1},
+				Probs: map[string]float64{"a": 0.1, "b": 0.3, "c": 0.6}},
+			Right: &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 2, "b": 1},
+				Probs: map[string]float64{"a": 0.6, "b": 0.3, "c": 0.1}},
+		}
+		if err := tree.Prune(tree); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]float64{"a": 2.0 / 6, "b": 2.0 / 6, "c": 2.0 / 6}
+		if tree.Class != "a" || len(tree.Probs) != len(want) {
+			t.Fatalf("hoja podada %+v, se esperaba la clase a", tree)
+		}
+		for class, p := range want {
+			if tree.Probs[class] != p {
+				t.Fatalf("Probs[%s] = %v, se esperaba %v", class, tree.Probs[class], p)
+			}
+		}
+	}
+
+	// Con pesos decide el peso, y sin Probs en las hojas la hoja podada tampoco las tiene
+	tree := &DecisionTree[string]{
+		Column: 0, Value: 1,
+		Left:  &DecisionTree[string]{Class: "b", Counts: map[string]int{"b": 3}, Weights: map[string]float64{"b": 1.5}},
+		Right: &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 1}, Weights: map[string]float64{"a": 1.5}},
+	}
+	if err := tree.Prune(tree); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Class != "a" || tree.Probs != nil || tree.Weights["a"] != 1.5 || tree.Counts["b"] != 3 {
+		t.Errorf("hoja podada con pesos %+v", tree)
+	}
+}
+
+func TestCollapsePure(t *testing.T) {
+	tree := &DecisionTree[string]{
+		Column: 0, Value: 1,
+		Left: &DecisionTree[string]{
+			Column: 1, Value: 2,
+			Left:  &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 2}},
+			Right: &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 1, "b": 1}},
+		},
+		Right: &DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 4}},
+	}
+	collapsed, err := tree.CollapsePure()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collapsed != 2 || !tree.IsLeaf() || tree.Counts["a"] != 7 || tree.Counts["b"] != 1 {
+		t.Errorf("CollapsePure = %d, árbol %+v", collapsed, tree)
+	}
+}
+
+func TestMinCostClassConsidersAbsentClasses(t *testing.T) {
+	// Predecir "revisar" cuesta poco sea cual sea la clase real, aunque ninguna fila de
+	// la hoja lo sea
+	costs := map[string]map[string]float64{
+		"fraude": {"ok": 10, "revisar": 1},
+		"ok":     {"fraude": 10, "revisar": 1},
+	}
+	cost := TrainOptions[string]{CostMatrix: costs}.cost
+	weights := map[string]float64{"fraude": 5, "ok": 5}
+	if got := MinCostClass(weights, []string{"fraude", "ok", "revisar"}, cost); got != "revisar" {
+		t.Errorf("MinCostClass = %s, se esperaba revisar", got)
+	}
+	if got := MinCostClass(weights, nil, cost); got != "fraude" {
+		t.Errorf("MinCostClass sin clases = %s, se esperaba fraude", got)
+	}
+}
+
+// benchmarkTree es un árbol de profundidad 3 sobre dos features, con hojas de conteos
+func benchmarkTree() *DecisionTree[string] {
+	leaf := func(a, b int) *DecisionTree[string] {
+		class := "a"
+		if b > a {
+			class = "b"
+		}
+		return &DecisionTree[string]{Class: class, Counts: map[string]int{"a": a, "b": b}}
+	}
+	return &DecisionTree[string]{
+		Column: 0, Value: 5,
+		Left: &DecisionTree[string]{
+			Column: 1, Value: 2,
+			Left:  leaf(8, 1),
+			Right: &DecisionTree[string]{Column: 0, Value: 1, Left: leaf(3, 3), Right: leaf(1, 4)},
+		},
+		Right: leaf(2, 9),
+	}
+}
+
+func TestPredictProbaIntoDoesNotAllocate(t *testing.T) {
+	tree := benchmarkTree()
+	classes := tree.Classes()
+	features := []float64{3, 4}
+	out := make([]float64, len(classes))
+	allocs := testing.AllocsPerRun(100, func() {
+		out = tree.PredictProbaInto(features, classes, out)
+	})
+	if allocs != 0 {
+		t.Errorf("PredictProbaInto hace %v reservas por llamada, se esperaban 0", allocs)
+	}
+	if out[0] != 0.2 || out[1] != 0.8 {
+		t.Errorf("PredictProbaInto = %v, se esperaba [0.2 0.8]", out)
+	}
+}
+
+func BenchmarkPredictProbaInto(b *testing.B) {
+	tree := benchmarkTree()
+	classes := tree.Classes()
+	features := []float64{3, 4}
+	out := make([]float64, len(classes))
+	b.ReportAllocs()
+	for b.Loop() {
+		out = tree.PredictProbaInto(features, classes, out)
+	}
+}
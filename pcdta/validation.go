@@ -0,0 +1,301 @@
+package pcdta
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+)
+
+// KFolds baraja los índices [0, n) y los reparte en k pliegues de prueba; k debe
+// estar entre 2 y n para que ningún pliegue quede vacío
+func KFolds(n, k int, rng *rand.Rand) ([][]int, error) {
+	if k < 2 || k > n {
+		return nil, fmt.Errorf("el número de pliegues debe estar entre 2 y el de filas (%d), no %d", n, k)
+	}
+	order := rng.Perm(n)
+	folds := make([][]int, k)
+	for i, index := range order {
+		folds[i%k] = append(folds[i%k], index)
+	}
+	return folds, nil
+}
+
+// splitFold separa los ejemplos del pliegue de prueba del resto
+func splitFold[L comparable](examples []Example[L], fold []int) (train, test []Example[L]) {
+	inFold := make(map[int]bool, len(fold))
+	for _, index := range fold {
+		inFold[index] = true
+	}
+	for i, example := range examples {
+		if inFold[i] {
+			test = append(test, example)
+		} else {
+			train = append(train, example)
+		}
+	}
+	return train, test
+}
+
+// CrossValidate devuelve la precisión de prueba de cada pliegue
+func CrossValidate[L comparable](examples []Example[L], opts TrainOptions[L], folds [][]int) []float64 {
+	// Un árbol no puede fallar al entrenar
+	scores, _ := CrossValidateEstimator(TreeEstimator(opts), examples, folds)
+	return scores
+}
+
+// CrossValidateEstimator es CrossValidate para cualquier Estimator: entrena con todas
+// las filas salvo las de cada pliegue y devuelve la precisión sobre el pliegue
+func CrossValidateEstimator[L comparable](estimator Estimator[L], examples []Example[L], folds [][]int) ([]float64, error) {
+	scores := make([]float64, len(folds))
+	for f, fold := range folds {
+		train, test := splitFold(examples, fold)
+		model, err := estimator.Fit(train)
+		if err != nil {
+			return nil, fmt.Errorf("pliegue %d: %w", f+1, err)
+		}
+		scores[f] = Accuracy(model, test)
+	}
+	return scores, nil
+}
+
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// NestedCVResult guarda, por pliegue externo, la profundidad elegida por la
+// validación cruzada interna y su precisión sobre el pliegue externo
+type NestedCVResult struct {
+	SelectedDepths []int
+	OuterScores    []float64
+	Estimate       float64 // media de OuterScores: estimación sin sesgo de selección
+	Depth          int     // profundidad elegida con validación cruzada sobre todos los datos
+}
+
+func (r NestedCVResult) String() string {
+	var sb strings.Builder
+	for f, score := range r.OuterScores {
+		fmt.Fprintf(&sb, "  pliegue %d: profundidad %d, precisión %.3f\n", f+1, r.SelectedDepths[f], score)
+	}
+	fmt.Fprintf(&sb, "Precisión estimada (validación cruzada anidada): %.3f\n", r.Estimate)
+	fmt.Fprintf(&sb, "Profundidad seleccionada: %d\n", r.Depth)
+	return sb.String()
+}
+
+// tuneDepth elige la profundidad con mejor precisión media en validación cruzada;
+// en empate gana la menor, que es la más regularizada
+func tuneDepth[L comparable](examples []Example[L], opts TrainOptions[L], depths []int, k int, rng *rand.Rand) (int, error) {
+	folds, err := KFolds(len(examples), k, rng)
+	if err != nil {
+		return 0, err
+	}
+	best, bestScore := depths[0], math.Inf(-1)
+	for _, depth := range depths {
+		opts.MaxDepth = depth
+		score := Mean(CrossValidate(examples, opts, folds))
+		if score > bestScore || (score == bestScore && depth < best) {
+			best, bestScore = depth, score
+		}
+	}
+	return best, nil
+}
+
+// NestedCV ajusta MaxDepth entre depths con validación cruzada interna dentro de
+// cada pliegue externo, de modo que los pliegues externos nunca participan en la
+// selección y su media estima el rendimiento del procedimiento completo
+func NestedCV[L comparable](examples []Example[L], opts TrainOptions[L], depths []int, outerK, innerK int, rng *rand.Rand) (NestedCVResult, error) {
+	var result NestedCVResult
+	outer, err := KFolds(len(examples), outerK, rng)
+	if err != nil {
+		return result, fmt.Errorf("pliegues externos: %w", err)
+	}
+	for _, fold := range outer {
+		train, test := splitFold(examples, fold)
+		depth, err := tuneDepth(train, opts, depths, innerK, rng)
+		if err != nil {
+			return result, fmt.Errorf("pliegues internos: %w", err)
+		}
+		opts.MaxDepth = depth
+		tree, _ := BuildDecisionTreeConcurrent(train, opts)
+		result.SelectedDepths = append(result.SelectedDepths, depth)
+		result.OuterScores = append(result.OuterScores, Accuracy(tree, test))
+	}
+	result.Estimate = Mean(result.OuterScores)
+	result.Depth, err = tuneDepth(examples, opts, depths, innerK, rng)
+	return result, err
+}
+
+// BacktestFold es una ventana de la evaluación con origen móvil: se entrena con las
+// filas de (TrainFrom, Origin] y se prueba con las de (Origin, Origin+horizonte]
+type BacktestFold struct {
+	TrainFrom, Origin, TestTo float64
+	TrainRows, TestRows       int
+	Accuracy, MacroF1         float64
+}
+
+// timeLayouts son los formatos de fecha que acepta ParseTime, además de los números
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// ParseTime lee un instante para Backtest: un número se toma tal cual y una fecha
+// (2024-03-01, 2024-03-01 12:00:00 o RFC3339) se convierte en días desde 1970-01-01
+// UTC, para que -horizon, -step y -window se expresen en días
+func ParseTime(field string) (float64, error) {
+	field = strings.TrimSpace(field)
+	if isMissingField(field) {
+		return 0, fmt.Errorf("falta el valor")
+	}
+	if value, err := parseFeature(field); err == nil {
+		return value, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, field); err == nil {
+			return (float64(t.Unix()) + float64(t.Nanosecond())/1e9) / 86400, nil
+		}
+	}
+	return 0, fmt.Errorf("%q no es un número ni una fecha", field)
+}
+
+// LoadTimeSeries lee filename para Backtest: devuelve los ejemplos sin la columna de
+// tiempo column y el instante de cada fila según ParseTime
+func LoadTimeSeries(filename, column string) (*Dataset[string], []float64, error) {
+	header, records, err := readRawRecords(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := header[:len(header)-1]
+	col := slices.Index(names, column)
+	if col < 0 {
+		return nil, nil, fmt.Errorf("%s no tiene la columna %s", filename, column)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%s no tiene filas", filename)
+	}
+
+	data := &Dataset[string]{
+		FeatureNames: slices.Delete(slices.Clone(names), col, col+1),
+		Examples:     make([]Example[string], len(records)),
+	}
+	times := make([]float64, len(records))
+	for i, record := range records {
+		if times[i], err = ParseTime(record[col]); err != nil {
+			return nil, nil, fmt.Errorf("%s: fila %d, %s: %w", filename, i+1, column, err)
+		}
+		features := make([]float64, 0, len(data.FeatureNames))
+		for j, field := range record[:len(record)-1] {
+			if j == col {
+				continue
+			}
+			value, err := parseFeature(field)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: fila %d, columna %d: %w", filename, i+1, j, err)
+			}
+			features = append(features, value)
+		}
+		data.Examples[i] = Example[string]{Features: features, Class: record[len(record)-1]}
+	}
+	return data, times, nil
+}
+
+// BacktestReport agrega los pliegues; las medias se ponderan por filas de prueba
+type BacktestReport struct {
+	Horizon, Step, Window float64
+	Folds                 []BacktestFold
+	Accuracy, MacroF1     float64
+}
+
+func (r BacktestReport) String() string {
+	var sb strings.Builder
+	window := "creciente"
+	if r.Window > 0 {
+		window = fmt.Sprintf("%g", r.Window)
+	}
+	fmt.Fprintf(&sb, "Backtest con origen móvil: horizonte %g, paso %g, ventana %s\n", r.Horizon, r.Step, window)
+	for f, fold := range r.Folds {
+		fmt.Fprintf(&sb, "  pliegue %d: entrena (%g, %g] %d filas, prueba hasta %g %d filas: precisión %.3f, F1 macro %.3f\n",
+			f+1, fold.TrainFrom, fold.Origin, fold.TrainRows, fold.TestTo, fold.TestRows, fold.Accuracy, fold.MacroF1)
+	}
+	fmt.Fprintf(&sb, "Global (%d pliegues): precisión %.3f, F1 macro %.3f\n", len(r.Folds), r.Accuracy, r.MacroF1)
+	return sb.String()
+}
+
+// Backtest evalúa el entrenamiento como se usaría en producción: para cada origen t
+// desde start, con saltos de step, entrena con las filas de tiempo <= t (solo las de
+// (t-window, t] si window > 0) y prueba con las de (t, t+horizon]. times[i] es el
+// instante del ejemplo i; los pliegues sin filas de entrenamiento o de prueba se omiten.
+// horizon y step han de ser positivos.
+func Backtest[L comparable](examples []Example[L], times []float64, opts TrainOptions[L], start, horizon, step, window float64) (BacktestReport, error) {
+	if len(times) != len(examples) {
+		return BacktestReport{}, fmt.Errorf("%d instantes para %d ejemplos", len(times), len(examples))
+	}
+	// Escrito así para que NaN también se rechace
+	if !(horizon > 0) {
+		return BacktestReport{}, fmt.Errorf("el horizonte debe ser positivo, no %v", horizon)
+	}
+	if !(step > 0) {
+		return BacktestReport{}, fmt.Errorf("el paso debe ser positivo, no %v", step)
+	}
+	report := BacktestReport{Horizon: horizon, Step: step, Window: window}
+	latest := math.Inf(-1)
+	for _, t := range times {
+		latest = math.Max(latest, t)
+	}
+
+	var actual, predicted []L
+	for origin := start; origin < latest; origin += step {
+		// Un paso menor que la resolución de origin no lo haría avanzar
+		if origin+step == origin {
+			return BacktestReport{}, fmt.Errorf("el paso %v es demasiado pequeño para avanzar desde %v", step, origin)
+		}
+		from := math.Inf(-1)
+		if window > 0 {
+			from = origin - window
+		}
+		var train, test []Example[L]
+		for i, t := range times {
+			switch {
+			case t > from && t <= origin:
+				train = append(train, examples[i])
+			case t > origin && t <= origin+horizon:
+				test = append(test, examples[i])
+			}
+		}
+		if len(train) == 0 || len(test) == 0 {
+			continue
+		}
+
+		tree, _ := BuildDecisionTreeConcurrent(train, opts)
+		foldActual := make([]L, len(test))
+		foldPredicted := make([]L, len(test))
+		for i, example := range test {
+			foldActual[i], foldPredicted[i] = example.Class, tree.Predict(example.Features)
+		}
+		report.Folds = append(report.Folds, BacktestFold{
+			TrainFrom: from, Origin: origin, TestTo: origin + horizon,
+			TrainRows: len(train), TestRows: len(test),
+			Accuracy: Accuracy(tree, test), MacroF1: macroF1(foldActual, foldPredicted),
+		})
+		actual = append(actual, foldActual...)
+		predicted = append(predicted, foldPredicted...)
+	}
+
+	if len(actual) > 0 {
+		correct := 0
+		for i := range actual {
+			if actual[i] == predicted[i] {
+				correct++
+			}
+		}
+		report.Accuracy = float64(correct) / float64(len(actual))
+		report.MacroF1 = macroF1(actual, predicted)
+	}
+	return report, nil
+}
@@ -0,0 +1,150 @@
+package pcdta
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestKFoldsRejectsInvalidCounts(t *testing.T) {
+	for _, k := range []int{-1, 0, 1, 11} {
+		if _, err := KFolds(10, k, rand.New(rand.NewSource(1))); err == nil {
+			t.Errorf("KFolds(10, %d) no devolvió error", k)
+		}
+	}
+}
+
+func TestKFoldsPartitionsIndices(t *testing.T) {
+	for _, k := range []int{2, 3, 10} {
+		folds, err := KFolds(10, k, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var all []int
+		for _, fold := range folds {
+			if len(fold) == 0 {
+				t.Fatalf("k=%d: pliegue vacío", k)
+			}
+			all = append(all, fold...)
+		}
+		sort.Ints(all)
+		for i, index := range all {
+			if index != i {
+				t.Fatalf("k=%d: los pliegues no reparten [0, 10): %v", k, all)
+			}
+		}
+	}
+}
+
+func TestNestedCVValidatesInnerFolds(t *testing.T) {
+	examples := separable(10, 5)
+	rng := rand.New(rand.NewSource(1))
+	if _, err := NestedCV(examples, DefaultTrainOptions[string](), []int{1, 2}, 5, 9, rng); err == nil {
+		t.Fatal("9 pliegues internos sobre 8 filas de entrenamiento deberían dar error")
+	}
+
+	result, err := NestedCV(separable(60, 5), DefaultTrainOptions[string](), []int{1, 2}, 3, 3, rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.OuterScores) != 3 || result.Estimate < 0.9 {
+		t.Errorf("validación cruzada anidada sobre datos separables: %+v", result)
+	}
+}
+
+func TestParseTimeAcceptsNumbersAndDates(t *testing.T) {
+	for field, want := range map[string]float64{
+		"42":                        42,
+		"1970-01-02":                1,
+		"1970-01-01 12:00:00":       0.5,
+		"1970-01-03T00:00:00Z":      2,
+		"1970-01-02T01:00:00+01:00": 1,
+	} {
+		if got, err := ParseTime(field); err != nil || got != want {
+			t.Errorf("ParseTime(%q) = %v, %v; se esperaba %v", field, got, err, want)
+		}
+	}
+	for _, field := range []string{"", "NA", "ayer"} {
+		if _, err := ParseTime(field); err == nil {
+			t.Errorf("ParseTime(%q): se esperaba un error", field)
+		}
+	}
+}
+
+func TestLoadTimeSeriesDropsTheTimeColumn(t *testing.T) {
+	path := writeTemp(t, "serie.csv", "x,day,class\n1,2024-01-01,a\n2,2024-01-02,b\n")
+	data, times, err := LoadTimeSeries(path, "day")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(data.FeatureNames, []string{"x"}) || data.Examples[1].Features[0] != 2 || times[1]-times[0] != 1 {
+		t.Errorf("features %v, ejemplos %v, tiempos %v", data.FeatureNames, data.Examples, times)
+	}
+	if _, _, err := LoadTimeSeries(writeTemp(t, "vacia.csv", "x,day,class\n"), "day"); err == nil {
+		t.Error("se esperaba un error sin filas")
+	}
+}
+
+func TestBacktestRejectsStepsThatNeverAdvance(t *testing.T) {
+	examples := separable(20, 3)
+	times := make([]float64, len(examples))
+	for i := range times {
+		times[i] = float64(i)
+	}
+	opts := DefaultTrainOptions[string]()
+	for _, tc := range []struct {
+		name                 string
+		start, horizon, step float64
+	}{
+		{"paso cero", 10, 2, 0},
+		{"paso negativo", 10, 2, -1},
+		{"paso NaN", 10, 2, math.NaN()},
+		{"horizonte cero", 10, 0, 2},
+		{"horizonte NaN", 10, math.NaN(), 2},
+		{"paso bajo la resolución", -1e17, 2, 1},
+	} {
+		if _, err := Backtest(examples, times, opts, tc.start, tc.horizon, tc.step, 0); err == nil {
+			t.Errorf("%s: se esperaba un error", tc.name)
+		}
+	}
+	if _, err := Backtest(examples, times[:5], opts, 10, 2, 2, 0); err == nil {
+		t.Error("se esperaba un error con menos instantes que ejemplos")
+	}
+
+	// Orígenes 10, 15: prueba (10, 15] y (15, 19]
+	report, err := Backtest(examples, times, opts, 10, 5, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Folds) != 2 || report.Folds[0].TrainRows != 11 || report.Folds[0].TestRows != 5 || report.Folds[1].TestRows != 4 {
+		t.Errorf("pliegues %+v", report.Folds)
+	}
+}
+
+func TestCrossValidateEstimator(t *testing.T) {
+	// Todos los modelos del paquete cumplen ProbabilisticClassifier
+	_ = []ProbabilisticClassifier[string]{&DecisionTree[string]{}, &OneVsRest[string]{}, &OneVsOne[string]{},
+		&Ordinal[string]{}, &LeafKNN[string]{}, &RuleList[string]{}}
+
+	examples := separable(100, 6)
+	folds, err := KFolds(len(examples), 5, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultTrainOptions[string]()
+	got, err := CrossValidateEstimator(TreeEstimator(opts), examples, folds)
+	if err != nil || !slices.Equal(got, CrossValidate(examples, opts, folds)) {
+		t.Errorf("CrossValidateEstimator = %v, %v; CrossValidate da %v", got, err, CrossValidate(examples, opts, folds))
+	}
+
+	failing := EstimatorFunc[string](func([]Example[string]) (ProbabilisticClassifier[string], error) {
+		return nil, errors.New("sin memoria")
+	})
+	if _, err := CrossValidateEstimator(failing, examples, folds); err == nil || !strings.Contains(err.Error(), "sin memoria") {
+		t.Errorf("error %v, se esperaba el del estimador", err)
+	}
+}
@@ -0,0 +1,226 @@
+// Package registry guarda modelos versionados, con sus métricas, en un directorio.
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iStorm30/PCDTA2/pcdta"
+)
+
+// Registry guarda modelos versionados en un directorio:
+// <Root>/<nombre>/v<N>/model.json, con sus métricas en metrics.json al lado
+type Registry struct {
+	Root string
+}
+
+type RegistryEntry struct {
+	Name     string
+	Version  int
+	Metadata pcdta.ModelMetadata
+	Metrics  map[string]float64 // nil si se publicó sin métricas
+}
+
+// checkName exige que name sea un único componente de ruta, para que no pueda salir
+// del registro con ".." o separadores; ":" separa la versión en la CLI
+func checkName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\:`) || filepath.Base(name) != name {
+		return fmt.Errorf("nombre de modelo inválido %q", name)
+	}
+	return nil
+}
+
+func (r Registry) versionDir(name string, version int) string {
+	return filepath.Join(r.Root, name, "v"+strconv.Itoa(version))
+}
+
+func (r Registry) Versions(name string) ([]int, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(r.Root, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var versions []int
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "v") {
+			continue
+		}
+		if v, err := strconv.Atoi(entry.Name()[1:]); err == nil && v > 0 {
+			versions = append(versions, v)
+		}
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+// Push copia el archivo de modelo al registro como la siguiente versión de name, con
+// sus métricas (puede ser nil)
+func (r Registry) Push(name, modelFile string, metrics map[string]float64) (int, error) {
+	if err := checkName(name); err != nil {
+		return 0, err
+	}
+
+	// Se carga el modelo para validarlo y normalizarlo al formato actual
+	model, err := pcdta.LoadModel[string](modelFile)
+	if err != nil {
+		return 0, err
+	}
+
+	versions, err := r.Versions(name)
+	if err != nil {
+		return 0, err
+	}
+	version := 1
+	if len(versions) > 0 {
+		version = versions[len(versions)-1] + 1
+	}
+
+	// os.Mkdir reserva la versión: si otro Push creó antes el mismo vN, falla con
+	// ErrExist y se prueba con el siguiente en lugar de pisarlo
+	if err := os.MkdirAll(filepath.Join(r.Root, name), 0755); err != nil {
+		return 0, err
+	}
+	dir := r.versionDir(name, version)
+	for {
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return 0, err
+		}
+		version++
+		dir = r.versionDir(name, version)
+	}
+	if len(metrics) > 0 {
+		data, err := json.MarshalIndent(metrics, "", "  ")
+		if err != nil {
+			return 0, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "metrics.json"), data, 0644); err != nil {
+			return 0, err
+		}
+	}
+	// El modelo se escribe aparte y se renombra al final, para que nadie lea un
+	// model.json a medias de una versión recién reservada
+	tmp := filepath.Join(dir, "model.json.tmp")
+	if err := pcdta.SaveModel(model, tmp); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "model.json")); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// Pull copia la versión indicada de name a dest; version 0 significa la última
+func (r Registry) Pull(name string, version int, dest string) (int, error) {
+	if err := checkName(name); err != nil {
+		return 0, err
+	}
+	if version < 0 {
+		return 0, fmt.Errorf("versión inválida %d", version)
+	}
+	if version == 0 {
+		versions, err := r.Versions(name)
+		if err != nil {
+			return 0, err
+		}
+		if len(versions) == 0 {
+			return 0, fmt.Errorf("el modelo %q no existe en el registro", name)
+		}
+		version = versions[len(versions)-1]
+	}
+
+	model, err := pcdta.LoadModel[string](filepath.Join(r.versionDir(name, version), "model.json"))
+	if err != nil {
+		return 0, err
+	}
+	return version, pcdta.SaveModel(model, dest)
+}
+
+// Entry lee una versión del registro con sus metadatos y métricas
+func (r Registry) Entry(name string, version int) (RegistryEntry, error) {
+	if err := checkName(name); err != nil {
+		return RegistryEntry{}, err
+	}
+	dir := r.versionDir(name, version)
+	model, err := pcdta.LoadModel[string](filepath.Join(dir, "model.json"))
+	if err != nil {
+		return RegistryEntry{}, err
+	}
+	entry := RegistryEntry{Name: name, Version: version, Metadata: model.Metadata()}
+	data, err := os.ReadFile(filepath.Join(dir, "metrics.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return entry, nil
+	}
+	if err != nil {
+		return RegistryEntry{}, err
+	}
+	if err := json.Unmarshal(data, &entry.Metrics); err != nil {
+		return RegistryEntry{}, fmt.Errorf("%s v%d: métricas: %w", name, version, err)
+	}
+	return entry, nil
+}
+
+// List devuelve todas las versiones de todos los modelos; los directorios cuyo nombre
+// no es un nombre de modelo válido se ignoran
+func (r Registry) List() ([]RegistryEntry, error) {
+	names, err := os.ReadDir(r.Root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var list []RegistryEntry
+	for _, n := range names {
+		if !n.IsDir() || checkName(n.Name()) != nil {
+			continue
+		}
+		versions, err := r.Versions(n.Name())
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			entry, err := r.Entry(n.Name(), v)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, entry)
+		}
+	}
+	return list, nil
+}
+
+// ByMetric devuelve las versiones que registraron metric, de mayor a menor valor; con
+// name no vacío solo las de ese modelo
+func (r Registry) ByMetric(name, metric string) ([]RegistryEntry, error) {
+	list, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	var out []RegistryEntry
+	for _, entry := range list {
+		if _, ok := entry.Metrics[metric]; ok && (name == "" || entry.Name == name) {
+			out = append(out, entry)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Metrics[metric] > out[j].Metrics[metric] })
+	return out, nil
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/iStorm30/PCDTA2/pcdta"
+)
+
+func TestRegistryMetricsAndNames(t *testing.T) {
+	dir := t.TempDir()
+	modelFile := filepath.Join(dir, "m.json")
+	tree := &pcdta.DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 1}}
+	if err := pcdta.SaveModel(pcdta.NewModel(tree, nil, []string{"x"}, nil), modelFile); err != nil {
+		t.Fatal(err)
+	}
+
+	r := Registry{Root: filepath.Join(dir, "registro")}
+	for _, accuracy := range []float64{0.8, 0.9, 0.7} {
+		if _, err := r.Push("m", modelFile, map[string]float64{"accuracy": accuracy}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Push("m", modelFile, nil); err != nil {
+		t.Fatal(err)
+	}
+	best, err := r.ByMetric("m", "accuracy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(best) != 3 || best[0].Version != 2 || best[0].Metrics["accuracy"] != 0.9 {
+		t.Errorf("ByMetric = %+v, se esperaban 3 versiones con v2 primero", best)
+	}
+
+	for _, name := range []string{"", "..", "../fuera", "a/b", `a\\b`, "m:1"} {
+		if _, err := r.Push(name, modelFile, nil); err == nil {
+			t.Errorf("Push(%q) no devolvió error", name)
+		}
+		if _, err := r.Pull(name, 0, filepath.Join(dir, "x.json")); err == nil {
+			t.Errorf("Pull(%q) no devolvió error", name)
+		}
+	}
+}
+
+func TestRegistryConcurrentPushes(t *testing.T) {
+	dir := t.TempDir()
+	modelFile := filepath.Join(dir, "m.json")
+	tree := &pcdta.DecisionTree[string]{Class: "a", Counts: map[string]int{"a": 1}}
+	if err := pcdta.SaveModel(pcdta.NewModel(tree, nil, []string{"x"}, nil), modelFile); err != nil {
+		t.Fatal(err)
+	}
+
+	r := Registry{Root: filepath.Join(dir, "registro")}
+	const pushes = 8
+	versions := make([]int, pushes)
+	errs := make([]error, pushes)
+	var wg sync.WaitGroup
+	for i := range pushes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			versions[i], errs[i] = r.Push("m", modelFile, nil)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	slices.Sort(versions)
+	for i, v := range versions {
+		if v != i+1 {
+			t.Fatalf("versiones %v, se esperaban 1..%d sin repetir", versions, pushes)
+		}
+	}
+	if stored, err := r.Versions("m"); err != nil || len(stored) != pushes {
+		t.Errorf("Versions = %v, %v", stored, err)
+	}
+}